@@ -0,0 +1,47 @@
+package application
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wosai/deepmock/misc"
+)
+
+const (
+	metricsNamespace = "deepmock"
+
+	matchResultMatched   = "matched"
+	matchResultUnmatched = "unmatched"
+)
+
+var (
+	mockedRequestsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "mocked_requests_total",
+			Help:      "Total number of mocked requests, labeled by matched rule id and response status code.",
+		},
+		[]string{"rule_id", "status_code"},
+	)
+
+	ruleMatchCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "rule_match_total",
+			Help:      "Total number of rule lookups, labeled by whether a rule was matched.",
+		},
+		[]string{"result"},
+	)
+
+	renderDurationHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "render_duration_seconds",
+			Help:      "Time spent on rendering the mocked response.",
+			Buckets:   prometheus.ExponentialBuckets(0.00005, 2, 12),
+		},
+		[]string{"rule_id"},
+	)
+)
+
+func init() {
+	misc.MetricsRegistry.MustRegister(mockedRequestsCounter, ruleMatchCounter, renderDurationHistogram)
+}
@@ -0,0 +1,53 @@
+package application
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	upstreamTimeout = 30 * time.Second
+)
+
+type (
+	// upstreamProxy 将未匹配任何规则的请求转发到真实的上游服务
+	upstreamProxy struct {
+		base   string
+		host   string
+		client *fasthttp.Client
+	}
+)
+
+// newUpstreamProxy 工厂函数，upstream为上游服务的base url，为空时返回nil，即不转发
+func newUpstreamProxy(upstream string) (*upstreamProxy, error) {
+	if upstream == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(upstream)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("bad upstream url: " + upstream)
+	}
+	return &upstreamProxy{
+		base:   strings.TrimRight(upstream, "/"),
+		host:   u.Host,
+		client: &fasthttp.Client{ReadTimeout: upstreamTimeout, WriteTimeout: upstreamTimeout},
+	}, nil
+}
+
+// forward 转发请求，并将上游的响应原样写回
+func (up *upstreamProxy) forward(ctx *fasthttp.RequestCtx) error {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	ctx.Request.CopyTo(req)
+	req.SetRequestURI(up.base + string(ctx.Request.RequestURI()))
+	req.Header.SetHost(up.host)
+	return up.client.DoTimeout(req, &ctx.Response, upstreamTimeout)
+}
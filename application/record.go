@@ -0,0 +1,82 @@
+package application
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"regexp"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/valyala/fasthttp"
+	"github.com/wosai/deepmock/misc"
+	"github.com/wosai/deepmock/types"
+	"go.uber.org/zap"
+)
+
+var (
+	// recordSkippedHeaders 录制时忽略的响应头，由deepmock在返回时重新生成
+	recordSkippedHeaders = map[string]struct{}{
+		fasthttp.HeaderContentLength:    {},
+		fasthttp.HeaderConnection:       {},
+		fasthttp.HeaderTransferEncoding: {},
+		fasthttp.HeaderDate:             {},
+		fasthttp.HeaderServer:           {},
+	}
+)
+
+// newRecordedRule 将转发到上游的请求及其响应转换为规则，非UTF-8的响应报文以base64编码保存
+func newRecordedRule(ctx *fasthttp.RequestCtx) *types.RuleDTO {
+	tmpl := &types.TemplateDTO{
+		Header:     make(map[string]misc.StringValues),
+		StatusCode: ctx.Response.StatusCode(),
+	}
+	ctx.Response.Header.VisitAll(func(key, value []byte) {
+		if _, skipped := recordSkippedHeaders[string(key)]; skipped {
+			return
+		}
+		tmpl.Header[string(key)] = append(tmpl.Header[string(key)], string(value))
+	})
+	if body := ctx.Response.Body(); utf8.Valid(body) {
+		tmpl.Body = string(body)
+	} else {
+		tmpl.B64EncodeBody = base64.StdEncoding.EncodeToString(body)
+	}
+
+	return &types.RuleDTO{
+		Path:   "^" + regexp.QuoteMeta(string(ctx.Request.URI().Path())) + "$",
+		Method: string(ctx.Request.Header.Method()),
+		Regulations: []*types.RegulationDTO{
+			{IsDefault: true, Template: tmpl},
+		},
+	}
+}
+
+// record 录制模式下，将上游的响应保存为规则
+func (srv *mockApplication) record(index uint64, ctx *fasthttp.RequestCtx) {
+	rid, err := srv.CreateRule(context.TODO(), newRecordedRule(ctx))
+	if err != nil {
+		misc.Logger.Warn("failed to record upstream response as rule", zap.Uint64("index", index), zap.String("rule_id", rid), zap.Error(err))
+		return
+	}
+	misc.Logger.Info("recorded upstream response as rule", zap.Uint64("index", index), zap.String("rule_id", rid))
+}
+
+// SetRecording 开启或关闭录制模式，开启时需要配置上游服务
+func (srv *mockApplication) SetRecording(_ context.Context, enabled bool) error {
+	if enabled && srv.upstream == nil {
+		return errors.New("record mode requires an upstream")
+	}
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&srv.recording, v)
+	misc.Logger.Info("switched record mode", zap.Bool("enabled", enabled))
+	return nil
+}
+
+// Recording 是否处于录制模式
+func (srv *mockApplication) Recording(_ context.Context) bool {
+	return atomic.LoadInt32(&srv.recording) == 1
+}
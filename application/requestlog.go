@@ -0,0 +1,79 @@
+package application
+
+import (
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"github.com/wosai/deepmock/types"
+)
+
+type (
+	// requestLog 定长的环形缓冲区，保留最近收到的mock请求
+	requestLog struct {
+		records []*types.RequestLogDTO
+		next    int
+		full    bool
+		mu      sync.Mutex
+	}
+)
+
+func newRequestLog(size int) *requestLog {
+	if size <= 0 {
+		return nil
+	}
+	return &requestLog{records: make([]*types.RequestLogDTO, size)}
+}
+
+func newRequestLogRecord(req *fasthttp.Request) *types.RequestLogDTO {
+	record := &types.RequestLogDTO{
+		Time:       time.Now(),
+		Method:     string(req.Header.Method()),
+		Path:       string(req.URI().Path()),
+		Query:      string(req.URI().QueryString()),
+		Header:     make(map[string]string),
+		Body:       string(req.Body()),
+		Regulation: -1,
+	}
+	req.Header.VisitAll(func(key, value []byte) {
+		record.Header[string(key)] = string(value)
+	})
+	return record
+}
+
+// add 写入一条记录，缓冲区已满时覆盖最旧的记录
+func (rl *requestLog) add(record *types.RequestLogDTO) {
+	if rl == nil {
+		return
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.records[rl.next] = record
+	rl.next++
+	if rl.next == len(rl.records) {
+		rl.next = 0
+		rl.full = true
+	}
+}
+
+// list 按时间先后返回缓冲区中的所有记录
+func (rl *requestLog) list() []*types.RequestLogDTO {
+	if rl == nil {
+		return []*types.RequestLogDTO{}
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if !rl.full {
+		ret := make([]*types.RequestLogDTO, rl.next)
+		copy(ret, rl.records[:rl.next])
+		return ret
+	}
+	ret := make([]*types.RequestLogDTO, 0, len(rl.records))
+	ret = append(ret, rl.records[rl.next:]...)
+	ret = append(ret, rl.records[:rl.next]...)
+	return ret
+}
@@ -2,14 +2,21 @@ package application
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
-	"net/http"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/valyala/fasthttp"
 	"github.com/wosai/deepmock/domain"
 	"github.com/wosai/deepmock/misc"
+	"github.com/wosai/deepmock/option"
 	"github.com/wosai/deepmock/types"
 	"go.uber.org/zap"
 )
@@ -22,6 +29,9 @@ var (
 	ErrRuleNotFound = errors.New("rule not found")
 )
 
+// HeaderRuleVersion 响应头，命中规则的版本号，规则每次更新后递增
+const HeaderRuleVersion = "X-Mock-Rule-Version"
+
 type (
 	// AsyncJob 异步的摆渡任务接口定义
 	AsyncJob interface {
@@ -32,36 +42,96 @@ type (
 	}
 
 	mockApplication struct {
-		rule     domain.RuleRepository
-		executor domain.ExecutorRepository
-		job      AsyncJob
-		counter  uint64
+		rule        domain.RuleRepository
+		executor    domain.ExecutorRepository
+		job         AsyncJob
+		counter     uint64 // 收到的mock请求总数，同时作为请求序号
+		unmatched   uint64 // 未匹配任何规则的mock请求数
+		requests    *requestLog
+		concurrency *domain.ConcurrencyLimiter
+		upstream    *upstreamProxy
+		recording   int32
+		conflict    string
+		maxDecoded  int // 解压后请求body的大小上限
+		startedAt   time.Time
+		stop        chan struct{} // 关闭后停止后台的定时任务
+		closeOnce   sync.Once
 	}
 )
 
 // BuildMockApplication mockApplication的工厂函数
-func BuildMockApplication(rr domain.RuleRepository, er domain.ExecutorRepository, job AsyncJob) *mockApplication {
-	MockApplication = &mockApplication{rule: rr, executor: er, job: job}
-	go func() {
+func BuildMockApplication(rr domain.RuleRepository, er domain.ExecutorRepository, job AsyncJob, opt option.MockOption) *mockApplication {
+	upstream, err := newUpstreamProxy(opt.Upstream)
+	if err != nil {
+		misc.Logger.Panic("failed to parse upstream url", zap.String("upstream", opt.Upstream), zap.Error(err))
+	}
+
+	domain.SetAllowedTemplateFuncs(opt.TemplateFuncs...)
+	domain.SetSniffBinaryBody(opt.SniffBinaryBody)
+	domain.SetBodyFileDir(opt.BodyFileDir)
+	domain.SetTimeRandSeed(opt.TimeRandSeed)
+	domain.SetBoolRandSeed(opt.BoolRandSeed)
+	if err := domain.SetDefaultCompress(opt.Compress, opt.CompressMinSize); err != nil {
+		misc.Logger.Panic("failed to set default compress", zap.String("compress", opt.Compress), zap.Error(err))
+	}
+	MockApplication = &mockApplication{
+		rule:        rr,
+		executor:    er,
+		job:         job,
+		requests:    newRequestLog(opt.RequestLogSize),
+		concurrency: domain.NewConcurrencyLimiter(opt.MaxConcurrency),
+		upstream:    upstream,
+		conflict:    opt.RuleConflict,
+		maxDecoded:  opt.MaxDecodedBodySize,
+		startedAt:   time.Now(),
+		stop:        make(chan struct{}),
+	}
+	if err := MockApplication.SetRecording(context.TODO(), opt.Record); err != nil {
+		misc.Logger.Panic("failed to enable record mode", zap.Error(err))
+	}
+	if opt.RuleSweepInterval > 0 {
+		go MockApplication.sweepExpiredRules(opt.RuleSweepInterval)
+	}
+	go func(stop <-chan struct{}) {
 		job.WithRuleRepository(rr)
 		job.WithExecutorRepository(er)
 		t := time.NewTicker(job.Period())
-		for range t.C {
+		defer t.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+			}
 			misc.Logger.Info("async job complete")
 			if err := job.Do(); err != nil {
 				misc.Logger.Error("occur error on job", zap.Error(err))
 			}
 		}
-	}()
+	}(MockApplication.stop)
 	return MockApplication
 }
 
+// Close 停止后台的同步任务与过期规则清理任务，可以重复调用
+func (srv *mockApplication) Close() {
+	srv.closeOnce.Do(func() { close(srv.stop) })
+}
+
 func convertRuleDTO(rule *types.RuleDTO) *domain.Rule {
 	r := &domain.Rule{
-		ID:       rule.ID,
-		Path:     rule.Path,
-		Method:   rule.Method,
-		Variable: rule.Variable,
+		ID:             rule.ID,
+		Path:           rule.Path,
+		PathType:       rule.PathType,
+		Host:           rule.Host,
+		HostType:       rule.HostType,
+		Method:         rule.Method,
+		Variable:       rule.Variable,
+		Enabled:        rule.Enabled,
+		Debug:          rule.Debug,
+		RateLimit:      rule.RateLimit,
+		MaxConcurrency: rule.MaxConcurrency,
+		TTLSeconds:     rule.TTLSeconds,
+		Priority:       rule.Priority,
 	}
 	if rule.Weight != nil {
 		r.Weight = make(map[string]domain.WeightFactor)
@@ -70,6 +140,50 @@ func convertRuleDTO(rule *types.RuleDTO) *domain.Rule {
 		}
 	}
 
+	if rule.CORS != nil {
+		r.CORS = &domain.CORS{
+			AllowOrigins: rule.CORS.AllowOrigins,
+			AllowMethods: rule.CORS.AllowMethods,
+			AllowHeaders: rule.CORS.AllowHeaders,
+			MaxAge:       rule.CORS.MaxAge,
+		}
+	}
+
+	if rule.SlowStart != nil {
+		r.SlowStart = &domain.SlowStart{
+			DelayMS:  rule.SlowStart.DelayMS,
+			Requests: rule.SlowStart.Requests,
+			Seconds:  rule.SlowStart.Seconds,
+		}
+	}
+
+	if rule.Duplicate != nil {
+		r.Duplicate = &domain.Duplicate{WindowSeconds: rule.Duplicate.WindowSeconds}
+		if rule.Duplicate.Response != nil {
+			r.Duplicate.Response = convertTemplateDTO(rule.Duplicate.Response)
+		}
+	}
+
+	r.ResponseSchema = rule.ResponseSchema
+
+	if rule.StatusDelay != nil {
+		r.StatusDelay = &domain.StatusDelay{DelayMS: rule.StatusDelay.DelayMS, StatusCodes: rule.StatusDelay.StatusCodes}
+	}
+
+	if rule.Maintenance != nil {
+		r.Maintenance = &domain.Maintenance{Windows: make([]*domain.MaintenanceWindow, len(rule.Maintenance.Windows))}
+		for i, w := range rule.Maintenance.Windows {
+			r.Maintenance.Windows[i] = &domain.MaintenanceWindow{Start: w.Start, End: w.End, Weekdays: w.Weekdays}
+		}
+		if rule.Maintenance.Response != nil {
+			r.Maintenance.Response = convertTemplateDTO(rule.Maintenance.Response)
+		}
+	}
+
+	if rule.OverflowResponse != nil {
+		r.OverflowResponse = convertTemplateDTO(rule.OverflowResponse)
+	}
+
 	r.Regulations = make([]*domain.Regulation, len(rule.Regulations))
 
 	for index, regulation := range rule.Regulations {
@@ -79,34 +193,101 @@ func convertRuleDTO(rule *types.RuleDTO) *domain.Rule {
 }
 
 func convertRegulationDTO(reg *types.RegulationDTO) *domain.Regulation {
-	r := &domain.Regulation{IsDefault: reg.IsDefault}
+	r := &domain.Regulation{IsDefault: reg.IsDefault, ReflectHeaders: reg.ReflectHeaders}
 	if reg.Filter != nil {
 		r.Filter = &domain.Filter{
-			Query:  reg.Filter.Query,
-			Header: reg.Filter.Header,
-			Body:   reg.Filter.Body,
+			Query:      reg.Filter.Query,
+			Form:       reg.Filter.Form,
+			RawQuery:   reg.Filter.RawQuery,
+			Header:     reg.Filter.Header,
+			Cookie:     reg.Filter.Cookie,
+			Body:       reg.Filter.Body,
+			Expression: reg.Filter.Expression,
+		}
+		if cl := reg.Filter.ContentLength; cl != nil {
+			r.Filter.ContentLength = &domain.ContentLengthFilterParams{Min: cl.Min, Max: cl.Max, AllowUnknown: cl.AllowUnknown}
+		}
+		if ps := reg.Filter.PathSegments; ps != nil {
+			r.Filter.PathSegments = &domain.PathSegmentsFilterParams{Exact: ps.Exact, Min: ps.Min, Max: ps.Max}
+		}
+		if up := reg.Filter.Upload; up != nil {
+			r.Filter.Upload = &domain.UploadFilterParams{Field: up.Field, ContentType: up.ContentType}
+		}
+		if ua := reg.Filter.UserAgent; ua != nil {
+			r.Filter.UserAgent = &domain.UserAgentFilterParams{Clients: ua.Clients}
+			for _, pattern := range ua.Patterns {
+				if pattern != nil {
+					r.Filter.UserAgent.Patterns = append(r.Filter.UserAgent.Patterns, &domain.UserAgentPattern{Client: pattern.Client, Regex: pattern.Regex})
+				}
+			}
+		}
+		if sample := reg.Filter.Sample; sample != nil {
+			r.Filter.Sample = &domain.SampleFilterParams{Source: sample.Source, Key: sample.Key, Percent: sample.Percent, Salt: sample.Salt}
 		}
 	}
 	if reg.Template != nil {
-		r.Template = &domain.Template{
-			IsTemplate:     reg.Template.IsTemplate,
-			Header:         reg.Template.Header,
-			Body:           reg.Template.Body,
-			B64EncodedBody: reg.Template.B64EncodeBody,
+		r.Template = convertTemplateDTO(reg.Template)
+	}
+	if reg.Sequence != nil {
+		r.Sequence = &domain.Sequence{Loop: reg.Sequence.Loop, Responses: make([]*domain.Template, len(reg.Sequence.Responses))}
+		for index, tmpl := range reg.Sequence.Responses {
+			if tmpl != nil {
+				r.Sequence.Responses[index] = convertTemplateDTO(tmpl)
+			}
 		}
-		if reg.Template.StatusCode == 0 {
-			r.Template.StatusCode = http.StatusOK
+	}
+	if reg.Created != nil {
+		r.Created = &domain.Created{Location: reg.Created.Location, IDField: reg.Created.IDField, IDType: reg.Created.IDType}
+	}
+	if reg.Retry != nil {
+		r.Retry = &domain.Retry{Header: reg.Retry.Header, Responses: make([]*domain.Template, len(reg.Retry.Responses))}
+		for index, tmpl := range reg.Retry.Responses {
+			if tmpl != nil {
+				r.Retry.Responses[index] = convertTemplateDTO(tmpl)
+			}
 		}
 	}
 	return r
 }
 
+func convertTemplateDTO(tmpl *types.TemplateDTO) *domain.Template {
+	t := &domain.Template{
+		IsTemplate:     tmpl.IsTemplate,
+		Header:         tmpl.Header,
+		StatusCode:     tmpl.StatusCode,
+		StatusTemplate: tmpl.StatusTemplate,
+		Body:           tmpl.Body,
+		B64EncodedBody: tmpl.B64EncodeBody,
+		BodyFile:       tmpl.BodyFile,
+		Compress:       tmpl.Compress,
+		MultipartType:  tmpl.MultipartType,
+		Fragments:      tmpl.Fragments,
+		Locales:        tmpl.Locales,
+		DefaultLocale:  tmpl.DefaultLocale,
+	}
+	for _, part := range tmpl.Multipart {
+		t.Multipart = append(t.Multipart, &domain.Part{Name: part.Name, FileName: part.FileName, Header: part.Header, Body: part.Body})
+	}
+	return t
+}
+
 func convertRuleEntity(rule *domain.Rule) *types.RuleDTO {
+	version := rule.Version
 	r := &types.RuleDTO{
-		ID:       rule.ID,
-		Path:     rule.Path,
-		Method:   rule.Method,
-		Variable: rule.Variable,
+		ID:             rule.ID,
+		Path:           rule.Path,
+		PathType:       rule.PathType,
+		Host:           rule.Host,
+		HostType:       rule.HostType,
+		Method:         rule.Method,
+		Version:        &version,
+		Variable:       rule.Variable,
+		Enabled:        rule.Enabled,
+		Debug:          rule.Debug,
+		RateLimit:      rule.RateLimit,
+		MaxConcurrency: rule.MaxConcurrency,
+		TTLSeconds:     rule.TTLSeconds,
+		Priority:       rule.Priority,
 	}
 	if rule.Weight != nil {
 		r.Weight = make(types.WeightDTO)
@@ -115,6 +296,47 @@ func convertRuleEntity(rule *domain.Rule) *types.RuleDTO {
 		}
 	}
 
+	if rule.CORS != nil {
+		r.CORS = &types.CORSDTO{
+			AllowOrigins: rule.CORS.AllowOrigins,
+			AllowMethods: rule.CORS.AllowMethods,
+			AllowHeaders: rule.CORS.AllowHeaders,
+			MaxAge:       rule.CORS.MaxAge,
+		}
+	}
+
+	if rule.SlowStart != nil {
+		r.SlowStart = &types.SlowStartDTO{
+			DelayMS:  rule.SlowStart.DelayMS,
+			Requests: rule.SlowStart.Requests,
+			Seconds:  rule.SlowStart.Seconds,
+		}
+	}
+
+	if rule.OverflowResponse != nil {
+		r.OverflowResponse = convertTemplateVO(rule.OverflowResponse)
+	}
+
+	if rule.Duplicate != nil {
+		r.Duplicate = &types.DuplicateDTO{WindowSeconds: rule.Duplicate.WindowSeconds}
+		if rule.Duplicate.Response != nil {
+			r.Duplicate.Response = convertTemplateVO(rule.Duplicate.Response)
+		}
+	}
+	r.ResponseSchema = rule.ResponseSchema
+	if rule.StatusDelay != nil {
+		r.StatusDelay = &types.StatusDelayDTO{DelayMS: rule.StatusDelay.DelayMS, StatusCodes: rule.StatusDelay.StatusCodes}
+	}
+	if rule.Maintenance != nil {
+		r.Maintenance = &types.MaintenanceDTO{Windows: make([]*types.MaintenanceWindowDTO, len(rule.Maintenance.Windows))}
+		for i, w := range rule.Maintenance.Windows {
+			r.Maintenance.Windows[i] = &types.MaintenanceWindowDTO{Start: w.Start, End: w.End, Weekdays: w.Weekdays}
+		}
+		if rule.Maintenance.Response != nil {
+			r.Maintenance.Response = convertTemplateVO(rule.Maintenance.Response)
+		}
+	}
+
 	r.Regulations = make([]*types.RegulationDTO, len(rule.Regulations))
 	for index, regulation := range rule.Regulations {
 		r.Regulations[index] = convertRegulationVO(regulation)
@@ -122,23 +344,78 @@ func convertRuleEntity(rule *domain.Rule) *types.RuleDTO {
 	return r
 }
 
+func convertTemplateVO(tmpl *domain.Template) *types.TemplateDTO {
+	t := &types.TemplateDTO{
+		IsTemplate:     tmpl.IsTemplate,
+		Header:         tmpl.Header,
+		StatusCode:     tmpl.StatusCode,
+		StatusTemplate: tmpl.StatusTemplate,
+		Body:           tmpl.Body,
+		B64EncodeBody:  tmpl.B64EncodedBody,
+		BodyFile:       tmpl.BodyFile,
+		Compress:       tmpl.Compress,
+		MultipartType:  tmpl.MultipartType,
+		Fragments:      tmpl.Fragments,
+		Locales:        tmpl.Locales,
+		DefaultLocale:  tmpl.DefaultLocale,
+	}
+	for _, part := range tmpl.Multipart {
+		t.Multipart = append(t.Multipart, &types.PartDTO{Name: part.Name, FileName: part.FileName, Header: part.Header, Body: part.Body})
+	}
+	return t
+}
+
 func convertRegulationVO(reg *domain.Regulation) *types.RegulationDTO {
 	r := &types.RegulationDTO{
-		IsDefault: reg.IsDefault,
-		Template: &types.TemplateDTO{
-			IsTemplate:    reg.Template.IsTemplate,
-			Header:        reg.Template.Header,
-			StatusCode:    reg.Template.StatusCode,
-			Body:          reg.Template.Body,
-			B64EncodeBody: reg.Template.B64EncodedBody,
-		},
+		IsDefault:      reg.IsDefault,
+		ReflectHeaders: reg.ReflectHeaders,
+	}
+	if reg.Template != nil {
+		r.Template = convertTemplateVO(reg.Template)
+	}
+	if reg.Sequence != nil {
+		r.Sequence = &types.SequenceDTO{Loop: reg.Sequence.Loop, Responses: make([]*types.TemplateDTO, len(reg.Sequence.Responses))}
+		for index, tmpl := range reg.Sequence.Responses {
+			r.Sequence.Responses[index] = convertTemplateVO(tmpl)
+		}
+	}
+	if reg.Created != nil {
+		r.Created = &types.CreatedDTO{Location: reg.Created.Location, IDField: reg.Created.IDField, IDType: reg.Created.IDType}
+	}
+	if reg.Retry != nil {
+		r.Retry = &types.RetryDTO{Header: reg.Retry.Header, Responses: make([]*types.TemplateDTO, len(reg.Retry.Responses))}
+		for index, tmpl := range reg.Retry.Responses {
+			r.Retry.Responses[index] = convertTemplateVO(tmpl)
+		}
 	}
 
 	if reg.Filter != nil {
 		r.Filter = &types.FilterDTO{
-			Header: reg.Filter.Header,
-			Query:  reg.Filter.Query,
-			Body:   reg.Filter.Body,
+			Header:     reg.Filter.Header,
+			Cookie:     reg.Filter.Cookie,
+			Query:      reg.Filter.Query,
+			Form:       reg.Filter.Form,
+			RawQuery:   reg.Filter.RawQuery,
+			Body:       reg.Filter.Body,
+			Expression: reg.Filter.Expression,
+		}
+		if cl := reg.Filter.ContentLength; cl != nil {
+			r.Filter.ContentLength = &types.ContentLengthFilterDTO{Min: cl.Min, Max: cl.Max, AllowUnknown: cl.AllowUnknown}
+		}
+		if ps := reg.Filter.PathSegments; ps != nil {
+			r.Filter.PathSegments = &types.PathSegmentsFilterDTO{Exact: ps.Exact, Min: ps.Min, Max: ps.Max}
+		}
+		if up := reg.Filter.Upload; up != nil {
+			r.Filter.Upload = &types.UploadFilterDTO{Field: up.Field, ContentType: up.ContentType}
+		}
+		if ua := reg.Filter.UserAgent; ua != nil {
+			r.Filter.UserAgent = &types.UserAgentFilterDTO{Clients: ua.Clients}
+			for _, pattern := range ua.Patterns {
+				r.Filter.UserAgent.Patterns = append(r.Filter.UserAgent.Patterns, &types.UserAgentPatternDTO{Client: pattern.Client, Regex: pattern.Regex})
+			}
+		}
+		if sample := reg.Filter.Sample; sample != nil {
+			r.Filter.Sample = &types.SampleFilterDTO{Source: sample.Source, Key: sample.Key, Percent: sample.Percent, Salt: sample.Salt}
 		}
 	}
 	return r
@@ -147,11 +424,20 @@ func convertRegulationVO(reg *domain.Regulation) *types.RegulationDTO {
 // CreateRule 创建规则的user case
 func (srv *mockApplication) CreateRule(ctx context.Context, rule *types.RuleDTO) (string, error) {
 	ru := convertRuleDTO(rule)
+	ru.CreatedAt = domain.Now()
 	rid, _ := ru.SupplyID()
 	if err := ru.Validate(); err != nil {
 		misc.Logger.Error("failed to validate rule content", zap.Error(err))
 		return rid, err
 	}
+	// 提前解析所有的模板，避免保存在请求时才会报错的规则
+	if _, err := ru.To(); err != nil {
+		misc.Logger.Error("failed to compile rule", zap.Error(err))
+		return rid, err
+	}
+	if err := srv.checkConflict(ctx, ru); err != nil {
+		return rid, err
+	}
 
 	if err := srv.rule.CreateRule(ctx, ru); err != nil {
 		misc.Logger.Error("failed to create rule record", zap.Error(err))
@@ -161,6 +447,56 @@ func (srv *mockApplication) CreateRule(ctx context.Context, rule *types.RuleDTO)
 	return rid, nil
 }
 
+// checkConflict 按配置检测新规则是否与已有规则重叠，reject模式下返回第一条重叠规则的错误
+func (srv *mockApplication) checkConflict(ctx context.Context, ru *domain.Rule) error {
+	if srv.conflict != option.RuleConflictWarn && srv.conflict != option.RuleConflictReject {
+		return nil
+	}
+	rules, err := srv.exportUnexpired(ctx)
+	if err != nil {
+		misc.Logger.Error("failed to export rules for conflict detection", zap.Error(err))
+		return err
+	}
+	for _, rule := range rules {
+		if !ru.Overlaps(rule) {
+			continue
+		}
+		if srv.conflict == option.RuleConflictReject {
+			misc.Logger.Error("rejected rule overlapping with existing rule", zap.String("rule_id", ru.ID), zap.String("conflict_rule_id", rule.ID))
+			return fmt.Errorf("%w: %s %s overlaps with existing rule %s", domain.ErrRuleConflict, ru.Method, ru.Path, rule.ID)
+		}
+		misc.Logger.Warn("rule overlaps with existing rule", zap.String("rule_id", ru.ID), zap.String("conflict_rule_id", rule.ID))
+	}
+	return nil
+}
+
+// sweepExpiredRules 定期从执行器存储库中移除过期的执行器，并删除对应的规则，直到Close
+func (srv *mockApplication) sweepExpiredRules(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-srv.stop:
+			return
+		case <-t.C:
+			srv.SweepExpiredRules(context.TODO(), domain.Now())
+		}
+	}
+}
+
+// SweepExpiredRules 清理过期规则的user case，返回被清理的规则ID
+func (srv *mockApplication) SweepExpiredRules(ctx context.Context, now time.Time) []string {
+	expired := srv.executor.RemoveExpired(ctx, now)
+	for _, rid := range expired {
+		if err := srv.rule.DeleteRule(ctx, rid); err != nil {
+			misc.Logger.Error("failed to delete expired rule", zap.String("rule_id", rid), zap.Error(err))
+			continue
+		}
+		misc.Logger.Info("deleted expired rule", zap.String("rule_id", rid))
+	}
+	return expired
+}
+
 // GetRule 获取规则的user case
 func (srv *mockApplication) GetRule(ctx context.Context, rid string) (*types.RuleDTO, error) {
 	re, err := srv.rule.GetRuleByID(ctx, rid)
@@ -168,6 +504,9 @@ func (srv *mockApplication) GetRule(ctx context.Context, rid string) (*types.Rul
 		misc.Logger.Error("failed to find rule record", zap.String("rule_id", rid), zap.Error(err))
 		return nil, err
 	}
+	if re.Expired(domain.Now()) { // 已过期但尚未被清理
+		return nil, ErrRuleNotFound
+	}
 	if err := re.Validate(); err != nil {
 		misc.Logger.Error("failed to validate rule content", zap.String("rule_id", rid), zap.Error(err))
 		return nil, err
@@ -193,11 +532,23 @@ func (srv *mockApplication) PutRule(ctx context.Context, rule *types.RuleDTO) er
 		return err
 	}
 
+	// 未提供版本号时不校验
+	if rule.Version != nil {
+		if err := or.CheckVersion(*rule.Version); err != nil {
+			misc.Logger.Error("refused to put rule with stale version", zap.String("rule_id", rule.ID), zap.Error(err))
+			return err
+		}
+	}
+
 	nr := convertRuleDTO(rule)
 	if err := or.Put(nr); err != nil {
 		misc.Logger.Error("failed to validate rule after put", zap.String("rule_id", rule.ID), zap.Error(err))
 		return err
 	}
+	if _, err := or.To(); err != nil {
+		misc.Logger.Error("failed to compile rule after put", zap.String("rule_id", rule.ID), zap.Error(err))
+		return err
+	}
 	if err := srv.rule.UpdateRule(ctx, or); err != nil {
 		misc.Logger.Error("failed to update rule record", zap.String("rule_id", rule.ID), zap.Error(err))
 		return err
@@ -214,11 +565,23 @@ func (srv *mockApplication) PatchRule(ctx context.Context, rule *types.RuleDTO)
 		return err
 	}
 
+	// 未提供版本号时不校验
+	if rule.Version != nil {
+		if err := or.CheckVersion(*rule.Version); err != nil {
+			misc.Logger.Error("refused to patch rule with stale version", zap.String("rule_id", rule.ID), zap.Error(err))
+			return err
+		}
+	}
+
 	nr := convertRuleDTO(rule)
 	if err := or.Patch(nr); err != nil {
 		misc.Logger.Error("failed to validate rule after patch", zap.String("rule_id", rule.ID), zap.Error(err))
 		return err
 	}
+	if _, err := or.To(); err != nil {
+		misc.Logger.Error("failed to compile rule after patch", zap.String("rule_id", rule.ID), zap.Error(err))
+		return err
+	}
 	if err := srv.rule.UpdateRule(ctx, or); err != nil {
 		misc.Logger.Error("failed to update rule record", zap.String("rule_id", rule.ID), zap.Error(err))
 		return err
@@ -229,7 +592,7 @@ func (srv *mockApplication) PatchRule(ctx context.Context, rule *types.RuleDTO)
 
 // Export 导出的user case
 func (srv *mockApplication) Export(ctx context.Context) ([]*types.RuleDTO, error) {
-	res, err := srv.rule.Export(ctx)
+	res, err := srv.exportUnexpired(ctx)
 	if err != nil {
 		misc.Logger.Error("failed to export rules", zap.Error(err))
 		return nil, err
@@ -246,12 +609,88 @@ func (srv *mockApplication) Export(ctx context.Context) ([]*types.RuleDTO, error
 	return rules, nil
 }
 
+// exportUnexpired 导出所有未过期的规则，已过期但尚未被清理的规则与mock请求一样视为不存在
+func (srv *mockApplication) exportUnexpired(ctx context.Context) ([]*domain.Rule, error) {
+	res, err := srv.rule.Export(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := domain.Now()
+	rules := make([]*domain.Rule, 0, len(res))
+	for _, re := range res {
+		if !re.Expired(now) {
+			rules = append(rules, re)
+		}
+	}
+	return rules, nil
+}
+
+// ListRuleSummaries 列出所有规则概要信息的user case，按path、method排序，不转换完整的规则定义
+func (srv *mockApplication) ListRuleSummaries(ctx context.Context) ([]*types.RuleSummaryDTO, error) {
+	res, err := srv.exportUnexpired(ctx)
+	if err != nil {
+		misc.Logger.Error("failed to list rules", zap.Error(err))
+		return nil, err
+	}
+	summaries := make([]*types.RuleSummaryDTO, len(res))
+	for index, re := range res {
+		summaries[index] = &types.RuleSummaryDTO{ID: re.ID, Path: re.Path, Method: re.Method}
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Path != summaries[j].Path {
+			return summaries[i].Path < summaries[j].Path
+		}
+		return summaries[i].Method < summaries[j].Method
+	})
+	return summaries, nil
+}
+
+// ListRules 按条件分页查询规则的user case，规则按path、method排序
+func (srv *mockApplication) ListRules(ctx context.Context, query *types.RuleQueryDTO) (*types.RuleListDTO, error) {
+	if query.Offset < 0 || query.Limit < 0 {
+		return nil, errors.New("bad offset or limit")
+	}
+	rules, err := srv.Export(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]*types.RuleDTO, 0, len(rules))
+	for _, rule := range rules {
+		if query.Method != "" && !strings.EqualFold(query.Method, rule.Method) {
+			continue
+		}
+		if !strings.Contains(rule.Path, query.PathContains) {
+			continue
+		}
+		matched = append(matched, rule)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].Path != matched[j].Path {
+			return matched[i].Path < matched[j].Path
+		}
+		return matched[i].Method < matched[j].Method
+	})
+
+	list := &types.RuleListDTO{Total: len(matched), Rules: []*types.RuleDTO{}}
+	if query.Offset >= len(matched) {
+		return list, nil
+	}
+	end := len(matched)
+	if query.Limit > 0 && query.Offset+query.Limit < end {
+		end = query.Offset + query.Limit
+	}
+	list.Rules = matched[query.Offset:end]
+	return list, nil
+}
+
 // Import 导入规则的user case
 func (srv *mockApplication) Import(ctx context.Context, rules ...*types.RuleDTO) error {
 	res := make([]*domain.Rule, len(rules))
 	for index, rule := range rules {
 		ru := convertRuleDTO(rule)
-		if err := ru.Validate(); err != nil {
+		ru.CreatedAt = domain.Now()
+		if err := validateImportedRule(ru); err != nil {
 			misc.Logger.Error("failed to validate rule content", zap.String("rule_id", rule.ID), zap.Error(err))
 			return err
 		}
@@ -266,15 +705,439 @@ func (srv *mockApplication) Import(ctx context.Context, rules ...*types.RuleDTO)
 	return nil
 }
 
+// ValidateImport 对待导入的规则逐条执行完整校验并返回校验报告，不会保存任何规则
+func (srv *mockApplication) ValidateImport(_ context.Context, rules ...*types.RuleDTO) *types.ImportReportDTO {
+	report := &types.ImportReportDTO{Valid: true, Rules: make([]*types.RuleValidationDTO, len(rules))}
+	seen := make(map[string]int, len(rules))
+	for index, rule := range rules {
+		ru := convertRuleDTO(rule)
+		err := validateImportedRule(ru)
+		if first, exists := seen[ru.ID]; !exists {
+			seen[ru.ID] = index
+		} else if err == nil {
+			err = fmt.Errorf("duplicate rule with index %d", first)
+		}
+
+		result := &types.RuleValidationDTO{Index: index, ID: ru.ID, Path: rule.Path, Method: ru.Method, Valid: err == nil}
+		if err != nil {
+			result.Error = err.Error()
+			report.Valid = false
+		}
+		report.Rules[index] = result
+	}
+	return report
+}
+
+// validateImportedRule 校验规则并提前解析所有模板
+func validateImportedRule(ru *domain.Rule) error {
+	if err := ru.Validate(); err != nil {
+		return err
+	}
+	_, err := ru.To()
+	return err
+}
+
 // MockAPI Mock接口的user case
 func (srv *mockApplication) MockAPI(ctx *fasthttp.RequestCtx) error {
 	index := atomic.AddUint64(&srv.counter, 1)
 	misc.Logger.Info("received request", zap.Uint64("index", index), zap.ByteString("path", ctx.Request.URI().Path()), zap.ByteString("method", ctx.Request.Header.Method()))
-	exec, founded := srv.executor.FindExecutor(context.TODO(), ctx.Request.URI().Path(), ctx.Request.Header.Method())
+	decodeErr := domain.DecodeRequestBody(&ctx.Request, srv.maxDecoded)
+	record := newRequestLogRecord(&ctx.Request)
+	defer func() {
+		srv.requests.add(record)
+		mockedRequestsCounter.WithLabelValues(record.RuleID, strconv.Itoa(ctx.Response.StatusCode())).Inc()
+	}()
+	if decodeErr != nil {
+		misc.Logger.Warn("failed to decode request body", zap.Uint64("index", index), zap.Error(decodeErr))
+		renderBadRequestBody(ctx, decodeErr)
+		return nil
+	}
+
+	if !srv.concurrency.Acquire() {
+		misc.Logger.Warn("too many concurrent requests on server", zap.Uint64("index", index))
+		renderServiceUnavailable(ctx)
+		return nil
+	}
+	defer srv.concurrency.Release()
+
+	// 跨域预检请求优先交由其所声明方法的规则处理，在筛选报文规则之前直接响应
+	if domain.IsPreflight(&ctx.Request) {
+		exec, founded := srv.executor.FindExecutor(context.TODO(), ctx.Request.URI().Path(), ctx.Request.Header.Peek(fasthttp.HeaderAccessControlRequestMethod), ctx.Request.Header.Host())
+		if founded && exec.CORS != nil {
+			ruleMatchCounter.WithLabelValues(matchResultMatched).Inc()
+			misc.Logger.Info("responded to cors preflight request", zap.Uint64("index", index), zap.String("rule_id", exec.ID))
+			record.RuleID = exec.ID
+			exec.CORS.Preflight(ctx)
+			ctx.Response.Header.Set(HeaderRuleVersion, strconv.Itoa(exec.Version))
+			return nil
+		}
+	}
+
+	exec, founded := srv.executor.FindExecutor(context.TODO(), ctx.Request.URI().Path(), ctx.Request.Header.Method(), ctx.Request.Header.Host())
 	if !founded {
+		// 只统计确实未匹配到规则的请求，解码失败或超过并发上限而被拒绝的请求尚未进行匹配
+		atomic.AddUint64(&srv.unmatched, 1)
+		ruleMatchCounter.WithLabelValues(matchResultUnmatched).Inc()
+		if srv.upstream != nil {
+			if srv.forwardToUpstream(index, ctx) && srv.Recording(context.TODO()) {
+				srv.record(index, ctx)
+			}
+			return nil
+		}
 		misc.Logger.Warn("no matched rule founded", zap.Uint64("index", index))
 		return ErrRuleNotFound
 	}
+	ruleMatchCounter.WithLabelValues(matchResultMatched).Inc()
 	misc.Logger.Info("found matched rule", zap.Uint64("index", index), zap.String("rule_id", exec.ID))
-	return exec.FindRegulationExecutor(&ctx.Request).Render(ctx, exec.Variable, exec.Weight.DiceAll())
+	record.RuleID = exec.ID
+	// 各种响应都可能重置response，因此在返回前才写入规则版本
+	defer ctx.Response.Header.Set(HeaderRuleVersion, strconv.Itoa(exec.Version))
+
+	if ok, wait := exec.RateLimiter.Take(); !ok {
+		misc.Logger.Warn("request was rate limited", zap.Uint64("index", index), zap.String("rule_id", exec.ID))
+		renderTooManyRequests(ctx, wait)
+		return nil
+	}
+
+	if remaining, ok := exec.Maintenance.Remaining(); ok {
+		misc.Logger.Warn("rule is under maintenance", zap.Uint64("index", index), zap.String("rule_id", exec.ID))
+		if exec.Maintenance.Response == nil {
+			renderMaintenance(ctx, remaining)
+			return nil
+		}
+		ctx.Response.Reset()
+		err := exec.Maintenance.Response.Render(ctx, exec.Variable, exec.Weight.DiceAll())
+		if len(ctx.Response.Header.Peek("Retry-After")) == 0 {
+			ctx.Response.Header.Set("Retry-After", retryAfterSeconds(remaining))
+		}
+		return err
+	}
+
+	if exec.Duplicate.Seen(ctx.Request.Body()) {
+		misc.Logger.Warn("received duplicate submission", zap.Uint64("index", index), zap.String("rule_id", exec.ID))
+		return exec.Duplicate.Response.Render(ctx, exec.Variable, exec.Weight.DiceAll())
+	}
+
+	if !exec.Concurrency.Acquire() {
+		misc.Logger.Warn("too many concurrent requests on rule", zap.Uint64("index", index), zap.String("rule_id", exec.ID))
+		if exec.Overflow == nil {
+			renderServiceUnavailable(ctx)
+			return nil
+		}
+		ctx.Response.Reset()
+		return exec.Overflow.Render(ctx, exec.Variable, exec.Weight.DiceAll())
+	}
+	defer exec.Concurrency.Release()
+
+	if delay := exec.SlowStart.Delay(exec.Hits.Total()); delay > 0 {
+		time.Sleep(delay)
+	}
+
+	regulation := exec.FindRegulationExecutor(&ctx.Request)
+	record.Regulation = regulation.Index
+	exec.Hit(regulation)
+	if exec.Debug {
+		srv.debugRegulation(index, exec, regulation, &ctx.Request)
+	}
+
+	start := time.Now()
+	err := regulation.Render(ctx, exec.Variable, exec.Weight.DiceAll())
+	renderDurationHistogram.WithLabelValues(exec.ID).Observe(time.Since(start).Seconds())
+	if err == nil && exec.Debug {
+		if err := exec.ResponseSchema.Validate(&ctx.Response); err != nil {
+			misc.Logger.Error("rendered response violates schema", zap.Uint64("index", index), zap.String("rule_id", exec.ID), zap.Int("regulation", regulation.Index), zap.Error(err))
+			renderSchemaViolation(ctx, err)
+		}
+	}
+	// 渲染后才能确定状态码，因此在返回之前延迟
+	if delay := exec.StatusDelay.Delay(ctx.Response.StatusCode()); err == nil && delay > 0 {
+		time.Sleep(delay)
+	}
+	exec.CORS.Apply(ctx)
+	return err
+}
+
+// forwardToUpstream 将未匹配的请求转发到上游服务，转发失败时返回502
+func (srv *mockApplication) forwardToUpstream(index uint64, ctx *fasthttp.RequestCtx) bool {
+	if err := srv.upstream.forward(ctx); err != nil {
+		misc.Logger.Error("failed to forward request to upstream", zap.Uint64("index", index), zap.String("upstream", srv.upstream.base), zap.Error(err))
+		ctx.Response.Reset()
+		ctx.Response.SetStatusCode(fasthttp.StatusBadGateway)
+		ctx.Response.SetBodyString(fasthttp.StatusMessage(fasthttp.StatusBadGateway))
+		return false
+	}
+	misc.Logger.Info("forwarded unmatched request to upstream", zap.Uint64("index", index), zap.String("upstream", srv.upstream.base), zap.Int("status_code", ctx.Response.StatusCode()))
+	return true
+}
+
+// renderTooManyRequests 触发限流时返回429，Retry-After为向上取整的等待秒数
+func renderTooManyRequests(ctx *fasthttp.RequestCtx, wait time.Duration) {
+	ctx.Response.Reset()
+	ctx.Response.SetStatusCode(fasthttp.StatusTooManyRequests)
+	ctx.Response.Header.Set("Retry-After", retryAfterSeconds(wait))
+	ctx.Response.SetBodyString(fasthttp.StatusMessage(fasthttp.StatusTooManyRequests))
+}
+
+// renderMaintenance 规则处于维护窗口内且未配置响应时返回503，Retry-After为距维护结束向上取整的秒数
+func renderMaintenance(ctx *fasthttp.RequestCtx, remaining time.Duration) {
+	ctx.Response.Reset()
+	ctx.Response.SetStatusCode(fasthttp.StatusServiceUnavailable)
+	ctx.Response.Header.Set("Retry-After", retryAfterSeconds(remaining))
+	ctx.Response.SetBodyString(fasthttp.StatusMessage(fasthttp.StatusServiceUnavailable))
+}
+
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// renderServiceUnavailable 超出并发数上限时返回503
+func renderServiceUnavailable(ctx *fasthttp.RequestCtx) {
+	ctx.Response.Reset()
+	ctx.Response.SetStatusCode(fasthttp.StatusServiceUnavailable)
+	ctx.Response.SetBodyString(fasthttp.StatusMessage(fasthttp.StatusServiceUnavailable))
+}
+
+// renderBadRequestBody 请求body无法解压时返回400，超过解压后的大小上限时返回413
+func renderBadRequestBody(ctx *fasthttp.RequestCtx, err error) {
+	ctx.Response.Reset()
+	if errors.Is(err, domain.ErrDecodedBodyTooLarge) {
+		ctx.Response.SetStatusCode(fasthttp.StatusRequestEntityTooLarge)
+	} else {
+		ctx.Response.SetStatusCode(fasthttp.StatusBadRequest)
+	}
+	ctx.Response.SetBodyString("bad request body: " + err.Error())
+}
+
+// renderSchemaViolation 渲染结果不符合响应报文的JSON Schema时返回500，说明模板本身有问题
+func renderSchemaViolation(ctx *fasthttp.RequestCtx, err error) {
+	ctx.Response.Reset()
+	ctx.Response.SetStatusCode(fasthttp.StatusInternalServerError)
+	ctx.Response.SetBodyString("response violates schema: " + err.Error())
+}
+
+// debugRegulation 输出命中的报文规则，以及在其之前被跳过的报文规则所未通过的筛选器
+func (srv *mockApplication) debugRegulation(index uint64, exec *domain.Executor, chosen *domain.RegulationExecutor, req *fasthttp.Request) {
+	skipped := make(map[int]string)
+	for _, regulation := range exec.Regulations {
+		failed := regulation.Filter.Diagnose(req)
+		if failed == "" { // 第一个通过筛选的即为命中的报文规则
+			break
+		}
+		skipped[regulation.Index] = failed
+	}
+	misc.Logger.Info("debug regulation matching",
+		zap.Uint64("index", index),
+		zap.String("rule_id", exec.ID),
+		zap.Int("regulation", chosen.Index),
+		zap.Bool("is_default", chosen.IsDefault),
+		zap.Any("skipped", skipped),
+	)
+}
+
+// Hits 返回所有规则及其报文规则的命中次数
+func (srv *mockApplication) Hits(ctx context.Context) []*types.RuleHitsDTO {
+	executors := srv.executor.ListExecutors(ctx)
+	sort.Slice(executors, func(i, j int) bool { return executors[i].ID < executors[j].ID })
+
+	ret := make([]*types.RuleHitsDTO, len(executors))
+	for index, exec := range executors {
+		hits := &types.RuleHitsDTO{
+			RuleID:      exec.ID,
+			Path:        exec.Path.String(),
+			Method:      string(exec.Method),
+			Total:       exec.Hits.Total(),
+			LastHit:     lastHitTime(&exec.Hits),
+			Regulations: make([]*types.HitsDTO, len(exec.Regulations)),
+		}
+		for i, regulation := range exec.Regulations {
+			hits.Regulations[i] = &types.HitsDTO{Total: regulation.Hits.Total(), LastHit: lastHitTime(&regulation.Hits)}
+		}
+		ret[index] = hits
+	}
+	return ret
+}
+
+// ResetSequence 重置规则中所有顺序响应的进度
+func (srv *mockApplication) ResetSequence(ctx context.Context, rid string) error {
+	for _, exec := range srv.executor.ListExecutors(ctx) {
+		if exec.ID == rid {
+			exec.ResetSequences()
+			return nil
+		}
+	}
+	return ErrRuleNotFound
+}
+
+// TraceRule 使用模拟的请求依次评估规则中每个报文规则的筛选条件，并给出最终命中的报文规则，不计入命中次数
+func (srv *mockApplication) TraceRule(ctx context.Context, req *types.TraceRuleDTO) (*types.RuleTraceDTO, error) {
+	var exec *domain.Executor
+	for _, e := range srv.executor.ListExecutors(ctx) {
+		if e.ID == req.ID {
+			exec = e
+			break
+		}
+	}
+	if exec == nil {
+		return nil, ErrRuleNotFound
+	}
+
+	request := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(request)
+	request.Header.SetMethodBytes(exec.Method)
+	if req.Method != "" {
+		request.Header.SetMethod(strings.ToUpper(req.Method))
+	}
+	// 与method一样，未提供path时使用规则的path，否则path_segments等筛选条件会按"/"评估
+	path := req.Path
+	if path == "" {
+		rule, err := srv.rule.GetRuleByID(ctx, exec.ID)
+		if err != nil {
+			misc.Logger.Error("failed to get rule record", zap.String("rule_id", exec.ID), zap.Error(err))
+			return nil, err
+		}
+		path = rule.Path
+	}
+	request.URI().SetPath(path)
+	request.URI().SetQueryString(req.Query)
+	for k, v := range req.Header {
+		request.Header.Set(k, v)
+	}
+	request.SetBodyString(req.Body)
+
+	ret := &types.RuleTraceDTO{
+		RuleID:      exec.ID,
+		Regulation:  -1,
+		Regulations: make([]*types.RegulationTraceDTO, len(exec.Regulations)),
+	}
+	var matched bool
+	for index, regulation := range exec.Regulations {
+		rt := &types.RegulationTraceDTO{
+			Index:     regulation.Index,
+			IsDefault: regulation.IsDefault,
+			Passed:    true,
+			Filters:   []*types.FilterTraceDTO{},
+		}
+		for _, trace := range regulation.Filter.Trace(request) {
+			rt.Filters = append(rt.Filters, &types.FilterTraceDTO{Filter: trace.Filter, Key: trace.Key, Passed: trace.Passed})
+			rt.Passed = rt.Passed && trace.Passed
+		}
+		ret.Regulations[index] = rt
+
+		// 与FindRegulationExecutor一致：第一个通过筛选的报文规则命中，都未通过时使用默认的报文规则
+		if matched {
+			continue
+		}
+		if rt.Passed {
+			ret.Regulation, ret.IsDefault, matched = regulation.Index, regulation.IsDefault, true
+		} else if regulation.IsDefault {
+			ret.Regulation, ret.IsDefault = regulation.Index, true
+		}
+	}
+	return ret, nil
+}
+
+func lastHitTime(hc *domain.HitCounter) *time.Time {
+	last := hc.LastHit()
+	if last.IsZero() {
+		return nil
+	}
+	return &last
+}
+
+// Health 返回存活检查的概要信息，规则数取自内存中已载入的执行器，不访问存储库
+func (srv *mockApplication) Health(ctx context.Context) *types.HealthDTO {
+	return &types.HealthDTO{
+		Status:        "ok",
+		UptimeSeconds: int64(time.Since(srv.startedAt).Seconds()),
+		Rules:         len(srv.executor.ListExecutors(ctx)),
+		Version:       misc.Version,
+	}
+}
+
+// RequestCount 返回启动以来收到的mock请求总数以及其中未匹配任何规则的请求数
+func (srv *mockApplication) RequestCount(_ context.Context) *types.RequestCountDTO {
+	return &types.RequestCountDTO{Total: atomic.LoadUint64(&srv.counter), Unmatched: atomic.LoadUint64(&srv.unmatched)}
+}
+
+// RequestLog 返回最近收到的mock请求记录
+func (srv *mockApplication) RequestLog(_ context.Context) []*types.RequestLogDTO {
+	return srv.requests.list()
+}
+
+// RenderTemplate 使用模拟的请求上下文试渲染响应模板，返回渲染结果或解析、执行模板时的错误
+func (srv *mockApplication) RenderTemplate(_ context.Context, req *types.RenderTemplateDTO) (*types.RenderedTemplateDTO, error) {
+	if req.Template == nil {
+		return nil, errors.New("missing response template")
+	}
+
+	rc := &domain.RenderContext{
+		Variable:   req.Variable,
+		Weight:     req.Weight,
+		Header:     req.Header,
+		Query:      req.Query,
+		Form:       req.Form,
+		Json:       req.Json,
+		Cookie:     req.Cookie,
+		RemoteAddr: req.RemoteAddr,
+	}
+	tmpl := convertTemplateDTO(req.Template)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	if err := tmpl.DryRun(resp, rc); err != nil {
+		return nil, err
+	}
+
+	ret := &types.RenderedTemplateDTO{
+		StatusCode: resp.StatusCode(),
+		Header:     make(map[string]misc.StringValues),
+	}
+	resp.Header.VisitAll(func(key, value []byte) {
+		ret.Header[string(key)] = append(ret.Header[string(key)], string(value))
+	})
+	if tmpl.B64EncodedBody != "" {
+		ret.B64EncodeBody = base64.StdEncoding.EncodeToString(resp.Body())
+	} else {
+		ret.Body = string(resp.Body())
+	}
+	return ret, nil
+}
+
+// CompileTemplate 使用当前注册的模板函数编译并执行模板，返回渲染结果或解析、执行模板时的错误
+func (srv *mockApplication) CompileTemplate(_ context.Context, req *types.CompileTemplateDTO) (*types.CompiledTemplateDTO, error) {
+	if req.Template == "" {
+		return nil, errors.New("missing template")
+	}
+
+	output, err := domain.CompileTemplate(req.Template, &domain.RenderContext{
+		Variable:   req.Variable,
+		Weight:     req.Weight,
+		Header:     req.Header,
+		Query:      req.Query,
+		Form:       req.Form,
+		Json:       req.Json,
+		Cookie:     req.Cookie,
+		RemoteAddr: req.RemoteAddr,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &types.CompiledTemplateDTO{Output: output}, nil
+}
+
+// ListTemplateFuncs 列出已注册的模板函数
+func (srv *mockApplication) ListTemplateFuncs(_ context.Context) []string {
+	return domain.ListTemplateFuncs()
+}
+
+// InspectTemplateFuncs 列出模板中调用的函数以及其中未注册的函数，不执行模板
+func (srv *mockApplication) InspectTemplateFuncs(_ context.Context, req *types.InspectTemplateFuncsDTO) (*types.TemplateFuncsReportDTO, error) {
+	if req.Template == "" {
+		return nil, errors.New("missing template")
+	}
+
+	referenced, unregistered, err := domain.InspectTemplateFuncs(req.Template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return &types.TemplateFuncsReportDTO{Valid: len(unregistered) == 0, Functions: referenced, Unregistered: unregistered}, nil
 }
@@ -8,8 +8,8 @@ import (
 	"time"
 
 	"github.com/jacexh/multiconfig"
-	"github.com/valyala/fasthttp"
 	"github.com/wosai/deepmock/application"
+	"github.com/wosai/deepmock/domain"
 	"github.com/wosai/deepmock/infrastructure"
 	"github.com/wosai/deepmock/misc"
 	"github.com/wosai/deepmock/option"
@@ -22,29 +22,61 @@ var (
 )
 
 func main() {
+	misc.Version = version
 	loader := multiconfig.NewWithPathAndEnvPrefix("", "DEEPMOCK")
 	opt := new(option.Option)
 	loader.MustLoad(opt)
 
-	// 连接数据库
-	db := infrastructure.BuildDBConnection(opt.DB)
+	// 设置了规则文件时使用本地文件保存规则，否则连接数据库
+	var rules domain.RuleRepository
+	if opt.Mock.RuleFile != "" {
+		fr, err := infrastructure.NewFileRuleRepository(opt.Mock.RuleFile)
+		if err != nil {
+			misc.Logger.Panic("failed to load rule file", zap.String("file", opt.Mock.RuleFile), zap.Error(err))
+		}
+		rules = fr
+	} else {
+		rules = infrastructure.NewRuleRepository(infrastructure.BuildDBConnection(opt.DB))
+	}
 	mem := infrastructure.NewExecutorRepository(1000)
 	job := infrastructure.NewJob(2 * time.Second)
 
+	// 载入模板片段，退出时停止后台的重新载入
+	stop := make(chan struct{})
+	defer close(stop)
+	if opt.Mock.PartialsDir != "" {
+		loader := infrastructure.NewPartialLoader(opt.Mock.PartialsDir)
+		if err := loader.Load(); err != nil {
+			misc.Logger.Panic("failed to load template partials", zap.String("dir", opt.Mock.PartialsDir), zap.Error(err))
+		}
+		if opt.Mock.PartialsReload > 0 {
+			go loader.Watch(opt.Mock.PartialsReload, stop)
+		}
+	}
+
+	// 恢复gid的分配进度
+	if opt.Mock.GIDFile != "" {
+		gf := infrastructure.NewGlobalIDFile(opt.Mock.GIDFile)
+		start, err := gf.Load()
+		if err != nil {
+			misc.Logger.Panic("failed to load global id file", zap.String("file", opt.Mock.GIDFile), zap.Error(err))
+		}
+		if err := domain.SetGlobalIDReserver(start, 1000, gf.Save); err != nil {
+			misc.Logger.Panic("failed to set global id reserver", zap.Error(err))
+		}
+	}
+
 	// 初始化service
 	application.BuildMockApplication(
-		infrastructure.NewRuleRepository(db),
+		rules,
 		mem,
 		job,
+		opt.Mock,
 	)
 
 	// 初始化http handler
 	app := router.BuildRouter()
-	server := &fasthttp.Server{
-		Name:        "DeepMock Service",
-		Handler:     app.Handler,
-		Concurrency: 1024 * 1024,
-	}
+	server := router.BuildServer(opt.Server, app.Handler)
 	misc.Logger.Info("deepmock is running on port "+opt.Server.Port, zap.String("version", version))
 
 	errChan := make(chan error, 1)
@@ -1,6 +1,9 @@
 CREATE TABLE `rule` (
   `id` varchar(36) NOT NULL COMMENT 'rule规则ID',
   `path` varchar(128) NOT NULL COMMENT 'Mock API监听路径，支持正则表达式',
+  `path_type` varchar(16) NOT NULL DEFAULT '' COMMENT '监听路径的语法，regex(默认)或glob',
+  `host` varchar(128) NOT NULL DEFAULT '' COMMENT '匹配请求的Host头，为空时匹配任意Host，exact时存储为小写',
+  `host_type` varchar(16) NOT NULL DEFAULT '' COMMENT 'host的语法，exact或regex，设置host时为空的host_type存储为exact',
   `method` varchar(16) NOT NULL COMMENT 'Mock API请求方式，GET/POST/PATCH/PUT/DELETE等',
   `variable` blob COMMENT '规则级别的变量',
   `weight` blob COMMENT '规则级别的权重字段',
@@ -8,8 +11,20 @@ CREATE TABLE `rule` (
   `version` int(8) NOT NULL DEFAULT '0' COMMENT '规则版本号，每更新一次+1',
   `ctime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '规则创建时间',
   `mtime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '规则修改时间',
-  `disabled` tinyint(1) NOT NULL DEFAULT '0' COMMENT '规则是否启用',
+  `disabled` tinyint(1) NOT NULL DEFAULT '0' COMMENT '规则是否被停用，停用的规则保留定义但不再匹配请求',
+  `debug` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否输出规则匹配的调试日志',
+  `rate_limit` int(8) unsigned NOT NULL DEFAULT '0' COMMENT '每秒允许的请求数，0表示不限流',
+  `cors` blob COMMENT '规则的跨域配置',
+  `max_concurrency` int(8) unsigned NOT NULL DEFAULT '0' COMMENT '同时处理的请求数上限，0表示不限制',
+  `slow_start` blob COMMENT '规则的慢启动配置',
+  `overflow_response` blob COMMENT '超出并发数上限时返回的响应',
+  `duplicate` blob COMMENT '重复提交检测配置',
+  `response_schema` blob COMMENT '响应报文的JSON Schema，调试模式下校验',
+  `status_delay` blob COMMENT '按响应状态码延迟的配置',
+  `ttl_seconds` int(8) unsigned NOT NULL DEFAULT '0' COMMENT '规则自创建起的存活秒数，0表示永不过期',
+  `priority` int(8) NOT NULL DEFAULT '0' COMMENT '规则的优先级，path重叠时优先级高的规则优先匹配',
+  `maintenance` blob COMMENT '计划维护窗口配置',
   PRIMARY KEY (`id`),
   UNIQUE KEY `rule_id_uindex` (`id`),
-  UNIQUE KEY `rule_api_uindex` (`path`,`method`)
+  UNIQUE KEY `rule_api_uindex` (`path`,`method`,`host`,`host_type`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4
\ No newline at end of file
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// bodyFileDir body_file所在的目录，值为string，为空时不允许使用body_file
+var bodyFileDir atomic.Value
+
+// SetBodyFileDir 设置body_file所在的目录，body_file只能是该目录下的相对路径；为空时不允许使用body_file，
+// 以免能够创建规则的调用方读取服务器上的任意文件，只影响之后创建或更新的规则
+func SetBodyFileDir(dir string) {
+	bodyFileDir.Store(dir)
+}
+
+// readBodyFile 读取body_file，拒绝绝对路径以及通过..或符号链接指向目录之外的路径
+func readBodyFile(name string) ([]byte, error) {
+	dir, _ := bodyFileDir.Load().(string)
+	if dir == "" {
+		return nil, errors.New("body file is disabled, body_file_dir is not configured")
+	}
+	if filepath.IsAbs(name) {
+		return nil, fmt.Errorf("body file %s must be relative to body_file_dir", name)
+	}
+
+	base, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, fmt.Errorf("bad body_file_dir: %w", err)
+	}
+	if !withinDir(base, filepath.Join(base, name)) {
+		return nil, fmt.Errorf("body file %s is outside body_file_dir", name)
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(base, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body file: %w", err)
+	}
+	if !withinDir(base, path) {
+		return nil, fmt.Errorf("body file %s is outside body_file_dir", name)
+	}
+
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body file: %w", err)
+	}
+	return body, nil
+}
+
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// isBinaryBody 不是合法UTF-8文本的body视为二进制报文
+func isBinaryBody(body []byte) bool {
+	return !utf8.Valid(body)
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadBodyFile(t *testing.T) {
+	root, err := ioutil.TempDir("", "deepmock-body")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	dir := filepath.Join(root, "bodies")
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "users"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "users", "1.json"), []byte(`{"id": 1}`), 0644))
+	secret := filepath.Join(root, "secret.txt")
+	assert.NoError(t, ioutil.WriteFile(secret, []byte("password"), 0644))
+
+	// 未配置目录时不允许使用body_file
+	SetBodyFileDir("")
+	_, err = readBodyFile("users/1.json")
+	assert.Error(t, err)
+
+	SetBodyFileDir(dir)
+	defer SetBodyFileDir("")
+	body, err := readBodyFile("users/1.json")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id": 1}`, string(body))
+	body, err = readBodyFile("users/../users/1.json")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id": 1}`, string(body))
+
+	for _, name := range []string{secret, "/etc/passwd", "../secret.txt", "users/../../secret.txt", ".."} {
+		_, err = readBodyFile(name)
+		assert.Error(t, err, name)
+	}
+
+	// 符号链接指向目录之外时同样拒绝
+	if err := os.Symlink(secret, filepath.Join(dir, "link.txt")); err == nil {
+		_, err = readBodyFile("link.txt")
+		assert.Error(t, err)
+	}
+
+	_, err = (&Template{BodyFile: "/etc/passwd"}).To()
+	assert.Error(t, err)
+}
@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// clock 规则过期、维护窗口等判断使用的时钟，值为func() time.Time
+var clock atomic.Value
+
+// SetClock 替换规则过期、维护窗口等判断使用的时钟，用于测试或回放时控制当前时间，传入nil时恢复为time.Now；
+// 只影响之后的判断，已创建的规则的创建时间不变
+func SetClock(now func() time.Time) {
+	if now == nil {
+		now = time.Now
+	}
+	clock.Store(now)
+}
+
+// Now 返回SetClock设置的时钟的当前时间，未设置时为time.Now
+func Now() time.Time {
+	if now, ok := clock.Load().(func() time.Time); ok {
+		return now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"bytes"
+	"errors"
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// CompressGzip 客户端支持gzip时使用gzip压缩响应报文
+	CompressGzip = "gzip"
+	// CompressDeflate 客户端支持deflate时使用deflate压缩响应报文
+	CompressDeflate = "deflate"
+	// CompressAuto 根据请求的Accept-Encoding选择压缩算法，不支持压缩时不压缩
+	CompressAuto = "auto"
+)
+
+var (
+	// compressedTypePrefixes 本身已经压缩过的Content-Type前缀，再次压缩只会浪费CPU甚至让报文变大
+	compressedTypePrefixes = [][]byte{
+		[]byte("image/"),
+		[]byte("video/"),
+		[]byte("audio/"),
+		[]byte("font/woff"),
+		[]byte("application/gzip"),
+		[]byte("application/x-gzip"),
+		[]byte("application/zip"),
+		[]byte("application/x-bzip2"),
+		[]byte("application/x-7z-compressed"),
+		[]byte("application/x-rar-compressed"),
+		[]byte("application/zstd"),
+	}
+	// compressibleImageTypes 文本格式的图片，仍然值得压缩
+	compressibleImageTypes = [][]byte{
+		[]byte("image/svg+xml"),
+		[]byte("image/bmp"),
+	}
+)
+
+type compressDefaults struct {
+	compress string
+	minSize  int
+}
+
+// defaultCompress 全局的压缩配置，值为*compressDefaults
+var defaultCompress atomic.Value
+
+// SetDefaultCompress 设置全局的压缩配置：compress用于未设置compress的响应，body小于minSize字节时不压缩，0表示不限制
+func SetDefaultCompress(compress string, minSize int) error {
+	if err := validateCompress(compress); err != nil {
+		return err
+	}
+	if minSize < 0 {
+		return errors.New("bad compress min size")
+	}
+	defaultCompress.Store(&compressDefaults{compress: compress, minSize: minSize})
+	return nil
+}
+
+func loadCompressDefaults() *compressDefaults {
+	if defaults, ok := defaultCompress.Load().(*compressDefaults); ok {
+		return defaults
+	}
+	return &compressDefaults{}
+}
+
+func validateCompress(compress string) error {
+	switch compress {
+	case "", CompressGzip, CompressDeflate, CompressAuto:
+		return nil
+	default:
+		return errors.New("unsupported compress method: " + compress)
+	}
+}
+
+// negotiateEncoding 返回本次响应实际使用的压缩算法，请求的Accept-Encoding不支持配置的算法时返回空字符串，即不压缩
+func negotiateEncoding(compress string, req *fasthttp.RequestHeader) string {
+	if compress != CompressAuto {
+		if req.HasAcceptEncoding(compress) {
+			return compress
+		}
+		return ""
+	}
+	switch {
+	case req.HasAcceptEncoding(CompressGzip):
+		return CompressGzip
+	case req.HasAcceptEncoding(CompressDeflate):
+		return CompressDeflate
+	default:
+		return ""
+	}
+}
+
+// isCompressedContentType 判断Content-Type对应的报文是否本身已经压缩过
+func isCompressedContentType(contentType []byte) bool {
+	contentType = bytes.ToLower(bytes.TrimSpace(contentType))
+	for _, t := range compressibleImageTypes {
+		if bytes.HasPrefix(contentType, t) {
+			return false
+		}
+	}
+	for _, prefix := range compressedTypePrefixes {
+		if bytes.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressBody 按配置压缩已渲染的响应报文，并设置Content-Encoding，compress为空时使用全局配置；
+// 客户端的Accept-Encoding不支持该算法、已设置Content-Encoding、报文本身已经压缩过或者小于全局配置的大小下限时不压缩
+func compressBody(ctx *fasthttp.RequestCtx, compress string) {
+	defaults := loadCompressDefaults()
+	if compress == "" {
+		compress = defaults.compress
+	}
+	if compress == "" {
+		return
+	}
+	if len(ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding)) > 0 || isCompressedContentType(ctx.Response.Header.ContentType()) {
+		return
+	}
+	if len(ctx.Response.Body()) < defaults.minSize {
+		return
+	}
+	// 是否压缩取决于请求的Accept-Encoding，无论本次是否压缩都需要告知缓存
+	ctx.Response.Header.Add(fasthttp.HeaderVary, fasthttp.HeaderAcceptEncoding)
+
+	encoding := negotiateEncoding(compress, &ctx.Request.Header)
+	switch encoding {
+	case CompressGzip:
+		ctx.Response.SetBody(fasthttp.AppendGzipBytes(nil, ctx.Response.Body()))
+	case CompressDeflate:
+		ctx.Response.SetBody(fasthttp.AppendDeflateBytes(nil, ctx.Response.Body()))
+	default:
+		return
+	}
+	ctx.Response.Header.Set(fasthttp.HeaderContentEncoding, encoding)
+}
@@ -0,0 +1,47 @@
+package domain
+
+import "sync/atomic"
+
+type (
+	// ConcurrencyLimiter 并发数限制器，限制同时处理中的请求数
+	ConcurrencyLimiter struct {
+		limit    int64
+		inflight int64
+	}
+)
+
+// NewConcurrencyLimiter 工厂函数，limit为允许同时处理的请求数，为0时返回nil，即不限制
+func NewConcurrencyLimiter(limit uint) *ConcurrencyLimiter {
+	if limit == 0 {
+		return nil
+	}
+	return &ConcurrencyLimiter{limit: int64(limit)}
+}
+
+// Acquire 尝试占用一个并发名额，成功后必须调用Release归还
+func (cl *ConcurrencyLimiter) Acquire() bool {
+	if cl == nil {
+		return true
+	}
+	if atomic.AddInt64(&cl.inflight, 1) > cl.limit {
+		atomic.AddInt64(&cl.inflight, -1)
+		return false
+	}
+	return true
+}
+
+// Release 归还Acquire占用的并发名额
+func (cl *ConcurrencyLimiter) Release() {
+	if cl == nil {
+		return
+	}
+	atomic.AddInt64(&cl.inflight, -1)
+}
+
+// InFlight 返回当前处理中的请求数
+func (cl *ConcurrencyLimiter) InFlight() int64 {
+	if cl == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&cl.inflight)
+}
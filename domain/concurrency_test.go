@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	var unlimited *ConcurrencyLimiter
+	assert.Nil(t, NewConcurrencyLimiter(0))
+	assert.True(t, unlimited.Acquire())
+	unlimited.Release()
+
+	cl := NewConcurrencyLimiter(2)
+	assert.True(t, cl.Acquire())
+	assert.True(t, cl.Acquire())
+	assert.False(t, cl.Acquire())
+	assert.EqualValues(t, 2, cl.InFlight())
+
+	cl.Release()
+	assert.True(t, cl.Acquire())
+	assert.False(t, cl.Acquire())
+}
+
+func TestRule_OverflowResponseStatus(t *testing.T) {
+	rule := &Rule{
+		Path:             "/overflow",
+		Method:           "GET",
+		MaxConcurrency:   1,
+		OverflowResponse: &Template{Body: "busy"},
+		Regulations:      []*Regulation{{IsDefault: true, Template: &Template{Body: "ok"}}},
+	}
+	exec, err := rule.To()
+	assert.NoError(t, err)
+	// 未指定状态码时返回503，但不修改规则本身
+	assert.Equal(t, http.StatusServiceUnavailable, exec.Overflow.header.StatusCode())
+	assert.Zero(t, rule.OverflowResponse.StatusCode)
+}
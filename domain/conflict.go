@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrRuleConflict 新规则与已有规则匹配相同的请求
+var ErrRuleConflict = errors.New("rule conflict")
+
+// Overlaps 判断两条规则是否可能匹配相同的请求：method相同，且任意一方的路径正则能够匹配另一方的路径。
+// 路径正则之间的重叠无法精确判定，这里只检查双方的路径字面值，无法编译的路径视为不重叠；
+// 双方都精确匹配host且host不同时不会匹配相同的请求
+func (rule *Rule) Overlaps(other *Rule) bool {
+	if other == nil || rule.ID == other.ID || !strings.EqualFold(rule.Method, other.Method) {
+		return false
+	}
+	if rule.Host != "" && other.Host != "" && rule.HostType != HostTypeRegex && other.HostType != HostTypeRegex && !strings.EqualFold(rule.Host, other.Host) {
+		return false
+	}
+	this, err := rule.compilePath()
+	if err != nil {
+		return false
+	}
+	that, err := other.compilePath()
+	if err != nil {
+		return false
+	}
+	return this.MatchString(other.Path) || that.MatchString(rule.Path)
+}
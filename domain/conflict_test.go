@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRule_Overlaps(t *testing.T) {
+	newRule := func(path, method string) *Rule {
+		rule := &Rule{Path: path, Method: method}
+		rule.SupplyID()
+		return rule
+	}
+
+	cases := []struct {
+		a, b     *Rule
+		overlaps bool
+	}{
+		{newRule("/user/\\d+", "GET"), newRule("/user/123", "GET"), true},
+		{newRule("/user/123", "get"), newRule("/user/\\d+", "GET"), true},
+		{newRule("/user/\\d+", "GET"), newRule("/user/123", "POST"), false},
+		{newRule("/order", "GET"), newRule("/user", "GET"), false},
+		{newRule("/order", "GET"), newRule("/order", "GET"), false}, // 同一条规则
+		{newRule("/order/(", "GET"), newRule("/order/(", "POST"), false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.overlaps, c.a.Overlaps(c.b), c.a.Path+" "+c.b.Path)
+	}
+	assert.False(t, newRule("/order", "GET").Overlaps(nil))
+
+	withHost := func(rule *Rule, host, hostType string) *Rule {
+		rule.ID, rule.Host, rule.HostType = "", host, hostType
+		rule.SupplyID()
+		return rule
+	}
+	a := withHost(newRule("/order", "GET"), "a.example.com", "")
+	assert.False(t, a.Overlaps(withHost(newRule("/order", "GET"), "b.example.com", "")))
+	// 只有host大小写不同的精确匹配规则是同一条规则
+	assert.Equal(t, a.ID, withHost(newRule("/order", "GET"), "A.example.com", HostTypeExact).ID)
+	assert.True(t, a.Overlaps(withHost(newRule("/ord(er)?", "GET"), "A.example.com", HostTypeExact)))
+	assert.True(t, a.Overlaps(withHost(newRule("/order", "GET"), `^b\.`, HostTypeRegex)))
+	assert.True(t, a.Overlaps(newRule("/order", "GET")))
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"errors"
+
+	"github.com/valyala/fasthttp"
+)
+
+type (
+	// ContentLengthFilterParams Content-Length筛选参数值对象，Min、Max为0时表示不限制，
+	// 长度未知的请求（如chunked编码）仅在AllowUnknown为true时通过
+	ContentLengthFilterParams struct {
+		Min          int  `json:"min,omitempty"`
+		Max          int  `json:"max,omitempty"`
+		AllowUnknown bool `json:"allow_unknown,omitempty"`
+	}
+
+	// ContentLengthFilterExecutor Content-Length筛选执行器
+	ContentLengthFilterExecutor struct {
+		min          int
+		max          int
+		allowUnknown bool
+	}
+)
+
+// To 转换成ContentLengthFilterExecutor，未设置时返回nil，即总是通过
+func (clp *ContentLengthFilterParams) To() (*ContentLengthFilterExecutor, error) {
+	if clp == nil {
+		return nil, nil
+	}
+	if clp.Min < 0 || clp.Max < 0 || (clp.Max > 0 && clp.Min > clp.Max) {
+		return nil, errors.New("bad content length range")
+	}
+	return &ContentLengthFilterExecutor{min: clp.Min, max: clp.Max, allowUnknown: clp.AllowUnknown}, nil
+}
+
+// Filter 根据请求头中声明的Content-Length筛选
+func (clfe *ContentLengthFilterExecutor) Filter(header *fasthttp.RequestHeader) bool {
+	if clfe == nil {
+		return true
+	}
+
+	length := header.ContentLength()
+	if length < 0 { // -1: chunked编码，-2: 未声明长度
+		return clfe.allowUnknown
+	}
+	if length < clfe.min {
+		return false
+	}
+	return clfe.max == 0 || length <= clfe.max
+}
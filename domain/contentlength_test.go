@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func newContentLengthRequest(length int) *fasthttp.Request {
+	req := new(fasthttp.Request)
+	req.Header.SetMethod("POST")
+	req.SetRequestURI("/api/v1/upload")
+	req.Header.SetContentLength(length)
+	return req
+}
+
+func TestContentLengthFilter_Filter(t *testing.T) {
+	var clp *ContentLengthFilterParams
+	clfe, err := clp.To()
+	assert.NoError(t, err)
+	assert.Nil(t, clfe)
+	assert.True(t, clfe.Filter(&newContentLengthRequest(-1).Header))
+
+	clfe, err = (&ContentLengthFilterParams{Min: 10, Max: 100}).To()
+	assert.NoError(t, err)
+	assert.True(t, clfe.Filter(&newContentLengthRequest(10).Header))
+	assert.True(t, clfe.Filter(&newContentLengthRequest(100).Header))
+	assert.False(t, clfe.Filter(&newContentLengthRequest(9).Header))
+	assert.False(t, clfe.Filter(&newContentLengthRequest(101).Header))
+	assert.False(t, clfe.Filter(&newContentLengthRequest(-1).Header)) // chunked
+
+	// 仅限制下限
+	clfe, err = (&ContentLengthFilterParams{Min: 1024, AllowUnknown: true}).To()
+	assert.NoError(t, err)
+	assert.True(t, clfe.Filter(&newContentLengthRequest(1<<20).Header))
+	assert.False(t, clfe.Filter(&newContentLengthRequest(0).Header))
+	assert.True(t, clfe.Filter(&newContentLengthRequest(-1).Header))
+
+	_, err = (&ContentLengthFilterParams{Min: 100, Max: 10}).To()
+	assert.Error(t, err)
+	_, err = (&ContentLengthFilterParams{Min: -1}).To()
+	assert.Error(t, err)
+}
+
+func TestRegulation_ContentLength(t *testing.T) {
+	rule := &Rule{
+		Path:   "/api/v1/upload",
+		Method: "POST",
+		Regulations: []*Regulation{
+			{
+				Filter:   &Filter{ContentLength: &ContentLengthFilterParams{Min: 1 << 20}},
+				Template: &Template{StatusCode: 413, Body: "too large"},
+			},
+			{IsDefault: true, Template: &Template{Body: "ok"}},
+		},
+	}
+	executor, err := rule.To()
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, executor.FindRegulationExecutor(newContentLengthRequest(2<<20)).Index)
+	assert.Equal(t, 1, executor.FindRegulationExecutor(newContentLengthRequest(512)).Index)
+
+	fe := executor.Regulations[0].Filter
+	assert.Equal(t, "content_length", fe.Diagnose(newContentLengthRequest(512)))
+}
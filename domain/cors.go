@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	corsWildcard = "*"
+)
+
+type (
+	// CORS 跨域配置值对象，未设置时不处理跨域
+	CORS struct {
+		AllowOrigins []string `json:"allow_origins,omitempty"` // 为空时允许所有来源
+		AllowMethods []string `json:"allow_methods,omitempty"` // 为空时允许预检请求所声明的方法
+		AllowHeaders []string `json:"allow_headers,omitempty"` // 为空时允许预检请求所声明的请求头
+		MaxAge       int      `json:"max_age,omitempty"`
+	}
+)
+
+// IsPreflight 判断是否为跨域预检请求
+func IsPreflight(req *fasthttp.Request) bool {
+	return req.Header.IsOptions() && len(req.Header.Peek(fasthttp.HeaderAccessControlRequestMethod)) > 0
+}
+
+// allowOrigin 返回Access-Control-Allow-Origin的值，不允许的来源返回空字符串
+func (c *CORS) allowOrigin(origin []byte) string {
+	if len(origin) == 0 {
+		return ""
+	}
+	if len(c.AllowOrigins) == 0 {
+		return corsWildcard
+	}
+	for _, o := range c.AllowOrigins {
+		if o == corsWildcard {
+			return corsWildcard
+		}
+		if strings.EqualFold(o, string(origin)) {
+			return string(origin)
+		}
+	}
+	return ""
+}
+
+// Preflight 直接响应跨域预检请求
+func (c *CORS) Preflight(ctx *fasthttp.RequestCtx) {
+	ctx.Response.Reset()
+	ctx.Response.SetStatusCode(fasthttp.StatusNoContent)
+
+	origin := c.allowOrigin(ctx.Request.Header.Peek(fasthttp.HeaderOrigin))
+	if origin == "" {
+		return
+	}
+	header := &ctx.Response.Header
+	header.Set(fasthttp.HeaderAccessControlAllowOrigin, origin)
+	header.Add(fasthttp.HeaderVary, fasthttp.HeaderOrigin)
+
+	if len(c.AllowMethods) > 0 {
+		header.Set(fasthttp.HeaderAccessControlAllowMethods, strings.Join(c.AllowMethods, ", "))
+	} else {
+		header.SetBytesV(fasthttp.HeaderAccessControlAllowMethods, ctx.Request.Header.Peek(fasthttp.HeaderAccessControlRequestMethod))
+	}
+
+	if len(c.AllowHeaders) > 0 {
+		header.Set(fasthttp.HeaderAccessControlAllowHeaders, strings.Join(c.AllowHeaders, ", "))
+	} else if requested := ctx.Request.Header.Peek(fasthttp.HeaderAccessControlRequestHeaders); len(requested) > 0 {
+		header.SetBytesV(fasthttp.HeaderAccessControlAllowHeaders, requested)
+	}
+
+	if c.MaxAge > 0 {
+		header.Set(fasthttp.HeaderAccessControlMaxAge, strconv.Itoa(c.MaxAge))
+	}
+}
+
+// Apply 在普通响应上注入跨域响应头
+func (c *CORS) Apply(ctx *fasthttp.RequestCtx) {
+	if c == nil {
+		return
+	}
+	origin := c.allowOrigin(ctx.Request.Header.Peek(fasthttp.HeaderOrigin))
+	if origin == "" {
+		return
+	}
+	ctx.Response.Header.Set(fasthttp.HeaderAccessControlAllowOrigin, origin)
+	ctx.Response.Header.Add(fasthttp.HeaderVary, fasthttp.HeaderOrigin)
+}
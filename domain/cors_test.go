@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestCORS_allowOrigin(t *testing.T) {
+	c := &CORS{}
+	assert.Equal(t, "", c.allowOrigin(nil))
+	assert.Equal(t, "*", c.allowOrigin([]byte("http://a.com")))
+
+	c = &CORS{AllowOrigins: []string{"http://a.com"}}
+	assert.Equal(t, "http://A.com", c.allowOrigin([]byte("http://A.com")))
+	assert.Equal(t, "", c.allowOrigin([]byte("http://b.com")))
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetMethod("OPTIONS")
+	assert.False(t, IsPreflight(&ctx.Request))
+
+	ctx.Request.Header.Set(fasthttp.HeaderOrigin, "http://a.com")
+	ctx.Request.Header.Set(fasthttp.HeaderAccessControlRequestMethod, "PUT")
+	ctx.Request.Header.Set(fasthttp.HeaderAccessControlRequestHeaders, "X-Token")
+	assert.True(t, IsPreflight(&ctx.Request))
+
+	c := &CORS{}
+	c.Preflight(ctx)
+	assert.Equal(t, fasthttp.StatusNoContent, ctx.Response.StatusCode())
+	assert.Equal(t, "*", string(ctx.Response.Header.Peek(fasthttp.HeaderAccessControlAllowOrigin)))
+	assert.Equal(t, "PUT", string(ctx.Response.Header.Peek(fasthttp.HeaderAccessControlAllowMethods)))
+	assert.Equal(t, "X-Token", string(ctx.Response.Header.Peek(fasthttp.HeaderAccessControlAllowHeaders)))
+	assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderAccessControlMaxAge))
+}
+
+func TestCORS_Apply(t *testing.T) {
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.Set(fasthttp.HeaderOrigin, "http://a.com")
+
+	var c *CORS
+	c.Apply(ctx)
+	assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderAccessControlAllowOrigin))
+
+	c = &CORS{AllowOrigins: []string{"*"}}
+	c.Apply(ctx)
+	assert.Equal(t, "*", string(ctx.Response.Header.Peek(fasthttp.HeaderAccessControlAllowOrigin)))
+}
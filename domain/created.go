@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// CreatedIDTypeUUID 使用uuid作为新建资源的id
+	CreatedIDTypeUUID = "uuid"
+	// CreatedIDTypeGID 使用全局递增的gid作为新建资源的id
+	CreatedIDTypeGID = "gid"
+
+	createdIDPlaceholder = "{id}"
+)
+
+type (
+	// Created 模拟创建资源接口的值对象：生成id，返回201、Location响应头，以及补充了id的请求JSON作为资源
+	Created struct {
+		Location string `json:"location"`           // Location响应头，其中的{id}替换为生成的id
+		IDField  string `json:"id_field,omitempty"` // 响应中id的字段名，默认为id
+		IDType   string `json:"id_type,omitempty"`  // id的生成方式，uuid(默认)或gid
+	}
+
+	// CreatedExecutor 模拟创建资源的执行器
+	CreatedExecutor struct {
+		location string
+		idField  string
+		idType   string
+	}
+)
+
+// Validate 校验函数
+func (c *Created) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.Location == "" {
+		return errors.New("missing location in created response")
+	}
+	switch c.IDType {
+	case "", CreatedIDTypeUUID, CreatedIDTypeGID:
+	default:
+		return fmt.Errorf("unsupported id type %s in created response", c.IDType)
+	}
+	return nil
+}
+
+// To 转换成CreatedExecutor，未配置时返回nil
+func (c *Created) To() (*CreatedExecutor, error) {
+	if c == nil {
+		return nil, nil
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	ce := &CreatedExecutor{location: c.Location, idField: c.IDField, idType: c.IDType}
+	if ce.idField == "" {
+		ce.idField = "id"
+	}
+	if ce.idType == "" {
+		ce.idType = CreatedIDTypeUUID
+	}
+	return ce, nil
+}
+
+func (ce *CreatedExecutor) genID() interface{} {
+	if ce.idType == CreatedIDTypeGID {
+		return genGlobalID()
+	}
+	return genUUID()
+}
+
+// Render 生成id并返回新建的资源，请求body不是JSON对象时资源只包含id
+func (ce *CreatedExecutor) Render(ctx *fasthttp.RequestCtx) error {
+	id := ce.genID()
+	resource := make(map[string]interface{})
+	if err := json.Unmarshal(ctx.Request.Body(), &resource); err != nil || resource == nil {
+		resource = make(map[string]interface{})
+	}
+	resource[ce.idField] = id
+
+	body, err := json.Marshal(resource)
+	if err != nil {
+		return err
+	}
+	ctx.Response.SetStatusCode(fasthttp.StatusCreated)
+	ctx.Response.Header.SetContentType("application/json")
+	ctx.Response.Header.Set(fasthttp.HeaderLocation, strings.Replace(ce.location, createdIDPlaceholder, fmt.Sprint(id), -1))
+	ctx.Response.SetBody(body)
+	return nil
+}
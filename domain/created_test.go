@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestCreatedExecutor_Render(t *testing.T) {
+	reg := &Regulation{IsDefault: true, Created: &Created{Location: "/api/v1/users/{id}"}}
+	assert.NoError(t, reg.Validate())
+	exec, err := reg.To()
+	assert.NoError(t, err)
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.SetBody([]byte(`{"name": "deepmock", "age": 3}`))
+	assert.NoError(t, exec.Render(ctx, nil, nil))
+	assert.Equal(t, fasthttp.StatusCreated, ctx.Response.StatusCode())
+	assert.Equal(t, "application/json", string(ctx.Response.Header.ContentType()))
+
+	resource := make(map[string]interface{})
+	assert.NoError(t, json.Unmarshal(ctx.Response.Body(), &resource))
+	id := resource["id"].(string)
+	_, err = uuid.Parse(id)
+	assert.NoError(t, err)
+	assert.Equal(t, "deepmock", resource["name"])
+	assert.EqualValues(t, 3, resource["age"])
+	assert.Equal(t, "/api/v1/users/"+id, string(ctx.Response.Header.Peek("Location")))
+
+	// 每次请求生成新的id
+	ctx = new(fasthttp.RequestCtx)
+	assert.NoError(t, exec.Render(ctx, nil, nil))
+	assert.NotContains(t, string(ctx.Response.Body()), id)
+}
+
+func TestCreatedExecutor_GID(t *testing.T) {
+	ce, err := (&Created{Location: "/orders/{id}", IDField: "order_id", IDType: CreatedIDTypeGID}).To()
+	assert.NoError(t, err)
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.SetBody([]byte(`not json`))
+	assert.NoError(t, ce.Render(ctx))
+
+	resource := make(map[string]interface{})
+	assert.NoError(t, json.Unmarshal(ctx.Response.Body(), &resource))
+	assert.Len(t, resource, 1)
+	id := strconv.FormatFloat(resource["order_id"].(float64), 'f', -1, 64)
+	assert.Equal(t, "/orders/"+id, string(ctx.Response.Header.Peek("Location")))
+	assert.True(t, strings.HasPrefix(string(ctx.Response.Body()), `{"order_id":`))
+}
+
+func TestRegulation_ValidateCreated(t *testing.T) {
+	reg := &Regulation{IsDefault: true, Created: &Created{}}
+	assert.Error(t, reg.Validate())
+
+	reg = &Regulation{IsDefault: true, Created: &Created{Location: "/a/{id}", IDType: "snowflake"}}
+	assert.Error(t, reg.Validate())
+
+	reg = &Regulation{IsDefault: true, Template: &Template{Body: "ok"}, Created: &Created{Location: "/a/{id}"}}
+	assert.Error(t, reg.Validate())
+
+	// 重试足够次数后再创建资源
+	reg = &Regulation{
+		IsDefault: true,
+		Created:   &Created{Location: "/a/{id}"},
+		Retry:     &Retry{Responses: []*Template{{Body: "busy"}}},
+	}
+	assert.NoError(t, reg.Validate())
+	exec, err := reg.To()
+	assert.NoError(t, err)
+	ctx := new(fasthttp.RequestCtx)
+	assert.NoError(t, exec.Render(ctx, nil, nil))
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode())
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.Header.Set(DefaultRetryHeader, "1")
+	assert.NoError(t, exec.Render(ctx, nil, nil))
+	assert.Equal(t, fasthttp.StatusCreated, ctx.Response.StatusCode())
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultMaxDecodedBodySize 解压后请求body的默认大小上限，单位为字节
+const DefaultMaxDecodedBodySize = 16 << 20
+
+// ErrDecodedBodyTooLarge 解压后的请求body超过上限，用于防止解压炸弹
+var ErrDecodedBodyTooLarge = errors.New("decoded request body too large")
+
+// DecodeRequestBody 按照Content-Encoding解压gzip、deflate编码的请求body，并移除Content-Encoding请求头，
+// 之后的筛选与模板渲染都使用解压后的body；limit为解压后的大小上限，不大于0时使用DefaultMaxDecodedBodySize
+func DecodeRequestBody(req *fasthttp.Request, limit int) error {
+	encoding := string(bytes.ToLower(bytes.TrimSpace(req.Header.Peek(fasthttp.HeaderContentEncoding))))
+	if encoding == "" || encoding == "identity" || len(req.Body()) == 0 {
+		return nil
+	}
+	if limit <= 0 {
+		limit = DefaultMaxDecodedBodySize
+	}
+
+	var (
+		reader io.ReadCloser
+		err    error
+	)
+	switch encoding {
+	case CompressGzip:
+		reader, err = gzip.NewReader(bytes.NewReader(req.Body()))
+	case CompressDeflate:
+		reader, err = zlib.NewReader(bytes.NewReader(req.Body()))
+	default:
+		return nil // 不认识的编码保持原样
+	}
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(reader, int64(limit)+1))
+	if err != nil {
+		return err
+	}
+	if len(body) > limit {
+		return ErrDecodedBodyTooLarge
+	}
+	req.SetBody(body)
+	req.Header.Del(fasthttp.HeaderContentEncoding)
+	return nil
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"bytes"
+	"compress/zlib"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestDecodeRequestBody(t *testing.T) {
+	body := `{"name": "deepmock"}`
+
+	req := new(fasthttp.Request)
+	req.Header.Set(fasthttp.HeaderContentEncoding, "GZIP")
+	req.SetBody(fasthttp.AppendGzipBytes(nil, []byte(body)))
+	assert.NoError(t, DecodeRequestBody(req, 0))
+	assert.Equal(t, body, string(req.Body()))
+	assert.Empty(t, req.Header.Peek(fasthttp.HeaderContentEncoding))
+
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	_, _ = w.Write([]byte(body))
+	_ = w.Close()
+	req = new(fasthttp.Request)
+	req.Header.Set(fasthttp.HeaderContentEncoding, CompressDeflate)
+	req.SetBody(buf.Bytes())
+	assert.NoError(t, DecodeRequestBody(req, 0))
+	assert.Equal(t, body, string(req.Body()))
+
+	// 未编码或不认识的编码保持原样
+	for _, encoding := range []string{"", "identity", "br"} {
+		req = new(fasthttp.Request)
+		req.Header.Set(fasthttp.HeaderContentEncoding, encoding)
+		req.SetBodyString(body)
+		assert.NoError(t, DecodeRequestBody(req, 0))
+		assert.Equal(t, body, string(req.Body()))
+	}
+
+	req = new(fasthttp.Request)
+	req.Header.Set(fasthttp.HeaderContentEncoding, CompressGzip)
+	req.SetBodyString(body)
+	assert.Error(t, DecodeRequestBody(req, 0))
+	assert.Equal(t, body, string(req.Body()))
+}
+
+func TestDecodeRequestBody_TooLarge(t *testing.T) {
+	bomb := fasthttp.AppendGzipBytes(nil, []byte(strings.Repeat("0", 1<<20)))
+
+	req := new(fasthttp.Request)
+	req.Header.Set(fasthttp.HeaderContentEncoding, CompressGzip)
+	req.SetBody(bomb)
+	assert.Equal(t, ErrDecodedBodyTooLarge, DecodeRequestBody(req, 1<<20-1))
+	assert.Equal(t, bomb, req.Body())
+
+	assert.NoError(t, DecodeRequestBody(req, 1<<20))
+	assert.Len(t, req.Body(), 1<<20)
+}
@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"errors"
+	"html/template"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type (
+	// Duplicate 重复提交检测配置值对象：WindowSeconds秒内收到body完全相同的请求时，
+	// 直接返回Response，用于模拟接口的幂等校验
+	Duplicate struct {
+		WindowSeconds uint      `json:"window_seconds"`
+		Response      *Template `json:"response"`
+	}
+
+	// DuplicateExecutor 重复提交检测执行器，seen记录窗口内每个body摘要首次出现的时间
+	DuplicateExecutor struct {
+		Response  *TemplateExecutor
+		window    time.Duration
+		mu        sync.Mutex
+		seen      map[[sha256.Size]byte]time.Time
+		lastPurge time.Time
+		now       func() time.Time
+	}
+)
+
+// Validate 校验函数
+func (d *Duplicate) Validate() error {
+	if d == nil {
+		return nil
+	}
+	if d.WindowSeconds == 0 {
+		return errors.New("duplicate detection requires window_seconds")
+	}
+	if d.Response == nil {
+		return errors.New("missing duplicate response")
+	}
+	return nil
+}
+
+// To 转换成DuplicateExecutor，未配置时返回nil，即不检测
+func (d *Duplicate) To(funcs ...template.FuncMap) (*DuplicateExecutor, error) {
+	if d == nil {
+		return nil, nil
+	}
+	// 未指定状态码时返回409
+	te, err := d.Response.toWithDefaultStatus(http.StatusConflict, funcs...)
+	if err != nil {
+		return nil, err
+	}
+	de := &DuplicateExecutor{
+		Response: te,
+		window:   time.Duration(d.WindowSeconds) * time.Second,
+		seen:     make(map[[sha256.Size]byte]time.Time),
+		now:      time.Now,
+	}
+	de.lastPurge = de.now()
+	return de, nil
+}
+
+// Seen 判断窗口内是否已经收到过相同的body，首次出现时记录下来；窗口从首次出现时开始计算，重复提交不会延长窗口；
+// 空body没有可以比较的内容，不视为重复提交，否则窗口内所有不带body的GET、DELETE请求都会被当作重复
+func (de *DuplicateExecutor) Seen(body []byte) bool {
+	if de == nil || len(body) == 0 {
+		return false
+	}
+	sum := sha256.Sum256(body)
+	now := de.now()
+
+	de.mu.Lock()
+	defer de.mu.Unlock()
+
+	// 每经过一个窗口清理一次过期的记录
+	if now.Sub(de.lastPurge) >= de.window {
+		for k, at := range de.seen {
+			if now.Sub(at) >= de.window {
+				delete(de.seen, k)
+			}
+		}
+		de.lastPurge = now
+	}
+
+	if at, exists := de.seen[sum]; exists && now.Sub(at) < de.window {
+		return true
+	}
+	de.seen[sum] = now
+	return false
+}
@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuplicate_Validate(t *testing.T) {
+	var d *Duplicate
+	assert.NoError(t, d.Validate())
+	de, err := d.To()
+	assert.NoError(t, err)
+	assert.Nil(t, de)
+	assert.False(t, de.Seen([]byte("body")))
+
+	assert.Error(t, (&Duplicate{Response: &Template{}}).Validate())
+	assert.Error(t, (&Duplicate{WindowSeconds: 10}).Validate())
+
+	d = &Duplicate{WindowSeconds: 10, Response: &Template{Body: "duplicated"}}
+	assert.NoError(t, d.Validate())
+	// 未指定状态码时返回409，但不修改配置本身
+	de, err = d.To()
+	assert.NoError(t, err)
+	assert.Equal(t, 409, de.Response.header.StatusCode())
+	assert.Zero(t, d.Response.StatusCode)
+}
+
+func TestDuplicateExecutor_Seen(t *testing.T) {
+	de, err := (&Duplicate{WindowSeconds: 10, Response: &Template{Body: "duplicated"}}).To()
+	assert.NoError(t, err)
+	now := time.Now()
+	de.now = func() time.Time { return now }
+
+	assert.False(t, de.Seen([]byte(`{"order": 1}`)))
+	assert.True(t, de.Seen([]byte(`{"order": 1}`)))
+	assert.False(t, de.Seen([]byte(`{"order": 2}`)))
+
+	// 空body不视为重复提交，也不会被记录
+	assert.False(t, de.Seen(nil))
+	assert.False(t, de.Seen([]byte{}))
+	assert.Len(t, de.seen, 2)
+
+	// 重复提交不会延长窗口
+	now = now.Add(9 * time.Second)
+	assert.True(t, de.Seen([]byte(`{"order": 1}`)))
+	now = now.Add(time.Second)
+	assert.False(t, de.Seen([]byte(`{"order": 1}`)))
+	assert.True(t, de.Seen([]byte(`{"order": 1}`)))
+
+	// 过期的记录会被清理
+	now = now.Add(time.Minute)
+	assert.False(t, de.Seen([]byte(`{"order": 3}`)))
+	assert.Len(t, de.seen, 1)
+}
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+)
+
+const (
+	// EnumVariableKey 规则变量中声明枚举定义的字段名称
+	EnumVariableKey = "enums"
+)
+
+type (
+	// EnumPicker 规则级别的枚举值选择器
+	EnumPicker map[string]*WeightDice
+)
+
+// parseEnums 从规则变量中解析枚举定义，支持以下两种格式：
+//   - 数组，每个枚举值的权重相同: {"status": ["CREATED", "CLOSED"]}
+//   - 对象，value为枚举值的权重: {"status": {"CREATED": 2, "CLOSED": 1}}
+func parseEnums(variable map[string]interface{}) (EnumPicker, error) {
+	raw, exists := variable[EnumVariableKey]
+	if !exists {
+		return EnumPicker{}, nil
+	}
+	definitions, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("enums in variable must be an object")
+	}
+
+	picker := make(EnumPicker, len(definitions))
+	for name, definition := range definitions {
+		factor := make(WeightFactor)
+		switch def := definition.(type) {
+		case []interface{}:
+			for _, v := range def {
+				factor[fmt.Sprint(v)] = 1
+			}
+
+		case map[string]interface{}:
+			for v, w := range def {
+				weight, ok := w.(float64)
+				if !ok || weight < 0 {
+					return nil, fmt.Errorf("bad weight of enum value %s.%s", name, v)
+				}
+				factor[v] = uint(weight)
+			}
+
+		default:
+			return nil, fmt.Errorf("enum %s must be an array or an object", name)
+		}
+
+		dice := factor.To()
+		if dice.total == 0 {
+			return nil, fmt.Errorf("enum %s has no selectable value", name)
+		}
+		picker[name] = dice
+	}
+	return picker, nil
+}
+
+// Pick 根据权重随机返回指定枚举的某个值
+func (ep EnumPicker) Pick(name string) (string, error) {
+	dice, exists := ep[name]
+	if !exists {
+		return "", errors.New("enum named " + name + " was not defined")
+	}
+	return dice.Dice(), nil
+}
+
+// templateFuncs 返回绑定了规则上下文的模板函数，优先于全局模板函数
+func (ep EnumPicker) templateFuncs() template.FuncMap {
+	return template.FuncMap{"enumFrom": ep.Pick}
+}
+
+func enumFromWithoutRule(name string) (string, error) {
+	return "", errors.New("enum named " + name + " was not defined")
+}
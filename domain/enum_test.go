@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestParseEnums(t *testing.T) {
+	picker, err := parseEnums(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, picker)
+
+	var variable map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"enums": {"status": ["CREATED", "PAID", "CLOSED"], "channel": {"alipay": 3, "wechat": 1, "unionpay": 0}}}`), &variable))
+	picker, err = parseEnums(variable)
+	assert.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		v, err := picker.Pick("status")
+		assert.NoError(t, err)
+		assert.Contains(t, []string{"CREATED", "PAID", "CLOSED"}, v)
+
+		v, err = picker.Pick("channel")
+		assert.NoError(t, err)
+		assert.Contains(t, []string{"alipay", "wechat"}, v)
+	}
+
+	_, err = picker.Pick("unknown")
+	assert.Error(t, err)
+
+	for _, bad := range []string{
+		`{"enums": ["CREATED"]}`,
+		`{"enums": {"status": "CREATED"}}`,
+		`{"enums": {"status": {"CREATED": "high"}}}`,
+		`{"enums": {"status": []}}`,
+		`{"enums": {"status": {"CREATED": 0}}}`,
+	} {
+		var v map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(bad), &v))
+		_, err = parseEnums(v)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestEnumFromFunc(t *testing.T) {
+	var variable map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"enums": {"status": ["CREATED", "CLOSED"]}}`), &variable))
+	rule := &Rule{
+		Path:     "/api/v1/order",
+		Method:   "GET",
+		Variable: variable,
+		Regulations: []*Regulation{
+			{
+				IsDefault: true,
+				Template:  &Template{IsTemplate: true, Body: `{"status": "{{enumFrom "status"}}"}`},
+			},
+		},
+	}
+	executor, err := rule.To()
+	assert.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		ctx := new(fasthttp.RequestCtx)
+		assert.NoError(t, executor.Regulations[0].Render(ctx, executor.Variable, nil))
+		assert.Contains(t, []string{`{"status": "CREATED"}`, `{"status": "CLOSED"}`}, string(ctx.Response.Body()))
+	}
+
+	// 未在规则上下文中定义的枚举
+	te, err := (&Template{IsTemplate: true, Body: `{{enumFrom "status"}}`}).To()
+	assert.NoError(t, err)
+	assert.Error(t, te.Render(new(fasthttp.RequestCtx), nil, nil))
+
+	rule.Variable = map[string]interface{}{"enums": "bad"}
+	assert.Error(t, rule.Validate())
+}
@@ -2,16 +2,29 @@ package domain
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"hash/fnv"
 	"html/template"
+	"math"
 	"math/rand"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	texttemplate "text/template"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/valyala/fasthttp"
 	"github.com/wosai/deepmock/misc"
+	"go.uber.org/zap"
 )
 
 const (
@@ -23,9 +36,23 @@ const (
 	FilterModeKeyword FilterMode = "keyword"
 	// FilterModeRegular 正则表达式模式
 	FilterModeRegular FilterMode = "regular"
+	// FilterModeHasKeys JSON报文中存在指定路径的字段即通过，不关心字段值
+	FilterModeHasKeys FilterMode = "has_keys"
+	// FilterModeSize 仅根据报文长度筛选
+	FilterModeSize FilterMode = "size"
+	// FilterModeBodyJSONPath 请求头的值与JSON请求报文中指定路径的字段值相等即通过
+	FilterModeBodyJSONPath FilterMode = "body_json_path"
 
 	// ModeField 筛选模式的字段名称
 	ModeField = "mode"
+	// MinSizeField body筛选中报文长度下限的字段名称，可以与任意筛选模式组合
+	MinSizeField = "min_size"
+	// MaxSizeField body筛选中报文长度上限的字段名称，可以与任意筛选模式组合
+	MaxSizeField = "max_size"
+	// DecodeField body筛选中报文解码方式的字段名称，解码后再进行长度检查与匹配，可以与任意筛选模式组合
+	DecodeField = "decode"
+	// DecodeBase64 先对报文做base64解码，无法解码时不通过
+	DecodeBase64 = "base64"
 )
 
 var (
@@ -38,17 +65,39 @@ type (
 
 	// Executor 规则执行器
 	Executor struct {
+		Hits        HitCounter
 		ID          string
 		Method      []byte
 		Path        *regexp.Regexp
 		Variable    map[string]interface{}
-		Weight      WeightPicker
+		Weight      *WeightPicker
 		Regulations []*RegulationExecutor
 		Version     int
+		Disabled    bool // 停用的执行器不参与匹配
+		Debug       bool
+		RateLimiter *TokenBucket
+		CORS        *CORS
+		Concurrency *ConcurrencyLimiter
+		Overflow    *TemplateExecutor // 超出并发数上限时的响应，为空时返回503
+		Duplicate   *DuplicateExecutor
+		SlowStart   *SlowStartExecutor
+		// ResponseSchema 调试模式下校验渲染结果，为空时不校验
+		ResponseSchema *SchemaValidator
+		// ExpireAt 规则的过期时间，零值表示永不过期
+		ExpireAt    time.Time
+		Priority    int          // path重叠时优先级高的执行器优先匹配
+		Host        *HostMatcher // 为空时匹配任意Host
+		StatusDelay *StatusDelayExecutor
+		Maintenance *MaintenanceExecutor
+		// PartialsRevision 构建执行器时模板片段的版本号，模板片段变更后需要重建执行器
+		PartialsRevision uint64
 	}
 
-	// WeightPicker 权重随机值选择器
-	WeightPicker map[string]*WeightDice
+	// WeightPicker 权重随机值选择器，创建后不再修改：更新规则时整体替换执行器，
+	// 正在渲染的请求继续使用取到的旧执行器及其权重，不会看到新旧权重混合的结果
+	WeightPicker struct {
+		dices map[string]*WeightDice
+	}
 
 	// WeightDice 权重随机值对象
 	WeightDice struct {
@@ -59,9 +108,15 @@ type (
 
 	// RegulationExecutor 报文规则执行器
 	RegulationExecutor struct {
-		IsDefault bool
-		Filter    *FilterExecutor
-		Template  *TemplateExecutor
+		Hits           HitCounter
+		Index          int
+		IsDefault      bool
+		Filter         *FilterExecutor
+		Template       *TemplateExecutor
+		Sequence       *SequenceExecutor // 不为空时代替Template依次返回响应
+		Retry          *RetryExecutor    // 不为空时根据请求的重试次数返回响应
+		Created        *CreatedExecutor  // 不为空时代替Template返回新建的资源
+		ReflectHeaders []string
 	}
 
 	// TemplateExecutor 响应报文模板执行器
@@ -69,8 +124,21 @@ type (
 		IsGolangTemplate bool
 		IsBinData        bool
 		template         *template.Template
+		status           *template.Template
+		compress         string
+		headerTemplates  []*headerTemplate
+		multipart        *multipartExecutor
 		header           *fasthttp.ResponseHeader
 		body             []byte
+		sniff            bool     // 未配置Content-Type时根据渲染后的body推断
+		fragments        []string // 非模板响应在渲染时依次拼接的模板片段
+		locales          *localeExecutors
+	}
+
+	// headerTemplate 需要渲染的响应头，values依次对应同名响应头的多个值
+	headerTemplate struct {
+		key    string
+		values []*texttemplate.Template
 	}
 
 	// RenderContext 动态渲染的上下文
@@ -81,13 +149,26 @@ type (
 		Query    map[string]string
 		Form     map[string]string
 		Json     map[string]interface{}
+		Xml      map[string]interface{} // XML请求报文解析后的结果，以根元素名为key
+		Cookie   map[string]string
+		// RemoteAddr 调用方的地址，格式为ip:port
+		RemoteAddr string
 	}
 
 	// FilterExecutor 筛选执行器
 	FilterExecutor struct {
-		Query  *QueryFilterExecutor
-		Header *HeaderFilterExecutor
-		Body   *BodyFilterExecutor
+		Query         *QueryFilterExecutor
+		RawQuery      *RawQueryFilterExecutor
+		Header        *HeaderFilterExecutor
+		Cookie        *CookieFilterExecutor
+		Body          *BodyFilterExecutor
+		Expression    *ExpressionFilterExecutor
+		ContentLength *ContentLengthFilterExecutor
+		PathSegments  *PathSegmentsFilterExecutor
+		UserAgent     *UserAgentFilterExecutor
+		Sample        *SampleFilterExecutor
+		Upload        *UploadFilterExecutor
+		Form          *QueryFilterExecutor // 未设置时为空，避免解析表单
 	}
 
 	// BodyFilterExecutor Body报文筛选执行器
@@ -95,6 +176,18 @@ type (
 		mode    FilterMode
 		regular *regexp.Regexp
 		keyword []byte
+		value   []byte // exact模式下完整的报文
+		keys    []string
+		minSize int    // 为0时表示不限制
+		maxSize int    // 为0时表示不限制
+		decode  string // 为空时不解码
+	}
+
+	// RawQueryFilterExecutor 原始query string筛选执行器，不经过解析，因此参数顺序同样参与匹配
+	RawQueryFilterExecutor struct {
+		mode    FilterMode
+		regular *regexp.Regexp
+		value   []byte
 	}
 
 	// HeaderFilterExecutor 请求头筛选执行器
@@ -104,6 +197,13 @@ type (
 		regulars map[string]*regexp.Regexp
 	}
 
+	// CookieFilterExecutor cookie筛选执行器，按名称匹配Cookie请求头中解析出的cookie值
+	CookieFilterExecutor struct {
+		params   map[string][]byte
+		mode     FilterMode
+		regulars map[string]*regexp.Regexp
+	}
+
 	// QueryFilterExecutor Query参数筛选执行器
 	QueryFilterExecutor struct {
 		params   map[string][]byte
@@ -113,16 +213,34 @@ type (
 )
 
 // DiceAll 返回所有权重因子的值
-func (wp WeightPicker) DiceAll() map[string]string {
+func (wp *WeightPicker) DiceAll() map[string]string {
 	ret := make(map[string]string)
-	for k, v := range wp {
+	if wp == nil {
+		return ret
+	}
+	for k, v := range wp.dices {
 		ret[k] = v.Dice()
 	}
 	return ret
 }
 
-// Dice 更具权重值随机返回某个值
+// NewWeightPicker 工厂函数
+func NewWeightPicker(weight map[string]WeightFactor) *WeightPicker {
+	dices := make(map[string]*WeightDice, len(weight))
+	for k, factor := range weight {
+		dices[k] = factor.To()
+	}
+	return &WeightPicker{dices: dices}
+}
+
+// Dice 更具权重值随机返回某个值，没有可选值时返回空字符串；测试模式下返回确定的值，见SetWeightTestMode
 func (wd *WeightDice) Dice() string {
+	if wd.total == 0 {
+		return ""
+	}
+	if mode := loadWeightTestMode(); mode != nil && mode.enabled {
+		return mode.pick(wd)
+	}
 	return wd.distribution[rand.Intn(wd.total)]
 }
 
@@ -153,7 +271,7 @@ func (hfe *HeaderFilterExecutor) filterByRegular(header *fasthttp.RequestHeader)
 	return true
 }
 
-// Filter 筛选函数
+// Filter 筛选函数，body_json_path模式需要读取请求报文，只能通过FilterRequest筛选
 func (hfe *HeaderFilterExecutor) Filter(header *fasthttp.RequestHeader) bool {
 	if hfe == nil {
 		return true
@@ -177,6 +295,45 @@ func (hfe *HeaderFilterExecutor) Filter(header *fasthttp.RequestHeader) bool {
 	}
 }
 
+// Filter 筛选函数，不存在的cookie视为空值
+func (cfe *CookieFilterExecutor) Filter(header *fasthttp.RequestHeader) bool {
+	if cfe == nil {
+		return true
+	}
+
+	switch cfe.mode {
+	case FilterModeAlwaysTrue:
+		return true
+
+	case FilterModeExact:
+		for k, v := range cfe.params {
+			if !bytes.Equal(header.Cookie(k), v) {
+				return false
+			}
+		}
+		return true
+
+	case FilterModeKeyword:
+		for k, v := range cfe.params {
+			if !bytes.Contains(header.Cookie(k), v) {
+				return false
+			}
+		}
+		return true
+
+	case FilterModeRegular:
+		for k, reg := range cfe.regulars {
+			if !reg.Match(header.Cookie(k)) {
+				return false
+			}
+		}
+		return true
+
+	default:
+		return false
+	}
+}
+
 func (qfe *QueryFilterExecutor) filterByExactKeyValue(args *fasthttp.Args) bool {
 	for k, v := range qfe.params {
 		if bytes.Compare(args.Peek(k), v) != 0 {
@@ -229,20 +386,23 @@ func (qfe *QueryFilterExecutor) Filter(args *fasthttp.Args) bool {
 }
 
 // Filter 筛选函数
-func (bfe *BodyFilterExecutor) Filter(body []byte) bool {
-	if bfe == nil {
+func (rqfe *RawQueryFilterExecutor) Filter(queryString []byte) bool {
+	if rqfe == nil {
 		return true
 	}
 
-	switch bfe.mode {
+	switch rqfe.mode {
 	case FilterModeAlwaysTrue:
 		return true
 
+	case FilterModeExact:
+		return bytes.Equal(queryString, rqfe.value)
+
 	case FilterModeKeyword:
-		return bytes.Contains(body, bfe.keyword)
+		return bytes.Contains(queryString, rqfe.value)
 
 	case FilterModeRegular:
-		return bfe.regular.Match(body)
+		return rqfe.regular.Match(queryString)
 
 	default:
 		return false
@@ -250,29 +410,196 @@ func (bfe *BodyFilterExecutor) Filter(body []byte) bool {
 }
 
 // Filter 筛选函数
-func (fe *FilterExecutor) Filter(request *fasthttp.Request) bool {
-	if fe == nil {
+func (bfe *BodyFilterExecutor) Filter(body []byte) bool {
+	if bfe == nil {
 		return true
 	}
-	if !fe.Header.Filter(&request.Header) {
-		return false
+
+	if bfe.decode == DecodeBase64 {
+		decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(body)))
+		if err != nil {
+			return false
+		}
+		body = decoded
 	}
-	if !fe.Query.Filter(request.URI().QueryArgs()) {
+
+	// 先检查报文长度，避免对长度不符的报文做关键字或正则匹配
+	if len(body) < bfe.minSize || (bfe.maxSize > 0 && len(body) > bfe.maxSize) {
 		return false
 	}
-	if !fe.Body.Filter(request.Body()) {
+
+	switch bfe.mode {
+	case FilterModeAlwaysTrue, FilterModeSize:
+		return true
+
+	case FilterModeExact:
+		return bytes.Equal(body, bfe.value)
+
+	case FilterModeKeyword:
+		return bytes.Contains(body, bfe.keyword)
+
+	case FilterModeRegular:
+		return bfe.regular.Match(body)
+
+	case FilterModeHasKeys:
+		return bfe.hasKeys(body)
+
+	default:
 		return false
 	}
+}
 
+// hasKeys 判断JSON(或XML)报文是否包含所有指定路径的字段，路径格式与模板函数ctx一致
+func (bfe *BodyFilterExecutor) hasKeys(body []byte) bool {
+	var doc map[string]interface{}
+	if looksLikeXML(body) {
+		var err error
+		if doc, err = decodeXML(body); err != nil {
+			return false
+		}
+	} else if err := json.Unmarshal(body, &doc); err != nil {
+		return false
+	}
+	for _, key := range bfe.keys {
+		if _, err := VariableReader(doc).Lookup(key); err != nil {
+			return false
+		}
+	}
 	return true
 }
 
+// filterStep 单个筛选器的评估步骤，Filter、Diagnose与Trace共用filterSteps，新增筛选器时只需要加入该列表
+type filterStep struct {
+	name       string
+	configured func(fe *FilterExecutor) bool                                     // 未配置(always_true)的筛选器不出现在Trace结果中
+	filter     func(fe *FilterExecutor, request *fasthttp.Request) bool          // 未配置时返回true
+	trace      func(fe *FilterExecutor, request *fasthttp.Request) []FilterTrace // 按参数拆分评估，为空时整体评估
+}
+
+// filterSteps 按评估顺序排列的筛选器，开销小的筛选器在前
+var filterSteps = []filterStep{
+	{
+		name:       "content_length",
+		configured: func(fe *FilterExecutor) bool { return fe.ContentLength != nil },
+		filter: func(fe *FilterExecutor, request *fasthttp.Request) bool {
+			return fe.ContentLength.Filter(&request.Header)
+		},
+	},
+	{
+		name:       "path_segments",
+		configured: func(fe *FilterExecutor) bool { return fe.PathSegments != nil },
+		filter: func(fe *FilterExecutor, request *fasthttp.Request) bool {
+			return fe.PathSegments.Filter(request.URI().Path())
+		},
+	},
+	{
+		name:       "user_agent",
+		configured: func(fe *FilterExecutor) bool { return fe.UserAgent != nil },
+		filter:     func(fe *FilterExecutor, request *fasthttp.Request) bool { return fe.UserAgent.Filter(&request.Header) },
+	},
+	{
+		name:       "header",
+		configured: func(fe *FilterExecutor) bool { return fe.Header != nil && fe.Header.mode != FilterModeAlwaysTrue },
+		filter:     func(fe *FilterExecutor, request *fasthttp.Request) bool { return fe.Header.FilterRequest(request) },
+		trace:      func(fe *FilterExecutor, request *fasthttp.Request) []FilterTrace { return fe.Header.trace(request) },
+	},
+	{
+		name:       "cookie",
+		configured: func(fe *FilterExecutor) bool { return fe.Cookie != nil && fe.Cookie.mode != FilterModeAlwaysTrue },
+		filter:     func(fe *FilterExecutor, request *fasthttp.Request) bool { return fe.Cookie.Filter(&request.Header) },
+		trace: func(fe *FilterExecutor, request *fasthttp.Request) []FilterTrace {
+			return fe.Cookie.trace(&request.Header)
+		},
+	},
+	{
+		name:       "query",
+		configured: func(fe *FilterExecutor) bool { return fe.Query != nil && fe.Query.mode != FilterModeAlwaysTrue },
+		filter: func(fe *FilterExecutor, request *fasthttp.Request) bool {
+			return fe.Query.Filter(request.URI().QueryArgs())
+		},
+		trace: func(fe *FilterExecutor, request *fasthttp.Request) []FilterTrace {
+			return fe.Query.trace("query", request.URI().QueryArgs())
+		},
+	},
+	{
+		name:       "raw_query",
+		configured: func(fe *FilterExecutor) bool { return fe.RawQuery != nil && fe.RawQuery.mode != FilterModeAlwaysTrue },
+		filter: func(fe *FilterExecutor, request *fasthttp.Request) bool {
+			return fe.RawQuery.Filter(request.URI().QueryString())
+		},
+	},
+	{
+		name: "body",
+		configured: func(fe *FilterExecutor) bool {
+			return fe.Body != nil && (fe.Body.mode != FilterModeAlwaysTrue || fe.Body.minSize > 0 || fe.Body.maxSize > 0 || fe.Body.decode != "")
+		},
+		filter: func(fe *FilterExecutor, request *fasthttp.Request) bool { return fe.Body.Filter(request.Body()) },
+	},
+	{
+		name:       "upload",
+		configured: func(fe *FilterExecutor) bool { return fe.Upload != nil },
+		filter:     func(fe *FilterExecutor, request *fasthttp.Request) bool { return fe.Upload.Filter(request) },
+	},
+	{
+		name:       "form",
+		configured: func(fe *FilterExecutor) bool { return fe.Form != nil && fe.Form.mode != FilterModeAlwaysTrue },
+		// 解析表单的开销较大，未配置时跳过
+		filter: func(fe *FilterExecutor, request *fasthttp.Request) bool {
+			return fe.Form == nil || fe.Form.Filter(extractFormArgs(request))
+		},
+		trace: func(fe *FilterExecutor, request *fasthttp.Request) []FilterTrace {
+			return fe.Form.trace("form", extractFormArgs(request))
+		},
+	},
+	{
+		name:       "expression",
+		configured: func(fe *FilterExecutor) bool { return fe.Expression != nil },
+		filter:     func(fe *FilterExecutor, request *fasthttp.Request) bool { return fe.Expression.Filter(request) },
+	},
+	{
+		name:       "sample",
+		configured: func(fe *FilterExecutor) bool { return fe.Sample != nil },
+		filter:     func(fe *FilterExecutor, request *fasthttp.Request) bool { return fe.Sample.Filter(request) },
+	},
+}
+
+// Filter 筛选函数
+func (fe *FilterExecutor) Filter(request *fasthttp.Request) bool {
+	return fe.Diagnose(request) == ""
+}
+
+// Diagnose 返回第一个未通过的筛选器名称，全部通过时返回空字符串
+func (fe *FilterExecutor) Diagnose(request *fasthttp.Request) string {
+	if fe == nil {
+		return ""
+	}
+	for _, step := range filterSteps {
+		if !step.filter(fe, request) {
+			return step.name
+		}
+	}
+	return ""
+}
+
 // Render 渲染函数
 func (te *TemplateExecutor) Render(ctx *fasthttp.RequestCtx, v map[string]interface{}, weight map[string]string) error {
-	te.header.CopyTo(&ctx.Response.Header)
-	if !te.IsGolangTemplate {
-		ctx.Response.SetBody(te.body)
-		return nil
+	target, locale := te.localize(ctx)
+	if err := target.render(ctx, v, weight); err != nil {
+		return err
+	}
+	if te.locales != nil {
+		ctx.Response.Header.Add(fasthttp.HeaderVary, headerAcceptLanguage)
+		if locale != "" {
+			ctx.Response.Header.Set(headerContentLanguage, locale)
+		}
+	}
+	compressBody(ctx, te.compress)
+	return nil
+}
+
+func (te *TemplateExecutor) render(ctx *fasthttp.RequestCtx, v map[string]interface{}, weight map[string]string) error {
+	if !te.IsGolangTemplate && te.status == nil {
+		return te.Execute(&ctx.Response, nil)
 	}
 
 	// 开始渲染模板
@@ -287,12 +614,118 @@ func (te *TemplateExecutor) Render(ctx *fasthttp.RequestCtx, v map[string]interf
 	rc.Query = q
 	rc.Form = f
 	rc.Json = j
-	return te.template.Execute(ctx.Response.BodyWriter(), rc)
+	rc.Xml = extractXMLBody(&ctx.Request)
+	rc.Cookie = extractCookieAsParams(&ctx.Request)
+	rc.RemoteAddr = ctx.RemoteAddr().String()
+	return te.Execute(&ctx.Response, &rc)
+}
+
+// Execute 使用给定的渲染上下文生成响应报文
+func (te *TemplateExecutor) Execute(resp *fasthttp.Response, rc *RenderContext) error {
+	te.header.CopyTo(&resp.Header)
+	if te.status != nil {
+		resp.SetStatusCode(te.renderStatusCode(rc))
+	}
+	if err := te.renderHeaders(resp, rc); err != nil {
+		return err
+	}
+	if te.multipart != nil {
+		return te.multipart.render(resp, rc)
+	}
+	switch {
+	case te.fragments != nil:
+		body, err := partials.concat(te.fragments)
+		if err != nil {
+			return err
+		}
+		resp.SetBody(body)
+	case !te.IsGolangTemplate:
+		resp.SetBody(te.body)
+		return nil
+	default:
+		if err := te.template.Execute(resp.BodyWriter(), rc); err != nil {
+			return err
+		}
+	}
+	if te.sniff {
+		if contentType := sniffContentType(resp.Body()); contentType != "" {
+			resp.Header.SetContentType(contentType)
+		}
+	}
+	return nil
+}
+
+// renderHeaders 渲染包含模板语法的响应头
+func (te *TemplateExecutor) renderHeaders(resp *fasthttp.Response, rc *RenderContext) error {
+	buf := new(bytes.Buffer)
+	for _, ht := range te.headerTemplates {
+		for index, tmpl := range ht.values {
+			buf.Reset()
+			if err := tmpl.Execute(buf, rc); err != nil {
+				return err
+			}
+			if index == 0 {
+				resp.Header.SetBytesV(ht.key, buf.Bytes())
+			} else {
+				resp.Header.AddBytesV(ht.key, buf.Bytes())
+			}
+		}
+	}
+	return nil
+}
+
+// renderStatusCode 渲染状态码模板，渲染失败或结果不是100-599之间的状态码时返回响应配置的status_code，未配置时为200
+func (te *TemplateExecutor) renderStatusCode(rc *RenderContext) int {
+	fallback := te.header.StatusCode()
+	buf := new(bytes.Buffer)
+	if err := te.status.Execute(buf, rc); err != nil {
+		misc.Logger.Warn("failed to render status code template, fallback to status_code", zap.Int("status_code", fallback), zap.Error(err))
+		return fallback
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(buf.String()))
+	if err != nil || code < 100 || code > 599 {
+		misc.Logger.Warn("bad status code rendered, fallback to status_code", zap.String("status", buf.String()), zap.Int("status_code", fallback))
+		return fallback
+	}
+	return code
 }
 
 // Render 渲染函数
 func (re *RegulationExecutor) Render(ctx *fasthttp.RequestCtx, v map[string]interface{}, w map[string]string) error {
-	return re.Template.Render(ctx, v, w)
+	te := re.Template
+	if re.Sequence != nil {
+		te = re.Sequence.Next()
+	}
+	var err error
+	switch retry := re.Retry.Pick(&ctx.Request.Header); {
+	case retry != nil:
+		err = retry.Render(ctx, v, w)
+	case re.Created != nil:
+		err = re.Created.Render(ctx)
+	default:
+		err = te.Render(ctx, v, w)
+	}
+	if err != nil {
+		return err
+	}
+	re.reflectHeaders(ctx)
+	return nil
+}
+
+// reflectHeaders 将白名单中且请求里存在的请求头回写到响应中，覆盖模板中的同名响应头
+func (re *RegulationExecutor) reflectHeaders(ctx *fasthttp.RequestCtx) {
+	for _, key := range re.ReflectHeaders {
+		if value := ctx.Request.Header.Peek(key); len(value) > 0 {
+			ctx.Response.Header.SetBytesV(key, value)
+		}
+	}
+}
+
+// ResetSequences 重置所有顺序响应，使其重新从第一个响应开始返回
+func (exe *Executor) ResetSequences() {
+	for _, regulation := range exe.Regulations {
+		regulation.Sequence.Reset()
+	}
 }
 
 // Match 请求匹配函数
@@ -303,6 +736,11 @@ func (exe *Executor) Match(path, method []byte) bool {
 	return exe.Path.Match(path)
 }
 
+// MatchHost 判断请求的Host头是否匹配，未声明host的执行器匹配任意Host
+func (exe *Executor) MatchHost(host []byte) bool {
+	return exe.Host.Match(host)
+}
+
 // FindRegulationExecutor 查找符合的报文规则执行器
 func (exe *Executor) FindRegulationExecutor(request *fasthttp.Request) *RegulationExecutor {
 	var reg *RegulationExecutor
@@ -327,6 +765,35 @@ func RegisterTemplateFunc(name string, f interface{}) error {
 	return nil
 }
 
+// ReplaceTemplateFunc 注册模板自定义函数，同名函数已存在时覆盖(如替换内置的date)；
+// 模板在创建规则时解析，因此替换只影响之后创建或更新的规则，已生效的规则继续使用原来的函数
+func ReplaceTemplateFunc(name string, f interface{}) error {
+	if f == nil || reflect.TypeOf(f).Kind() != reflect.Func {
+		return errors.New("func named " + name + " is not a function")
+	}
+	defaultTemplateFuncs[name] = f
+	return nil
+}
+
+// UnregisterTemplateFunc 注销模板自定义函数，与ReplaceTemplateFunc一样只影响之后创建或更新的规则
+func UnregisterTemplateFunc(name string) error {
+	if _, ok := defaultTemplateFuncs[name]; !ok {
+		return errors.New("func named " + name + " was not registered")
+	}
+	delete(defaultTemplateFuncs, name)
+	return nil
+}
+
+// ListTemplateFuncs 按名称排序列出已注册的模板自定义函数，不包括text/template的内置函数
+func ListTemplateFuncs() []string {
+	names := make([]string, 0, len(defaultTemplateFuncs))
+	for name := range defaultTemplateFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func genUUID() string {
 	return uuid.New().String()
 }
@@ -349,22 +816,78 @@ func formatDate(layout string) string {
 	return time.Now().Format(layout)
 }
 
+// locations 已加载的时区，避免每次渲染都读取时区数据；无效的时区缓存为UTC，每个时区只告警一次
+var locations sync.Map
+
+// dateIn 按指定的格式返回当前时间在zone(IANA时区名，如America/New_York)下的表示，时区无效时使用UTC
+func dateIn(layout, zone string) string {
+	if loc, ok := locations.Load(zone); ok {
+		return time.Now().In(loc.(*time.Location)).Format(layout)
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		loc = time.UTC
+	}
+	if _, loaded := locations.LoadOrStore(zone, loc); !loaded && err != nil {
+		misc.Logger.Warn("invalid time zone, fall back to UTC", zap.String("zone", zone), zap.Error(err))
+	}
+	return time.Now().In(loc).Format(layout)
+}
+
+// dateAddInvalidDuration dateAdd的时长无法解析时的返回值
+const dateAddInvalidDuration = "invalid duration"
+
+// dateAdd 按指定的格式返回当前时间加上duration(time.ParseDuration的格式，如72h，负数表示过去)后的时间
+func dateAdd(duration, layout string) string {
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return dateAddInvalidDuration
+	}
+	return time.Now().Add(d).Format(layout)
+}
+
+const (
+	arithmeticUnsupported    = "unsupported type"
+	arithmeticDivisionByZero = "division by zero"
+)
+
 func plus(v interface{}, i int) interface{} {
-	switch v.(type) {
+	return arithmetic(v, i, func(a, b int) int { return a + b }, func(a, b float64) float64 { return a + b })
+}
+
+func minus(v interface{}, i int) interface{} {
+	return arithmetic(v, i, func(a, b int) int { return a - b }, func(a, b float64) float64 { return a - b })
+}
+
+func mul(v interface{}, i int) interface{} {
+	return arithmetic(v, i, func(a, b int) int { return a * b }, func(a, b float64) float64 { return a * b })
+}
+
+// div 整数相除时向零取整，i为0时返回division by zero，不会导致模板执行失败
+func div(v interface{}, i int) interface{} {
+	if i == 0 {
+		return arithmeticDivisionByZero
+	}
+	return arithmetic(v, i, func(a, b int) int { return a / b }, func(a, b float64) float64 { return a / b })
+}
+
+// arithmetic 按v的类型计算v与i：int、float64、float32保持原有类型，string按int解析，无法计算时返回unsupported type
+func arithmetic(v interface{}, i int, intOp func(a, b int) int, floatOp func(a, b float64) float64) interface{} {
+	switch vv := v.(type) {
 	case int:
-		return v.(int) + i
+		return intOp(vv, i)
 	case float64:
-		return v.(float64) + float64(i)
+		return floatOp(vv, float64(i))
 	case float32:
-		return v.(float32) + float32(i)
+		return float32(floatOp(float64(vv), float64(i)))
 	case string:
-		vv, err := strconv.Atoi(v.(string))
+		n, err := strconv.Atoi(vv)
 		if err != nil {
-			return "unsupported type"
+			return arithmeticUnsupported
 		}
-		return vv + i
+		return intOp(n, i)
 	default:
-		return "unsupported type"
+		return arithmeticUnsupported
 	}
 }
 
@@ -376,6 +899,227 @@ func dateDelta(date, layout string, year, month, day int) string {
 	return t.AddDate(year, month, day).Format(layout)
 }
 
+// toSlice 将模板中传入的数组（如JSON数组、query参数）转换为[]interface{}
+func toSlice(slice interface{}) ([]interface{}, error) {
+	if slice == nil {
+		return nil, nil
+	}
+	v := reflect.ValueOf(slice)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("expected a slice but got %T", slice)
+	}
+	ret := make([]interface{}, v.Len())
+	for i := range ret {
+		ret[i] = v.Index(i).Interface()
+	}
+	return ret, nil
+}
+
+// sortStrings 将数组的每个元素转换为字符串后排序，相等元素保持原有的先后顺序
+func sortStrings(slice interface{}) ([]string, error) {
+	items, err := toSlice(slice)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]string, len(items))
+	for i, item := range items {
+		ret[i] = fmt.Sprint(item)
+	}
+	sort.Stable(sort.StringSlice(ret))
+	return ret, nil
+}
+
+// uniq 去除数组中的重复元素，保留每个元素第一次出现的位置，类型不同的元素（如1与"1"）不视为重复
+func uniq(slice interface{}) ([]interface{}, error) {
+	items, err := toSlice(slice)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]struct{}, len(items))
+	ret := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		key := fmt.Sprintf("%T:%v", item, item)
+		if _, exists := seen[key]; exists {
+			continue
+		}
+		seen[key] = struct{}{}
+		ret = append(ret, item)
+	}
+	return ret, nil
+}
+
+var (
+	// boolRand randBool使用的随机数源，可以通过SetBoolRandSeed固定种子
+	boolRand   = rand.New(rand.NewSource(time.Now().UnixNano()))
+	boolRandMu sync.Mutex
+)
+
+// SetBoolRandSeed 设置randBool使用的随机数种子，固定种子后生成的结果序列可以复现，便于测试；0表示使用随机种子
+func SetBoolRandSeed(seed int64) {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	boolRandMu.Lock()
+	defer boolRandMu.Unlock()
+	boolRand = rand.New(rand.NewSource(seed))
+}
+
+// randBool 以概率p返回true，p的取值范围为[0, 1]
+func randBool(p interface{}) (bool, error) {
+	var prob float64
+	switch v := p.(type) {
+	case float64:
+		prob = v
+	case int:
+		prob = float64(v)
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return false, fmt.Errorf("bad probability: %s", v)
+		}
+		prob = f
+	default:
+		return false, fmt.Errorf("unsupported probability type %T", p)
+	}
+	if prob < 0 || prob > 1 {
+		return false, fmt.Errorf("probability must be between 0 and 1, got %v", prob)
+	}
+
+	boolRandMu.Lock()
+	defer boolRandMu.Unlock()
+	return boolRand.Float64() < prob, nil
+}
+
+// defaultValue value为nil、空字符串或空的数组、切片、map时返回fallback，否则返回value
+func defaultValue(value, fallback interface{}) interface{} {
+	if isEmptyValue(value) {
+		return fallback
+	}
+	return value
+}
+
+func isEmptyValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Array, reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// ternary condition为真时返回ifTrue，否则返回ifFalse，真值的判断与模板中的if一致
+func ternary(condition, ifTrue, ifFalse interface{}) interface{} {
+	if truth, _ := template.IsTrue(condition); truth {
+		return ifTrue
+	}
+	return ifFalse
+}
+
+// colorFrom 根据seed计算稳定的十六进制颜色，如#3fa2c1，相同的seed总是返回相同的颜色
+func colorFrom(seed interface{}) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(fmt.Sprint(seed)))
+	return fmt.Sprintf("#%06x", h.Sum32()&0xffffff)
+}
+
+// hmacSHA256 使用secret计算message的HMAC-SHA256签名，encoding为hex(默认)或base64；
+// message为map或数组时使用其JSON序列化结果
+func hmacSHA256(secret string, message interface{}, encoding ...string) (string, error) {
+	var data []byte
+	switch v := message.(type) {
+	case nil:
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		data = b
+	default:
+		data = []byte(fmt.Sprint(v))
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(data)
+	digest := mac.Sum(nil)
+
+	enc := "hex"
+	if len(encoding) > 0 {
+		enc = encoding[0]
+	}
+	switch enc {
+	case "hex":
+		return hex.EncodeToString(digest), nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString(digest), nil
+	default:
+		return "", fmt.Errorf("unsupported encoding %s", enc)
+	}
+}
+
+var (
+	decimalByteUnits = []string{"B", "KB", "MB", "GB", "TB", "PB", "EB"}
+	binaryByteUnits  = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+)
+
+// humanBytes 将字节数转换为易读的大小，如1.5 GB；unit为decimal(默认，以1000进位)或binary(以1024进位)
+func humanBytes(v interface{}, unit ...string) (string, error) {
+	var size float64
+	switch n := v.(type) {
+	case int:
+		size = float64(n)
+	case int64:
+		size = float64(n)
+	case uint64:
+		size = float64(n)
+	case float64:
+		size = n
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		if err != nil {
+			return "", fmt.Errorf("bad byte count: %s", n)
+		}
+		size = f
+	default:
+		return "", fmt.Errorf("unsupported byte count type %T", v)
+	}
+
+	base, units := 1000.0, decimalByteUnits
+	if len(unit) > 0 {
+		switch unit[0] {
+		case "decimal":
+		case "binary":
+			base, units = 1024, binaryByteUnits
+		default:
+			return "", fmt.Errorf("unsupported unit system: %s", unit[0])
+		}
+	}
+
+	sign := ""
+	if size < 0 {
+		sign, size = "-", -size
+	}
+	if size < base {
+		return fmt.Sprintf("%s%d B", sign, int64(size)), nil
+	}
+	exp := 0
+	// 按保留一位小数后的值进位，避免出现1000 KB
+	for math.Round(size*10)/10 >= base && exp < len(units)-1 {
+		size /= base
+		exp++
+	}
+	formatted := strings.TrimSuffix(strconv.FormatFloat(size, 'f', 1, 64), ".0")
+	return fmt.Sprintf("%s%s %s", sign, formatted, units[exp]), nil
+}
+
 func init() {
 	// create build-in template functions
 	defaultTemplateFuncs = make(template.FuncMap)
@@ -383,6 +1127,27 @@ func init() {
 	_ = RegisterTemplateFunc("timestamp", currentTimestamp)
 	_ = RegisterTemplateFunc("date", formatDate)
 	_ = RegisterTemplateFunc("plus", plus)
+	_ = RegisterTemplateFunc("minus", minus)
+	_ = RegisterTemplateFunc("mul", mul)
+	_ = RegisterTemplateFunc("div", div)
 	_ = RegisterTemplateFunc("rand_string", misc.GenRandomString)
 	_ = RegisterTemplateFunc("date_delta", dateDelta)
+	_ = RegisterTemplateFunc("enumFrom", enumFromWithoutRule)
+	_ = RegisterTemplateFunc("ctx", ctxWithoutRule)
+	_ = RegisterTemplateFunc("gid", genGlobalID)
+	_ = RegisterTemplateFunc("sortStrings", sortStrings)
+	_ = RegisterTemplateFunc("uniq", uniq)
+	_ = RegisterTemplateFunc("humanBytes", humanBytes)
+	_ = RegisterTemplateFunc("randBool", randBool)
+	_ = RegisterTemplateFunc("default", defaultValue)
+	_ = RegisterTemplateFunc("ternary", ternary)
+	_ = RegisterTemplateFunc("fake", fake)
+	_ = RegisterTemplateFunc("colorFrom", colorFrom)
+	_ = RegisterTemplateFunc("hmacSHA256", hmacSHA256)
+	_ = RegisterTemplateFunc("paginate", paginate)
+	_ = RegisterTemplateFunc("grid", grid)
+	_ = RegisterTemplateFunc("randTime", randTime)
+	_ = RegisterTemplateFunc("xmlPath", xmlPath)
+	_ = RegisterTemplateFunc("dateIn", dateIn)
+	_ = RegisterTemplateFunc("dateAdd", dateAdd)
 }
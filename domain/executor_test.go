@@ -2,13 +2,26 @@ package domain
 
 import (
 	"bytes"
+	"compress/gzip"
+	"encoding/base64"
 	"fmt"
 	"html/template"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/valyala/fasthttp"
+	"github.com/wosai/deepmock/misc"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestHeaderFilter_Filter(t *testing.T) {
@@ -53,6 +66,47 @@ func TestHeaderFilter_Filter(t *testing.T) {
 	assert.False(t, hf.Filter(header))
 }
 
+func TestCookieFilter_Filter(t *testing.T) {
+	var params CookieFilterParams
+	cf, err := params.To()
+	assert.NoError(t, err)
+	assert.True(t, cf.Filter(&fasthttp.RequestHeader{}))
+
+	header := new(fasthttp.RequestHeader)
+	header.Set("Cookie", "theme=dark; session=abc123; lang=zh-CN")
+
+	cf, err = CookieFilterParams{"session": "abc123", "lang": "zh-CN", "mode": "exact"}.To()
+	assert.NoError(t, err)
+	assert.True(t, cf.Filter(header))
+	cf, err = CookieFilterParams{"session": "abc", "mode": "exact"}.To()
+	assert.NoError(t, err)
+	assert.False(t, cf.Filter(header))
+
+	cf, err = CookieFilterParams{"theme": "ar", "mode": "keyword"}.To()
+	assert.NoError(t, err)
+	assert.True(t, cf.Filter(header))
+
+	cf, err = CookieFilterParams{"session": "^[a-z]+[0-9]+$", "mode": "regular"}.To()
+	assert.NoError(t, err)
+	assert.True(t, cf.Filter(header))
+
+	// 缺少cookie时不通过
+	cf, err = CookieFilterParams{"token": ".+", "mode": "regular"}.To()
+	assert.NoError(t, err)
+	assert.False(t, cf.Filter(header))
+	assert.False(t, cf.Filter(new(fasthttp.RequestHeader)))
+
+	// 不应匹配到其他请求头或cookie值的一部分
+	header.Set("session", "xyz")
+	cf, err = CookieFilterParams{"session": "xyz", "mode": "exact"}.To()
+	assert.NoError(t, err)
+	assert.False(t, cf.Filter(header))
+
+	_, err = CookieFilterParams{"session": "[", "mode": "regular"}.To()
+	assert.Error(t, err)
+	assert.Error(t, (&Filter{Cookie: CookieFilterParams{"session": "abc"}}).Validate())
+}
+
 func TestBodyFilter_Filter(t *testing.T) {
 	var params BodyFilterParams
 	bf, err := params.To()
@@ -70,6 +124,96 @@ func TestBodyFilter_Filter(t *testing.T) {
 	assert.True(t, bf.Filter([]byte(`my phone number is 110`)))
 }
 
+func TestBodyFilter_Exact(t *testing.T) {
+	bf, err := BodyFilterParams{"exact": "foobar", "mode": "exact"}.To()
+	assert.NoError(t, err)
+	assert.True(t, bf.Filter([]byte(`foobar`)))
+	assert.False(t, bf.Filter([]byte(`hello foobar`)))
+	assert.False(t, bf.Filter(nil))
+}
+
+func TestBodyFilter_DecodeBase64(t *testing.T) {
+	encode := func(s string) []byte {
+		return []byte(base64.StdEncoding.EncodeToString([]byte(s)))
+	}
+
+	bf, err := BodyFilterParams{"keyword": "foobar", "mode": "keyword", "decode": "base64"}.To()
+	assert.NoError(t, err)
+	assert.True(t, bf.Filter(encode(`hello foobar`)))
+	assert.False(t, bf.Filter(encode(`hello world`)))
+	// 匹配解码后的内容，而不是原始报文
+	assert.False(t, bf.Filter([]byte(`hello foobar`)))
+	// 忽略首尾的空白
+	assert.True(t, bf.Filter(append(encode(`foobar`), '\n')))
+	// 无法解码时不通过
+	assert.False(t, bf.Filter([]byte(`!!foobar!!`)))
+
+	bf, err = BodyFilterParams{"regular": "^[0-9]+$", "mode": "regular", "decode": "base64"}.To()
+	assert.NoError(t, err)
+	assert.True(t, bf.Filter(encode(`110`)))
+	assert.False(t, bf.Filter(encode(`abc`)))
+
+	bf, err = BodyFilterParams{"exact": `{"id":1}`, "mode": "exact", "decode": "base64"}.To()
+	assert.NoError(t, err)
+	assert.True(t, bf.Filter(encode(`{"id":1}`)))
+	assert.False(t, bf.Filter(encode(`{"id":2}`)))
+
+	// 长度检查同样作用于解码后的内容
+	bf, err = BodyFilterParams{"max_size": "3", "mode": "size", "decode": "base64"}.To()
+	assert.NoError(t, err)
+	assert.True(t, bf.Filter(encode(`abc`)))
+	assert.False(t, bf.Filter(encode(`abcd`)))
+
+	_, err = BodyFilterParams{"keyword": "foobar", "mode": "keyword", "decode": "hex"}.To()
+	assert.Error(t, err)
+}
+
+func TestBodyFilter_HasKeys(t *testing.T) {
+	bf, err := BodyFilterParams{"keys": "user.id, user.tags.0, order", "mode": "has_keys"}.To()
+	assert.NoError(t, err)
+	assert.True(t, bf.Filter([]byte(`{"user": {"id": null, "tags": ["a"]}, "order": {}}`)))
+	assert.True(t, bf.Filter([]byte(`{"user": {"id": 0, "tags": [false], "name": "x"}, "order": 1}`)))
+	assert.False(t, bf.Filter([]byte(`{"user": {"id": 1, "tags": []}, "order": {}}`)))
+	assert.False(t, bf.Filter([]byte(`{"user": {"tags": ["a"]}, "order": {}}`)))
+	assert.False(t, bf.Filter([]byte(`user.id`)))
+	assert.False(t, bf.Filter(nil))
+
+	_, err = BodyFilterParams{"keys": " , ", "mode": "has_keys"}.To()
+	assert.Error(t, err)
+}
+
+func TestBodyFilter_Size(t *testing.T) {
+	bf, err := BodyFilterParams{"min_size": "4", "max_size": "8", "mode": "size"}.To()
+	assert.NoError(t, err)
+	assert.False(t, bf.Filter([]byte(`abc`)))
+	assert.True(t, bf.Filter([]byte(`abcd`)))
+	assert.True(t, bf.Filter([]byte(`abcdefgh`)))
+	assert.False(t, bf.Filter([]byte(`abcdefghi`)))
+	assert.False(t, bf.Filter(nil))
+
+	// 仅设置下限，模拟超大报文
+	bf, err = BodyFilterParams{"min_size": "1024", "mode": "size"}.To()
+	assert.NoError(t, err)
+	assert.False(t, bf.Filter(make([]byte, 1023)))
+	assert.True(t, bf.Filter(make([]byte, 1024)))
+
+	// 与其他筛选模式组合时，长度不符直接不通过
+	bf, err = BodyFilterParams{"regular": "^[0-9]+$", "max_size": "3", "mode": "regular"}.To()
+	assert.NoError(t, err)
+	assert.True(t, bf.Filter([]byte(`110`)))
+	assert.False(t, bf.Filter([]byte(`1100`)))
+	assert.False(t, bf.Filter([]byte(`abc`)))
+
+	_, err = BodyFilterParams{"mode": "size"}.To()
+	assert.Error(t, err)
+	_, err = BodyFilterParams{"min_size": "-1", "mode": "size"}.To()
+	assert.Error(t, err)
+	_, err = BodyFilterParams{"max_size": "1k", "mode": "size"}.To()
+	assert.Error(t, err)
+	_, err = BodyFilterParams{"min_size": "9", "max_size": "8", "mode": "size"}.To()
+	assert.Error(t, err)
+}
+
 func TestQueryFilter_Filter(t *testing.T) {
 	assertion := assert.New(t)
 
@@ -126,7 +270,7 @@ func TestEmptyFilterExecutor(t *testing.T) {
 func TestNewResponseTemplate(t *testing.T) {
 	res := &Template{
 		IsTemplate:     true,
-		Header:         map[string]string{"Content-Type": "application/json", "Authorization": "123123"},
+		Header:         map[string]misc.StringValues{"Content-Type": {"application/json"}, "Authorization": {"123123"}},
 		StatusCode:     500,
 		Body:           "hello world",
 		B64EncodedBody: "aGVsbG8gZm9vYmFyIQ==",
@@ -185,6 +329,59 @@ func TestTimestampFunc(t *testing.T) {
 	assert.Equal(t, len(buff.String()), 13)
 }
 
+// renderWithDefaultFuncs 使用defaultTemplateFuncs渲染text，未指定ctx时使用空的RenderContext
+func renderWithDefaultFuncs(t *testing.T, text string, ctx ...RenderContext) string {
+	var rc RenderContext
+	if len(ctx) > 0 {
+		rc = ctx[0]
+	}
+	tmpl, err := template.New("test").Funcs(defaultTemplateFuncs).Parse(text)
+	assert.NoError(t, err)
+	buf := bytes.NewBuffer(nil)
+	assert.NoError(t, tmpl.Execute(buf, rc))
+	return buf.String()
+}
+
+// assertRenderedNow 渲染前后各调用一次expected，避免渲染期间恰好跨过时间边界(整点、零点等)导致误判
+func assertRenderedNow(t *testing.T, text string, expected func() string) {
+	before := expected()
+	got := renderWithDefaultFuncs(t, text)
+	after := expected()
+	assert.Contains(t, []string{before, after}, got, text)
+}
+
+func TestDateInFunc(t *testing.T) {
+	utcNow := func(layout string) func() string {
+		return func() string { return time.Now().UTC().Format(layout) }
+	}
+	assertRenderedNow(t, `{{dateIn "2006-01-02 15 MST" "UTC"}}`, utcNow("2006-01-02 15 MST"))
+	// 无效的时区使用UTC
+	assertRenderedNow(t, `{{dateIn "15 MST" "Mars/Olympus_Mons"}}`, utcNow("15 MST"))
+	// 同一个无效时区只告警一次
+	core, logs := observer.New(zap.WarnLevel)
+	origin := misc.Logger
+	misc.Logger = zap.New(core)
+	defer func() { misc.Logger = origin }()
+	assertRenderedNow(t, `{{dateIn "15 MST" "Mars/Arsia_Mons"}}`, utcNow("15 MST"))
+	assertRenderedNow(t, `{{dateIn "15 MST" "Mars/Arsia_Mons"}}`, utcNow("15 MST"))
+	assert.Equal(t, 1, logs.FilterMessage("invalid time zone, fall back to UTC").Len())
+	if _, err := time.LoadLocation("Asia/Shanghai"); err == nil {
+		assert.Equal(t, "CST", renderWithDefaultFuncs(t, `{{dateIn "MST" "Asia/Shanghai"}}`))
+		// 第二次使用缓存的时区
+		assert.Equal(t, "CST", renderWithDefaultFuncs(t, `{{dateIn "MST" "Asia/Shanghai"}}`))
+	}
+}
+
+func TestDateAddFunc(t *testing.T) {
+	dayAfter := func(d time.Duration) func() string {
+		return func() string { return time.Now().Add(d).Format("2006-01-02") }
+	}
+	assertRenderedNow(t, `{{dateAdd "24h" "2006-01-02"}}`, dayAfter(24*time.Hour))
+	// 负数表示过去
+	assertRenderedNow(t, `{{dateAdd "-72h" "2006-01-02"}}`, dayAfter(-72*time.Hour))
+	assert.Equal(t, dateAddInvalidDuration, renderWithDefaultFuncs(t, `{{dateAdd "7d" "2006-01-02"}}`))
+}
+
 func TestPlusFunc(t *testing.T) {
 	tmpl, err := template.New("test").Funcs(defaultTemplateFuncs).Parse(
 		`{{ plus .Variable.string 2}}`)
@@ -210,6 +407,32 @@ func TestPlusFunc(t *testing.T) {
 	assert.Equal(t, buf.String(), "791.2")
 }
 
+func TestArithmeticFuncs(t *testing.T) {
+	ctx := RenderContext{Variable: map[string]interface{}{"string": "123", "int": 456, "float": 789.5, "bad": "1.5"}}
+
+	cases := map[string]string{
+		`{{minus .Variable.string 3}}`: "120",
+		`{{minus .Variable.int 500}}`:  "-44",
+		`{{minus .Variable.float 2}}`:  "787.5",
+		`{{mul .Variable.string 2}}`:   "246",
+		`{{mul .Variable.int -1}}`:     "-456",
+		`{{mul .Variable.float 2}}`:    "1579",
+		`{{div .Variable.string 10}}`:  "12",
+		`{{div .Variable.int 2}}`:      "228",
+		`{{div .Variable.float 2}}`:    "394.75",
+		`{{div .Variable.int 0}}`:      "division by zero",
+		`{{div .Variable.float 0}}`:    "division by zero",
+		`{{minus .Variable.bad 1}}`:    "unsupported type",
+		`{{mul .Variable.missing 1}}`:  "unsupported type",
+		// 组合计算
+		`{{div (mul .Variable.int 3) 4}}`: "342",
+	}
+	for text, expected := range cases {
+		assert.Equal(t, expected, renderWithDefaultFuncs(t, text, ctx), text)
+	}
+	assert.Equal(t, float32(2.5), div(float32(5), 2))
+}
+
 func TestGenRandString(t *testing.T) {
 	tmpl, err := template.New("test").Funcs(defaultTemplateFuncs).Parse(
 		`{{rand_string 8}}`)
@@ -274,3 +497,692 @@ func TestRuleExecutor_Minimal(t *testing.T) {
 	_, err := rule.To()
 	assert.NoError(t, err)
 }
+
+func TestFilterExecutor_Diagnose(t *testing.T) {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	req.SetRequestURI("/api/v1/query?version=1")
+	req.Header.Set("X-Env", "base")
+	req.SetBody([]byte(`{"hello":"deepmock"}`))
+
+	var fe *FilterExecutor
+	assert.Equal(t, "", fe.Diagnose(req))
+
+	fe = new(FilterExecutor)
+	fe.Header, _ = HeaderFilterParams{"X-Env": "prod", "mode": "exact"}.To()
+	assert.Equal(t, "header", fe.Diagnose(req))
+
+	fe.Header, _ = HeaderFilterParams{"X-Env": "base", "mode": "exact"}.To()
+	fe.Cookie, _ = CookieFilterParams{"session": ".+", "mode": "regular"}.To()
+	assert.Equal(t, "cookie", fe.Diagnose(req))
+	fe.Cookie = nil
+
+	fe.Header, _ = HeaderFilterParams{"X-Env": "base", "mode": "exact"}.To()
+	fe.Query, _ = QueryFilterParams{"version": "2", "mode": "exact"}.To()
+	assert.Equal(t, "query", fe.Diagnose(req))
+
+	fe.Query, _ = QueryFilterParams{"version": "1", "mode": "exact"}.To()
+	fe.Body, _ = BodyFilterParams{"keyword": "foobar", "mode": "keyword"}.To()
+	assert.Equal(t, "body", fe.Diagnose(req))
+
+	fe.Body, _ = BodyFilterParams{"keyword": "deepmock", "mode": "keyword"}.To()
+	assert.Equal(t, "", fe.Diagnose(req))
+	assert.True(t, fe.Filter(req))
+}
+
+func TestTemplateExecutor_DuplicateHeader(t *testing.T) {
+	te, err := (&Template{
+		Header: map[string]misc.StringValues{
+			"Content-Type": {"application/json"},
+			"Link":         {`<https://api.example.com/items?page=2>; rel="next"`, `<https://api.example.com/items?page=5>; rel="last"`},
+		},
+		StatusCode: 200,
+		Body:       `[]`,
+	}).To()
+	assert.NoError(t, err)
+
+	ctx := new(fasthttp.RequestCtx)
+	assert.NoError(t, te.Render(ctx, nil, nil))
+
+	var links []string
+	ctx.Response.Header.VisitAll(func(key, value []byte) {
+		if string(key) == "Link" {
+			links = append(links, string(value))
+		}
+	})
+	assert.Equal(t, []string{`<https://api.example.com/items?page=2>; rel="next"`, `<https://api.example.com/items?page=5>; rel="last"`}, links)
+	assert.Equal(t, []byte("application/json"), ctx.Response.Header.ContentType())
+}
+
+func TestRawQueryFilter_Filter(t *testing.T) {
+	var params RawQueryFilterParams
+	rqf, err := params.To()
+	assert.NoError(t, err)
+	assert.True(t, rqf.Filter(nil))
+
+	rqf, err = RawQueryFilterParams{"exact": "b=2&a=1&sign=abc", "mode": "exact"}.To()
+	assert.NoError(t, err)
+	assert.True(t, rqf.Filter([]byte("b=2&a=1&sign=abc")))
+	assert.False(t, rqf.Filter([]byte("a=1&b=2&sign=abc"))) // 参数顺序不同
+
+	rqf, err = RawQueryFilterParams{"regular": "^a=[0-9]+&b=", "mode": "regular"}.To()
+	assert.NoError(t, err)
+	assert.True(t, rqf.Filter([]byte("a=1&b=2")))
+	assert.False(t, rqf.Filter([]byte("b=2&a=1")))
+
+	_, err = RawQueryFilterParams{"regular": "[", "mode": "regular"}.To()
+	assert.Error(t, err)
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	req.SetRequestURI("/api/v1/pay?b=2&a=1")
+	fe := new(FilterExecutor)
+	fe.RawQuery, _ = RawQueryFilterParams{"exact": "b=2&a=1", "mode": "exact"}.To()
+	assert.True(t, fe.Filter(req))
+	req.SetRequestURI("/api/v1/pay?a=1&b=2")
+	assert.False(t, fe.Filter(req))
+	assert.Equal(t, "raw_query", fe.Diagnose(req))
+}
+
+func TestExecutor_Hit(t *testing.T) {
+	exec := &Executor{Regulations: []*RegulationExecutor{{Index: 0}, {Index: 1}}}
+	assert.True(t, exec.Hits.LastHit().IsZero())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			exec.Hit(exec.Regulations[i%2])
+		}(i)
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 100, exec.Hits.Total())
+	assert.EqualValues(t, 50, exec.Regulations[0].Hits.Total())
+	assert.EqualValues(t, 50, exec.Regulations[1].Hits.Total())
+	assert.False(t, exec.Hits.LastHit().IsZero())
+}
+
+func TestRegulationExecutor_ReflectHeaders(t *testing.T) {
+	re, err := (&Regulation{
+		IsDefault:      true,
+		Template:       &Template{StatusCode: 200, Body: "ok"},
+		ReflectHeaders: []string{"X-Request-Id", "X-Trace-Id", "Origin"},
+	}).To()
+	assert.NoError(t, err)
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.Set("X-Request-Id", "8c2f6e")
+	ctx.Request.Header.Set("Origin", "http://example.com")
+	ctx.Request.Header.Set("Authorization", "Bearer token")
+	assert.NoError(t, re.Render(ctx, nil, nil))
+
+	assert.Equal(t, "8c2f6e", string(ctx.Response.Header.Peek("X-Request-Id")))
+	assert.Equal(t, "http://example.com", string(ctx.Response.Header.Peek("Origin")))
+	assert.Empty(t, ctx.Response.Header.Peek("X-Trace-Id"))
+	assert.Empty(t, ctx.Response.Header.Peek("Authorization"))
+	assert.Equal(t, "ok", string(ctx.Response.Body()))
+}
+
+func TestTemplateExecutor_StatusTemplate(t *testing.T) {
+	te, err := (&Template{
+		StatusCode:     200,
+		StatusTemplate: `{{if eq .Query.exists "false"}}404{{else}}200{{end}}`,
+		Body:           `static`,
+	}).To()
+	assert.NoError(t, err)
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.SetRequestURI("/api/v1/user?exists=false")
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode())
+	assert.Equal(t, "static", string(ctx.Response.Body()))
+
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.SetRequestURI("/api/v1/user?exists=true")
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+
+	// 渲染结果不是合法的状态码时返回配置的status_code
+	te, err = (&Template{StatusCode: 201, StatusTemplate: `{{.Query.code}}`}).To()
+	assert.NoError(t, err)
+	for _, code := range []string{"abc", "99", "600", "999"} {
+		ctx = new(fasthttp.RequestCtx)
+		ctx.Request.SetRequestURI("/api/v1/user?code=" + code)
+		assert.NoError(t, te.Render(ctx, nil, nil))
+		assert.Equal(t, fasthttp.StatusCreated, ctx.Response.StatusCode(), code)
+	}
+
+	// 未配置status_code时返回200
+	te, err = (&Template{StatusTemplate: `{{.Query.code}}`}).To()
+	assert.NoError(t, err)
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.SetRequestURI("/api/v1/user?code=abc")
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.SetRequestURI("/api/v1/user?code=503")
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode())
+
+	// 按权重选择状态码
+	te, err = (&Template{StatusCode: 200, StatusTemplate: `{{.Weight.status}}`}).To()
+	assert.NoError(t, err)
+	ctx = new(fasthttp.RequestCtx)
+	assert.NoError(t, te.Render(ctx, nil, map[string]string{"status": "429"}))
+	assert.Equal(t, fasthttp.StatusTooManyRequests, ctx.Response.StatusCode())
+
+	_, err = (&Template{StatusTemplate: `{{.Query.code`}).To()
+	assert.Error(t, err)
+}
+
+func TestTemplateExecutor_Compress(t *testing.T) {
+	body := `{"name": "deepmock"}`
+
+	te, err := (&Template{StatusCode: 200, Body: body, Compress: CompressGzip}).To()
+	assert.NoError(t, err)
+	// 客户端未声明支持gzip时返回原始报文
+	ctx := new(fasthttp.RequestCtx)
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding))
+	assert.Equal(t, body, string(ctx.Response.Body()))
+	assert.Equal(t, fasthttp.HeaderAcceptEncoding, string(ctx.Response.Header.Peek(fasthttp.HeaderVary)))
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, "deflate")
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding))
+
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, "gzip")
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Equal(t, fasthttp.HeaderAcceptEncoding, string(ctx.Response.Header.Peek(fasthttp.HeaderVary)))
+	assert.Equal(t, "gzip", string(ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding)))
+	reader, err := gzip.NewReader(bytes.NewReader(ctx.Response.Body()))
+	assert.NoError(t, err)
+	decoded, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, body, string(decoded))
+
+	te, err = (&Template{StatusCode: 200, IsTemplate: true, Body: `{{.Query.name}}`, Compress: CompressDeflate}).To()
+	assert.NoError(t, err)
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.SetRequestURI("/?name=deepmock")
+	ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, "deflate")
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Equal(t, "deflate", string(ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding)))
+	decoded, err = fasthttp.AppendInflateBytes(nil, ctx.Response.Body())
+	assert.NoError(t, err)
+	assert.Equal(t, "deepmock", string(decoded))
+
+	// auto模式根据Accept-Encoding选择
+	te, err = (&Template{StatusCode: 200, Body: body, Compress: CompressAuto}).To()
+	assert.NoError(t, err)
+	ctx = new(fasthttp.RequestCtx)
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding))
+	assert.Equal(t, body, string(ctx.Response.Body()))
+
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, "deflate, gzip")
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Equal(t, "gzip", string(ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding)))
+	decoded, err = fasthttp.AppendGunzipBytes(nil, ctx.Response.Body())
+	assert.NoError(t, err)
+	assert.Equal(t, body, string(decoded))
+
+	_, err = (&Template{StatusCode: 200, Body: body, Compress: "br"}).To()
+	assert.Error(t, err)
+}
+
+func TestTemplateExecutor_CompressSkipsCompressedTypes(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	te, err := (&Template{
+		Header:         map[string]misc.StringValues{"Content-Type": {"image/png"}},
+		StatusCode:     200,
+		B64EncodedBody: base64.StdEncoding.EncodeToString(png),
+		Compress:       CompressGzip,
+	}).To()
+	assert.NoError(t, err)
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, "gzip, deflate")
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding))
+	assert.Equal(t, png, ctx.Response.Body())
+
+	te, err = (&Template{
+		Header:     map[string]misc.StringValues{"Content-Type": {"application/json"}},
+		StatusCode: 200,
+		Body:       `{"name": "deepmock"}`,
+		Compress:   CompressGzip,
+	}).To()
+	assert.NoError(t, err)
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, "gzip, deflate")
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Equal(t, CompressGzip, string(ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding)))
+	body, err := ctx.Response.BodyGunzip()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name": "deepmock"}`, string(body))
+}
+
+func TestSetDefaultCompress(t *testing.T) {
+	defer SetDefaultCompress("", 0)
+	assert.EqualError(t, SetDefaultCompress("br", 0), "unsupported compress method: br")
+	assert.EqualError(t, SetDefaultCompress(CompressGzip, -1), "bad compress min size")
+
+	assert.NoError(t, SetDefaultCompress(CompressGzip, 8))
+	te, err := (&Template{StatusCode: 200, Body: `{"name": "deepmock"}`}).To()
+	assert.NoError(t, err)
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, "gzip, deflate")
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Equal(t, CompressGzip, string(ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding)))
+
+	// 响应自身的配置优先于全局配置
+	te, err = (&Template{StatusCode: 200, Body: `{"name": "deepmock"}`, Compress: CompressDeflate}).To()
+	assert.NoError(t, err)
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, "gzip, deflate")
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Equal(t, CompressDeflate, string(ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding)))
+
+	te, err = (&Template{StatusCode: 200, Body: `ok`}).To()
+	assert.NoError(t, err)
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, "gzip, deflate")
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding))
+}
+
+func TestIsCompressedContentType(t *testing.T) {
+	for _, ct := range []string{"image/png", "image/jpeg", "video/mp4", "application/gzip", "application/zip", "font/woff2", " Image/WebP"} {
+		assert.True(t, isCompressedContentType([]byte(ct)), ct)
+	}
+	for _, ct := range []string{"", "application/json", "text/html; charset=utf-8", "image/svg+xml", "application/octet-stream"} {
+		assert.False(t, isCompressedContentType([]byte(ct)), ct)
+	}
+}
+
+func TestTemplateExecutor_HeaderTemplate(t *testing.T) {
+	te, err := (&Template{
+		IsTemplate: true,
+		Header: map[string]misc.StringValues{
+			"X-Request-Id": {"{{ uuid }}"},
+			"X-Echo":       {`{{index .Header "X-Trace-Id"}}`, "a&b"},
+			"X-Static":     {"a&b"},
+		},
+		StatusCode: 200,
+		Body:       `ok`,
+	}).To()
+	assert.NoError(t, err)
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.Set("X-Trace-Id", "trace&1")
+	assert.NoError(t, te.Render(ctx, nil, nil))
+
+	_, err = uuid.Parse(string(ctx.Response.Header.Peek("X-Request-Id")))
+	assert.NoError(t, err)
+	var echo []string
+	ctx.Response.Header.VisitAll(func(key, value []byte) {
+		if string(key) == "X-Echo" {
+			echo = append(echo, string(value))
+		}
+	})
+	assert.Equal(t, []string{"trace&1", "a&b"}, echo)
+	assert.Equal(t, "a&b", string(ctx.Response.Header.Peek("X-Static")))
+
+	// 响应头取自query参数
+	te, err = (&Template{
+		IsTemplate: true,
+		Header:     map[string]misc.StringValues{"X-Request-Id": {"{{.Query.request_id}}"}},
+		StatusCode: 200,
+	}).To()
+	assert.NoError(t, err)
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.SetRequestURI("/orders?request_id=req-42")
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Equal(t, "req-42", string(ctx.Response.Header.Peek("X-Request-Id")))
+
+	// 非模板响应保留原始值
+	te, err = (&Template{
+		Header:     map[string]misc.StringValues{"X-Request-Id": {"{{ uuid }}"}},
+		StatusCode: 200,
+	}).To()
+	assert.NoError(t, err)
+	ctx = new(fasthttp.RequestCtx)
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Equal(t, "{{ uuid }}", string(ctx.Response.Header.Peek("X-Request-Id")))
+
+	_, err = (&Template{
+		IsTemplate: true,
+		Header:     map[string]misc.StringValues{"X-Request-Id": {"{{ uuid "}},
+		StatusCode: 200,
+	}).To()
+	assert.Error(t, err)
+}
+
+func TestTemplateExecutor_CookieAndRemoteAddr(t *testing.T) {
+	te, err := (&Template{
+		IsTemplate: true,
+		StatusCode: 200,
+		Body:       `{{.Cookie.session}}|{{.Cookie.lang}}|{{.RemoteAddr}}`,
+	}).To()
+	assert.NoError(t, err)
+
+	req := new(fasthttp.Request)
+	req.Header.SetCookie("session", "abc123")
+	req.Header.SetCookie("lang", "zh")
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Init(req, &net.TCPAddr{IP: net.ParseIP("10.0.0.8"), Port: 52100}, nil)
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Equal(t, "abc123|zh|10.0.0.8:52100", string(ctx.Response.Body()))
+}
+
+func TestTemplateExecutor_BodyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deepmock-body")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	SetBodyFileDir(dir)
+	defer SetBodyFileDir("")
+
+	static, dynamic := "logo.bin", "user.json"
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, static), []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}, 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, dynamic), []byte(`{"name": "{{.Query.name}}"}`), 0644))
+
+	te, err := (&Template{StatusCode: 200, BodyFile: static}).To()
+	assert.NoError(t, err)
+	ctx := new(fasthttp.RequestCtx)
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Equal(t, []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}, ctx.Response.Body())
+	assert.True(t, te.IsBinData)
+	// 二进制文件不能作为模板
+	_, err = (&Template{IsTemplate: true, StatusCode: 200, BodyFile: static}).To()
+	assert.Error(t, err)
+
+	te, err = (&Template{IsTemplate: true, StatusCode: 200, BodyFile: dynamic}).To()
+	assert.NoError(t, err)
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.SetRequestURI("/user?name=deepmock")
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Equal(t, `{"name": "deepmock"}`, string(ctx.Response.Body()))
+
+	// 创建规则后文件的变化不影响已读取的body
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, dynamic), []byte(`changed`), 0644))
+	ctx = new(fasthttp.RequestCtx)
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Contains(t, string(ctx.Response.Body()), `"name"`)
+
+	_, err = (&Template{StatusCode: 200, BodyFile: "not-exists"}).To()
+	assert.Error(t, err)
+	_, err = (&Template{StatusCode: 200, BodyFile: static, Body: "inline"}).To()
+	assert.Error(t, err)
+}
+
+func TestWeightDice_Empty(t *testing.T) {
+	for _, factor := range []WeightFactor{nil, {}, {"a": 0, "b": 0}} {
+		dice := factor.To()
+		assert.NotPanics(t, func() { assert.Equal(t, "", dice.Dice()) })
+	}
+
+	rule := &Rule{
+		Path:        "/api/v1/weight",
+		Method:      "GET",
+		Weight:      map[string]WeightFactor{"status": {"a": 0}},
+		Regulations: []*Regulation{{IsDefault: true, Template: &Template{Body: "ok"}}},
+	}
+	assert.EqualError(t, rule.Validate(), "weight status has no selectable value")
+
+	rule.Weight["status"]["b"] = 1
+	assert.NoError(t, rule.Validate())
+}
+
+func TestWeightPicker_CopyOnWrite(t *testing.T) {
+	factor := WeightFactor{"a": 1}
+	wp := NewWeightPicker(map[string]WeightFactor{"status": factor})
+	factor["b"] = 1000 // 修改原始权重不影响已经生成的快照
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, map[string]string{"status": "a"}, wp.DiceAll())
+	}
+
+	var nilPicker *WeightPicker
+	assert.Empty(t, nilPicker.DiceAll())
+}
+
+func TestRandBoolFunc(t *testing.T) {
+	SetBoolRandSeed(1)
+	for i := 0; i < 1000; i++ {
+		v, err := randBool(0.0)
+		assert.NoError(t, err)
+		assert.False(t, v)
+
+		v, err = randBool(1)
+		assert.NoError(t, err)
+		assert.True(t, v)
+	}
+
+	hits := 0
+	for i := 0; i < 10000; i++ {
+		if v, _ := randBool("0.3"); v {
+			hits++
+		}
+	}
+	assert.InDelta(t, 3000, hits, 300)
+
+	// 相同种子得到相同序列
+	roll := func() []bool {
+		SetBoolRandSeed(42)
+		ret := make([]bool, 20)
+		for i := range ret {
+			ret[i], _ = randBool(0.5)
+		}
+		return ret
+	}
+	assert.Equal(t, roll(), roll())
+
+	_, err := randBool(1.5)
+	assert.Error(t, err)
+	_, err = randBool(-0.1)
+	assert.Error(t, err)
+	_, err = randBool("half")
+	assert.Error(t, err)
+	_, err = randBool(true)
+	assert.Error(t, err)
+
+	tmpl, err := template.New("test").Funcs(defaultTemplateFuncs).Parse(`{{if randBool 1.0}}yes{{end}}{{if randBool 0}}no{{end}}`)
+	assert.NoError(t, err)
+	buf := new(bytes.Buffer)
+	assert.NoError(t, tmpl.Execute(buf, nil))
+	assert.Equal(t, "yes", buf.String())
+}
+
+func TestDefaultAndTernaryFunc(t *testing.T) {
+	var nilPtr *Template
+	assert.Equal(t, "1", defaultValue(nil, "1"))
+	assert.Equal(t, "1", defaultValue("", "1"))
+	assert.Equal(t, "1", defaultValue([]interface{}{}, "1"))
+	assert.Equal(t, "1", defaultValue(map[string]string{}, "1"))
+	assert.Equal(t, "1", defaultValue(nilPtr, "1"))
+	assert.Equal(t, "3", defaultValue("3", "1"))
+	assert.Equal(t, 0, defaultValue(0, 1))
+	assert.Equal(t, false, defaultValue(false, true))
+
+	assert.Equal(t, "yes", ternary(true, "yes", "no"))
+	assert.Equal(t, "no", ternary(false, "yes", "no"))
+	assert.Equal(t, "yes", ternary("x", "yes", "no"))
+	assert.Equal(t, "no", ternary("", "yes", "no"))
+	assert.Equal(t, "no", ternary(nil, "yes", "no"))
+
+	rc := &RenderContext{
+		Query: map[string]string{"size": "20", "empty": ""},
+		Json:  map[string]interface{}{"vip": true},
+	}
+	tmpl, err := template.New("test").Funcs(defaultTemplateFuncs).Parse(
+		`{{default .Query.page "1"}},{{default .Query.empty "a"}},{{default .Query.size "10"}},{{default .Json.name "guest"}},{{ternary .Json.vip "vip" "normal"}},{{ternary .Json.missing "vip" "normal"}}`)
+	assert.NoError(t, err)
+	buf := new(bytes.Buffer)
+	assert.NoError(t, tmpl.Execute(buf, rc))
+	assert.Equal(t, "1,a,20,guest,vip,normal", buf.String())
+}
+
+func TestColorFromFunc(t *testing.T) {
+	hex := regexp.MustCompile(`^#[0-9a-f]{6}$`)
+	seen := make(map[string]bool)
+	for _, seed := range []string{"alice", "bob", "carol", "", "用户1"} {
+		color := colorFrom(seed)
+		assert.Regexp(t, hex, color, seed)
+		assert.Equal(t, color, colorFrom(seed))
+		seen[color] = true
+	}
+	assert.Len(t, seen, 5)
+	assert.Equal(t, colorFrom("42"), colorFrom(42))
+
+	tmpl, err := template.New("test").Funcs(defaultTemplateFuncs).Parse(`{{colorFrom .Query.user}}`)
+	assert.NoError(t, err)
+	buf := new(bytes.Buffer)
+	assert.NoError(t, tmpl.Execute(buf, &RenderContext{Query: map[string]string{"user": "alice"}}))
+	assert.Equal(t, colorFrom("alice"), buf.String())
+}
+
+func TestHmacSHA256Func(t *testing.T) {
+	// RFC 4231 test case 2
+	digest, err := hmacSHA256("Jefe", "what do ya want for nothing?")
+	assert.NoError(t, err)
+	assert.Equal(t, "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843", digest)
+	digest, err = hmacSHA256("Jefe", "what do ya want for nothing?", "base64")
+	assert.NoError(t, err)
+	assert.Equal(t, "W9zBRr9gdU5qBCQmCJV1x1oAPwidJzmDnexYuWTsOEM=", digest)
+
+	digest, err = hmacSHA256("", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "b613679a0814d9ec772f95d778c35fc5ff1697c493715653c6c712144292c5ad", digest)
+
+	digest, err = hmacSHA256("secret", map[string]interface{}{"a": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "aa9e2e3575f5d7098b6caccd790888c36d5fdb63342a73bada2d6a51747a8494", digest)
+
+	_, err = hmacSHA256("secret", "msg", "base32")
+	assert.Error(t, err)
+
+	var j map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"payload": "The quick brown fox jumps over the lazy dog"}`), &j))
+	tmpl, err := template.New("test").Funcs(defaultTemplateFuncs).Parse(`{{hmacSHA256 "key" .Json.payload}}`)
+	assert.NoError(t, err)
+	buf := new(bytes.Buffer)
+	assert.NoError(t, tmpl.Execute(buf, &RenderContext{Json: j}))
+	assert.Equal(t, "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8", buf.String())
+}
+
+func TestHumanBytesFunc(t *testing.T) {
+	cases := []struct {
+		v        interface{}
+		unit     []string
+		expected string
+	}{
+		{0, nil, "0 B"},
+		{999, nil, "999 B"},
+		{1000, nil, "1 KB"},
+		{1500000000, nil, "1.5 GB"},
+		{float64(2e12), []string{"decimal"}, "2 TB"},
+		{"1234567", nil, "1.2 MB"},
+		{999999, nil, "1 MB"},
+		{999949, nil, "999.9 KB"},
+		{-1500, nil, "-1.5 KB"},
+		{1023, []string{"binary"}, "1023 B"},
+		{1024, []string{"binary"}, "1 KiB"},
+		{int64(1610612736), []string{"binary"}, "1.5 GiB"},
+		{float64(5 << 20), []string{"binary"}, "5 MiB"},
+		{uint64(1) << 62, []string{"binary"}, "4 EiB"},
+	}
+	for _, c := range cases {
+		ret, err := humanBytes(c.v, c.unit...)
+		assert.NoError(t, err)
+		assert.Equal(t, c.expected, ret, "%v %v", c.v, c.unit)
+	}
+
+	_, err := humanBytes("1 GB")
+	assert.Error(t, err)
+	_, err = humanBytes(true)
+	assert.Error(t, err)
+	_, err = humanBytes(1024, "metric")
+	assert.Error(t, err)
+
+	var j map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"used": 1572864}`), &j))
+	tmpl, err := template.New("test").Funcs(defaultTemplateFuncs).Parse(`{{humanBytes .Json.used}} / {{humanBytes .Json.used "binary"}}`)
+	assert.NoError(t, err)
+	buf := new(bytes.Buffer)
+	assert.NoError(t, tmpl.Execute(buf, RenderContext{Json: j}))
+	assert.Equal(t, "1.6 MB / 1.5 MiB", buf.String())
+}
+
+func TestSortStringsAndUniqFunc(t *testing.T) {
+	var j map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"tags": ["go", "mock", "api", "go", 1, "1", "api"]}`), &j))
+	ctx := RenderContext{Json: j}
+
+	tmpl, err := template.New("test").Funcs(defaultTemplateFuncs).Parse(
+		`{{range sortStrings .Json.tags}}{{.}} {{end}}|{{range uniq .Json.tags}}{{.}} {{end}}|{{range sortStrings (uniq .Json.tags)}}{{.}} {{end}}`)
+	assert.Nil(t, err)
+	buf := bytes.NewBuffer(nil)
+	assert.Nil(t, tmpl.Execute(buf, ctx))
+	assert.Equal(t, "1 1 api api go go mock |go mock api 1 1 |1 1 api go mock ", buf.String())
+
+	sorted, err := sortStrings([]string{"b", "a", "c"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, sorted)
+
+	_, err = uniq("not a slice")
+	assert.Error(t, err)
+	ret, err := uniq(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
+
+func TestReplaceTemplateFunc(t *testing.T) {
+	original := defaultTemplateFuncs["date"]
+	defer func() { defaultTemplateFuncs["date"] = original }()
+
+	newExecutor := func() *TemplateExecutor {
+		te, err := (&Template{IsTemplate: true, StatusCode: 200, Body: `{{date "2006"}}`}).To()
+		assert.NoError(t, err)
+		return te
+	}
+	render := func(te *TemplateExecutor) string {
+		ctx := new(fasthttp.RequestCtx)
+		assert.NoError(t, te.Render(ctx, nil, nil))
+		return string(ctx.Response.Body())
+	}
+
+	before := newExecutor()
+	assert.EqualError(t, RegisterTemplateFunc("date", func(string) string { return "fixed" }), "func named date was exists")
+	assert.NoError(t, ReplaceTemplateFunc("date", func(string) string { return "fixed" }))
+
+	// 只有替换之后创建的模板使用新的函数
+	assert.Equal(t, "fixed", render(newExecutor()))
+	assert.Equal(t, time.Now().Format("2006"), render(before))
+
+	assert.EqualError(t, ReplaceTemplateFunc("date", "not a func"), "func named date is not a function")
+	assert.EqualError(t, ReplaceTemplateFunc("date", nil), "func named date is not a function")
+	assert.Equal(t, "fixed", render(newExecutor()))
+}
+
+func TestUnregisterTemplateFunc(t *testing.T) {
+	defer delete(defaultTemplateFuncs, "pluginEcho")
+
+	assert.NotContains(t, ListTemplateFuncs(), "pluginEcho")
+	assert.NoError(t, RegisterTemplateFunc("pluginEcho", func(s string) string { return s }))
+	names := ListTemplateFuncs()
+	assert.Contains(t, names, "pluginEcho")
+	assert.Contains(t, names, "uuid")
+	assert.True(t, sort.StringsAreSorted(names))
+
+	assert.NoError(t, UnregisterTemplateFunc("pluginEcho"))
+	assert.NotContains(t, ListTemplateFuncs(), "pluginEcho")
+	assert.EqualError(t, UnregisterTemplateFunc("pluginEcho"), "func named pluginEcho was not registered")
+
+	// 注销后新建的模板无法再引用该函数
+	_, err := (&Template{IsTemplate: true, Body: `{{pluginEcho "a"}}`}).To()
+	assert.Error(t, err)
+}
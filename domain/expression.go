@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"bytes"
+	"html/template"
+	"text/template/parse"
+
+	"github.com/valyala/fasthttp"
+)
+
+type (
+	// ExpressionFilterExecutor 表达式筛选执行器，表达式使用Go Template的语法，结果为true时通过，
+	// 如: and (eq .Query.type "vip") (eq .Body.user.level "gold")
+	ExpressionFilterExecutor struct {
+		template *template.Template
+	}
+
+	// ExpressionContext 表达式的求值上下文，Query为query参数，Body为解析后的JSON请求报文
+	ExpressionContext struct {
+		Query map[string]string
+		Body  map[string]interface{}
+	}
+)
+
+func newExpressionFilterExecutor(expression string) (*ExpressionFilterExecutor, error) {
+	if expression == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("expression").Funcs(defaultTemplateFuncs).Parse("{{" + expression + "}}")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTemplateFuncs(tmpl.Tree, func(string) *parse.Tree { return nil }); err != nil {
+		return nil, err
+	}
+	return &ExpressionFilterExecutor{template: tmpl}, nil
+}
+
+// Filter 对请求求值，表达式执行出错（如访问不存在的字段）时视为不通过
+func (efe *ExpressionFilterExecutor) Filter(req *fasthttp.Request) bool {
+	if efe == nil {
+		return true
+	}
+
+	ec := ExpressionContext{Query: extractQueryAsParams(req)}
+	if len(req.Body()) > 0 {
+		_ = json.Unmarshal(req.Body(), &ec.Body)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := efe.template.Execute(buf, ec); err != nil {
+		return false
+	}
+	return bytes.Equal(bytes.TrimSpace(buf.Bytes()), []byte("true"))
+}
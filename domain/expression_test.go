@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func newExpressionRequest(uri, body string) *fasthttp.Request {
+	req := new(fasthttp.Request)
+	req.SetRequestURI(uri)
+	req.Header.SetContentType("application/json")
+	req.SetBodyString(body)
+	return req
+}
+
+func TestExpressionFilter_Filter(t *testing.T) {
+	efe, err := newExpressionFilterExecutor("")
+	assert.NoError(t, err)
+	assert.Nil(t, efe)
+	assert.True(t, efe.Filter(newExpressionRequest("/", "")))
+
+	efe, err = newExpressionFilterExecutor(`and (eq .Query.type "vip") (eq .Body.user.level "gold")`)
+	assert.NoError(t, err)
+	assert.True(t, efe.Filter(newExpressionRequest("/?type=vip", `{"user": {"level": "gold"}}`)))
+	assert.False(t, efe.Filter(newExpressionRequest("/?type=normal", `{"user": {"level": "gold"}}`)))
+	assert.False(t, efe.Filter(newExpressionRequest("/?type=vip", `{"user": {"level": "silver"}}`)))
+	// 访问不存在的字段视为不通过
+	assert.False(t, efe.Filter(newExpressionRequest("/?type=vip", `{}`)))
+	assert.False(t, efe.Filter(newExpressionRequest("/?type=vip", `not json`)))
+
+	efe, err = newExpressionFilterExecutor(`or (eq .Query.debug "1") .Body.force`)
+	assert.NoError(t, err)
+	assert.True(t, efe.Filter(newExpressionRequest("/?debug=1", "")))
+	assert.True(t, efe.Filter(newExpressionRequest("/", `{"force": true}`)))
+	assert.False(t, efe.Filter(newExpressionRequest("/", `{"force": false}`)))
+
+	_, err = newExpressionFilterExecutor(`eq .Query.type`)
+	assert.NoError(t, err) // 参数个数不正确在执行时才会报错
+	_, err = newExpressionFilterExecutor(`eq (.Query.type "vip"`)
+	assert.Error(t, err)
+}
+
+func TestRegulation_Expression(t *testing.T) {
+	rule := &Rule{
+		Path:   "/api/v1/order",
+		Method: "POST",
+		Regulations: []*Regulation{
+			{
+				Filter:   &Filter{Expression: `and (eq .Query.channel "app") (eq .Body.status "PAID")`},
+				Template: &Template{Body: "paid from app"},
+			},
+			{IsDefault: true, Template: &Template{Body: "default"}},
+		},
+	}
+	executor, err := rule.To()
+	assert.NoError(t, err)
+
+	req := newExpressionRequest("/api/v1/order?channel=app", `{"status": "PAID"}`)
+	assert.Equal(t, 0, executor.FindRegulationExecutor(req).Index)
+	req = newExpressionRequest("/api/v1/order?channel=web", `{"status": "PAID"}`)
+	assert.Equal(t, 1, executor.FindRegulationExecutor(req).Index)
+}
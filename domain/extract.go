@@ -2,6 +2,9 @@ package domain
 
 import (
 	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/valyala/fasthttp"
@@ -14,6 +17,9 @@ var (
 	jsonContentType      = []byte("application/json")
 )
 
+// maxMultipartFieldsSize multipart请求中文本字段的总大小上限，超出后不再解析后续的字段
+const maxMultipartFieldsSize = 1 << 20
+
 func extractHeaderAsParams(req *fasthttp.Request) map[string]string {
 	p := make(map[string]string)
 	req.Header.VisitAll(func(key, value []byte) {
@@ -22,6 +28,14 @@ func extractHeaderAsParams(req *fasthttp.Request) map[string]string {
 	return p
 }
 
+func extractCookieAsParams(req *fasthttp.Request) map[string]string {
+	p := make(map[string]string)
+	req.Header.VisitAllCookie(func(key, value []byte) {
+		p[string(key)] = string(value)
+	})
+	return p
+}
+
 func extractQueryAsParams(req *fasthttp.Request) map[string]string {
 	p := make(map[string]string)
 	req.URI().QueryArgs().VisitAll(func(key, value []byte) {
@@ -42,15 +56,7 @@ func extractBodyAsParams(req *fasthttp.Request) (map[string]string, map[string]i
 		return p, nil
 
 	case bytes.HasPrefix(ct, multipartContentType):
-		p := make(map[string]string)
-		form, err := req.MultipartForm()
-		if err != nil {
-			return nil, nil
-		}
-		for k, v := range form.Value {
-			p[k] = v[0]
-		}
-		return p, nil
+		return extractMultipartFields(req), nil
 
 	case bytes.HasPrefix(ct, jsonContentType):
 		j := make(map[string]interface{})
@@ -64,3 +70,48 @@ func extractBodyAsParams(req *fasthttp.Request) (map[string]string, map[string]i
 		return nil, nil
 	}
 }
+
+// extractMultipartFields 流式解析multipart请求中的文本字段，跳过文件部分，同名字段只保留第一个值；
+// 文本字段的总大小超过maxMultipartFieldsSize后不再解析，避免占用过多内存
+func extractMultipartFields(req *fasthttp.Request) map[string]string {
+	boundary := req.Header.MultipartFormBoundary()
+	if len(boundary) == 0 {
+		return nil
+	}
+
+	p := make(map[string]string)
+	reader := multipart.NewReader(bytes.NewReader(req.Body()), string(boundary))
+	remaining := int64(maxMultipartFieldsSize)
+	for {
+		part, err := reader.NextPart()
+		if err != nil { // 读取完毕或者报文格式有误
+			return p
+		}
+		name := part.FormName()
+		if name == "" || part.FileName() != "" {
+			continue
+		}
+		value, err := ioutil.ReadAll(io.LimitReader(part, remaining+1))
+		remaining -= int64(len(value))
+		if err != nil || remaining < 0 {
+			return p
+		}
+		if _, exists := p[name]; !exists {
+			p[name] = string(value)
+		}
+	}
+}
+
+// extractFormArgs 将表单请求的字段转换为fasthttp.Args，供表单筛选器使用，非表单请求返回空的Args
+func extractFormArgs(req *fasthttp.Request) *fasthttp.Args {
+	if bytes.HasPrefix(req.Header.ContentType(), formContentType) {
+		return req.PostArgs()
+	}
+	args := new(fasthttp.Args)
+	if bytes.HasPrefix(req.Header.ContentType(), multipartContentType) {
+		for k, v := range extractMultipartFields(req) {
+			args.Set(k, v)
+		}
+	}
+	return args
+}
@@ -3,6 +3,7 @@ package domain
 import (
 	"mime/multipart"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -69,6 +70,35 @@ func TestExtractFromMultipartForm(t *testing.T) {
 	assert.EqualValues(t, map[string]string{"name": "foobar", "message": "中国"}, f)
 }
 
+func TestExtractMultipartFields(t *testing.T) {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	req.Header.SetMethod("POST")
+	writer := multipart.NewWriter(req.BodyWriter())
+	req.Header.SetContentType(writer.FormDataContentType())
+	assert.Nil(t, writer.WriteField("name", "foobar"))
+	file, err := writer.CreateFormFile("avatar", "avatar.png")
+	assert.Nil(t, err)
+	_, err = file.Write([]byte("binary"))
+	assert.Nil(t, err)
+	assert.Nil(t, writer.WriteField("name", "ignored"))
+	assert.Nil(t, writer.WriteField("large", strings.Repeat("a", maxMultipartFieldsSize)))
+	assert.Nil(t, writer.WriteField("after", "limit"))
+	assert.Nil(t, writer.Close())
+
+	// 文件部分被跳过，超出大小上限后的字段不再解析
+	assert.EqualValues(t, map[string]string{"name": "foobar"}, extractMultipartFields(req))
+
+	args := extractFormArgs(req)
+	assert.Equal(t, "foobar", string(args.Peek("name")))
+	assert.Empty(t, args.Peek("avatar"))
+
+	req.Header.SetContentType("application/x-www-form-urlencoded")
+	req.SetBodyString("name=deepmock")
+	assert.Equal(t, "deepmock", string(extractFormArgs(req).Peek("name")))
+}
+
 func TestExtractFromJson(t *testing.T) {
 	req := fasthttp.AcquireRequest()
 	defer fasthttp.ReleaseRequest(req)
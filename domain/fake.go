@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+var (
+	fakeSurnames   = []string{"王", "李", "张", "刘", "陈", "杨", "黄", "赵", "吴", "周", "徐", "孙", "马", "朱", "胡", "郭", "何", "林", "罗", "高"}
+	fakeGivenNames = []string{"伟", "芳", "娜", "敏", "静", "磊", "强", "洋", "艳", "勇", "军", "杰", "涛", "明", "超", "秀英", "建华", "晓东", "婷婷", "子涵", "浩然", "欣怡", "宇轩", "梓萱"}
+	fakeUserWords  = []string{"alice", "bob", "carol", "david", "emma", "frank", "grace", "henry", "iris", "jack", "kevin", "lucy", "mike", "nancy", "oscar", "peter", "ruby", "sam", "tony", "vivian"}
+	fakeDomains    = []string{"example.com", "example.net", "example.org", "mail.example.com", "test.example.cn"}
+	fakePhonePre   = []string{"130", "131", "132", "135", "136", "137", "138", "139", "150", "151", "152", "158", "159", "166", "176", "177", "181", "186", "188", "199"}
+	fakeCities     = []string{"北京市", "上海市", "广州市", "深圳市", "杭州市", "南京市", "成都市", "武汉市", "西安市", "苏州市"}
+	fakeDistricts  = []string{"东城区", "浦东新区", "天河区", "南山区", "西湖区", "鼓楼区", "武侯区", "江汉区", "雁塔区", "工业园区"}
+	fakeRoads      = []string{"人民路", "中山路", "解放路", "建设路", "世纪大道", "长江路", "和平路", "新华路", "科技路", "文化路"}
+
+	fakeGenerators = map[string]func() string{
+		"name":    fakeName,
+		"email":   fakeEmail,
+		"phone":   fakePhone,
+		"address": fakeAddress,
+		"ipv4":    fakeIPv4,
+	}
+)
+
+func pickWord(words []string) string {
+	return words[rand.Intn(len(words))]
+}
+
+func fakeName() string {
+	return pickWord(fakeSurnames) + pickWord(fakeGivenNames)
+}
+
+func fakeEmail() string {
+	return fmt.Sprintf("%s.%s%d@%s", pickWord(fakeUserWords), pickWord(fakeUserWords), rand.Intn(100), pickWord(fakeDomains))
+}
+
+// fakePhone 返回11位的手机号码
+func fakePhone() string {
+	return fmt.Sprintf("%s%08d", pickWord(fakePhonePre), rand.Intn(100000000))
+}
+
+func fakeAddress() string {
+	return fmt.Sprintf("%s%s%s%d号", pickWord(fakeCities), pickWord(fakeDistricts), pickWord(fakeRoads), rand.Intn(999)+1)
+}
+
+// fakeIPv4 返回A、B、C类地址中的随机地址，不包含0.x.x.x以及127.x.x.x
+func fakeIPv4() string {
+	first := rand.Intn(222) + 1
+	if first == 127 {
+		first = 128
+	}
+	return fmt.Sprintf("%d.%d.%d.%d", first, rand.Intn(256), rand.Intn(256), rand.Intn(254)+1)
+}
+
+// fake 按类别生成随机的假数据，category为name、email、phone、address或ipv4
+func fake(category string) (string, error) {
+	generator, ok := fakeGenerators[strings.ToLower(category)]
+	if !ok {
+		return "", fmt.Errorf("unsupported fake category %s", category)
+	}
+	return generator(), nil
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"bytes"
+	"html/template"
+	"net"
+	"regexp"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeFunc(t *testing.T) {
+	patterns := map[string]*regexp.Regexp{
+		"name":    regexp.MustCompile(`^\p{Han}{2,3}$`),
+		"email":   regexp.MustCompile(`^[a-z]+\.[a-z]+\d{1,2}@[a-z.]+\.(com|net|org|cn)$`),
+		"phone":   regexp.MustCompile(`^1[3-9]\d{9}$`),
+		"address": regexp.MustCompile(`^\p{Han}+\d{1,3}号$`),
+	}
+	for i := 0; i < 200; i++ {
+		for category, pattern := range patterns {
+			v, err := fake(category)
+			assert.NoError(t, err)
+			assert.Regexp(t, pattern, v, category)
+		}
+
+		v, err := fake("ipv4")
+		assert.NoError(t, err)
+		ip := net.ParseIP(v)
+		if assert.NotNil(t, ip, v) {
+			assert.NotNil(t, ip.To4())
+			assert.False(t, ip.IsLoopback() || ip.IsUnspecified(), v)
+		}
+	}
+
+	name, err := fake("NAME")
+	assert.NoError(t, err)
+	assert.True(t, utf8.RuneCountInString(name) >= 2)
+
+	_, err = fake("ssn")
+	assert.Error(t, err)
+
+	tmpl, err := template.New("test").Funcs(defaultTemplateFuncs).Parse(`{{fake "email"}}`)
+	assert.NoError(t, err)
+	buf := new(bytes.Buffer)
+	assert.NoError(t, tmpl.Execute(buf, nil))
+	assert.Contains(t, buf.String(), "@")
+}
@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"fmt"
+	"sync/atomic"
+	"text/template/parse"
+)
+
+// allowedTemplateFuncs 允许规则模板使用的模板函数，值为map[string]struct{}，为空表示不限制
+var allowedTemplateFuncs atomic.Value
+
+// SetAllowedTemplateFuncs 设置允许规则模板使用的模板函数，names为空时不限制；
+// 只限制注册的模板函数，and、len、index等模板内置函数不受影响
+func SetAllowedTemplateFuncs(names ...string) {
+	allowed := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		allowed[name] = struct{}{}
+	}
+	allowedTemplateFuncs.Store(allowed)
+}
+
+func templateFuncAllowed(name string) bool {
+	allowed, _ := allowedTemplateFuncs.Load().(map[string]struct{})
+	if len(allowed) == 0 {
+		return true
+	}
+	if _, registered := defaultTemplateFuncs[name]; !registered {
+		return true
+	}
+	_, ok := allowed[name]
+	return ok
+}
+
+// checkTemplateFuncs 遍历模板的语法树，检查其中以及通过{{template}}引用的模板中是否调用了不允许的模板函数
+func checkTemplateFuncs(tree *parse.Tree, lookup func(name string) *parse.Tree) error {
+	allowed, _ := allowedTemplateFuncs.Load().(map[string]struct{})
+	if len(allowed) == 0 {
+		return nil
+	}
+	return walkTemplateFuncs(tree, lookup, func(name string) error {
+		if !templateFuncAllowed(name) {
+			return fmt.Errorf("template func %s is not allowed", name)
+		}
+		return nil
+	})
+}
+
+// walkTemplateFuncs 遍历模板的语法树，对其中以及通过{{template}}引用的模板中调用的每个函数执行visit，lookup为nil时不跟随引用
+func walkTemplateFuncs(tree *parse.Tree, lookup func(name string) *parse.Tree, visit func(name string) error) error {
+	if tree == nil {
+		return nil
+	}
+	visited := map[string]bool{tree.Name: true}
+	var walk func(node parse.Node) error
+	walk = func(node parse.Node) error {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return nil
+			}
+			for _, child := range n.Nodes {
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+		case *parse.ActionNode:
+			return walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return nil
+			}
+			for _, cmd := range n.Cmds {
+				if err := walk(cmd); err != nil {
+					return err
+				}
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				if err := walk(arg); err != nil {
+					return err
+				}
+			}
+		case *parse.ChainNode:
+			return walk(n.Node)
+		case *parse.IdentifierNode:
+			return visit(n.Ident)
+		case *parse.IfNode:
+			return walkBranch(walk, &n.BranchNode)
+		case *parse.RangeNode:
+			return walkBranch(walk, &n.BranchNode)
+		case *parse.WithNode:
+			return walkBranch(walk, &n.BranchNode)
+		case *parse.TemplateNode:
+			if err := walk(n.Pipe); err != nil {
+				return err
+			}
+			if visited[n.Name] || lookup == nil {
+				return nil
+			}
+			visited[n.Name] = true
+			if referenced := lookup(n.Name); referenced != nil {
+				return walk(referenced.Root)
+			}
+		}
+		return nil
+	}
+	return walk(tree.Root)
+}
+
+func walkBranch(walk func(parse.Node) error, branch *parse.BranchNode) error {
+	if err := walk(branch.Pipe); err != nil {
+		return err
+	}
+	if err := walk(branch.List); err != nil {
+		return err
+	}
+	return walk(branch.ElseList)
+}
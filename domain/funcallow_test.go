@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"github.com/wosai/deepmock/misc"
+)
+
+func TestSetAllowedTemplateFuncs(t *testing.T) {
+	SetAllowedTemplateFuncs("uuid", "plus")
+	defer SetAllowedTemplateFuncs()
+
+	allowed := []string{
+		`{{uuid}}`,
+		`{{plus 1 2}}`,
+		`{{if eq (len .Query) 0}}{{uuid}}{{else}}{{index .Query "a" | printf "%s"}}{{end}}`,
+		`{{range $k, $v := .Query}}{{$k}}={{$v}}{{end}}`,
+		`{{with .Json}}{{.name}}{{end}}`,
+	}
+	for _, text := range allowed {
+		_, err := (&Template{IsTemplate: true, StatusCode: 200, Body: text}).To()
+		assert.NoError(t, err, text)
+	}
+
+	disallowed := []string{
+		`{{timestamp "ms"}}`,
+		`{{if true}}{{else}}{{date "2006"}}{{end}}`,
+		`{{range .Query}}{{rand_string 8}}{{end}}`,
+		`{{.Query.a | uniq}}`,
+		`{{define "inner"}}{{gid}}{{end}}{{template "inner" .}}`,
+	}
+	for _, text := range disallowed {
+		_, err := (&Template{IsTemplate: true, StatusCode: 200, Body: text}).To()
+		assert.Error(t, err, text)
+	}
+
+	_, err := (&Template{
+		IsTemplate: true,
+		StatusCode: 200,
+		Header:     map[string]misc.StringValues{"X-Ts": {`{{timestamp "ms"}}`}},
+	}).To()
+	assert.Error(t, err)
+
+	_, err = (&Template{IsTemplate: true, StatusCode: 200, StatusTemplate: `{{gid}}`}).To()
+	assert.Error(t, err)
+
+	_, err = newExpressionFilterExecutor(`eq (timestamp "ms") 0`)
+	assert.Error(t, err)
+
+	// 非模板响应不解析
+	_, err = (&Template{StatusCode: 200, Body: `{{timestamp "ms"}}`}).To()
+	assert.NoError(t, err)
+}
+
+func TestSetAllowedTemplateFuncs_Partials(t *testing.T) {
+	assert.NoError(t, SetTemplatePartials(map[string]string{"stamp": `{{timestamp "ms"}}`}))
+	defer SetTemplatePartials(nil)
+	SetAllowedTemplateFuncs("uuid")
+	defer SetAllowedTemplateFuncs()
+
+	// 未引用的片段不做检查
+	te, err := (&Template{IsTemplate: true, StatusCode: 200, Body: `{{uuid}}`}).To()
+	assert.NoError(t, err)
+	assert.NoError(t, te.Render(new(fasthttp.RequestCtx), nil, nil))
+
+	_, err = (&Template{IsTemplate: true, StatusCode: 200, Body: `{{template "stamp" .}}`}).To()
+	assert.Error(t, err)
+
+	SetAllowedTemplateFuncs()
+	_, err = (&Template{IsTemplate: true, StatusCode: 200, Body: `{{template "stamp" .}}`}).To()
+	assert.NoError(t, err)
+}
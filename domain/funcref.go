@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"text/template/parse"
+)
+
+var (
+	// builtinTemplateFuncs Go Template的内置函数
+	builtinTemplateFuncs = map[string]struct{}{
+		"and": {}, "call": {}, "html": {}, "index": {}, "slice": {}, "js": {}, "len": {}, "not": {}, "or": {},
+		"print": {}, "printf": {}, "println": {}, "urlquery": {},
+		"eq": {}, "ge": {}, "gt": {}, "le": {}, "lt": {}, "ne": {},
+	}
+
+	undefinedFuncPattern = regexp.MustCompile(`function "([^"]+)" not defined`)
+
+	// ErrUndefinedTemplateFunc 模板引用了从未注册或已被注销的函数
+	ErrUndefinedTemplateFunc = errors.New("undefined template func")
+)
+
+// explainUndefinedFunc 解析错误由未定义的函数引起时，返回指明函数名称的错误，其他错误原样返回
+func explainUndefinedFunc(err error) error {
+	matched := undefinedFuncPattern.FindStringSubmatch(err.Error())
+	if matched == nil {
+		return err
+	}
+	return fmt.Errorf("%w %q, it was never registered or has been unregistered (%s)", ErrUndefinedTemplateFunc, matched[1], err)
+}
+
+// InspectTemplateFuncs 解析模板并返回其中调用的函数以及未注册的函数，均按名称排序；
+// 不跟随{{template}}引用的模板片段，模板存在函数名以外的语法错误时返回错误
+func InspectTemplateFuncs(text string) (referenced []string, unregistered []string, err error) {
+	// 解析时遇到未定义的函数会直接报错，因此逐个补充占位函数后重新解析，以便一次报告所有未注册的函数
+	stubs := make(map[string]interface{})
+	var trees map[string]*parse.Tree
+	for {
+		trees, err = parse.Parse("inspect", text, "", "", defaultTemplateFuncs, builtinFuncStubs(), stubs)
+		if err == nil {
+			break
+		}
+		matched := undefinedFuncPattern.FindStringSubmatch(err.Error())
+		if matched == nil {
+			return nil, nil, err
+		}
+		if _, stubbed := stubs[matched[1]]; stubbed {
+			return nil, nil, err
+		}
+		stubs[matched[1]] = struct{}{}
+	}
+
+	names := make(map[string]struct{})
+	for _, tree := range trees {
+		_ = walkTemplateFuncs(tree, nil, func(name string) error {
+			names[name] = struct{}{}
+			return nil
+		})
+	}
+	referenced = make([]string, 0, len(names))
+	unregistered = make([]string, 0, len(stubs))
+	for name := range names {
+		referenced = append(referenced, name)
+		if _, ok := stubs[name]; ok {
+			unregistered = append(unregistered, name)
+		}
+	}
+	sort.Strings(referenced)
+	sort.Strings(unregistered)
+	return referenced, unregistered, nil
+}
+
+func builtinFuncStubs() map[string]interface{} {
+	stubs := make(map[string]interface{}, len(builtinTemplateFuncs))
+	for name := range builtinTemplateFuncs {
+		stubs[name] = struct{}{}
+	}
+	return stubs
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wosai/deepmock/misc"
+)
+
+func TestInspectTemplateFuncs(t *testing.T) {
+	referenced, unregistered, err := InspectTemplateFuncs(`{{plus 1 2 | printf "%d"}}{{with .Query.a}}{{len .}}{{end}}{{template "missing" uuid}}`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"len", "plus", "printf", "uuid"}, referenced)
+	assert.Empty(t, unregistered)
+
+	referenced, unregistered, err = InspectTemplateFuncs(`{{define "part"}}{{fooBar}}{{end}}{{uuid}}{{fooBar}}{{bazQux 1}}`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"bazQux", "fooBar", "uuid"}, referenced)
+	assert.Equal(t, []string{"bazQux", "fooBar"}, unregistered)
+
+	_, _, err = InspectTemplateFuncs(`{{if}}`)
+	assert.Error(t, err)
+}
+
+func TestTemplate_UndefinedFunc(t *testing.T) {
+	_, err := (&Template{IsTemplate: true, Body: `{{uuid}}{{notExists 1}}`}).To()
+	assert.True(t, errors.Is(err, ErrUndefinedTemplateFunc))
+	assert.Contains(t, err.Error(), `undefined template func "notExists"`)
+
+	// 响应头模板
+	_, err = (&Template{IsTemplate: true, Header: map[string]misc.StringValues{"X-Id": {`{{headerFunc}}`}}, Body: "ok"}).To()
+	assert.True(t, errors.Is(err, ErrUndefinedTemplateFunc))
+	assert.Contains(t, err.Error(), `"headerFunc"`)
+
+	// 已注销的函数
+	assert.NoError(t, RegisterTemplateFunc("goneFunc", func() string { return "gone" }))
+	_, err = (&Template{IsTemplate: true, Body: `{{goneFunc}}`}).To()
+	assert.NoError(t, err)
+	assert.NoError(t, UnregisterTemplateFunc("goneFunc"))
+	_, err = (&Template{IsTemplate: true, Body: `{{goneFunc}}`}).To()
+	assert.True(t, errors.Is(err, ErrUndefinedTemplateFunc))
+	assert.Contains(t, err.Error(), `"goneFunc"`)
+
+	// 其他语法错误原样返回
+	_, err = (&Template{IsTemplate: true, Body: `{{if}}`}).To()
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrUndefinedTemplateFunc))
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/wosai/deepmock/misc"
+	"go.uber.org/zap"
+)
+
+type (
+	// globalIDGenerator 全局单调递增的id生成器，所有规则共享
+	globalIDGenerator struct {
+		current uint64
+		limit   uint64
+		batch   uint64
+		reserve func(limit uint64) error
+		mu      sync.Mutex
+	}
+)
+
+var (
+	globalID = new(globalIDGenerator)
+)
+
+// SetGlobalIDReserver 开启gid的持久化：start为之前保存的上限，之后每分配batch个id调用一次reserve保存新的上限，
+// 重启后从上限继续分配，从而保证不会生成重复的id。需要在开始处理请求之前调用
+func SetGlobalIDReserver(start, batch uint64, reserve func(limit uint64) error) error {
+	if batch == 0 || reserve == nil {
+		return errors.New("bad global id reserver")
+	}
+	globalID.mu.Lock()
+	defer globalID.mu.Unlock()
+
+	atomic.StoreUint64(&globalID.current, start)
+	atomic.StoreUint64(&globalID.limit, start)
+	globalID.batch = batch
+	globalID.reserve = reserve
+	return nil
+}
+
+// next 返回下一个id，超出已保存的上限时先保存新的上限
+func (gen *globalIDGenerator) next() uint64 {
+	id := atomic.AddUint64(&gen.current, 1)
+	if gen.reserve != nil && id > atomic.LoadUint64(&gen.limit) {
+		gen.extend(id)
+	}
+	return id
+}
+
+func (gen *globalIDGenerator) extend(id uint64) {
+	gen.mu.Lock()
+	defer gen.mu.Unlock()
+
+	limit := atomic.LoadUint64(&gen.limit)
+	if id <= limit {
+		return
+	}
+	for limit < id {
+		limit += gen.batch
+	}
+	// 保存失败时不推进上限，之后分配的每个id都会重试保存，避免重启后重复分配未持久化的id
+	if err := gen.reserve(limit); err != nil {
+		misc.Logger.Error("failed to reserve global ids", zap.Uint64("limit", limit), zap.Error(err))
+		return
+	}
+	atomic.StoreUint64(&gen.limit, limit)
+}
+
+func genGlobalID() uint64 {
+	return globalID.next()
+}
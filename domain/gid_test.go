@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestGIDFunc_Concurrent(t *testing.T) {
+	var executors []*Executor
+	for _, path := range []string{"/api/v1/order", "/api/v1/payment"} {
+		rule := &Rule{
+			Path:        path,
+			Method:      "POST",
+			Regulations: []*Regulation{{IsDefault: true, Template: &Template{IsTemplate: true, Body: `{{gid}}`}}},
+		}
+		executor, err := rule.To()
+		assert.NoError(t, err)
+		executors = append(executors, executor)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	ids := make(map[uint64]struct{})
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(executor *Executor) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				ctx := new(fasthttp.RequestCtx)
+				assert.NoError(t, executor.Regulations[0].Render(ctx, nil, nil))
+				id, err := strconv.ParseUint(string(ctx.Response.Body()), 10, 64)
+				assert.NoError(t, err)
+
+				mu.Lock()
+				ids[id] = struct{}{}
+				mu.Unlock()
+			}
+		}(executors[i%len(executors)])
+	}
+	wg.Wait()
+	assert.Len(t, ids, 1000)
+}
+
+func TestSetGlobalIDReserver(t *testing.T) {
+	defer func() { globalID = new(globalIDGenerator) }()
+	globalID = new(globalIDGenerator)
+
+	assert.Error(t, SetGlobalIDReserver(0, 0, nil))
+
+	var reserved []uint64
+	assert.NoError(t, SetGlobalIDReserver(100, 10, func(limit uint64) error {
+		reserved = append(reserved, limit)
+		return nil
+	}))
+
+	assert.EqualValues(t, 101, genGlobalID())
+	assert.Equal(t, []uint64{110}, reserved)
+	for i := 0; i < 9; i++ {
+		genGlobalID()
+	}
+	assert.Equal(t, []uint64{110}, reserved)
+	assert.EqualValues(t, 111, genGlobalID())
+	assert.Equal(t, []uint64{110, 120}, reserved)
+}
+
+func TestSetGlobalIDReserver_Failure(t *testing.T) {
+	defer func() { globalID = new(globalIDGenerator) }()
+	globalID = new(globalIDGenerator)
+
+	var reserved []uint64
+	fail := true
+	assert.NoError(t, SetGlobalIDReserver(100, 10, func(limit uint64) error {
+		reserved = append(reserved, limit)
+		if fail {
+			return errors.New("disk full")
+		}
+		return nil
+	}))
+
+	// 保存失败时不推进上限，下一个id继续重试
+	assert.EqualValues(t, 101, genGlobalID())
+	assert.EqualValues(t, 100, atomic.LoadUint64(&globalID.limit))
+	assert.EqualValues(t, 102, genGlobalID())
+	assert.Equal(t, []uint64{110, 110}, reserved)
+
+	fail = false
+	assert.EqualValues(t, 103, genGlobalID())
+	assert.EqualValues(t, 110, atomic.LoadUint64(&globalID.limit))
+	assert.EqualValues(t, 104, genGlobalID())
+	assert.Equal(t, []uint64{110, 110, 110}, reserved)
+}
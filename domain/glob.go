@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// PathTypeRegex 规则的Path为正则表达式，未设置PathType时的默认值
+	PathTypeRegex = "regex"
+	// PathTypeGlob 规则的Path为glob通配符：*匹配一段路径中的任意字符(不含/)，**匹配任意多段路径，?匹配一个字符(不含/)
+	PathTypeGlob = "glob"
+)
+
+// compilePath 按PathType将Path编译成正则表达式
+func (rule *Rule) compilePath() (*regexp.Regexp, error) {
+	switch rule.PathType {
+	case "", PathTypeRegex:
+		re, err := regexp.Compile(rule.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path regexp %s: %w", rule.Path, err)
+		}
+		return re, nil
+	case PathTypeGlob:
+		return regexp.Compile(globToRegexp(rule.Path))
+	default:
+		return nil, fmt.Errorf("unsupported path type: %s", rule.PathType)
+	}
+}
+
+// globToRegexp 将glob通配符转换成完整匹配请求路径的正则表达式
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	sb.WriteByte('^')
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				// "/**/"同时匹配零段路径，即"/a/**/b"可以匹配"/a/b"
+				if i+1 < len(glob) && glob[i+1] == '/' && (i == 1 || glob[i-2] == '/') {
+					i++
+					sb.WriteString("(?:.*/)?")
+					continue
+				}
+				sb.WriteString(".*")
+				continue
+			}
+			sb.WriteString("[^/]*")
+		case '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteByte('$')
+	return sb.String()
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	assert.Equal(t, `^/api/v1/users/[^/]*$`, globToRegexp("/api/v1/users/*"))
+	assert.Equal(t, `^/static/.*$`, globToRegexp("/static/**"))
+	assert.Equal(t, `^/a/(?:.*/)?b\.json$`, globToRegexp("/a/**/b.json"))
+	assert.Equal(t, `^/v[^/]/ping$`, globToRegexp("/v?/ping"))
+}
+
+func TestRule_PathType(t *testing.T) {
+	cases := []struct {
+		glob, regex string
+		matched     []string
+		unmatched   []string
+	}{
+		{
+			glob:      "/api/v1/users/*",
+			regex:     `^/api/v1/users/[^/]*$`,
+			matched:   []string{"/api/v1/users/1", "/api/v1/users/abc"},
+			unmatched: []string{"/api/v1/users/1/orders", "/api/v1/users", "/api/v1/usersx/1"},
+		},
+		{
+			glob:      "/api/*/orders/*.json",
+			regex:     `^/api/[^/]*/orders/[^/]*\.json$`,
+			matched:   []string{"/api/v2/orders/1.json"},
+			unmatched: []string{"/api/v2/orders/1.xml", "/api/v2/x/orders/1.json", "/api/v2/orders/1json"},
+		},
+		{
+			glob:      "/static/**/app.js",
+			regex:     `^/static/(.*/)?app\.js$`,
+			matched:   []string{"/static/app.js", "/static/js/app.js", "/static/a/b/c/app.js"},
+			unmatched: []string{"/static/app.jsx", "/assets/app.js"},
+		},
+		{
+			glob:      "/files/**",
+			regex:     `^/files/.*$`,
+			matched:   []string{"/files/", "/files/a", "/files/a/b/c"},
+			unmatched: []string{"/files", "/file/a"},
+		},
+	}
+	for _, c := range cases {
+		glob := &Rule{Path: c.glob, PathType: PathTypeGlob, Method: "GET", Regulations: []*Regulation{{IsDefault: true, Template: &Template{}}}}
+		regex := &Rule{Path: c.regex, Method: "GET", Regulations: []*Regulation{{IsDefault: true, Template: &Template{}}}}
+		ge, err := glob.To()
+		assert.NoError(t, err)
+		re, err := regex.To()
+		assert.NoError(t, err)
+		for _, path := range c.matched {
+			assert.True(t, ge.Match([]byte(path), []byte("GET")), "%s should match %s", c.glob, path)
+			assert.True(t, re.Match([]byte(path), []byte("GET")), "%s should match %s", c.regex, path)
+		}
+		for _, path := range c.unmatched {
+			assert.False(t, ge.Match([]byte(path), []byte("GET")), "%s should not match %s", c.glob, path)
+			assert.False(t, re.Match([]byte(path), []byte("GET")), "%s should not match %s", c.regex, path)
+		}
+	}
+
+	// glob中的正则元字符按字面值处理
+	ge, err := (&Rule{Path: "/a+b/(c)", PathType: PathTypeGlob, Method: "GET", Regulations: []*Regulation{{IsDefault: true, Template: &Template{}}}}).To()
+	assert.NoError(t, err)
+	assert.True(t, ge.Match([]byte("/a+b/(c)"), []byte("GET")))
+	assert.False(t, ge.Match([]byte("/aab/c"), []byte("GET")))
+
+	assert.Error(t, (&Rule{Path: "/a", PathType: "wildcard", Method: "GET", Regulations: []*Regulation{{IsDefault: true, Template: &Template{}}}}).Validate())
+}
@@ -0,0 +1,34 @@
+package domain
+
+import "fmt"
+
+// maxGridCells grid模板函数允许生成的单元格数量上限，避免模板生成过大的响应
+const maxGridCells = 100000
+
+// grid 生成rows行cols列的二维数组，每个单元格包含从0开始的行号row、列号col以及按行展开的序号index，用于在模板中range出表格
+func grid(rows, cols interface{}) ([][]map[string]int, error) {
+	r, err := toIntArg("rows", rows)
+	if err != nil {
+		return nil, err
+	}
+	c, err := toIntArg("cols", cols)
+	if err != nil {
+		return nil, err
+	}
+	if r < 0 || c < 0 {
+		return nil, fmt.Errorf("bad grid size: %d x %d", r, c)
+	}
+	// 分别限制行数与列数，避免其中一边为0时不受限制，以及r*c溢出后绕过上限
+	if r > maxGridCells || c > maxGridCells || r*c > maxGridCells {
+		return nil, fmt.Errorf("grid size %d x %d exceeds %d cells", r, c, maxGridCells)
+	}
+
+	res := make([][]map[string]int, r)
+	for i := range res {
+		res[i] = make([]map[string]int, c)
+		for j := range res[i] {
+			res[i][j] = map[string]int{"row": i, "col": j, "index": i*c + j}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrid(t *testing.T) {
+	g, err := grid(3, "4")
+	assert.Nil(t, err)
+	assert.Len(t, g, 3)
+	for _, row := range g {
+		assert.Len(t, row, 4)
+	}
+	assert.Equal(t, map[string]int{"row": 2, "col": 1, "index": 9}, g[2][1])
+
+	g, err = grid(0, 5)
+	assert.Nil(t, err)
+	assert.Len(t, g, 0)
+
+	_, err = grid(-1, 2)
+	assert.EqualError(t, err, "bad grid size: -1 x 2")
+	_, err = grid(1000, 1000)
+	assert.EqualError(t, err, "grid size 1000 x 1000 exceeds 100000 cells")
+	// 一边为0时另一边同样受限
+	_, err = grid(1000000000, 0)
+	assert.EqualError(t, err, "grid size 1000000000 x 0 exceeds 100000 cells")
+	_, err = grid(0, 1000000000)
+	assert.EqualError(t, err, "grid size 0 x 1000000000 exceeds 100000 cells")
+	// 乘积溢出
+	_, err = grid(int64(1)<<32, int64(1)<<32)
+	assert.EqualError(t, err, "grid size 4294967296 x 4294967296 exceeds 100000 cells")
+	_, err = grid("a", 1)
+	assert.EqualError(t, err, "bad rows: a")
+}
+
+func TestGridFunc(t *testing.T) {
+	tmpl, err := template.New("").Funcs(defaultTemplateFuncs).Parse(`{{range $i, $row := grid 2 3}}{{if $i}},{{end}}[{{range $j, $cell := $row}}{{if $j}},{{end}}"{{$cell.row}}-{{$cell.col}}"{{end}}]{{end}}`)
+	assert.Nil(t, err)
+	buf := new(bytes.Buffer)
+	assert.Nil(t, tmpl.Execute(buf, nil))
+	assert.Equal(t, `["0-0","0-1","0-2"],["1-0","1-1","1-2"]`, buf.String())
+
+	// query参数驱动的超大尺寸返回错误，而不是分配内存
+	tmpl, err = template.New("").Funcs(defaultTemplateFuncs).Parse(`{{range grid .rows .cols}}x{{end}}`)
+	assert.Nil(t, err)
+	assert.Error(t, tmpl.Execute(new(bytes.Buffer), map[string]string{"rows": "1000000000", "cols": "0"}))
+}
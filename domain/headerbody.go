@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+)
+
+// FilterRequest 根据请求筛选，除body_json_path模式外与Filter一致
+func (hfe *HeaderFilterExecutor) FilterRequest(req *fasthttp.Request) bool {
+	if hfe != nil && hfe.mode == FilterModeBodyJSONPath {
+		return hfe.filterByBodyJSONPath(req)
+	}
+	return hfe.Filter(&req.Header)
+}
+
+// filterByBodyJSONPath 每个请求头的值都必须与JSON请求报文中对应路径的字段值相等，请求头或者字段不存在时不通过
+func (hfe *HeaderFilterExecutor) filterByBodyJSONPath(req *fasthttp.Request) bool {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(req.Body(), &doc); err != nil {
+		return false
+	}
+	for k, path := range hfe.params {
+		value := req.Header.Peek(k)
+		if len(value) == 0 {
+			return false
+		}
+		field, err := VariableReader(doc).Lookup(string(path))
+		if err != nil {
+			return false
+		}
+		expected, ok := stringifyJSONValue(field)
+		if !ok || expected != string(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// stringifyJSONValue 将JSON字段值转换为与请求头比较的字符串，对象与数组使用紧凑的JSON格式
+func stringifyJSONValue(v interface{}) (string, bool) {
+	switch val := v.(type) {
+	case string:
+		return val, true
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(val), true
+	case nil:
+		return "", false
+	default:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return "", false
+		}
+		return string(data), true
+	}
+}
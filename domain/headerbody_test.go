@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestHeaderFilterExecutor_FilterByBodyJSONPath(t *testing.T) {
+	hfe, err := HeaderFilterParams{"mode": FilterModeBodyJSONPath, "X-Signature": "meta.sign", "X-Count": "items.0.count"}.To()
+	assert.NoError(t, err)
+
+	newRequest := func(sign, count, body string) *fasthttp.Request {
+		req := new(fasthttp.Request)
+		req.Header.Set("X-Signature", sign)
+		req.Header.Set("X-Count", count)
+		req.SetBodyString(body)
+		return req
+	}
+	body := `{"meta": {"sign": "abc123"}, "items": [{"count": 3}]}`
+
+	assert.True(t, hfe.FilterRequest(newRequest("abc123", "3", body)))
+	assert.False(t, hfe.FilterRequest(newRequest("abc124", "3", body)))
+	assert.False(t, hfe.FilterRequest(newRequest("abc123", "3.0", body)))
+	assert.False(t, hfe.FilterRequest(newRequest("", "3", body)))
+	assert.False(t, hfe.FilterRequest(newRequest("abc123", "3", `{"meta": {}}`)))
+	assert.False(t, hfe.FilterRequest(newRequest("abc123", "3", `not json`)))
+	// 只检查请求头时无法读取报文，不通过
+	assert.False(t, hfe.Filter(&newRequest("abc123", "3", body).Header))
+
+	// 对象、数组与布尔值的比较
+	hfe, err = HeaderFilterParams{"mode": FilterModeBodyJSONPath, "X-Tags": "tags", "X-Vip": "vip"}.To()
+	assert.NoError(t, err)
+	req := new(fasthttp.Request)
+	req.Header.Set("X-Tags", `["a","b"]`)
+	req.Header.Set("X-Vip", "true")
+	req.SetBodyString(`{"tags": ["a", "b"], "vip": true}`)
+	assert.True(t, hfe.FilterRequest(req))
+
+	// 其他模式与Filter一致
+	hfe, err = HeaderFilterParams{"mode": FilterModeExact, "X-Signature": "abc123"}.To()
+	assert.NoError(t, err)
+	assert.True(t, hfe.FilterRequest(newRequest("abc123", "", "")))
+	var nilExecutor *HeaderFilterExecutor
+	assert.True(t, nilExecutor.FilterRequest(new(fasthttp.Request)))
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// HitCounter 命中次数统计，并发安全
+	HitCounter struct {
+		total   uint64
+		lastHit int64
+	}
+)
+
+// Hit 命中一次
+func (hc *HitCounter) Hit() {
+	atomic.AddUint64(&hc.total, 1)
+	atomic.StoreInt64(&hc.lastHit, time.Now().UnixNano())
+}
+
+// Total 总命中次数
+func (hc *HitCounter) Total() uint64 {
+	return atomic.LoadUint64(&hc.total)
+}
+
+// LastHit 最后一次命中的时间，未命中过时返回零值
+func (hc *HitCounter) LastHit() time.Time {
+	last := atomic.LoadInt64(&hc.lastHit)
+	if last == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, last)
+}
+
+// Hit 记录规则及其报文规则的命中次数
+func (exe *Executor) Hit(regulation *RegulationExecutor) {
+	exe.Hits.Hit()
+	regulation.Hits.Hit()
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+const (
+	// HostTypeExact 规则的Host与请求的Host头精确匹配(忽略大小写)，未设置HostType时的默认值
+	HostTypeExact = "exact"
+	// HostTypeRegex 规则的Host为正则表达式，与请求的完整Host头(可能包含端口)匹配
+	HostTypeRegex = "regex"
+)
+
+// HostMatcher 按请求的Host头匹配规则，用于在同一个deepmock后模拟多个只以域名区分的服务
+type HostMatcher struct {
+	exact []byte
+	regex *regexp.Regexp
+}
+
+// normalizeHost 统一精确匹配的写法：声明了host时空的HostType视为exact，exact的host转换为小写，
+// 保证匹配相同请求的规则生成相同的ID，并受存储库中(path, method, host, host_type)唯一索引的约束
+func normalizeHost(hostType, host string) (string, string) {
+	if host == "" {
+		return hostType, host
+	}
+	if hostType == "" {
+		hostType = HostTypeExact
+	}
+	if hostType == HostTypeExact {
+		host = strings.ToLower(host)
+	}
+	return hostType, host
+}
+
+// compileHost 按HostType编译规则的Host，未设置Host时返回nil，即匹配任意Host
+func (rule *Rule) compileHost() (*HostMatcher, error) {
+	if rule.Host == "" {
+		if rule.HostType != "" {
+			return nil, fmt.Errorf("host type %s requires host", rule.HostType)
+		}
+		return nil, nil
+	}
+	switch rule.HostType {
+	case "", HostTypeExact:
+		return &HostMatcher{exact: []byte(strings.ToLower(rule.Host))}, nil
+	case HostTypeRegex:
+		re, err := regexp.Compile(rule.Host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid host regexp %s: %w", rule.Host, err)
+		}
+		return &HostMatcher{regex: re}, nil
+	default:
+		return nil, fmt.Errorf("unsupported host type: %s", rule.HostType)
+	}
+}
+
+// Match 判断请求的Host头是否匹配；精确匹配时规则的Host未带端口则忽略请求Host中的端口
+func (hm *HostMatcher) Match(host []byte) bool {
+	if hm == nil {
+		return true
+	}
+	if hm.regex != nil {
+		return hm.regex.Match(host)
+	}
+	host = bytes.ToLower(host)
+	if bytes.Equal(host, hm.exact) {
+		return true
+	}
+	if bytes.IndexByte(hm.exact, ':') < 0 {
+		if h, _, err := net.SplitHostPort(string(host)); err == nil {
+			return h == string(hm.exact)
+		}
+	}
+	return false
+}
+
+// String 返回规则声明的Host，未声明时为空字符串
+func (hm *HostMatcher) String() string {
+	switch {
+	case hm == nil:
+		return ""
+	case hm.regex != nil:
+		return hm.regex.String()
+	default:
+		return string(hm.exact)
+	}
+}
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wosai/deepmock/misc"
+)
+
+func TestHostMatcher_Match(t *testing.T) {
+	var hm *HostMatcher
+	assert.True(t, hm.Match([]byte("any.example.com")))
+	assert.Equal(t, "", hm.String())
+
+	hm, err := (&Rule{Host: "API.example.com"}).compileHost()
+	assert.NoError(t, err)
+	assert.True(t, hm.Match([]byte("api.example.com")))
+	assert.True(t, hm.Match([]byte("api.example.com:8080")))
+	assert.False(t, hm.Match([]byte("web.example.com")))
+	assert.False(t, hm.Match(nil))
+
+	hm, err = (&Rule{Host: "api.example.com:8080"}).compileHost()
+	assert.NoError(t, err)
+	assert.True(t, hm.Match([]byte("api.example.com:8080")))
+	assert.False(t, hm.Match([]byte("api.example.com")))
+	assert.False(t, hm.Match([]byte("api.example.com:9090")))
+
+	hm, err = (&Rule{Host: `^(order|pay)\.example\.com`, HostType: HostTypeRegex}).compileHost()
+	assert.NoError(t, err)
+	assert.True(t, hm.Match([]byte("order.example.com")))
+	assert.True(t, hm.Match([]byte("pay.example.com:443")))
+	assert.False(t, hm.Match([]byte("user.example.com")))
+
+	_, err = (&Rule{Host: "(", HostType: HostTypeRegex}).compileHost()
+	assert.Error(t, err)
+	_, err = (&Rule{Host: "a", HostType: "glob"}).compileHost()
+	assert.Error(t, err)
+	_, err = (&Rule{HostType: HostTypeRegex}).compileHost()
+	assert.Error(t, err)
+}
+
+func TestRule_HostID(t *testing.T) {
+	// 未声明host的规则ID保持不变
+	rule := &Rule{Path: "/order", Method: "GET"}
+	rid, _ := rule.SupplyID()
+	assert.Equal(t, misc.GenID([]byte("/order"), []byte("GET")), rid)
+
+	a := &Rule{Path: "/order", Method: "GET", Host: "a.example.com"}
+	b := &Rule{Path: "/order", Method: "GET", Host: "b.example.com"}
+	aid, _ := a.SupplyID()
+	bid, _ := b.SupplyID()
+	assert.NotEqual(t, rid, aid)
+	assert.NotEqual(t, aid, bid)
+
+	// 大小写不同或省略host_type的精确host匹配相同的请求，生成相同的ID
+	upper := &Rule{Path: "/order", Method: "GET", Host: "A.Example.com"}
+	exact := &Rule{Path: "/order", Method: "GET", Host: "a.example.com", HostType: HostTypeExact}
+	uid, _ := upper.SupplyID()
+	eid, _ := exact.SupplyID()
+	assert.Equal(t, aid, uid)
+	assert.Equal(t, aid, eid)
+
+	// 正则host区分大小写，不做规范化
+	regex := &Rule{Path: "/order", Method: "GET", Host: "A.Example.com", HostType: HostTypeRegex}
+	xid, _ := regex.SupplyID()
+	assert.NotEqual(t, aid, xid)
+}
+
+func TestRule_NormalizeHost(t *testing.T) {
+	rule := &Rule{Path: "/order", Method: "get", Host: "A.Example.com", Regulations: []*Regulation{{IsDefault: true, Template: &Template{Body: "ok"}}}}
+	assert.NoError(t, rule.Validate())
+	assert.Equal(t, HostTypeExact, rule.HostType)
+	assert.Equal(t, "a.example.com", rule.Host)
+
+	rule = &Rule{Path: "/order", Method: "get", Host: `^A\.example\.com$`, HostType: HostTypeRegex, Regulations: []*Regulation{{IsDefault: true, Template: &Template{Body: "ok"}}}}
+	assert.NoError(t, rule.Validate())
+	assert.Equal(t, `^A\.example\.com$`, rule.Host)
+
+	rule = &Rule{Path: "/order", Method: "get", Regulations: []*Regulation{{IsDefault: true, Template: &Template{Body: "ok"}}}}
+	assert.NoError(t, rule.Validate())
+	assert.Empty(t, rule.HostType)
+}
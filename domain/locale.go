@@ -0,0 +1,157 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	headerAcceptLanguage  = "Accept-Language"
+	headerContentLanguage = "Content-Language"
+)
+
+type (
+	// localeExecutors 按Accept-Language协商的本地化响应，key为小写的语言标签
+	localeExecutors struct {
+		executors     map[string]*localeExecutor
+		defaultLocale string // 都不匹配时使用的语言标签，为空时使用Template自身的body
+	}
+
+	localeExecutor struct {
+		tag      string // 配置中的原始语言标签，写入Content-Language
+		executor *TemplateExecutor
+	}
+
+	// languageRange Accept-Language中带权重的一项
+	languageRange struct {
+		tag     string
+		quality float64
+	}
+)
+
+// newLocaleExecutors 为每个语言标签的body构建响应模板执行器，除body外与tmp的配置相同
+func newLocaleExecutors(tmp *Template, funcs ...template.FuncMap) (*localeExecutors, error) {
+	if len(tmp.Locales) == 0 {
+		if tmp.DefaultLocale != "" {
+			return nil, errors.New("default locale requires locales")
+		}
+		return nil, nil
+	}
+	if len(tmp.Multipart) > 0 {
+		return nil, errors.New("locales are mutually exclusive with multipart")
+	}
+
+	le := &localeExecutors{executors: make(map[string]*localeExecutor, len(tmp.Locales)), defaultLocale: strings.ToLower(tmp.DefaultLocale)}
+	for tag, body := range tmp.Locales {
+		if tag == "" || tag == "*" {
+			return nil, fmt.Errorf("bad locale tag %q", tag)
+		}
+		key := strings.ToLower(tag)
+		if exist, ok := le.executors[key]; ok { // 语言标签不区分大小写，zh-CN与zh-cn只能配置一个
+			tags := []string{exist.tag, tag}
+			sort.Strings(tags)
+			return nil, fmt.Errorf("duplicate locale tag %s and %s", tags[0], tags[1])
+		}
+		localized := *tmp
+		localized.Body, localized.B64EncodedBody, localized.BodyFile, localized.Fragments = body, "", "", nil
+		localized.Locales, localized.DefaultLocale = nil, ""
+		te, err := localized.To(funcs...)
+		if err != nil {
+			return nil, fmt.Errorf("bad body of locale %s: %w", tag, err)
+		}
+		le.executors[key] = &localeExecutor{tag: tag, executor: te}
+	}
+	if _, ok := le.executors[le.defaultLocale]; le.defaultLocale != "" && !ok {
+		return nil, fmt.Errorf("default locale %s not found in locales", tmp.DefaultLocale)
+	}
+	return le, nil
+}
+
+// negotiate 按Accept-Language的权重依次查找最合适的本地化响应：先精确匹配，再匹配去掉子标签后的语言(如zh-CN匹配zh)，
+// 最后匹配主语言相同的其他地区(如zh匹配zh-TW)；*或都不匹配时返回默认语言，未配置默认语言时返回nil
+func (le *localeExecutors) negotiate(acceptLanguage []byte) *localeExecutor {
+	for _, lr := range parseAcceptLanguage(string(acceptLanguage)) {
+		if lr.tag == "*" {
+			break
+		}
+		if exec := le.lookup(lr.tag); exec != nil {
+			return exec
+		}
+	}
+	return le.executors[le.defaultLocale]
+}
+
+func (le *localeExecutors) lookup(tag string) *localeExecutor {
+	for prefix := tag; prefix != ""; {
+		if exec, ok := le.executors[prefix]; ok {
+			return exec
+		}
+		index := strings.LastIndexByte(prefix, '-')
+		if index < 0 {
+			break
+		}
+		prefix = prefix[:index]
+	}
+
+	primary := tag
+	if index := strings.IndexByte(tag, '-'); index >= 0 {
+		primary = tag[:index]
+	}
+	// 多个地区都匹配时取字典序最小的，保证结果稳定
+	var matched string
+	for key := range le.executors {
+		if strings.HasPrefix(key, primary+"-") && (matched == "" || key < matched) {
+			matched = key
+		}
+	}
+	return le.executors[matched]
+}
+
+// parseAcceptLanguage 解析Accept-Language，按权重从高到低排序，权重相同时保持原有顺序，忽略权重为0或格式错误的项
+func parseAcceptLanguage(value string) []languageRange {
+	var ranges []languageRange
+	for _, item := range strings.Split(value, ",") {
+		parts := strings.Split(item, ";")
+		tag := strings.ToLower(strings.TrimSpace(parts[0]))
+		if tag == "" {
+			continue
+		}
+		lr := languageRange{tag: tag, quality: 1}
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			q, err := strconv.ParseFloat(param[2:], 64)
+			if err != nil || q < 0 || q > 1 {
+				lr.quality = 0
+			} else {
+				lr.quality = q
+			}
+		}
+		if lr.quality > 0 {
+			ranges = append(ranges, lr)
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+	return ranges
+}
+
+// localize 返回本次请求使用的响应模板执行器及其语言标签，未配置本地化响应或未协商出结果时返回te自身
+func (te *TemplateExecutor) localize(ctx *fasthttp.RequestCtx) (*TemplateExecutor, string) {
+	if te.locales == nil {
+		return te, ""
+	}
+	if exec := te.locales.negotiate(ctx.Request.Header.Peek(headerAcceptLanguage)); exec != nil {
+		return exec.executor, exec.tag
+	}
+	return te, ""
+}
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assert.Equal(t, []languageRange{
+		{tag: "fr-ch", quality: 1},
+		{tag: "fr", quality: 0.9},
+		{tag: "en", quality: 0.8},
+		{tag: "de", quality: 0.7},
+		{tag: "*", quality: 0.5},
+	}, parseAcceptLanguage("fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5"))
+	assert.Equal(t, []languageRange{{tag: "zh", quality: 0.8}, {tag: "en", quality: 0.3}}, parseAcceptLanguage("en;q=0.3, ja;q=0, zh;q=0.8, ko;q=abc"))
+	assert.Empty(t, parseAcceptLanguage(""))
+}
+
+func TestTemplateExecutor_Locales(t *testing.T) {
+	te, err := (&Template{
+		IsTemplate: true,
+		StatusCode: 200,
+		Body:       "hello {{.Query.name}}",
+		Locales: map[string]string{
+			"zh-CN": "你好 {{.Query.name}}",
+			"zh-TW": "妳好 {{.Query.name}}",
+			"fr":    "bonjour {{.Query.name}}",
+		},
+	}).To()
+	assert.NoError(t, err)
+
+	render := func(acceptLanguage string) *fasthttp.Response {
+		ctx := new(fasthttp.RequestCtx)
+		ctx.Request.SetRequestURI("/greet?name=deepmock")
+		if acceptLanguage != "" {
+			ctx.Request.Header.Set("Accept-Language", acceptLanguage)
+		}
+		assert.NoError(t, te.Render(ctx, nil, nil))
+		return &ctx.Response
+	}
+
+	cases := []struct {
+		acceptLanguage, body, contentLanguage string
+	}{
+		{"zh-CN,zh;q=0.9,en;q=0.8", "你好 deepmock", "zh-CN"},
+		{"zh-tw", "妳好 deepmock", "zh-TW"},
+		{"fr-CH, en;q=0.9", "bonjour deepmock", "fr"},  // 去掉地区后匹配
+		{"en;q=0.5, zh;q=0.8", "你好 deepmock", "zh-CN"}, // 按权重，主语言相同时取字典序最小的地区
+		{"de, *;q=0.1", "hello deepmock", ""},          // 都不匹配时使用body
+		{"fr;q=0, en", "hello deepmock", ""},           // 权重为0表示不接受
+		{"", "hello deepmock", ""},
+	}
+	for _, c := range cases {
+		resp := render(c.acceptLanguage)
+		assert.Equal(t, c.body, string(resp.Body()), c.acceptLanguage)
+		assert.Equal(t, c.contentLanguage, string(resp.Header.Peek("Content-Language")), c.acceptLanguage)
+		assert.Equal(t, "Accept-Language", string(resp.Header.Peek("Vary")))
+	}
+
+	// 设置默认语言后，都不匹配时使用该语言的body
+	te, err = (&Template{StatusCode: 200, Locales: map[string]string{"en": "hello", "ja": "こんにちは"}, DefaultLocale: "ja"}).To()
+	assert.NoError(t, err)
+	resp := render("de")
+	assert.Equal(t, "こんにちは", string(resp.Body()))
+	assert.Equal(t, "ja", string(resp.Header.Peek("Content-Language")))
+
+	_, err = (&Template{Locales: map[string]string{"en": "hello"}, DefaultLocale: "ja"}).To()
+	assert.EqualError(t, err, "default locale ja not found in locales")
+	_, err = (&Template{Locales: map[string]string{"zh-CN": "你好", "zh-cn": "您好"}}).To()
+	assert.EqualError(t, err, "duplicate locale tag zh-CN and zh-cn")
+	_, err = (&Template{DefaultLocale: "ja"}).To()
+	assert.Error(t, err)
+	_, err = (&Template{IsTemplate: true, Locales: map[string]string{"en": "{{"}}).To()
+	assert.Error(t, err)
+}
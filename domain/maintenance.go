@@ -0,0 +1,172 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+)
+
+const maintenanceClockLayout = "15:04"
+
+type (
+	// Maintenance 计划维护配置值对象：当前时间落在任一Windows内时直接返回Response(未配置时返回503)，
+	// 并通过Retry-After告知维护结束前的秒数，窗口外正常响应
+	Maintenance struct {
+		Windows  []*MaintenanceWindow `json:"windows"`
+		Response *Template            `json:"response,omitempty"`
+	}
+
+	// MaintenanceWindow 维护窗口值对象，Start与End同为RFC3339时间时表示一次性的时间段；
+	// 同为"15:04"格式时表示每天重复的本地时间段，End早于Start时跨越零点，
+	// Weekdays(0为周日)不为空时只在窗口开始于这几天时生效
+	MaintenanceWindow struct {
+		Start    string `json:"start"`
+		End      string `json:"end"`
+		Weekdays []int  `json:"weekdays,omitempty"`
+	}
+
+	// MaintenanceExecutor 计划维护执行器
+	MaintenanceExecutor struct {
+		Response *TemplateExecutor
+		windows  []maintenanceWindow
+		now      func() time.Time
+	}
+
+	maintenanceWindow struct {
+		// 一次性的时间段
+		start, end time.Time
+		// 每天重复的时间段，daily为true时生效，offset为窗口开始时距零点的时长
+		daily    bool
+		offset   time.Duration
+		duration time.Duration
+		weekdays map[time.Weekday]struct{}
+	}
+)
+
+// Validate 校验函数
+func (m *Maintenance) Validate() error {
+	if m == nil {
+		return nil
+	}
+	if len(m.Windows) == 0 {
+		return errors.New("maintenance requires windows")
+	}
+	for index, w := range m.Windows {
+		if _, err := w.parse(); err != nil {
+			return fmt.Errorf("bad maintenance window at index %d: %w", index, err)
+		}
+	}
+	return nil
+}
+
+func (w *MaintenanceWindow) parse() (maintenanceWindow, error) {
+	var mw maintenanceWindow
+	if w == nil {
+		return mw, errors.New("empty window")
+	}
+
+	start, startErr := time.Parse(time.RFC3339, w.Start)
+	end, endErr := time.Parse(time.RFC3339, w.End)
+	if startErr == nil && endErr == nil {
+		if !end.After(start) {
+			return mw, errors.New("end must be after start")
+		}
+		if len(w.Weekdays) > 0 {
+			return mw, errors.New("weekdays only apply to daily windows")
+		}
+		mw.start, mw.end = start, end
+		return mw, nil
+	}
+
+	startClock, startErr := time.Parse(maintenanceClockLayout, w.Start)
+	endClock, endErr := time.Parse(maintenanceClockLayout, w.End)
+	if startErr != nil || endErr != nil {
+		return mw, fmt.Errorf("start %q and end %q must both be RFC3339 times or both be HH:MM", w.Start, w.End)
+	}
+	mw.daily = true
+	mw.offset = time.Duration(startClock.Hour())*time.Hour + time.Duration(startClock.Minute())*time.Minute
+	mw.duration = endClock.Sub(startClock)
+	if mw.duration == 0 {
+		return mw, errors.New("end must differ from start")
+	}
+	if mw.duration < 0 {
+		mw.duration += 24 * time.Hour
+	}
+	if len(w.Weekdays) > 0 {
+		mw.weekdays = make(map[time.Weekday]struct{}, len(w.Weekdays))
+		for _, day := range w.Weekdays {
+			if day < 0 || day > 6 {
+				return mw, fmt.Errorf("bad weekday %d", day)
+			}
+			mw.weekdays[time.Weekday(day)] = struct{}{}
+		}
+	}
+	return mw, nil
+}
+
+// To 转换成MaintenanceExecutor，未配置时返回nil，即不进入维护
+func (m *Maintenance) To(funcs ...template.FuncMap) (*MaintenanceExecutor, error) {
+	if m == nil {
+		return nil, nil
+	}
+	me := &MaintenanceExecutor{windows: make([]maintenanceWindow, len(m.Windows)), now: Now}
+	for index, w := range m.Windows {
+		mw, err := w.parse()
+		if err != nil {
+			return nil, fmt.Errorf("bad maintenance window at index %d: %w", index, err)
+		}
+		me.windows[index] = mw
+	}
+	if m.Response != nil {
+		// 未指定状态码时返回503
+		te, err := m.Response.toWithDefaultStatus(http.StatusServiceUnavailable, funcs...)
+		if err != nil {
+			return nil, err
+		}
+		me.Response = te
+	}
+	return me, nil
+}
+
+// Remaining 判断当前是否处于维护窗口内，是则同时返回距维护结束的时长，多个窗口重叠时取最晚结束的窗口
+func (me *MaintenanceExecutor) Remaining() (time.Duration, bool) {
+	if me == nil {
+		return 0, false
+	}
+	now := me.now()
+	var (
+		remaining time.Duration
+		active    bool
+	)
+	for _, w := range me.windows {
+		if end, ok := w.endAt(now); ok && (!active || end.Sub(now) > remaining) {
+			remaining, active = end.Sub(now), true
+		}
+	}
+	return remaining, active
+}
+
+// endAt 当前时间处于窗口内时返回窗口的结束时间
+func (w maintenanceWindow) endAt(now time.Time) (time.Time, bool) {
+	if !w.daily {
+		return w.end, !now.Before(w.start) && now.Before(w.end)
+	}
+	// 跨越零点的窗口可能开始于前一天
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for _, day := range []time.Time{midnight, midnight.AddDate(0, 0, -1)} {
+		start := day.Add(w.offset)
+		end := start.Add(w.duration)
+		if now.Before(start) || !now.Before(end) {
+			continue
+		}
+		if w.weekdays != nil {
+			if _, ok := w.weekdays[start.Weekday()]; !ok {
+				continue
+			}
+		}
+		return end, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaintenance_Validate(t *testing.T) {
+	var m *Maintenance
+	assert.NoError(t, m.Validate())
+	me, err := m.To()
+	assert.NoError(t, err)
+	assert.Nil(t, me)
+	_, active := me.Remaining()
+	assert.False(t, active)
+
+	assert.Error(t, (&Maintenance{}).Validate())
+	assert.Error(t, (&Maintenance{Windows: []*MaintenanceWindow{{Start: "02:00", End: "02:00"}}}).Validate())
+	assert.Error(t, (&Maintenance{Windows: []*MaintenanceWindow{{Start: "02:00", End: "2020-01-01T00:00:00Z"}}}).Validate())
+	assert.Error(t, (&Maintenance{Windows: []*MaintenanceWindow{{Start: "2020-01-02T00:00:00Z", End: "2020-01-01T00:00:00Z"}}}).Validate())
+	assert.Error(t, (&Maintenance{Windows: []*MaintenanceWindow{{Start: "02:00", End: "03:00", Weekdays: []int{7}}}}).Validate())
+
+	m = &Maintenance{Windows: []*MaintenanceWindow{{Start: "02:00", End: "03:00"}}, Response: &Template{Body: "maintaining"}}
+	assert.NoError(t, m.Validate())
+	// 未指定状态码时返回503，但不修改配置本身
+	me, err = m.To()
+	assert.NoError(t, err)
+	assert.Equal(t, 503, me.Response.header.StatusCode())
+	assert.Zero(t, m.Response.StatusCode)
+}
+
+func TestMaintenanceExecutor_Remaining(t *testing.T) {
+	me, err := (&Maintenance{Windows: []*MaintenanceWindow{
+		{Start: "2020-05-01T10:00:00Z", End: "2020-05-01T12:00:00Z"},
+		// 跨越零点，只在周六开始
+		{Start: "23:30", End: "01:00", Weekdays: []int{6}},
+	}}).To()
+	assert.NoError(t, err)
+
+	var now time.Time
+	me.now = func() time.Time { return now }
+	cases := []struct {
+		now       string
+		active    bool
+		remaining time.Duration
+	}{
+		{"2020-05-01T09:59:59Z", false, 0},
+		{"2020-05-01T10:00:00Z", true, 2 * time.Hour},
+		{"2020-05-01T11:59:30Z", true, 30 * time.Second},
+		{"2020-05-01T12:00:00Z", false, 0},
+		// 2020-05-02为周六
+		{"2020-05-02T23:00:00Z", false, 0},
+		{"2020-05-02T23:45:00Z", true, 75 * time.Minute},
+		{"2020-05-03T00:30:00Z", true, 30 * time.Minute},
+		{"2020-05-03T01:00:00Z", false, 0},
+		{"2020-05-03T23:45:00Z", false, 0},
+	}
+	for _, c := range cases {
+		now, err = time.Parse(time.RFC3339, c.now)
+		assert.NoError(t, err)
+		remaining, active := me.Remaining()
+		assert.Equal(t, c.active, active, c.now)
+		assert.Equal(t, c.remaining, remaining, c.now)
+	}
+}
@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"errors"
+	"html/template"
+	"io/ioutil"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+type (
+	// Part multipart/form-data响应报文中的一个部分
+	Part struct {
+		Name     string            `json:"name,omitempty"`
+		FileName string            `json:"filename,omitempty"`
+		Header   map[string]string `json:"header,omitempty"`
+		Body     string            `json:"body,omitempty"`
+	}
+
+	// multipartExecutor multipart响应报文执行器，boundary在规则生效时生成
+	multipartExecutor struct {
+		boundary    string
+		contentType string
+		parts       []*partExecutor
+	}
+
+	partExecutor struct {
+		header   textproto.MIMEHeader
+		template *template.Template
+		body     []byte
+	}
+)
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// MultipartFormData 默认的multipart子类型
+const MultipartFormData = "form-data"
+
+// multipartTypes 支持的multipart子类型，其中只有form-data的部分才有name与filename
+var multipartTypes = map[string]struct{}{MultipartFormData: {}, "mixed": {}, "related": {}, "alternative": {}}
+
+func newMultipartExecutor(parts []*Part, subtype string, isTemplate bool, funcs ...template.FuncMap) (*multipartExecutor, error) {
+	if len(parts) == 0 {
+		if subtype != "" {
+			return nil, errors.New("multipart_type requires multipart parts")
+		}
+		return nil, nil
+	}
+	if subtype == "" {
+		subtype = MultipartFormData
+	}
+	if _, ok := multipartTypes[subtype]; !ok {
+		return nil, errors.New("unsupported multipart type: " + subtype)
+	}
+
+	me := &multipartExecutor{
+		boundary: multipart.NewWriter(ioutil.Discard).Boundary(),
+		parts:    make([]*partExecutor, len(parts)),
+	}
+	me.contentType = "multipart/" + subtype + "; boundary=" + me.boundary
+	for index, part := range parts {
+		pe := &partExecutor{header: make(textproto.MIMEHeader), body: []byte(part.Body)}
+		for k, v := range part.Header {
+			pe.header.Set(k, v)
+		}
+		if (part.Name != "" || part.FileName != "") && subtype != MultipartFormData {
+			return nil, errors.New("name and filename of part are only supported by multipart/form-data")
+		}
+		if part.Name != "" {
+			disposition := `form-data; name="` + quoteEscaper.Replace(part.Name) + `"`
+			if part.FileName != "" {
+				disposition += `; filename="` + quoteEscaper.Replace(part.FileName) + `"`
+			}
+			pe.header.Set("Content-Disposition", disposition)
+		}
+
+		if isTemplate {
+			tmpl, err := parseTemplate(part.Body, funcs...)
+			if err != nil {
+				return nil, err
+			}
+			pe.template = tmpl
+		}
+		me.parts[index] = pe
+	}
+	return me, nil
+}
+
+// render 依次渲染每个部分，并设置带有boundary的Content-Type
+func (me *multipartExecutor) render(resp *fasthttp.Response, rc *RenderContext) error {
+	writer := multipart.NewWriter(resp.BodyWriter())
+	if err := writer.SetBoundary(me.boundary); err != nil {
+		return err
+	}
+	resp.Header.SetContentType(me.contentType)
+
+	for _, part := range me.parts {
+		w, err := writer.CreatePart(part.header)
+		if err != nil {
+			return err
+		}
+		if part.template == nil {
+			_, err = w.Write(part.body)
+		} else {
+			err = part.template.Execute(w, rc)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestTemplateExecutor_Multipart(t *testing.T) {
+	te, err := (&Template{
+		IsTemplate: true,
+		StatusCode: 200,
+		Multipart: []*Part{
+			{Name: "meta", Header: map[string]string{"Content-Type": "application/json"}, Body: `{"id": "{{.Query.id}}"}`},
+			{Name: "file", FileName: "report.csv", Body: "id,name\n{{.Query.id}},deepmock"},
+		},
+	}).To()
+	assert.NoError(t, err)
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.SetRequestURI("/api/v1/report?id=42")
+	assert.NoError(t, te.Render(ctx, nil, nil))
+
+	mediaType, params, err := mime.ParseMediaType(string(ctx.Response.Header.ContentType()))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/form-data", mediaType)
+
+	reader := multipart.NewReader(bytes.NewReader(ctx.Response.Body()), params["boundary"])
+	part, err := reader.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, "meta", part.FormName())
+	assert.Equal(t, "application/json", part.Header.Get("Content-Type"))
+	body, _ := ioutil.ReadAll(part)
+	assert.Equal(t, `{"id": "42"}`, string(body))
+
+	part, err = reader.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, "file", part.FormName())
+	assert.Equal(t, "report.csv", part.FileName())
+	body, _ = ioutil.ReadAll(part)
+	assert.Equal(t, "id,name\n42,deepmock", string(body))
+
+	_, err = reader.NextPart()
+	assert.Error(t, err)
+
+	// 非模板响应原样返回每个部分
+	te, err = (&Template{StatusCode: 200, Multipart: []*Part{{Name: "raw", Body: "{{.Query.id}}"}}}).To()
+	assert.NoError(t, err)
+	ctx = new(fasthttp.RequestCtx)
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Contains(t, string(ctx.Response.Body()), "{{.Query.id}}")
+
+	_, err = (&Template{IsTemplate: true, Multipart: []*Part{{Name: "bad", Body: "{{.Query.id"}}}).To()
+	assert.Error(t, err)
+}
+
+func TestTemplateExecutor_MultipartMixed(t *testing.T) {
+	te, err := (&Template{
+		IsTemplate:    true,
+		StatusCode:    200,
+		MultipartType: "mixed",
+		Multipart: []*Part{
+			{Header: map[string]string{"Content-Type": "application/http", "Content-ID": "<response-1>"}, Body: "HTTP/1.1 200 OK\r\n\r\n{\"id\": \"{{.Query.id}}\"}"},
+			{Header: map[string]string{"Content-Type": "application/http", "Content-ID": "<response-2>"}, Body: "HTTP/1.1 404 Not Found\r\n\r\n"},
+		},
+	}).To()
+	assert.NoError(t, err)
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.SetRequestURI("/batch?id=42")
+	assert.NoError(t, te.Render(ctx, nil, nil))
+
+	mediaType, params, err := mime.ParseMediaType(string(ctx.Response.Header.ContentType()))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/mixed", mediaType)
+
+	reader := multipart.NewReader(bytes.NewReader(ctx.Response.Body()), params["boundary"])
+	for _, expected := range []struct{ id, body string }{
+		{"<response-1>", "HTTP/1.1 200 OK\r\n\r\n{\"id\": \"42\"}"},
+		{"<response-2>", "HTTP/1.1 404 Not Found\r\n\r\n"},
+	} {
+		part, err := reader.NextPart()
+		assert.NoError(t, err)
+		assert.Equal(t, expected.id, part.Header.Get("Content-ID"))
+		assert.Empty(t, part.Header.Get("Content-Disposition"))
+		body, _ := ioutil.ReadAll(part)
+		assert.Equal(t, expected.body, string(body))
+	}
+	_, err = reader.NextPart()
+	assert.Error(t, err)
+
+	_, err = (&Template{MultipartType: "signed", Multipart: []*Part{{Body: "a"}}}).To()
+	assert.EqualError(t, err, "unsupported multipart type: signed")
+	_, err = (&Template{MultipartType: "mixed", Multipart: []*Part{{Name: "a", Body: "a"}}}).To()
+	assert.EqualError(t, err, "name and filename of part are only supported by multipart/form-data")
+	_, err = (&Template{MultipartType: "mixed", Body: "a"}).To()
+	assert.EqualError(t, err, "multipart_type requires multipart parts")
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// paginate 根据总数、页码(从1开始)和每页数量生成分页信息，link为翻页链接的基础地址，会在其query中设置page与page_size
+func paginate(total, page, pageSize interface{}, link ...string) (map[string]interface{}, error) {
+	t, err := toIntArg("total", total)
+	if err != nil {
+		return nil, err
+	}
+	p, err := toIntArg("page", page)
+	if err != nil {
+		return nil, err
+	}
+	size, err := toIntArg("page_size", pageSize)
+	if err != nil {
+		return nil, err
+	}
+	if t < 0 || p < 1 || size < 1 {
+		return nil, fmt.Errorf("bad pagination: total %d, page %d, page_size %d", t, p, size)
+	}
+
+	base := &url.URL{}
+	if len(link) > 0 {
+		if base, err = url.Parse(link[0]); err != nil {
+			return nil, fmt.Errorf("bad pagination link: %w", err)
+		}
+	}
+	pageLink := func(n int) string {
+		u := *base
+		query := u.Query()
+		query.Set("page", strconv.Itoa(n))
+		query.Set("page_size", strconv.Itoa(size))
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+
+	pages := (t + size - 1) / size
+	res := map[string]interface{}{
+		"total":       t,
+		"page":        p,
+		"page_size":   size,
+		"total_pages": pages,
+		"has_next":    p < pages,
+		"has_prev":    p > 1,
+		"next":        "",
+		"prev":        "",
+	}
+	if p < pages {
+		res["next"] = pageLink(p + 1)
+	}
+	if p > 1 {
+		// 超出最后一页时上一页指向最后一页
+		prev := p - 1
+		if prev > pages && pages > 0 {
+			prev = pages
+		}
+		res["prev"] = pageLink(prev)
+	}
+	return res, nil
+}
+
+func toIntArg(name string, v interface{}) (int, error) {
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case int64:
+		return int(n), nil
+	case float64:
+		return int(n), nil
+	case string:
+		i, err := strconv.Atoi(n)
+		if err != nil {
+			return 0, fmt.Errorf("bad %s: %s", name, n)
+		}
+		return i, nil
+	default:
+		return 0, fmt.Errorf("unsupported %s type %T", name, v)
+	}
+}
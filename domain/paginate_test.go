@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaginate(t *testing.T) {
+	// 第一页
+	p, err := paginate(45, "1", "10", "/api/items?sort=name")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"total": 45, "page": 1, "page_size": 10, "total_pages": 5,
+		"has_next": true, "has_prev": false,
+		"next": "/api/items?page=2&page_size=10&sort=name", "prev": "",
+	}, p)
+
+	// 中间页
+	p, err = paginate("45", 3, float64(10))
+	assert.Nil(t, err)
+	assert.Equal(t, true, p["has_next"])
+	assert.Equal(t, true, p["has_prev"])
+	assert.Equal(t, "?page=4&page_size=10", p["next"])
+	assert.Equal(t, "?page=2&page_size=10", p["prev"])
+
+	// 最后一页
+	p, err = paginate(45, 5, 10, "/api/items")
+	assert.Nil(t, err)
+	assert.Equal(t, false, p["has_next"])
+	assert.Equal(t, true, p["has_prev"])
+	assert.Equal(t, "", p["next"])
+	assert.Equal(t, "/api/items?page=4&page_size=10", p["prev"])
+
+	// 超出最后一页
+	p, err = paginate(45, 9, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, "?page=5&page_size=10", p["prev"])
+
+	// 没有数据
+	p, err = paginate(0, 1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, p["total_pages"])
+	assert.Equal(t, false, p["has_next"])
+
+	_, err = paginate(45, "abc", 10)
+	assert.EqualError(t, err, "bad page: abc")
+	_, err = paginate(45, 0, 10)
+	assert.EqualError(t, err, "bad pagination: total 45, page 0, page_size 10")
+	_, err = paginate(45, 1, nil)
+	assert.EqualError(t, err, "unsupported page_size type <nil>")
+}
+
+func TestPaginateFunc(t *testing.T) {
+	tmpl, err := template.New("").Funcs(defaultTemplateFuncs).Parse(`{{$p := paginate 45 .Query.page .Query.page_size "/items"}}{"total_pages": {{$p.total_pages}}, "next": "{{$p.next}}"}`)
+	assert.Nil(t, err)
+	buf := new(bytes.Buffer)
+	assert.Nil(t, tmpl.Execute(buf, map[string]interface{}{"Query": map[string]string{"page": "2", "page_size": "20"}}))
+	assert.Equal(t, `{"total_pages": 3, "next": "/items?page=3&page_size=20"}`, buf.String())
+}
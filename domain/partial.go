@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	partials = &templatePartials{texts: map[string]string{}}
+)
+
+type (
+	// templatePartials 全局的命名模板片段，规则模板中可以通过{{template "name" .}}引用
+	templatePartials struct {
+		texts    map[string]string
+		revision uint64
+		mu       sync.RWMutex
+	}
+)
+
+// SetTemplatePartials 整体替换命名模板片段，只有在所有片段都能成功解析时才会生效
+func SetTemplatePartials(texts map[string]string) error {
+	for name, text := range texts {
+		if _, err := template.New(name).Funcs(defaultTemplateFuncs).Parse(text); err != nil {
+			return err
+		}
+	}
+
+	replaced := make(map[string]string, len(texts))
+	for name, text := range texts {
+		replaced[name] = text
+	}
+
+	partials.mu.Lock()
+	defer partials.mu.Unlock()
+	partials.texts = replaced
+	partials.revision++
+	return nil
+}
+
+// snapshot 返回当前的模板片段以及版本号
+func (tp *templatePartials) snapshot() (map[string]string, uint64) {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+	return tp.texts, tp.revision
+}
+
+// associate 将模板片段关联到模板上
+func (tp *templatePartials) associate(tmpl *template.Template) error {
+	texts, _ := tp.snapshot()
+	for name, text := range texts {
+		if _, err := tmpl.New(name).Parse(text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check 检查names中的模板片段是否都存在
+func (tp *templatePartials) check(names []string) error {
+	texts, _ := tp.snapshot()
+	for _, name := range names {
+		if _, ok := texts[name]; !ok {
+			return fmt.Errorf("fragment %s not found", name)
+		}
+	}
+	return nil
+}
+
+// concat 按顺序拼接模板片段的原始文本，不做模板渲染
+func (tp *templatePartials) concat(names []string) ([]byte, error) {
+	texts, _ := tp.snapshot()
+	buf := new(bytes.Buffer)
+	for _, name := range names {
+		text, ok := texts[name]
+		if !ok {
+			return nil, fmt.Errorf("fragment %s not found", name)
+		}
+		buf.WriteString(text)
+	}
+	return buf.Bytes(), nil
+}
+
+// includeFragments 生成依次引用模板片段的模板文本
+func includeFragments(names []string) string {
+	var sb strings.Builder
+	for _, name := range names {
+		sb.WriteString("{{template " + strconv.Quote(name) + " .}}")
+	}
+	return sb.String()
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestTemplate_Fragments(t *testing.T) {
+	assert.NoError(t, SetTemplatePartials(map[string]string{
+		"header": `{"code": 200, `,
+		"footer": `"data": {"name": "{{.Query.name}}"}}`,
+	}))
+	defer SetTemplatePartials(nil)
+
+	render := func(te *TemplateExecutor) *fasthttp.Response {
+		ctx := new(fasthttp.RequestCtx)
+		ctx.Request.SetRequestURI("/fragments?name=deepmock")
+		assert.NoError(t, te.Render(ctx, nil, nil))
+		return &ctx.Response
+	}
+
+	// 模板中引用片段，片段内的模板语法会被渲染
+	te, err := (&Template{IsTemplate: true, StatusCode: 200, Fragments: []string{"header", "footer"}}).To()
+	assert.NoError(t, err)
+	resp := render(te)
+	assert.Equal(t, `{"code": 200, "data": {"name": "deepmock"}}`, string(resp.Body()))
+	assert.Equal(t, contentTypeJSON, string(resp.Header.ContentType()))
+
+	// 非模板按原样拼接，渲染时才读取片段
+	te, err = (&Template{StatusCode: 200, Fragments: []string{"header", "footer"}}).To()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"code": 200, "data": {"name": "{{.Query.name}}"}}`, string(render(te).Body()))
+	assert.NoError(t, SetTemplatePartials(map[string]string{"header": `{"code": 201, `, "footer": `"data": null}`}))
+	resp = render(te)
+	assert.Equal(t, `{"code": 201, "data": null}`, string(resp.Body()))
+	assert.Equal(t, contentTypeJSON, string(resp.Header.ContentType()))
+
+	// 渲染时片段已被移除
+	assert.NoError(t, SetTemplatePartials(map[string]string{"header": `{}`}))
+	assert.EqualError(t, te.Render(new(fasthttp.RequestCtx), nil, nil), "fragment footer not found")
+
+	_, err = (&Template{StatusCode: 200, Fragments: []string{"missing"}}).To()
+	assert.EqualError(t, err, "fragment missing not found")
+	_, err = (&Template{StatusCode: 200, Body: "body", Fragments: []string{"header"}}).To()
+	assert.EqualError(t, err, "fragments are mutually exclusive with body")
+}
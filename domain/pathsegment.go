@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"bytes"
+	"errors"
+)
+
+type (
+	// PathSegmentsFilterParams 请求路径段数筛选参数值对象，Exact大于0时要求段数完全相等，否则按Min、Max筛选，为0时表示不限制
+	PathSegmentsFilterParams struct {
+		Exact int `json:"exact,omitempty"`
+		Min   int `json:"min,omitempty"`
+		Max   int `json:"max,omitempty"`
+	}
+
+	// PathSegmentsFilterExecutor 请求路径段数筛选执行器
+	PathSegmentsFilterExecutor struct {
+		min int
+		max int
+	}
+)
+
+// To 转换成PathSegmentsFilterExecutor，未设置时返回nil，即总是通过
+func (psp *PathSegmentsFilterParams) To() (*PathSegmentsFilterExecutor, error) {
+	if psp == nil {
+		return nil, nil
+	}
+	if psp.Exact < 0 || psp.Min < 0 || psp.Max < 0 || (psp.Max > 0 && psp.Min > psp.Max) {
+		return nil, errors.New("bad path segments range")
+	}
+	if psp.Exact > 0 {
+		if psp.Min > 0 || psp.Max > 0 {
+			return nil, errors.New("path segments exact is mutually exclusive with min and max")
+		}
+		return &PathSegmentsFilterExecutor{min: psp.Exact, max: psp.Exact}, nil
+	}
+	return &PathSegmentsFilterExecutor{min: psp.Min, max: psp.Max}, nil
+}
+
+// Filter 根据请求路径的段数筛选
+func (psfe *PathSegmentsFilterExecutor) Filter(path []byte) bool {
+	if psfe == nil {
+		return true
+	}
+
+	count := countPathSegments(path)
+	if count < psfe.min {
+		return false
+	}
+	return psfe.max == 0 || count <= psfe.max
+}
+
+// countPathSegments 计算路径中非空的段数，如/api/v1/users/为3，/为0
+func countPathSegments(path []byte) int {
+	var count int
+	for _, segment := range bytes.Split(path, []byte("/")) {
+		if len(segment) > 0 {
+			count++
+		}
+	}
+	return count
+}
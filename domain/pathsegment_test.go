@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestCountPathSegments(t *testing.T) {
+	assert.Equal(t, 0, countPathSegments([]byte("/")))
+	assert.Equal(t, 1, countPathSegments([]byte("/api")))
+	assert.Equal(t, 3, countPathSegments([]byte("/api/v1/users")))
+	assert.Equal(t, 3, countPathSegments([]byte("/api//v1/users/")))
+}
+
+func TestPathSegmentsFilterExecutor_Filter(t *testing.T) {
+	var nilExecutor *PathSegmentsFilterExecutor
+	assert.True(t, nilExecutor.Filter([]byte("/api")))
+
+	exact, err := (&PathSegmentsFilterParams{Exact: 3}).To()
+	assert.Nil(t, err)
+	atLeast, err := (&PathSegmentsFilterParams{Min: 2}).To()
+	assert.Nil(t, err)
+	between, err := (&PathSegmentsFilterParams{Min: 1, Max: 2}).To()
+	assert.Nil(t, err)
+
+	cases := []struct {
+		path                    string
+		exact, atLeast, between bool
+	}{
+		{"/", false, false, false},
+		{"/api", false, false, true},
+		{"/api/v1", false, true, true},
+		{"/api/v1/users", true, true, false},
+		{"/api/v1/users/42", false, true, false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.exact, exact.Filter([]byte(c.path)), c.path)
+		assert.Equal(t, c.atLeast, atLeast.Filter([]byte(c.path)), c.path)
+		assert.Equal(t, c.between, between.Filter([]byte(c.path)), c.path)
+	}
+
+	_, err = (&PathSegmentsFilterParams{Min: 3, Max: 2}).To()
+	assert.EqualError(t, err, "bad path segments range")
+	_, err = (&PathSegmentsFilterParams{Exact: 2, Min: 1}).To()
+	assert.EqualError(t, err, "path segments exact is mutually exclusive with min and max")
+}
+
+func TestFilterExecutor_PathSegments(t *testing.T) {
+	regulation := &Regulation{Filter: &Filter{PathSegments: &PathSegmentsFilterParams{Exact: 2}}, Template: &Template{Body: "ok"}}
+	exec, err := regulation.To()
+	assert.Nil(t, err)
+
+	req := new(fasthttp.Request)
+	req.SetRequestURI("http://localhost/users/42?verbose=1")
+	assert.True(t, exec.Filter.Filter(req))
+	req.SetRequestURI("http://localhost/users/42/orders")
+	assert.False(t, exec.Filter.Filter(req))
+	assert.Equal(t, "path_segments", exec.Filter.Diagnose(req))
+}
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	// timeRand randTime使用的随机数源，可以通过SetTimeRandSeed固定种子
+	timeRand   = rand.New(rand.NewSource(time.Now().UnixNano()))
+	timeRandMu sync.Mutex
+)
+
+// SetTimeRandSeed 设置randTime使用的随机数种子，固定种子后生成的时间序列可以复现，便于测试；0表示使用随机种子
+func SetTimeRandSeed(seed int64) {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	timeRandMu.Lock()
+	defer timeRandMu.Unlock()
+	timeRand = rand.New(rand.NewSource(seed))
+}
+
+// randTime 返回在base前后jitter范围内随机偏移的RFC3339时间；base为RFC3339字符串或time.Time，
+// "now"或空字符串表示当前时间，jitter为"90s"、"5m"形式的时长或者秒数
+func randTime(base, jitter interface{}) (string, error) {
+	var t time.Time
+	switch v := base.(type) {
+	case time.Time:
+		t = v
+	case string:
+		if v == "" || v == "now" {
+			t = time.Now()
+			break
+		}
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return "", fmt.Errorf("bad base time: %s", v)
+		}
+		t = parsed
+	default:
+		return "", fmt.Errorf("unsupported base time type %T", base)
+	}
+
+	var d time.Duration
+	switch v := jitter.(type) {
+	case int:
+		d = time.Duration(v) * time.Second
+	case int64:
+		d = time.Duration(v) * time.Second
+	case float64:
+		d = time.Duration(v * float64(time.Second))
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return "", fmt.Errorf("bad jitter: %s", v)
+		}
+		d = parsed
+	default:
+		return "", fmt.Errorf("unsupported jitter type %T", jitter)
+	}
+	if d < 0 {
+		return "", fmt.Errorf("jitter must not be negative, got %s", d)
+	}
+
+	// 精度为秒，与RFC3339的输出格式一致
+	seconds := int64(d / time.Second)
+	timeRandMu.Lock()
+	offset := timeRand.Int63n(2*seconds+1) - seconds
+	timeRandMu.Unlock()
+	return t.Add(time.Duration(offset) * time.Second).Format(time.RFC3339), nil
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRandTime(t *testing.T) {
+	base := "2020-06-01T12:00:00+08:00"
+	baseTime, _ := time.Parse(time.RFC3339, base)
+
+	seen := make(map[string]bool)
+	for _, jitter := range []interface{}{"5m", 300, 300.0} {
+		for i := 0; i < 200; i++ {
+			ret, err := randTime(base, jitter)
+			assert.NoError(t, err)
+			parsed, err := time.Parse(time.RFC3339, ret)
+			assert.NoError(t, err)
+			offset := parsed.Sub(baseTime)
+			assert.True(t, offset >= -5*time.Minute && offset <= 5*time.Minute, ret)
+			seen[ret] = true
+		}
+	}
+	assert.True(t, len(seen) > 1)
+
+	ret, err := randTime(baseTime, "0s")
+	assert.NoError(t, err)
+	assert.Equal(t, base, ret)
+
+	ret, err = randTime("now", 1)
+	assert.NoError(t, err)
+	parsed, err := time.Parse(time.RFC3339, ret)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), parsed, 2*time.Second)
+
+	// 固定种子后结果可以复现
+	sequence := func() []string {
+		SetTimeRandSeed(42)
+		ret := make([]string, 10)
+		for i := range ret {
+			ret[i], _ = randTime(base, "1h")
+		}
+		return ret
+	}
+	assert.Equal(t, sequence(), sequence())
+
+	_, err = randTime("yesterday", "1m")
+	assert.EqualError(t, err, "bad base time: yesterday")
+	_, err = randTime(base, "-1m")
+	assert.EqualError(t, err, "jitter must not be negative, got -1m0s")
+	_, err = randTime(base, "soon")
+	assert.EqualError(t, err, "bad jitter: soon")
+	_, err = randTime(1, "1m")
+	assert.EqualError(t, err, "unsupported base time type int")
+}
+
+func TestTemplateExecutor_RandTime(t *testing.T) {
+	tmpl, err := parseTemplate(`{{randTime "2020-06-01T12:00:00Z" "0s"}}`)
+	assert.NoError(t, err)
+	buf := new(bytes.Buffer)
+	assert.NoError(t, tmpl.Execute(buf, nil))
+	assert.Equal(t, "2020-06-01T12:00:00Z", buf.String())
+}
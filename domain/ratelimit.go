@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type (
+	// TokenBucket 令牌桶限流器，按需惰性补充令牌，桶容量与每秒速率一致
+	TokenBucket struct {
+		rate   float64
+		tokens float64
+		last   time.Time
+		now    func() time.Time
+		mu     sync.Mutex
+	}
+)
+
+// NewTokenBucket 工厂函数，rate为每秒允许的请求数，为0时返回nil，即不限流
+func NewTokenBucket(rate uint) *TokenBucket {
+	if rate == 0 {
+		return nil
+	}
+	tb := &TokenBucket{rate: float64(rate), tokens: float64(rate), now: time.Now}
+	tb.last = tb.now()
+	return tb
+}
+
+// Take 尝试获取一个令牌，获取失败时同时返回需要等待的时长
+func (tb *TokenBucket) Take() (bool, time.Duration) {
+	if tb == nil {
+		return true, 0
+	}
+
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := tb.now()
+	tb.tokens = math.Min(tb.rate, tb.tokens+now.Sub(tb.last).Seconds()*tb.rate)
+	tb.last = now
+	if tb.tokens >= 1 {
+		tb.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - tb.tokens) / tb.rate * float64(time.Second))
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket_Take(t *testing.T) {
+	var tb *TokenBucket
+	ok, _ := tb.Take()
+	assert.True(t, ok)
+	assert.Nil(t, NewTokenBucket(0))
+
+	now := time.Now()
+	tb = NewTokenBucket(2)
+	tb.now = func() time.Time { return now }
+	tb.last = now
+
+	ok, _ = tb.Take()
+	assert.True(t, ok)
+	ok, _ = tb.Take()
+	assert.True(t, ok)
+	ok, wait := tb.Take()
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = tb.Take()
+	assert.True(t, ok)
+	ok, _ = tb.Take()
+	assert.False(t, ok)
+
+	// 令牌数不会超过桶容量
+	now = now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		ok, _ = tb.Take()
+		assert.True(t, ok)
+	}
+	ok, _ = tb.Take()
+	assert.False(t, ok)
+}
@@ -1,6 +1,9 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type (
 	// RuleRepository 规则存储库接口定义
@@ -15,7 +18,9 @@ type (
 
 	// ExecutorRepository 执行器接口定义
 	ExecutorRepository interface {
-		FindExecutor(context.Context, []byte, []byte) (*Executor, bool)
+		FindExecutor(context.Context, []byte, []byte, []byte) (*Executor, bool) // 按path、method、host查询
 		ImportAll(context.Context, ...*Executor)
+		ListExecutors(context.Context) []*Executor
+		RemoveExpired(context.Context, time.Time) []string
 	}
 )
@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultRetryHeader 未指定时读取的重试次数请求头
+const DefaultRetryHeader = "X-Retry"
+
+type (
+	// Retry 按重试次数返回响应的值对象：请求头Header中的重试次数为n时返回Responses[n]，
+	// n不小于len(Responses)时返回报文规则本身的响应，即重试len(Responses)次后成功
+	Retry struct {
+		Header    string      `json:"header,omitempty"`
+		Responses []*Template `json:"responses"`
+	}
+
+	// RetryExecutor 按重试次数返回响应的执行器
+	RetryExecutor struct {
+		header    string
+		templates []*TemplateExecutor
+	}
+)
+
+// Validate 校验函数
+func (r *Retry) Validate() error {
+	if r == nil {
+		return nil
+	}
+	if len(r.Responses) == 0 {
+		return errors.New("retry requires at least one response")
+	}
+	for index, tmpl := range r.Responses {
+		if tmpl == nil {
+			return fmt.Errorf("missing retry response at index %d", index)
+		}
+	}
+	return nil
+}
+
+// To 转换成RetryExecutor，未配置时返回nil
+func (r *Retry) To(funcs ...template.FuncMap) (*RetryExecutor, error) {
+	if r == nil {
+		return nil, nil
+	}
+	re := &RetryExecutor{header: r.Header, templates: make([]*TemplateExecutor, len(r.Responses))}
+	if re.header == "" {
+		re.header = DefaultRetryHeader
+	}
+	for index, tmpl := range r.Responses {
+		// 未指定状态码时返回503
+		te, err := tmpl.toWithDefaultStatus(fasthttp.StatusServiceUnavailable, funcs...)
+		if err != nil {
+			return nil, fmt.Errorf("bad retry response at index %d: %w", index, err)
+		}
+		re.templates[index] = te
+	}
+	return re, nil
+}
+
+// Pick 根据请求中的重试次数返回对应的响应模板执行器，缺少或无法解析重试次数时视为首次请求；
+// 已经达到成功所需的重试次数时返回nil
+func (re *RetryExecutor) Pick(header *fasthttp.RequestHeader) *TemplateExecutor {
+	if re == nil {
+		return nil
+	}
+	retry, err := strconv.Atoi(string(header.Peek(re.header)))
+	if err != nil || retry < 0 {
+		retry = 0
+	}
+	if retry >= len(re.templates) {
+		return nil
+	}
+	return re.templates[retry]
+}
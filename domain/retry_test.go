@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestRetryExecutor_Pick(t *testing.T) {
+	reg := &Regulation{
+		IsDefault: true,
+		Template:  &Template{StatusCode: fasthttp.StatusOK, Body: "ok"},
+		Retry: &Retry{Responses: []*Template{
+			{Body: "first"},
+			{StatusCode: fasthttp.StatusBadGateway, Body: "second"},
+		}},
+	}
+	assert.NoError(t, reg.Validate())
+	exec, err := reg.To()
+	assert.NoError(t, err)
+	// 未指定状态码的默认值只作用于执行器，不修改配置本身
+	assert.Zero(t, reg.Retry.Responses[0].StatusCode)
+
+	cases := []struct {
+		retry  string
+		status int
+		body   string
+	}{
+		{"", fasthttp.StatusServiceUnavailable, "first"},
+		{"0", fasthttp.StatusServiceUnavailable, "first"},
+		{"abc", fasthttp.StatusServiceUnavailable, "first"},
+		{"-1", fasthttp.StatusServiceUnavailable, "first"},
+		{"1", fasthttp.StatusBadGateway, "second"},
+		{"2", fasthttp.StatusOK, "ok"},
+		{"10", fasthttp.StatusOK, "ok"},
+	}
+	for _, c := range cases {
+		ctx := new(fasthttp.RequestCtx)
+		if c.retry != "" {
+			ctx.Request.Header.Set(DefaultRetryHeader, c.retry)
+		}
+		assert.NoError(t, exec.Render(ctx, nil, nil))
+		assert.Equal(t, c.status, ctx.Response.StatusCode(), c.retry)
+		assert.Equal(t, c.body, string(ctx.Response.Body()), c.retry)
+	}
+}
+
+func TestRetryExecutor_CustomHeader(t *testing.T) {
+	re, err := (&Retry{Header: "Attempt", Responses: []*Template{{Body: "fail"}}}).To()
+	assert.NoError(t, err)
+
+	header := new(fasthttp.RequestHeader)
+	header.Set(DefaultRetryHeader, "1")
+	assert.NotNil(t, re.Pick(header))
+
+	header.Set("Attempt", "1")
+	assert.Nil(t, re.Pick(header))
+}
+
+func TestRegulation_ValidateRetry(t *testing.T) {
+	reg := &Regulation{IsDefault: true, Template: &Template{Body: "ok"}, Retry: &Retry{}}
+	assert.Error(t, reg.Validate())
+
+	reg = &Regulation{IsDefault: true, Template: &Template{Body: "ok"}, Retry: &Retry{Responses: []*Template{nil}}}
+	assert.Error(t, reg.Validate())
+
+	reg = &Regulation{
+		IsDefault: true,
+		Sequence:  &Sequence{Responses: []*Template{{Body: "ok"}}},
+		Retry:     &Retry{Responses: []*Template{{Body: "fail"}}},
+	}
+	assert.Error(t, reg.Validate())
+}
@@ -1,58 +1,121 @@
 package domain
 
 import (
+	"bytes"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"html/template"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+	"time"
 
 	"github.com/valyala/fasthttp"
 	"github.com/wosai/deepmock/misc"
 )
 
+var (
+	// ErrVersionConflict 更新规则时提供的版本号与当前版本不一致
+	ErrVersionConflict = errors.New("rule version conflict")
+)
+
 type (
 	// Rule 规则实体
 	Rule struct {
-		ID          string
-		Path        string
+		ID   string
+		Path string
+		// PathType Path的语法，为空或regex时为正则表达式，glob时为通配符
+		PathType string
+		// Host 匹配请求的Host头，为空时匹配任意Host；与Path、Method一同决定规则ID，更新时不可修改
+		Host string
+		// HostType Host的语法，为空或exact时精确匹配，regex时为正则表达式
+		HostType    string
 		Method      string
 		Variable    map[string]interface{}
 		Weight      map[string]WeightFactor
 		Regulations []*Regulation
 		Version     int
+		// Enabled 规则是否启用，为空时视为启用，停用的规则保留定义但不再匹配请求
+		Enabled        *bool
+		Debug          bool
+		RateLimit      uint
+		CORS           *CORS
+		MaxConcurrency uint
+		SlowStart      *SlowStart
+		// OverflowResponse 超出MaxConcurrency时返回的响应，为空时返回503
+		OverflowResponse *Template
+		Duplicate        *Duplicate
+		// ResponseSchema 响应报文的JSON Schema，调试模式下校验渲染结果
+		ResponseSchema ResponseSchema
+		// TTLSeconds 规则自创建起的存活秒数，过期后视为不存在并会被清理，0表示永不过期
+		TTLSeconds uint
+		CreatedAt  time.Time
+		// StatusDelay 即将返回指定状态码时延迟响应
+		StatusDelay *StatusDelay
+		// Priority 优先级，多条规则的path匹配同一个请求时优先级高的规则生效，相同时按ID排序
+		Priority int
+		// Maintenance 计划维护窗口，窗口内返回503
+		Maintenance *Maintenance
 	}
 
 	// Regulation 响应报文值对象
 	Regulation struct {
-		IsDefault bool      `json:"is_default,omitempty"`
-		Filter    *Filter   `json:"filter,omitempty"`
-		Template  *Template `json:"response,omitempty"`
+		IsDefault      bool      `json:"is_default,omitempty"`
+		Filter         *Filter   `json:"filter,omitempty"`
+		Template       *Template `json:"response,omitempty"`
+		ReflectHeaders []string  `json:"reflect_headers,omitempty"` // 原样回写到响应中的请求头
+		Sequence       *Sequence `json:"sequence,omitempty"`        // 顺序响应，与Template互斥
+		Retry          *Retry    `json:"retry,omitempty"`           // 按重试次数返回响应，重试足够次数后返回Template
+		Created        *Created  `json:"created,omitempty"`         // 模拟创建资源的响应，与Template、Sequence互斥
 	}
 
 	// Filter 筛选规则值对象
 	Filter struct {
-		Query  QueryFilterParams  `json:"query,omitempty"`
-		Header HeaderFilterParams `json:"header,omitempty"`
-		Body   BodyFilterParams   `json:"body,omitempty"`
+		Query         QueryFilterParams          `json:"query,omitempty"`
+		RawQuery      RawQueryFilterParams       `json:"raw_query,omitempty"`
+		Header        HeaderFilterParams         `json:"header,omitempty"`
+		Cookie        CookieFilterParams         `json:"cookie,omitempty"`
+		Body          BodyFilterParams           `json:"body,omitempty"`
+		Expression    string                     `json:"expression,omitempty"` // 同时引用Query与Body的筛选表达式
+		ContentLength *ContentLengthFilterParams `json:"content_length,omitempty"`
+		PathSegments  *PathSegmentsFilterParams  `json:"path_segments,omitempty"`
+		UserAgent     *UserAgentFilterParams     `json:"user_agent,omitempty"`
+		Sample        *SampleFilterParams        `json:"sample,omitempty"`
+		Form          QueryFilterParams          `json:"form,omitempty"` // 表单字段筛选，支持urlencoded与multipart请求
+		Upload        *UploadFilterParams        `json:"upload,omitempty"`
 	}
 
 	// Template 模板值对象
 	Template struct {
-		IsTemplate     bool              `json:"is_template,omitempty"`
-		Header         map[string]string `json:"header,omitempty"`
-		StatusCode     int               `json:"status_code,omitempty"`
-		Body           string            `json:"body,omitempty"`
-		B64EncodedBody string            `json:"b64encoded_body,omitempty"`
+		IsTemplate     bool                         `json:"is_template,omitempty"`
+		Header         map[string]misc.StringValues `json:"header,omitempty"`
+		StatusCode     int                          `json:"status_code,omitempty"`
+		StatusTemplate string                       `json:"status_template,omitempty"` // 渲染结果作为响应状态码，为空时使用StatusCode
+		Body           string                       `json:"body,omitempty"`
+		B64EncodedBody string                       `json:"b64encoded_body,omitempty"`
+		BodyFile       string                       `json:"body_file,omitempty"`      // 响应body所在的文件，相对于SetBodyFileDir设置的目录，创建规则时读取，与Body、B64EncodedBody互斥
+		Compress       string                       `json:"compress,omitempty"`       // gzip、deflate或auto
+		Multipart      []*Part                      `json:"multipart,omitempty"`      // 设置后以multipart格式返回，忽略Body
+		MultipartType  string                       `json:"multipart_type,omitempty"` // multipart的子类型：form-data(默认)、mixed、related或alternative
+		Fragments      []string                     `json:"fragments,omitempty"`      // 依次拼接的模板片段名称，与Body、B64EncodedBody、BodyFile互斥
+		Locales        map[string]string            `json:"locales,omitempty"`        // 按Accept-Language协商的本地化body，key为语言标签
+		DefaultLocale  string                       `json:"default_locale,omitempty"` // 都不匹配时使用的语言标签，为空时使用Body
 	}
 
 	// WeightFactor 权重因子值对象
 	WeightFactor map[string]uint
 	// QueryFilterParams query筛选参数值对象
 	QueryFilterParams map[string]string
+	// RawQueryFilterParams 原始query string筛选参数值对象
+	RawQueryFilterParams map[string]string
 	// HeaderFilterParams 请求头筛选参数值对象
 	HeaderFilterParams map[string]string
+	// CookieFilterParams cookie筛选参数值对象
+	CookieFilterParams map[string]string
 	// BodyFilterParams body筛选参数值对象
 	BodyFilterParams map[string]string
 )
@@ -68,12 +131,24 @@ func (f *Filter) Validate() error {
 		}
 	}
 
+	if f.Cookie != nil {
+		if _, ok := f.Cookie[ModeField]; !ok {
+			return errors.New("missing mode in cookie filter")
+		}
+	}
+
 	if f.Query != nil {
 		if _, ok := f.Query[ModeField]; !ok {
 			return errors.New("missing mode in query filter")
 		}
 	}
 
+	if f.RawQuery != nil {
+		if _, ok := f.RawQuery[ModeField]; !ok {
+			return errors.New("missing mode in raw query filter")
+		}
+	}
+
 	if f.Body != nil {
 		if _, ok := f.Body[ModeField]; !ok {
 			return errors.New("missing mode in body filter")
@@ -90,6 +165,27 @@ func (r *Regulation) Validate() error {
 	if err := r.Filter.Validate(); err != nil {
 		return err
 	}
+	if r.Created != nil {
+		if r.Template != nil || r.Sequence != nil {
+			return errors.New("created is mutually exclusive with response and sequence")
+		}
+		if err := r.Retry.Validate(); err != nil {
+			return err
+		}
+		return r.Created.Validate()
+	}
+	if r.Sequence != nil {
+		if r.Template != nil {
+			return errors.New("response and sequence are mutually exclusive")
+		}
+		if r.Retry != nil {
+			return errors.New("retry and sequence are mutually exclusive")
+		}
+		return r.Sequence.Validate()
+	}
+	if err := r.Retry.Validate(); err != nil {
+		return err
+	}
 	if r.Template == nil {
 		return errors.New("missing response template")
 	}
@@ -99,14 +195,15 @@ func (r *Regulation) Validate() error {
 	return nil
 }
 
-// To 转换成响应规则执行器
-func (r *Regulation) To() (*RegulationExecutor, error) {
+// To 转换成响应规则执行器，funcs为规则级别的模板函数
+func (r *Regulation) To(funcs ...template.FuncMap) (*RegulationExecutor, error) {
 	var err error
 
 	exec := &RegulationExecutor{
-		IsDefault: r.IsDefault,
-		Filter:    new(FilterExecutor),
-		Template:  new(TemplateExecutor),
+		IsDefault:      r.IsDefault,
+		Filter:         new(FilterExecutor),
+		Template:       new(TemplateExecutor),
+		ReflectHeaders: r.ReflectHeaders,
 	}
 	if r.Filter != nil {
 		exec.Filter.Query, err = r.Filter.Query.To()
@@ -114,18 +211,80 @@ func (r *Regulation) To() (*RegulationExecutor, error) {
 			return nil, err
 		}
 
+		if r.Filter.Form != nil {
+			exec.Filter.Form, err = r.Filter.Form.To()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		exec.Filter.RawQuery, err = r.Filter.RawQuery.To()
+		if err != nil {
+			return nil, err
+		}
+
 		exec.Filter.Header, err = r.Filter.Header.To()
 		if err != nil {
 			return nil, err
 		}
 
+		exec.Filter.Cookie, err = r.Filter.Cookie.To()
+		if err != nil {
+			return nil, err
+		}
+
 		exec.Filter.Body, err = r.Filter.Body.To()
 		if err != nil {
 			return nil, err
 		}
+
+		exec.Filter.Expression, err = newExpressionFilterExecutor(r.Filter.Expression)
+		if err != nil {
+			return nil, err
+		}
+
+		exec.Filter.ContentLength, err = r.Filter.ContentLength.To()
+		if err != nil {
+			return nil, err
+		}
+
+		exec.Filter.PathSegments, err = r.Filter.PathSegments.To()
+		if err != nil {
+			return nil, err
+		}
+
+		exec.Filter.Upload, err = r.Filter.Upload.To()
+		if err != nil {
+			return nil, err
+		}
+
+		exec.Filter.UserAgent, err = r.Filter.UserAgent.To()
+		if err != nil {
+			return nil, err
+		}
+
+		exec.Filter.Sample, err = r.Filter.Sample.To()
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	exec.Template, err = r.Template.To()
+	if r.Sequence != nil {
+		exec.Sequence, err = r.Sequence.To(funcs...)
+		return exec, err
+	}
+
+	exec.Retry, err = r.Retry.To(funcs...)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Created != nil {
+		exec.Created, err = r.Created.To()
+		return exec, err
+	}
+
+	exec.Template, err = r.Template.To(funcs...)
 	if err != nil {
 		return nil, err
 	}
@@ -135,14 +294,21 @@ func (r *Regulation) To() (*RegulationExecutor, error) {
 // Validate 校验Rule的有效性
 func (rule *Rule) Validate() error {
 	rule.Method = strings.ToUpper(rule.Method)
+	rule.HostType, rule.Host = normalizeHost(rule.HostType, rule.Host)
 	rule.SupplyID()
 
-	if rule.ID != "" && misc.GenID([]byte(rule.Path), []byte(rule.Method)) != rule.ID {
+	if rule.ID != "" && rule.genID() != rule.ID {
 		return errors.New("invalid rule id")
 	}
 	if len(rule.Path) == 0 {
 		return errors.New("bad rule Path")
 	}
+	if _, err := rule.compilePath(); err != nil {
+		return err
+	}
+	if _, err := rule.compileHost(); err != nil {
+		return err
+	}
 	if len(rule.Method) == 0 {
 		return errors.New("bad rule method")
 	}
@@ -162,7 +328,44 @@ func (rule *Rule) Validate() error {
 	if d != 1 {
 		return errors.New("no default regulation or provided more than one")
 	}
-	return nil
+
+	for name, factor := range rule.Weight {
+		if !factor.selectable() {
+			return fmt.Errorf("weight %s has no selectable value", name)
+		}
+	}
+
+	if rule.OverflowResponse != nil {
+		if rule.MaxConcurrency == 0 {
+			return errors.New("overflow response requires max concurrency")
+		}
+	}
+
+	if err := rule.Duplicate.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := rule.ResponseSchema.To(); err != nil {
+		return fmt.Errorf("bad response schema: %w", err)
+	}
+
+	if err := rule.StatusDelay.Validate(); err != nil {
+		return err
+	}
+
+	if err := rule.Maintenance.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := parseEnums(rule.Variable); err != nil {
+		return err
+	}
+	return rule.SlowStart.Validate()
+}
+
+// IsEnabled 规则是否启用，未设置Enabled时视为启用
+func (rule *Rule) IsEnabled() bool {
+	return rule.Enabled == nil || *rule.Enabled
 }
 
 // SupplyID 补充对象ID，如果不存在的话
@@ -171,10 +374,27 @@ func (rule *Rule) SupplyID() (string, bool) {
 		return rule.ID, false
 	}
 
-	rule.ID = misc.GenID([]byte(rule.Path), []byte(rule.Method))
+	rule.ID = rule.genID()
 	return rule.ID, true
 }
 
+// genID 根据path、method以及规范化后的host生成规则ID，未设置host的规则ID保持不变
+func (rule *Rule) genID() string {
+	if rule.Host == "" {
+		return misc.GenID([]byte(rule.Path), []byte(rule.Method))
+	}
+	hostType, host := normalizeHost(rule.HostType, rule.Host)
+	return misc.GenID([]byte(rule.Path), []byte(rule.Method), []byte(hostType), []byte(host))
+}
+
+// CheckVersion 乐观锁校验，expected与当前版本不一致时返回ErrVersionConflict，调用方未提供版本号时不应调用
+func (rule *Rule) CheckVersion(expected int) error {
+	if expected != rule.Version {
+		return fmt.Errorf("%w: expected version %d but current version is %d", ErrVersionConflict, expected, rule.Version)
+	}
+	return nil
+}
+
 // Patch 更新对象
 func (rule *Rule) Patch(nr *Rule) error {
 	rule.Version++
@@ -216,6 +436,62 @@ func (rule *Rule) Patch(nr *Rule) error {
 		rule.Regulations = nr.Regulations
 	}
 
+	if nr.Enabled != nil {
+		rule.Enabled = nr.Enabled
+	}
+
+	if nr.Debug {
+		rule.Debug = nr.Debug
+	}
+
+	if nr.RateLimit > 0 {
+		rule.RateLimit = nr.RateLimit
+	}
+
+	if nr.CORS != nil {
+		rule.CORS = nr.CORS
+	}
+
+	if nr.MaxConcurrency > 0 {
+		rule.MaxConcurrency = nr.MaxConcurrency
+	}
+
+	if nr.SlowStart != nil {
+		rule.SlowStart = nr.SlowStart
+	}
+
+	if nr.OverflowResponse != nil {
+		rule.OverflowResponse = nr.OverflowResponse
+	}
+
+	if nr.Duplicate != nil {
+		rule.Duplicate = nr.Duplicate
+	}
+
+	if nr.ResponseSchema != nil {
+		rule.ResponseSchema = nr.ResponseSchema
+	}
+
+	if nr.TTLSeconds > 0 {
+		rule.TTLSeconds = nr.TTLSeconds
+	}
+
+	if nr.StatusDelay != nil {
+		rule.StatusDelay = nr.StatusDelay
+	}
+
+	if nr.Priority != 0 {
+		rule.Priority = nr.Priority
+	}
+
+	if nr.Maintenance != nil {
+		rule.Maintenance = nr.Maintenance
+	}
+
+	if nr.PathType != "" {
+		rule.PathType = nr.PathType
+	}
+
 	return rule.Validate()
 }
 
@@ -226,6 +502,20 @@ func (rule *Rule) Put(nr *Rule) error {
 	rule.Variable = nr.Variable
 	rule.Weight = nr.Weight
 	rule.Regulations = nr.Regulations
+	rule.Enabled = nr.Enabled
+	rule.Debug = nr.Debug
+	rule.RateLimit = nr.RateLimit
+	rule.CORS = nr.CORS
+	rule.MaxConcurrency = nr.MaxConcurrency
+	rule.SlowStart = nr.SlowStart
+	rule.OverflowResponse = nr.OverflowResponse
+	rule.Duplicate = nr.Duplicate
+	rule.ResponseSchema = nr.ResponseSchema
+	rule.TTLSeconds = nr.TTLSeconds
+	rule.StatusDelay = nr.StatusDelay
+	rule.Priority = nr.Priority
+	rule.Maintenance = nr.Maintenance
+	rule.PathType = nr.PathType
 	return rule.Validate()
 }
 
@@ -241,36 +531,87 @@ func (rule *Rule) To() (*Executor, error) {
 		Variable:    rule.Variable,
 		Regulations: nil,
 		Version:     rule.Version,
+		Disabled:    !rule.IsEnabled(),
+		Debug:       rule.Debug,
+		RateLimiter: NewTokenBucket(rule.RateLimit),
+		CORS:        rule.CORS,
+		Concurrency: NewConcurrencyLimiter(rule.MaxConcurrency),
+		SlowStart:   rule.SlowStart.To(),
+		ExpireAt:    rule.ExpireAt(),
+		Priority:    rule.Priority,
+		StatusDelay: rule.StatusDelay.To(),
 	}
-	exec.Path, err = regexp.Compile(rule.Path)
+	_, exec.PartialsRevision = partials.snapshot()
+	exec.Path, err = rule.compilePath()
 	if err != nil {
 		return nil, err
 	}
-	exec.Weight = make(WeightPicker, len(rule.Weight))
-	for k, factor := range rule.Weight {
-		exec.Weight[k] = factor.To()
+	exec.Host, err = rule.compileHost()
+	if err != nil {
+		return nil, err
+	}
+	exec.Weight = NewWeightPicker(rule.Weight)
+
+	enums, err := parseEnums(rule.Variable)
+	if err != nil {
+		return nil, err
 	}
 
 	exec.Regulations = make([]*RegulationExecutor, len(rule.Regulations))
 	for index, regulation := range rule.Regulations {
-		re, err := regulation.To()
+		re, err := regulation.To(enums.templateFuncs(), VariableReader(rule.Variable).templateFuncs())
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("bad response at index %d: %w", index, err)
 		}
+		re.Index = index
 		exec.Regulations[index] = re
 	}
+
+	if rule.OverflowResponse != nil {
+		// 未指定状态码时仍然返回503
+		exec.Overflow, err = rule.OverflowResponse.toWithDefaultStatus(http.StatusServiceUnavailable, enums.templateFuncs(), VariableReader(rule.Variable).templateFuncs())
+		if err != nil {
+			return nil, fmt.Errorf("bad overflow response: %w", err)
+		}
+	}
+
+	exec.Duplicate, err = rule.Duplicate.To(enums.templateFuncs(), VariableReader(rule.Variable).templateFuncs())
+	if err != nil {
+		return nil, fmt.Errorf("bad duplicate response: %w", err)
+	}
+
+	exec.Maintenance, err = rule.Maintenance.To(enums.templateFuncs(), VariableReader(rule.Variable).templateFuncs())
+	if err != nil {
+		return nil, fmt.Errorf("bad maintenance response: %w", err)
+	}
+
+	exec.ResponseSchema, err = rule.ResponseSchema.To()
+	if err != nil {
+		return nil, fmt.Errorf("bad response schema: %w", err)
+	}
 	return exec, nil
 }
 
-// To 转换成WeightDice
+// selectable 是否存在权重大于0的值
+func (wf WeightFactor) selectable() bool {
+	for _, v := range wf {
+		if v > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// To 转换成WeightDice，会复制一份权重，之后对wf的修改不影响WeightDice
 func (wf WeightFactor) To() *WeightDice {
 	wd := &WeightDice{
 		total:        0,
 		distribution: []string{},
-		factor:       wf,
+		factor:       make(map[string]uint, len(wf)),
 	}
 
 	for k, v := range wf {
+		wd.factor[k] = v
 		for i := 0; i < int(v); i++ {
 			wd.distribution = append(wd.distribution, k)
 			wd.total++
@@ -311,6 +652,38 @@ func (qfp QueryFilterParams) To() (*QueryFilterExecutor, error) {
 	return qfe, nil
 }
 
+// To 转换成RawQueryFilterExecutor
+func (rqfp RawQueryFilterParams) To() (*RawQueryFilterExecutor, error) {
+	if rqfp == nil {
+		return &RawQueryFilterExecutor{mode: FilterModeAlwaysTrue}, nil
+	}
+
+	mode := rqfp[ModeField]
+	rqfe := &RawQueryFilterExecutor{mode: mode}
+	if rqfe.mode == "" {
+		rqfe.mode = FilterModeAlwaysTrue
+	}
+
+	for k, v := range rqfp {
+		if k == ModeField {
+			continue
+		}
+
+		switch mode {
+		case FilterModeExact, FilterModeKeyword:
+			rqfe.value = []byte(v)
+
+		case FilterModeRegular:
+			reg, err := regexp.Compile(v)
+			if err != nil {
+				return nil, err
+			}
+			rqfe.regular = reg
+		}
+	}
+	return rqfe, nil
+}
+
 // To 转换成HeaderFilterExecutor
 func (hfp HeaderFilterParams) To() (*HeaderFilterExecutor, error) {
 	if hfp == nil {
@@ -343,6 +716,38 @@ func (hfp HeaderFilterParams) To() (*HeaderFilterExecutor, error) {
 	return hfe, nil
 }
 
+// To 转换成CookieFilterExecutor
+func (cfp CookieFilterParams) To() (*CookieFilterExecutor, error) {
+	if cfp == nil {
+		return &CookieFilterExecutor{mode: FilterModeAlwaysTrue}, nil
+	}
+
+	mode := cfp[ModeField]
+	cfe := &CookieFilterExecutor{
+		params:   make(map[string][]byte),
+		regulars: make(map[string]*regexp.Regexp),
+		mode:     mode,
+	}
+	if cfe.mode == "" {
+		cfe.mode = FilterModeAlwaysTrue
+	}
+
+	for k, v := range cfp {
+		if k == ModeField {
+			continue
+		}
+		cfe.params[k] = []byte(v)
+		if mode == FilterModeRegular {
+			reg, err := regexp.Compile(v)
+			if err != nil {
+				return nil, err
+			}
+			cfe.regulars[k] = reg
+		}
+	}
+	return cfe, nil
+}
+
 // To 转换成BodyFilterExecutor
 func (bfp BodyFilterParams) To() (*BodyFilterExecutor, error) {
 	if bfp == nil {
@@ -359,8 +764,30 @@ func (bfp BodyFilterParams) To() (*BodyFilterExecutor, error) {
 		if k == ModeField {
 			continue
 		}
+		if k == MinSizeField || k == MaxSizeField {
+			size, err := strconv.Atoi(v)
+			if err != nil || size < 0 {
+				return nil, fmt.Errorf("bad %s in body filter: %s", k, v)
+			}
+			if k == MinSizeField {
+				bfe.minSize = size
+			} else {
+				bfe.maxSize = size
+			}
+			continue
+		}
+		if k == DecodeField {
+			if v != DecodeBase64 {
+				return nil, fmt.Errorf("unsupported decode in body filter: %s", v)
+			}
+			bfe.decode = v
+			continue
+		}
 
 		switch mode {
+		case FilterModeExact:
+			bfe.value = []byte(v)
+
 		case FilterModeKeyword:
 			bfe.keyword = []byte(v)
 
@@ -370,20 +797,161 @@ func (bfp BodyFilterParams) To() (*BodyFilterExecutor, error) {
 				return nil, err
 			}
 			bfe.regular = reg
+
+		case FilterModeHasKeys:
+			for _, key := range strings.Split(v, ",") {
+				if key = strings.TrimSpace(key); key != "" {
+					bfe.keys = append(bfe.keys, key)
+				}
+			}
 		}
 	}
+	if mode == FilterModeHasKeys && len(bfe.keys) == 0 {
+		return nil, errors.New("missing keys in body filter")
+	}
+	if mode == FilterModeSize && bfe.minSize == 0 && bfe.maxSize == 0 {
+		return nil, errors.New("missing min_size or max_size in body filter")
+	}
+	if bfe.maxSize > 0 && bfe.minSize > bfe.maxSize {
+		return nil, errors.New("min_size is greater than max_size in body filter")
+	}
 	return bfe, nil
 }
 
-// To 转换成TemplateExecutor
-func (tmp *Template) To() (*TemplateExecutor, error) {
+// DryRun 使用模拟的渲染上下文试渲染模板，用于在保存规则前检查模板能否正常渲染
+func (tmp *Template) DryRun(resp *fasthttp.Response, rc *RenderContext) error {
+	enums, err := parseEnums(rc.Variable)
+	if err != nil {
+		return err
+	}
+	te, err := tmp.To(enums.templateFuncs(), VariableReader(rc.Variable).templateFuncs())
+	if err != nil {
+		return err
+	}
+	return te.Execute(resp, rc)
+}
+
+// CompileTemplate 使用当前注册的模板函数解析模板文本，并以模拟的渲染上下文执行，用于验证自定义模板函数是否可用
+func CompileTemplate(text string, rc *RenderContext) (string, error) {
+	enums, err := parseEnums(rc.Variable)
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := parseTemplate(text, enums.templateFuncs(), VariableReader(rc.Variable).templateFuncs())
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, rc); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// parseTemplate 使用全局模板函数、funcs中的规则级别模板函数以及模板片段解析模板
+func parseTemplate(text string, funcs ...template.FuncMap) (*template.Template, error) {
+	tmpl := template.New(misc.GenRandomString(8)).Funcs(defaultTemplateFuncs)
+	for _, f := range funcs {
+		tmpl = tmpl.Funcs(f)
+	}
+	if err := partials.associate(tmpl); err != nil {
+		return nil, err
+	}
+	tmpl, err := tmpl.Parse(text)
+	if err != nil {
+		return nil, explainUndefinedFunc(err)
+	}
+	if err := checkTemplateFuncs(tmpl.Tree, func(name string) *parse.Tree {
+		if t := tmpl.Lookup(name); t != nil {
+			return t.Tree
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// parseTextTemplate 与parseTemplate相同，但不会对渲染结果做HTML转义，用于响应头等非报文内容
+func parseTextTemplate(text string, funcs ...template.FuncMap) (*texttemplate.Template, error) {
+	tmpl := texttemplate.New(misc.GenRandomString(8)).Funcs(texttemplate.FuncMap(defaultTemplateFuncs))
+	for _, f := range funcs {
+		tmpl = tmpl.Funcs(texttemplate.FuncMap(f))
+	}
+	tmpl, err := tmpl.Parse(text)
+	if err != nil {
+		return nil, explainUndefinedFunc(err)
+	}
+	if err := checkTemplateFuncs(tmpl.Tree, func(name string) *parse.Tree {
+		if t := tmpl.Lookup(name); t != nil {
+			return t.Tree
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+func hasTemplateAction(values []string) bool {
+	for _, v := range values {
+		if strings.Contains(v, "{{") {
+			return true
+		}
+	}
+	return false
+}
+
+// toWithDefaultStatus 转换成TemplateExecutor，未指定状态码时使用code，不修改模板本身
+func (tmp *Template) toWithDefaultStatus(code int, funcs ...template.FuncMap) (*TemplateExecutor, error) {
+	t := *tmp
+	if t.StatusCode == 0 {
+		t.StatusCode = code
+	}
+	return t.To(funcs...)
+}
+
+// To 转换成TemplateExecutor，funcs中的模板函数会覆盖同名的全局模板函数
+func (tmp *Template) To(funcs ...template.FuncMap) (*TemplateExecutor, error) {
 	te := &TemplateExecutor{
 		IsGolangTemplate: tmp.IsTemplate,
 		IsBinData:        false,
 		template:         nil,
+		compress:         tmp.Compress,
+	}
+	if err := validateCompress(tmp.Compress); err != nil {
+		return nil, err
 	}
 
-	if tmp.B64EncodedBody != "" {
+	if len(tmp.Fragments) > 0 {
+		if tmp.Body != "" || tmp.B64EncodedBody != "" || tmp.BodyFile != "" {
+			return nil, errors.New("fragments are mutually exclusive with body")
+		}
+		if err := partials.check(tmp.Fragments); err != nil {
+			return nil, err
+		}
+		// 模板通过{{template}}引用片段，在解析时关联；非模板在渲染时才读取片段，片段重新载入后立即生效
+		if te.IsGolangTemplate {
+			te.body = []byte(includeFragments(tmp.Fragments))
+		} else {
+			te.fragments = tmp.Fragments
+		}
+	} else if tmp.BodyFile != "" {
+		if tmp.Body != "" || tmp.B64EncodedBody != "" {
+			return nil, errors.New("body file is mutually exclusive with body")
+		}
+		body, err := readBodyFile(tmp.BodyFile)
+		if err != nil {
+			return nil, err
+		}
+		if isBinaryBody(body) {
+			if te.IsGolangTemplate {
+				return nil, fmt.Errorf("binary body file %s can not be a template", tmp.BodyFile)
+			}
+			te.IsBinData = true
+		}
+		te.body = body
+	} else if tmp.B64EncodedBody != "" {
 		te.IsBinData = true
 		body, err := base64.StdEncoding.DecodeString(tmp.B64EncodedBody)
 		if err != nil {
@@ -396,17 +964,70 @@ func (tmp *Template) To() (*TemplateExecutor, error) {
 
 	header := new(fasthttp.ResponseHeader)
 	header.SetStatusCode(tmp.StatusCode)
-	for k, v := range tmp.Header {
-		header.Set(k, v)
+	for k, values := range tmp.Header {
+		// 模板响应中包含模板语法的响应头，在渲染时逐个求值
+		if tmp.IsTemplate && hasTemplateAction(values) {
+			ht := &headerTemplate{key: k, values: make([]*texttemplate.Template, len(values))}
+			for index, v := range values {
+				tmpl, err := parseTextTemplate(v, funcs...)
+				if err != nil {
+					return nil, err
+				}
+				ht.values[index] = tmpl
+			}
+			te.headerTemplates = append(te.headerTemplates, ht)
+			continue
+		}
+
+		for index, v := range values {
+			if index == 0 {
+				header.Set(k, v)
+			} else {
+				header.Add(k, v) // 同名响应头的多个值
+			}
+		}
+	}
+	// 未配置Content-Type时推断，静态body只需推断一次
+	if !hasContentTypeHeader(tmp.Header) && len(tmp.Multipart) == 0 {
+		switch {
+		case te.IsBinData:
+			if sniffBinaryBodyEnabled() {
+				header.SetContentType(http.DetectContentType(te.body))
+			}
+		case te.IsGolangTemplate, te.fragments != nil:
+			te.sniff = true
+		default:
+			if contentType := sniffContentType(te.body); contentType != "" {
+				header.SetContentType(contentType)
+			}
+		}
 	}
 	te.header = header
 
-	if te.IsGolangTemplate {
-		tmpl, err := template.New(misc.GenRandomString(8)).Funcs(defaultTemplateFuncs).Parse(string(te.body))
+	if te.IsGolangTemplate && len(tmp.Multipart) == 0 {
+		tmpl, err := parseTemplate(string(te.body), funcs...)
 		if err != nil {
 			return nil, err
 		}
 		te.template = tmpl
 	}
+
+	if tmp.StatusTemplate != "" {
+		status, err := parseTemplate(tmp.StatusTemplate, funcs...)
+		if err != nil {
+			return nil, err
+		}
+		te.status = status
+	}
+
+	me, err := newMultipartExecutor(tmp.Multipart, tmp.MultipartType, tmp.IsTemplate, funcs...)
+	if err != nil {
+		return nil, err
+	}
+	te.multipart = me
+
+	if te.locales, err = newLocaleExecutors(tmp, funcs...); err != nil {
+		return nil, err
+	}
 	return te, nil
 }
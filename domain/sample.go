@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"github.com/valyala/fasthttp"
+)
+
+// 抽样筛选器读取key的位置
+const (
+	SampleSourceHeader = "header"
+	SampleSourceQuery  = "query"
+	SampleSourceCookie = "cookie"
+)
+
+type (
+	// SampleFilterParams 抽样筛选参数值对象，对请求中Source位置名为Key的值计算哈希后分桶，
+	// 只有落在前Percent%桶内的请求才能通过，相同的值总是得到相同的结果；请求中缺少该值时不通过
+	SampleFilterParams struct {
+		Source  string  `json:"source"`
+		Key     string  `json:"key"`
+		Percent float64 `json:"percent"`        // 通过的比例，取值范围[0, 100]
+		Salt    string  `json:"salt,omitempty"` // 参与哈希计算，使不同规则抽中不同的值
+	}
+
+	// SampleFilterExecutor 抽样筛选执行器
+	SampleFilterExecutor struct {
+		source    string
+		key       string
+		threshold uint32
+		salt      string
+	}
+)
+
+// sampleBuckets 抽样分桶的数量，即支持到0.01%的精度
+const sampleBuckets = 10000
+
+// To 转换成SampleFilterExecutor，未设置时返回nil，即总是通过
+func (sfp *SampleFilterParams) To() (*SampleFilterExecutor, error) {
+	if sfp == nil {
+		return nil, nil
+	}
+	switch sfp.Source {
+	case SampleSourceHeader, SampleSourceQuery, SampleSourceCookie:
+	default:
+		return nil, fmt.Errorf("unsupported sample source %s", sfp.Source)
+	}
+	if sfp.Key == "" {
+		return nil, errors.New("missing key in sample filter")
+	}
+	if sfp.Percent < 0 || sfp.Percent > 100 {
+		return nil, fmt.Errorf("sample percent must be between 0 and 100, got %v", sfp.Percent)
+	}
+	return &SampleFilterExecutor{
+		source:    sfp.Source,
+		key:       sfp.Key,
+		threshold: uint32(math.Round(sfp.Percent * sampleBuckets / 100)),
+		salt:      sfp.Salt,
+	}, nil
+}
+
+func (sfe *SampleFilterExecutor) value(request *fasthttp.Request) []byte {
+	switch sfe.source {
+	case SampleSourceHeader:
+		return request.Header.Peek(sfe.key)
+	case SampleSourceQuery:
+		return request.URI().QueryArgs().Peek(sfe.key)
+	default:
+		return request.Header.Cookie(sfe.key)
+	}
+}
+
+// bucket 返回值所在的桶，范围为[0, sampleBuckets)
+func (sfe *SampleFilterExecutor) bucket(value []byte) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(sfe.salt))
+	_, _ = h.Write(value)
+	return h.Sum32() % sampleBuckets
+}
+
+// Filter 根据请求中key的值所在的桶筛选
+func (sfe *SampleFilterExecutor) Filter(request *fasthttp.Request) bool {
+	if sfe == nil {
+		return true
+	}
+	value := sfe.value(request)
+	if len(value) == 0 {
+		return false
+	}
+	return sfe.bucket(value) < sfe.threshold
+}
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func newSampleRequest(userID string) *fasthttp.Request {
+	req := new(fasthttp.Request)
+	req.SetRequestURI("/api/v1/feed?uid=" + userID)
+	req.Header.Set("X-User-Id", userID)
+	req.Header.SetCookie("uid", userID)
+	return req
+}
+
+func TestSampleFilter_Fraction(t *testing.T) {
+	for _, source := range []string{SampleSourceHeader, SampleSourceQuery, SampleSourceCookie} {
+		key := "uid"
+		if source == SampleSourceHeader {
+			key = "X-User-Id"
+		}
+		sfe, err := (&SampleFilterParams{Source: source, Key: key, Percent: 10}).To()
+		assert.NoError(t, err)
+
+		const total = 10000
+		var matched int
+		for i := 0; i < total; i++ {
+			if sfe.Filter(newSampleRequest("user-" + strconv.Itoa(i))) {
+				matched++
+			}
+		}
+		assert.InDelta(t, total/10, matched, total/100, source)
+	}
+}
+
+func TestSampleFilter_Stable(t *testing.T) {
+	sfe, err := (&SampleFilterParams{Source: SampleSourceHeader, Key: "X-User-Id", Percent: 50}).To()
+	assert.NoError(t, err)
+	salted, err := (&SampleFilterParams{Source: SampleSourceHeader, Key: "X-User-Id", Percent: 50, Salt: "campaign"}).To()
+	assert.NoError(t, err)
+
+	var differs bool
+	for i := 0; i < 100; i++ {
+		req := newSampleRequest("user-" + strconv.Itoa(i))
+		first := sfe.Filter(req)
+		for j := 0; j < 5; j++ {
+			assert.Equal(t, first, sfe.Filter(req))
+		}
+		if salted.Filter(req) != first {
+			differs = true
+		}
+	}
+	assert.True(t, differs, "salt should change the sampled keys")
+
+	// 缺少key时不通过
+	assert.False(t, sfe.Filter(new(fasthttp.Request)))
+
+	none, err := (&SampleFilterParams{Source: SampleSourceQuery, Key: "uid", Percent: 0}).To()
+	assert.NoError(t, err)
+	all, err := (&SampleFilterParams{Source: SampleSourceQuery, Key: "uid", Percent: 100}).To()
+	assert.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		req := newSampleRequest(strconv.Itoa(i))
+		assert.False(t, none.Filter(req))
+		assert.True(t, all.Filter(req))
+	}
+
+	var sfp *SampleFilterParams
+	sfe, err = sfp.To()
+	assert.NoError(t, err)
+	assert.True(t, sfe.Filter(new(fasthttp.Request)))
+
+	_, err = (&SampleFilterParams{Source: "body", Key: "uid", Percent: 10}).To()
+	assert.Error(t, err)
+	_, err = (&SampleFilterParams{Source: SampleSourceQuery, Percent: 10}).To()
+	assert.Error(t, err)
+	_, err = (&SampleFilterParams{Source: SampleSourceQuery, Key: "uid", Percent: 101}).To()
+	assert.Error(t, err)
+}
@@ -0,0 +1,298 @@
+package domain
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+type (
+	// ResponseSchema 规则响应报文的JSON Schema，支持type、enum、properties、required、additionalProperties、
+	// items、minimum、maximum、minLength、maxLength、pattern、minItems、maxItems关键字
+	ResponseSchema map[string]interface{}
+
+	// SchemaValidator 校验响应报文是否符合ResponseSchema的执行器
+	SchemaValidator struct {
+		root *jsonSchema
+	}
+
+	jsonSchema struct {
+		types                []string
+		enum                 []interface{}
+		properties           map[string]*jsonSchema
+		required             []string
+		additionalProperties *bool
+		items                *jsonSchema
+		minimum              *float64
+		maximum              *float64
+		minLength            *float64
+		maxLength            *float64
+		minItems             *float64
+		maxItems             *float64
+		pattern              *regexp.Regexp
+	}
+)
+
+var schemaTypes = map[string]bool{"object": true, "array": true, "string": true, "number": true, "integer": true, "boolean": true, "null": true}
+
+// To 转换成SchemaValidator，未设置时返回nil，即不校验
+func (rs ResponseSchema) To() (*SchemaValidator, error) {
+	if rs == nil {
+		return nil, nil
+	}
+	root, err := compileSchema(rs, "$")
+	if err != nil {
+		return nil, err
+	}
+	return &SchemaValidator{root: root}, nil
+}
+
+func compileSchema(raw map[string]interface{}, path string) (*jsonSchema, error) {
+	s := new(jsonSchema)
+	for key, value := range raw {
+		var err error
+		switch key {
+		case "type":
+			s.types, err = schemaTypeList(value)
+		case "enum":
+			list, ok := value.([]interface{})
+			if !ok {
+				err = fmt.Errorf("enum must be an array")
+			}
+			s.enum = list
+		case "properties":
+			props, ok := value.(map[string]interface{})
+			if !ok {
+				err = fmt.Errorf("properties must be an object")
+				break
+			}
+			s.properties = make(map[string]*jsonSchema, len(props))
+			for name, prop := range props {
+				sub, ok := prop.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("bad schema at %s.%s: schema must be an object", path, name)
+				}
+				if s.properties[name], err = compileSchema(sub, path+"."+name); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			list, ok := value.([]interface{})
+			if !ok {
+				err = fmt.Errorf("required must be an array")
+			}
+			for _, name := range list {
+				str, ok := name.(string)
+				if !ok {
+					err = fmt.Errorf("required must be an array of string")
+					break
+				}
+				s.required = append(s.required, str)
+			}
+		case "additionalProperties":
+			b, ok := value.(bool)
+			if !ok {
+				err = fmt.Errorf("additionalProperties must be a boolean")
+			}
+			s.additionalProperties = &b
+		case "items":
+			sub, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("bad schema at %s[]: schema must be an object", path)
+			}
+			if s.items, err = compileSchema(sub, path+"[]"); err != nil {
+				return nil, err
+			}
+		case "minimum":
+			s.minimum, err = schemaNumber(key, value)
+		case "maximum":
+			s.maximum, err = schemaNumber(key, value)
+		case "minLength":
+			s.minLength, err = schemaNumber(key, value)
+		case "maxLength":
+			s.maxLength, err = schemaNumber(key, value)
+		case "minItems":
+			s.minItems, err = schemaNumber(key, value)
+		case "maxItems":
+			s.maxItems, err = schemaNumber(key, value)
+		case "pattern":
+			str, ok := value.(string)
+			if !ok {
+				err = fmt.Errorf("pattern must be a string")
+				break
+			}
+			s.pattern, err = regexp.Compile(str)
+		default: // 忽略$schema、title、description等不影响校验的关键字
+		}
+		if err != nil {
+			return nil, fmt.Errorf("bad schema at %s: %w", path, err)
+		}
+	}
+	return s, nil
+}
+
+func schemaTypeList(value interface{}) ([]string, error) {
+	var list []interface{}
+	switch v := value.(type) {
+	case string:
+		list = []interface{}{v}
+	case []interface{}:
+		list = v
+	default:
+		return nil, fmt.Errorf("type must be a string or an array of string")
+	}
+	types := make([]string, len(list))
+	for index, t := range list {
+		str, ok := t.(string)
+		if !ok || !schemaTypes[str] {
+			return nil, fmt.Errorf("unsupported type %v", t)
+		}
+		types[index] = str
+	}
+	return types, nil
+}
+
+func schemaNumber(key string, value interface{}) (*float64, error) {
+	f, ok := value.(float64)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a number", key)
+	}
+	return &f, nil
+}
+
+// Validate 校验响应报文，会先解压gzip、deflate编码的报文
+func (sv *SchemaValidator) Validate(resp *fasthttp.Response) error {
+	if sv == nil {
+		return nil
+	}
+	body := resp.Body()
+	var err error
+	switch encoding := resp.Header.Peek(fasthttp.HeaderContentEncoding); {
+	case bytes.Equal(encoding, []byte(CompressGzip)):
+		body, err = resp.BodyGunzip()
+	case bytes.Equal(encoding, []byte(CompressDeflate)):
+		body, err = resp.BodyInflate()
+	}
+	if err != nil {
+		return err
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return fmt.Errorf("response is not valid json: %w", err)
+	}
+	return sv.root.validate(v, "$")
+}
+
+func schemaTypeOf(v interface{}) string {
+	switch n := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if n == math.Trunc(n) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func (s *jsonSchema) validate(v interface{}, path string) error {
+	actual := schemaTypeOf(v)
+	if len(s.types) > 0 {
+		var matched bool
+		for _, t := range s.types {
+			if t == actual || (t == "number" && actual == "integer") {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: expected %s but got %s", path, strings.Join(s.types, " or "), actual)
+		}
+	}
+	if len(s.enum) > 0 {
+		var matched bool
+		for _, e := range s.enum {
+			if fmt.Sprintf("%T:%v", e, e) == fmt.Sprintf("%T:%v", v, v) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: %v is not one of %v", path, v, s.enum)
+		}
+	}
+
+	switch value := v.(type) {
+	case float64:
+		if s.minimum != nil && value < *s.minimum {
+			return fmt.Errorf("%s: %v is less than minimum %v", path, value, *s.minimum)
+		}
+		if s.maximum != nil && value > *s.maximum {
+			return fmt.Errorf("%s: %v is greater than maximum %v", path, value, *s.maximum)
+		}
+	case string:
+		length := float64(len([]rune(value)))
+		if s.minLength != nil && length < *s.minLength {
+			return fmt.Errorf("%s: length %v is less than minLength %v", path, length, *s.minLength)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			return fmt.Errorf("%s: length %v is greater than maxLength %v", path, length, *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(value) {
+			return fmt.Errorf("%s: %q does not match pattern %s", path, value, s.pattern.String())
+		}
+	case []interface{}:
+		count := float64(len(value))
+		if s.minItems != nil && count < *s.minItems {
+			return fmt.Errorf("%s: %v items is less than minItems %v", path, count, *s.minItems)
+		}
+		if s.maxItems != nil && count > *s.maxItems {
+			return fmt.Errorf("%s: %v items is greater than maxItems %v", path, count, *s.maxItems)
+		}
+		if s.items != nil {
+			for index, item := range value {
+				if err := s.items.validate(item, fmt.Sprintf("%s[%d]", path, index)); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.required {
+			if _, ok := value[name]; !ok {
+				return fmt.Errorf("%s: missing required property %s", path, name)
+			}
+		}
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names) // 保证总是报告同一个错误
+		for _, name := range names {
+			prop, ok := s.properties[name]
+			if !ok {
+				if s.additionalProperties != nil && !*s.additionalProperties {
+					return fmt.Errorf("%s: additional property %s is not allowed", path, name)
+				}
+				continue
+			}
+			if err := prop.validate(value[name], path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
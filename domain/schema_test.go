@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestResponseSchema_To(t *testing.T) {
+	sv, err := ResponseSchema(nil).To()
+	assert.Nil(t, err)
+	assert.Nil(t, sv)
+	assert.Nil(t, sv.Validate(&fasthttp.Response{})) // 未设置时不校验
+
+	_, err = ResponseSchema{"type": "decimal"}.To()
+	assert.EqualError(t, err, "bad schema at $: unsupported type decimal")
+	_, err = ResponseSchema{"properties": map[string]interface{}{"name": map[string]interface{}{"pattern": "[a-"}}}.To()
+	assert.Contains(t, err.Error(), "bad schema at $.name: error parsing regexp")
+	_, err = ResponseSchema{"items": "string"}.To()
+	assert.EqualError(t, err, "bad schema at $[]: schema must be an object")
+}
+
+func TestSchemaValidator_Validate(t *testing.T) {
+	schema := ResponseSchema{
+		"type":     "object",
+		"required": []interface{}{"code", "items"},
+		"properties": map[string]interface{}{
+			"code":   map[string]interface{}{"type": "integer", "minimum": float64(0), "maximum": float64(599)},
+			"status": map[string]interface{}{"enum": []interface{}{"ok", "failed"}},
+			"items": map[string]interface{}{
+				"type":     "array",
+				"maxItems": float64(2),
+				"items": map[string]interface{}{
+					"type":                 "object",
+					"additionalProperties": false,
+					"properties": map[string]interface{}{
+						"name":  map[string]interface{}{"type": "string", "minLength": float64(1), "pattern": "^[a-z]+$"},
+						"price": map[string]interface{}{"type": []interface{}{"number", "null"}},
+					},
+				},
+			},
+		},
+	}
+	sv, err := schema.To()
+	assert.Nil(t, err)
+
+	cases := []struct {
+		body string
+		err  string
+	}{
+		{`{"code": 200, "status": "ok", "items": [{"name": "apple", "price": 1.5}, {"name": "pear", "price": null}]}`, ""},
+		{`[]`, "$: expected object but got array"},
+		{`{"items": []}`, "$: missing required property code"},
+		{`{"code": 1.5, "items": []}`, "$.code: expected integer but got number"},
+		{`{"code": 600, "items": []}`, "$.code: 600 is greater than maximum 599"},
+		{`{"code": 200, "status": "unknown", "items": []}`, "$.status: unknown is not one of [ok failed]"},
+		{`{"code": 200, "items": [{}, {}, {}]}`, "$.items: 3 items is greater than maxItems 2"},
+		{`{"code": 200, "items": [{"name": "Apple"}]}`, `$.items[0].name: "Apple" does not match pattern ^[a-z]+$`},
+		{`{"code": 200, "items": [{"name": ""}]}`, "$.items[0].name: length 0 is less than minLength 1"},
+		{`{"code": 200, "items": [{"name": "apple", "price": "1.5"}]}`, "$.items[0].price: expected number or null but got string"},
+		{`{"code": 200, "items": [{"name": "apple", "color": "red"}]}`, "$.items[0]: additional property color is not allowed"},
+		{`not json`, "response is not valid json"},
+	}
+	for _, c := range cases {
+		resp := new(fasthttp.Response)
+		resp.SetBodyString(c.body)
+		err := sv.Validate(resp)
+		if c.err == "" {
+			assert.Nil(t, err, c.body)
+		} else {
+			assert.Error(t, err, c.body)
+			assert.Contains(t, err.Error(), c.err, c.body)
+		}
+	}
+}
+
+func TestSchemaValidator_ValidateCompressed(t *testing.T) {
+	sv, err := ResponseSchema{"type": "object", "required": []interface{}{"id"}}.To()
+	assert.Nil(t, err)
+
+	resp := new(fasthttp.Response)
+	resp.Header.Set(fasthttp.HeaderContentEncoding, CompressGzip)
+	resp.SetBody(fasthttp.AppendGzipBytes(nil, []byte(`{"name": "deepmock"}`)))
+	assert.EqualError(t, sv.Validate(resp), "$: missing required property id")
+
+	resp.Header.Set(fasthttp.HeaderContentEncoding, CompressDeflate)
+	resp.SetBody(fasthttp.AppendDeflateBytes(nil, []byte(`{"id": 1}`)))
+	assert.Nil(t, sv.Validate(resp))
+}
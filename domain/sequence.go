@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sync/atomic"
+)
+
+type (
+	// Sequence 顺序响应值对象，每次命中时依次返回Responses中的下一个响应，
+	// 返回最后一个响应后，Loop为true时从头开始，否则一直返回最后一个响应
+	Sequence struct {
+		Responses []*Template `json:"responses"`
+		Loop      bool        `json:"loop,omitempty"`
+	}
+
+	// SequenceExecutor 顺序响应执行器，counter为已经返回的响应数
+	SequenceExecutor struct {
+		templates []*TemplateExecutor
+		loop      bool
+		counter   uint64
+	}
+)
+
+// Validate 校验函数
+func (seq *Sequence) Validate() error {
+	if seq == nil {
+		return nil
+	}
+	if len(seq.Responses) == 0 {
+		return errors.New("sequence requires at least one response")
+	}
+	for index, tmpl := range seq.Responses {
+		if tmpl == nil {
+			return fmt.Errorf("missing sequence response at index %d", index)
+		}
+		if tmpl.StatusCode == 0 {
+			tmpl.StatusCode = http.StatusOK
+		}
+	}
+	return nil
+}
+
+// To 转换成SequenceExecutor，未配置时返回nil
+func (seq *Sequence) To(funcs ...template.FuncMap) (*SequenceExecutor, error) {
+	if seq == nil {
+		return nil, nil
+	}
+	se := &SequenceExecutor{templates: make([]*TemplateExecutor, len(seq.Responses)), loop: seq.Loop}
+	for index, tmpl := range seq.Responses {
+		te, err := tmpl.To(funcs...)
+		if err != nil {
+			return nil, fmt.Errorf("bad sequence response at index %d: %w", index, err)
+		}
+		se.templates[index] = te
+	}
+	return se, nil
+}
+
+// Next 返回本次请求对应的响应模板执行器
+func (se *SequenceExecutor) Next() *TemplateExecutor {
+	n := atomic.AddUint64(&se.counter, 1) - 1
+	size := uint64(len(se.templates))
+	if n >= size {
+		if se.loop {
+			n %= size
+		} else {
+			n = size - 1
+		}
+	}
+	return se.templates[n]
+}
+
+// Reset 重新从第一个响应开始返回
+func (se *SequenceExecutor) Reset() {
+	if se == nil {
+		return
+	}
+	atomic.StoreUint64(&se.counter, 0)
+}
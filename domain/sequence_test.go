@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func newSequenceRule(loop bool) *Rule {
+	return &Rule{
+		Path:   "/api/v1/job",
+		Method: "GET",
+		Regulations: []*Regulation{
+			{
+				IsDefault: true,
+				Sequence: &Sequence{
+					Loop: loop,
+					Responses: []*Template{
+						{StatusCode: 202, Body: "pending"},
+						{StatusCode: 202, Body: "running"},
+						{Body: "finished"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func renderSequence(t *testing.T, exec *Executor, times int) []string {
+	bodies := make([]string, times)
+	for i := range bodies {
+		ctx := new(fasthttp.RequestCtx)
+		assert.NoError(t, exec.FindRegulationExecutor(&ctx.Request).Render(ctx, nil, nil))
+		bodies[i] = string(ctx.Response.Body())
+		if bodies[i] == "finished" {
+			assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+		} else {
+			assert.Equal(t, fasthttp.StatusAccepted, ctx.Response.StatusCode())
+		}
+	}
+	return bodies
+}
+
+func TestSequenceExecutor_Stick(t *testing.T) {
+	exec, err := newSequenceRule(false).To()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"pending", "running", "finished", "finished", "finished"}, renderSequence(t, exec, 5))
+
+	exec.ResetSequences()
+	assert.Equal(t, []string{"pending", "running"}, renderSequence(t, exec, 2))
+}
+
+func TestSequenceExecutor_Loop(t *testing.T) {
+	exec, err := newSequenceRule(true).To()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"pending", "running", "finished", "pending", "running", "finished", "pending"}, renderSequence(t, exec, 7))
+}
+
+func TestRegulation_ValidateSequence(t *testing.T) {
+	reg := &Regulation{IsDefault: true, Sequence: &Sequence{}}
+	assert.Error(t, reg.Validate())
+
+	reg = &Regulation{IsDefault: true, Template: &Template{Body: "ok"}, Sequence: &Sequence{Responses: []*Template{{Body: "ok"}}}}
+	assert.Error(t, reg.Validate())
+
+	reg = &Regulation{IsDefault: true, Sequence: &Sequence{Responses: []*Template{{Body: "ok"}, nil}}}
+	assert.Error(t, reg.Validate())
+
+	reg = &Regulation{IsDefault: true, Sequence: &Sequence{Responses: []*Template{{IsTemplate: true, Body: "{{notExists}}"}}}}
+	assert.NoError(t, reg.Validate())
+	_, err := reg.To()
+	assert.Error(t, err)
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+type (
+	// SlowStart 慢启动配置值对象，模拟预热中的后端：规则生效后响应延迟从DelayMS开始，
+	// 在前Requests个请求或前Seconds秒内（以先达到者为准）线性递减，之后不再延迟
+	SlowStart struct {
+		DelayMS  uint `json:"delay_ms"`
+		Requests uint `json:"requests,omitempty"`
+		Seconds  uint `json:"seconds,omitempty"`
+	}
+
+	// SlowStartExecutor 慢启动执行器，since为规则生效的时间
+	SlowStartExecutor struct {
+		delay    time.Duration
+		requests uint64
+		period   time.Duration
+		since    time.Time
+		now      func() time.Time
+	}
+)
+
+// Validate 校验函数
+func (ss *SlowStart) Validate() error {
+	if ss == nil || ss.DelayMS == 0 {
+		return nil
+	}
+	if ss.Requests == 0 && ss.Seconds == 0 {
+		return errors.New("slow start requires requests or seconds")
+	}
+	return nil
+}
+
+// To 转换成SlowStartExecutor，未配置延迟时返回nil，即不延迟
+func (ss *SlowStart) To() *SlowStartExecutor {
+	if ss == nil || ss.DelayMS == 0 {
+		return nil
+	}
+	sse := &SlowStartExecutor{
+		delay:    time.Duration(ss.DelayMS) * time.Millisecond,
+		requests: uint64(ss.Requests),
+		period:   time.Duration(ss.Seconds) * time.Second,
+		now:      time.Now,
+	}
+	sse.since = sse.now()
+	return sse
+}
+
+// Delay 根据已处理的请求数以及生效时长计算本次响应的延迟
+func (sse *SlowStartExecutor) Delay(hits uint64) time.Duration {
+	if sse == nil {
+		return 0
+	}
+
+	var progress float64
+	if sse.requests > 0 {
+		progress = float64(hits) / float64(sse.requests)
+	}
+	if sse.period > 0 {
+		progress = math.Max(progress, float64(sse.now().Sub(sse.since))/float64(sse.period))
+	}
+	if progress >= 1 {
+		return 0
+	}
+	return time.Duration(math.Round(float64(sse.delay) * (1 - progress)))
+}
@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlowStart_Validate(t *testing.T) {
+	var ss *SlowStart
+	assert.NoError(t, ss.Validate())
+	assert.Nil(t, ss.To())
+
+	assert.NoError(t, (&SlowStart{}).Validate())
+	assert.Error(t, (&SlowStart{DelayMS: 100}).Validate())
+	assert.NoError(t, (&SlowStart{DelayMS: 100, Requests: 10}).Validate())
+}
+
+func TestSlowStartExecutor_Delay(t *testing.T) {
+	var sse *SlowStartExecutor
+	assert.Zero(t, sse.Delay(0))
+
+	// 按请求数递减
+	sse = (&SlowStart{DelayMS: 1000, Requests: 4}).To()
+	assert.Equal(t, time.Second, sse.Delay(0))
+	assert.Equal(t, 750*time.Millisecond, sse.Delay(1))
+	assert.Equal(t, 250*time.Millisecond, sse.Delay(3))
+	assert.Zero(t, sse.Delay(4))
+	assert.Zero(t, sse.Delay(100))
+
+	// 按生效时长递减
+	now := time.Now()
+	sse = (&SlowStart{DelayMS: 1000, Seconds: 10}).To()
+	sse.since = now
+	sse.now = func() time.Time { return now }
+	assert.Equal(t, time.Second, sse.Delay(0))
+	sse.now = func() time.Time { return now.Add(5 * time.Second) }
+	assert.Equal(t, 500*time.Millisecond, sse.Delay(0))
+	sse.now = func() time.Time { return now.Add(time.Minute) }
+	assert.Zero(t, sse.Delay(0))
+
+	// 同时配置时以先达到者为准
+	sse = (&SlowStart{DelayMS: 1000, Requests: 10, Seconds: 10}).To()
+	sse.since = now
+	sse.now = func() time.Time { return now.Add(2 * time.Second) }
+	assert.Equal(t, 500*time.Millisecond, sse.Delay(5))
+	sse.now = func() time.Time { return now.Add(8 * time.Second) }
+	assert.Equal(t, 200*time.Millisecond, sse.Delay(5))
+}
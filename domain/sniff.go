@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"bytes"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+	"github.com/wosai/deepmock/misc"
+)
+
+const (
+	contentTypeJSON = "application/json; charset=utf-8"
+	contentTypeXML  = "application/xml; charset=utf-8"
+	contentTypeHTML = "text/html; charset=utf-8"
+)
+
+// sniffBinaryBody 是否根据base64编码的body推断Content-Type，值为bool
+var sniffBinaryBody atomic.Value
+
+// SetSniffBinaryBody 设置是否根据base64编码的body的前512字节推断Content-Type(如image/png)，只影响之后创建或更新的规则
+func SetSniffBinaryBody(enabled bool) {
+	sniffBinaryBody.Store(enabled)
+}
+
+func sniffBinaryBodyEnabled() bool {
+	enabled, _ := sniffBinaryBody.Load().(bool)
+	return enabled
+}
+
+// sniffContentType 根据body的首个非空白字符推断Content-Type：{或[为JSON，<?xml为XML，其他以<开头的为HTML，无法推断时返回空字符串
+func sniffContentType(body []byte) string {
+	body = bytes.TrimLeft(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if len(body) == 0 {
+		return ""
+	}
+	switch body[0] {
+	case '{', '[':
+		return contentTypeJSON
+	case '<':
+		if bytes.HasPrefix(body, []byte("<?xml")) {
+			return contentTypeXML
+		}
+		return contentTypeHTML
+	default:
+		return ""
+	}
+}
+
+// hasContentTypeHeader 模板是否显式配置了Content-Type响应头
+func hasContentTypeHeader(header map[string]misc.StringValues) bool {
+	for k := range header {
+		if http.CanonicalHeaderKey(k) == fasthttp.HeaderContentType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"github.com/wosai/deepmock/misc"
+)
+
+func TestSniffContentType(t *testing.T) {
+	assert.Equal(t, contentTypeJSON, sniffContentType([]byte(`{"id": 1}`)))
+	assert.Equal(t, contentTypeJSON, sniffContentType([]byte("\n  [1, 2]")))
+	assert.Equal(t, contentTypeHTML, sniffContentType([]byte("<!DOCTYPE html><html></html>")))
+	assert.Equal(t, contentTypeXML, sniffContentType([]byte("\xef\xbb\xbf<?xml version=\"1.0\"?><note/>")))
+	assert.Equal(t, "", sniffContentType([]byte("plain text")))
+	assert.Equal(t, "", sniffContentType(nil))
+}
+
+func TestTemplateExecutor_SniffContentType(t *testing.T) {
+	render := func(tmpl *Template) *fasthttp.Response {
+		te, err := tmpl.To()
+		assert.NoError(t, err)
+		ctx := new(fasthttp.RequestCtx)
+		ctx.Request.SetRequestURI("/users?name=deepmock")
+		assert.NoError(t, te.Render(ctx, nil, nil))
+		return &ctx.Response
+	}
+
+	resp := render(&Template{StatusCode: 200, Body: `{"name": "deepmock"}`})
+	assert.Equal(t, contentTypeJSON, string(resp.Header.ContentType()))
+
+	resp = render(&Template{IsTemplate: true, StatusCode: 200, Body: `<html><body>{{.Query.name}}</body></html>`})
+	assert.Equal(t, contentTypeHTML, string(resp.Header.ContentType()))
+	assert.Equal(t, "<html><body>deepmock</body></html>", string(resp.Body()))
+
+	// 显式配置的Content-Type不会被覆盖
+	resp = render(&Template{
+		IsTemplate: true,
+		Header:     map[string]misc.StringValues{"content-type": {"text/plain"}},
+		StatusCode: 200,
+		Body:       `{"name": "{{.Query.name}}"}`,
+	})
+	assert.Equal(t, "text/plain", string(resp.Header.ContentType()))
+
+	// 无法推断时保持fasthttp的默认值
+	resp = render(&Template{StatusCode: 200, Body: `ok`})
+	assert.Equal(t, "text/plain; charset=utf-8", string(resp.Header.ContentType()))
+}
+
+func TestTemplateExecutor_SniffBinaryBody(t *testing.T) {
+	defer SetSniffBinaryBody(false)
+
+	// 1x1的PNG图片
+	png := "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNkYPhfDwAChwGA60e6kgAAAABJRU5ErkJggg=="
+	render := func(tmpl *Template) *fasthttp.Response {
+		te, err := tmpl.To()
+		assert.NoError(t, err)
+		ctx := new(fasthttp.RequestCtx)
+		assert.NoError(t, te.Render(ctx, nil, nil))
+		return &ctx.Response
+	}
+
+	// 未开启时保持原有行为
+	resp := render(&Template{StatusCode: 200, B64EncodedBody: png})
+	assert.Equal(t, "text/plain; charset=utf-8", string(resp.Header.ContentType()))
+
+	SetSniffBinaryBody(true)
+	resp = render(&Template{StatusCode: 200, B64EncodedBody: png})
+	assert.Equal(t, "image/png", string(resp.Header.ContentType()))
+	assert.Equal(t, []byte("\x89PNG"), resp.Body()[:4])
+
+	resp = render(&Template{StatusCode: 200, B64EncodedBody: "AAECAwQ="})
+	assert.Equal(t, "application/octet-stream", string(resp.Header.ContentType()))
+
+	// 显式配置的Content-Type不会被覆盖
+	resp = render(&Template{
+		Header:         map[string]misc.StringValues{"Content-Type": {"application/x-custom"}},
+		StatusCode:     200,
+		B64EncodedBody: png,
+	})
+	assert.Equal(t, "application/x-custom", string(resp.Header.ContentType()))
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type (
+	// StatusDelay 按响应状态码延迟的配置值对象，只有即将返回的状态码在StatusCodes中时才延迟DelayMS毫秒，
+	// 用于模拟失败时超时、成功时正常返回的后端
+	StatusDelay struct {
+		DelayMS     uint  `json:"delay_ms"`
+		StatusCodes []int `json:"status_codes"`
+	}
+
+	// StatusDelayExecutor 按响应状态码延迟的执行器
+	StatusDelayExecutor struct {
+		delay    time.Duration
+		statuses map[int]struct{}
+	}
+)
+
+// Validate 校验函数
+func (sd *StatusDelay) Validate() error {
+	if sd == nil {
+		return nil
+	}
+	if sd.DelayMS == 0 {
+		return errors.New("status delay requires delay_ms")
+	}
+	if len(sd.StatusCodes) == 0 {
+		return errors.New("status delay requires status codes")
+	}
+	for _, code := range sd.StatusCodes {
+		if code < 100 || code > 999 {
+			return fmt.Errorf("bad status code %d in status delay", code)
+		}
+	}
+	return nil
+}
+
+// To 转换成StatusDelayExecutor，未设置时返回nil，即不延迟
+func (sd *StatusDelay) To() *StatusDelayExecutor {
+	if sd == nil {
+		return nil
+	}
+	sde := &StatusDelayExecutor{delay: time.Duration(sd.DelayMS) * time.Millisecond, statuses: make(map[int]struct{}, len(sd.StatusCodes))}
+	for _, code := range sd.StatusCodes {
+		sde.statuses[code] = struct{}{}
+	}
+	return sde
+}
+
+// Delay 返回即将返回status状态码时需要延迟的时间
+func (sde *StatusDelayExecutor) Delay(status int) time.Duration {
+	if sde == nil {
+		return 0
+	}
+	if _, ok := sde.statuses[status]; !ok {
+		return 0
+	}
+	return sde.delay
+}
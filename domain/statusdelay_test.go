@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusDelay_Validate(t *testing.T) {
+	assert.Nil(t, (*StatusDelay)(nil).Validate())
+	assert.Nil(t, (&StatusDelay{DelayMS: 100, StatusCodes: []int{500}}).Validate())
+	assert.EqualError(t, (&StatusDelay{StatusCodes: []int{500}}).Validate(), "status delay requires delay_ms")
+	assert.EqualError(t, (&StatusDelay{DelayMS: 100}).Validate(), "status delay requires status codes")
+	assert.EqualError(t, (&StatusDelay{DelayMS: 100, StatusCodes: []int{50}}).Validate(), "bad status code 50 in status delay")
+}
+
+func TestStatusDelayExecutor_Delay(t *testing.T) {
+	var sde *StatusDelayExecutor
+	assert.Equal(t, time.Duration(0), sde.Delay(500))
+
+	sde = (&StatusDelay{DelayMS: 1500, StatusCodes: []int{500, 503}}).To()
+	assert.Equal(t, 1500*time.Millisecond, sde.Delay(500))
+	assert.Equal(t, 1500*time.Millisecond, sde.Delay(503))
+	assert.Equal(t, time.Duration(0), sde.Delay(200))
+}
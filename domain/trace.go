@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/valyala/fasthttp"
+)
+
+// FilterTrace 单个筛选条件的评估结果，请求头、cookie、query与表单筛选按参数拆分，Key为参数名，其他筛选器的Key为空
+type FilterTrace struct {
+	Filter string
+	Key    string
+	Passed bool
+}
+
+// Trace 依次评估所有已配置的筛选条件，与Filter不同，某个条件未通过时不会提前结束，便于排查筛选为什么没有命中；
+// 与Filter共用filterSteps，未配置(always_true)的筛选器不出现在结果中，全部通过等价于Filter返回true
+func (fe *FilterExecutor) Trace(request *fasthttp.Request) []FilterTrace {
+	if fe == nil {
+		return nil
+	}
+
+	var traces []FilterTrace
+	for _, step := range filterSteps {
+		if !step.configured(fe) {
+			continue
+		}
+		if step.trace != nil {
+			traces = append(traces, step.trace(fe, request)...)
+			continue
+		}
+		traces = append(traces, FilterTrace{Filter: step.name, Passed: step.filter(fe, request)})
+	}
+	return traces
+}
+
+// trace 按参数逐个评估请求头筛选
+func (hfe *HeaderFilterExecutor) trace(request *fasthttp.Request) []FilterTrace {
+	keys := sortedParamKeys(hfe.params)
+	traces := make([]FilterTrace, len(keys))
+	for index, k := range keys {
+		sub := &HeaderFilterExecutor{mode: hfe.mode, params: pickParam(hfe.params, k), regulars: pickRegular(hfe.regulars, k)}
+		traces[index] = FilterTrace{Filter: "header", Key: k, Passed: sub.FilterRequest(request)}
+	}
+	return traces
+}
+
+// trace 按参数逐个评估cookie筛选
+func (cfe *CookieFilterExecutor) trace(header *fasthttp.RequestHeader) []FilterTrace {
+	keys := sortedParamKeys(cfe.params)
+	traces := make([]FilterTrace, len(keys))
+	for index, k := range keys {
+		sub := &CookieFilterExecutor{mode: cfe.mode, params: pickParam(cfe.params, k), regulars: pickRegular(cfe.regulars, k)}
+		traces[index] = FilterTrace{Filter: "cookie", Key: k, Passed: sub.Filter(header)}
+	}
+	return traces
+}
+
+// trace 按参数逐个评估query或表单筛选
+func (qfe *QueryFilterExecutor) trace(filter string, args *fasthttp.Args) []FilterTrace {
+	keys := sortedParamKeys(qfe.params)
+	traces := make([]FilterTrace, len(keys))
+	for index, k := range keys {
+		sub := &QueryFilterExecutor{mode: qfe.mode, params: pickParam(qfe.params, k), regulars: pickRegular(qfe.regulars, k)}
+		traces[index] = FilterTrace{Filter: filter, Key: k, Passed: sub.Filter(args)}
+	}
+	return traces
+}
+
+func sortedParamKeys(params map[string][]byte) []string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func pickParam(params map[string][]byte, key string) map[string][]byte {
+	return map[string][]byte{key: params[key]}
+}
+
+func pickRegular(regulars map[string]*regexp.Regexp, key string) map[string]*regexp.Regexp {
+	if reg, ok := regulars[key]; ok {
+		return map[string]*regexp.Regexp{key: reg}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestFilterExecutor_Trace(t *testing.T) {
+	regulation := &Regulation{
+		Filter: &Filter{
+			Header: HeaderFilterParams{"mode": FilterModeExact, "X-Env": "test", "X-Version": "2"},
+			Query:  QueryFilterParams{"mode": FilterModeRegular, "id": `^\d+$`, "name": "^a"},
+			Body:   BodyFilterParams{"mode": FilterModeKeyword, "keyword": "hello"},
+		},
+		Template: &Template{Body: "ok"},
+	}
+	exec, err := regulation.To()
+	assert.NoError(t, err)
+
+	req := new(fasthttp.Request)
+	req.SetRequestURI("/trace?id=123&name=bob")
+	req.Header.Set("X-Env", "test")
+	req.Header.Set("X-Version", "1")
+	req.SetBodyString("hello world")
+
+	// 未配置的筛选器不出现在结果中，未通过的条件不会中断后续条件的评估
+	assert.Equal(t, []FilterTrace{
+		{Filter: "header", Key: "X-Env", Passed: true},
+		{Filter: "header", Key: "X-Version", Passed: false},
+		{Filter: "query", Key: "id", Passed: true},
+		{Filter: "query", Key: "name", Passed: false},
+		{Filter: "body", Passed: true},
+	}, exec.Filter.Trace(req))
+	assert.False(t, exec.Filter.Filter(req))
+
+	req.Header.Set("X-Version", "2")
+	req.SetRequestURI("/trace?id=123&name=alice")
+	for _, trace := range exec.Filter.Trace(req) {
+		assert.True(t, trace.Passed, trace.Filter+" "+trace.Key)
+	}
+	assert.True(t, exec.Filter.Filter(req))
+
+	// 没有筛选条件时结果为空
+	exec, err = (&Regulation{Template: &Template{Body: "ok"}}).To()
+	assert.NoError(t, err)
+	assert.Empty(t, exec.Filter.Trace(req))
+	var nilExecutor *FilterExecutor
+	assert.Nil(t, nilExecutor.Trace(req))
+}
+
+func TestFilterExecutor_TraceMatchesFilter(t *testing.T) {
+	exec, err := (&Regulation{
+		Filter: &Filter{
+			ContentLength: &ContentLengthFilterParams{Max: 64, AllowUnknown: true},
+			PathSegments:  &PathSegmentsFilterParams{Exact: 2},
+			UserAgent:     &UserAgentFilterParams{Clients: []string{UserAgentClientMobile}},
+			Header:        HeaderFilterParams{"mode": FilterModeExact, "X-Env": "test"},
+			Cookie:        CookieFilterParams{"mode": FilterModeExact, "session": "abc"},
+			Query:         QueryFilterParams{"mode": FilterModeKeyword, "name": "ali"},
+			RawQuery:      RawQueryFilterParams{"mode": FilterModeRegular, "regular": "name="},
+			Body:          BodyFilterParams{"mode": FilterModeKeyword, "keyword": "vip"},
+			Form:          QueryFilterParams{"mode": FilterModeExact, "level": "vip"},
+			Expression:    `eq .Query.name "alice"`,
+			Sample:        &SampleFilterParams{Source: SampleSourceQuery, Key: "name", Percent: 100},
+		},
+		Template: &Template{Body: "ok"},
+	}).To()
+	assert.NoError(t, err)
+
+	newRequest := func(uri, ua, body string) *fasthttp.Request {
+		req := new(fasthttp.Request)
+		req.SetRequestURI(uri)
+		req.Header.SetMethod("POST")
+		req.Header.SetContentType("application/x-www-form-urlencoded")
+		req.Header.Set("X-Env", "test")
+		req.Header.SetCookie("session", "abc")
+		req.Header.SetUserAgent(ua)
+		req.SetBodyString(body)
+		return req
+	}
+	mobile := "Mozilla/5.0 (iPhone; CPU iPhone OS 14_0 like Mac OS X) Mobile/15E148"
+	requests := []*fasthttp.Request{
+		newRequest("/api/order?name=alice", mobile, "level=vip"),
+		newRequest("/api/order?name=bob", mobile, "level=vip"),
+		newRequest("/api/order/1?name=alice", mobile, "level=vip"),
+		newRequest("/api/order?name=alice", "curl/7.64.1", "level=vip"),
+		newRequest("/api/order?name=alice", mobile, "level=normal"),
+	}
+
+	// 评估结果全部通过与Filter、Diagnose的结果一致，已配置的筛选器都出现在评估结果中
+	check := func(fe *FilterExecutor, req *fasthttp.Request, skipped ...string) {
+		passed := true
+		filters := make(map[string]struct{})
+		for _, trace := range fe.Trace(req) {
+			passed = passed && trace.Passed
+			filters[trace.Filter] = struct{}{}
+		}
+		assert.Equal(t, fe.Filter(req), passed, req.URI().String())
+		assert.Equal(t, passed, fe.Diagnose(req) == "", req.URI().String())
+		for _, step := range filterSteps {
+			if step.configured(fe) {
+				assert.Contains(t, filters, step.name)
+			}
+		}
+		assert.Len(t, filters, len(filterSteps)-len(skipped))
+	}
+	// 上传筛选需要multipart请求，单独验证
+	for _, req := range requests {
+		check(exec.Filter, req, "upload")
+	}
+	assert.True(t, exec.Filter.Filter(requests[0]))
+	for _, req := range requests[1:] {
+		assert.False(t, exec.Filter.Filter(req))
+	}
+
+	exec, err = (&Regulation{
+		Filter:   &Filter{Upload: &UploadFilterParams{Field: "avatar"}, Form: QueryFilterParams{"mode": FilterModeExact, "name": "foobar"}},
+		Template: &Template{Body: "ok"},
+	}).To()
+	assert.NoError(t, err)
+	others := []string{"content_length", "path_segments", "user_agent", "header", "cookie", "query", "raw_query", "body", "expression", "sample"}
+	check(exec.Filter, newUploadRequest(t, "avatar", "image/png"), others...)
+	check(exec.Filter, newUploadRequest(t, "file", "image/png"), others...)
+	assert.True(t, exec.Filter.Filter(newUploadRequest(t, "avatar", "image/png")))
+	assert.False(t, exec.Filter.Filter(newUploadRequest(t, "file", "image/png")))
+}
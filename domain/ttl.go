@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// ExpireAt 规则的过期时间，未设置TTLSeconds或者不知道创建时间时返回零值，即永不过期
+func (rule *Rule) ExpireAt() time.Time {
+	if rule.TTLSeconds == 0 || rule.CreatedAt.IsZero() {
+		return time.Time{}
+	}
+	return rule.CreatedAt.Add(time.Duration(rule.TTLSeconds) * time.Second)
+}
+
+// Expired 规则是否已经过期，过期的规则等待清理，查询时视为不存在
+func (rule *Rule) Expired(now time.Time) bool {
+	expireAt := rule.ExpireAt()
+	return !expireAt.IsZero() && !now.Before(expireAt)
+}
+
+// Expired 执行器是否已经过期，过期的执行器视为不存在
+func (exe *Executor) Expired(now time.Time) bool {
+	return !exe.ExpireAt.IsZero() && !now.Before(exe.ExpireAt)
+}
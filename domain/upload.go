@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"mime/multipart"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+type (
+	// UploadFilterParams 文件上传筛选参数值对象，请求为multipart/form-data且包含文件时通过；
+	// Field不为空时要求该字段包含文件，ContentType不为空时要求文件的类型一致，支持image/*形式的通配
+	UploadFilterParams struct {
+		Field       string `json:"field,omitempty"`
+		ContentType string `json:"content_type,omitempty"`
+	}
+
+	// UploadFilterExecutor 文件上传筛选执行器
+	UploadFilterExecutor struct {
+		field       string
+		contentType string
+	}
+)
+
+// To 转换成UploadFilterExecutor，未设置时返回nil，即总是通过
+func (ufp *UploadFilterParams) To() (*UploadFilterExecutor, error) {
+	if ufp == nil {
+		return nil, nil
+	}
+	contentType := strings.ToLower(strings.TrimSpace(ufp.ContentType))
+	if contentType != "" && !strings.Contains(contentType, "/") {
+		return nil, errors.New("bad upload content type: " + ufp.ContentType)
+	}
+	return &UploadFilterExecutor{field: ufp.Field, contentType: contentType}, nil
+}
+
+// Filter 根据请求中上传的文件筛选，只有multipart/form-data请求才会解析body；
+// 与extractMultipartFields一样流式读取各个部分的头部，不读取也不缓存文件内容
+func (ufe *UploadFilterExecutor) Filter(req *fasthttp.Request) bool {
+	if ufe == nil {
+		return true
+	}
+	if !bytes.HasPrefix(req.Header.ContentType(), multipartContentType) {
+		return false
+	}
+	boundary := req.Header.MultipartFormBoundary()
+	if len(boundary) == 0 {
+		return false
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(req.Body()), string(boundary))
+	for {
+		part, err := reader.NextPart()
+		if err != nil { // 读取完毕或者报文格式有误
+			return false
+		}
+		if part.FileName() == "" || (ufe.field != "" && part.FormName() != ufe.field) {
+			continue
+		}
+		if ufe.matchContentType(part.Header.Get(fasthttp.HeaderContentType)) {
+			return true
+		}
+	}
+}
+
+func (ufe *UploadFilterExecutor) matchContentType(value string) bool {
+	if ufe.contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return false
+	}
+	if strings.HasSuffix(ufe.contentType, "/*") {
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(ufe.contentType, "*"))
+	}
+	return mediaType == ufe.contentType
+}
@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/textproto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func newUploadRequest(t *testing.T, field, contentType string) *fasthttp.Request {
+	req := new(fasthttp.Request)
+	req.Header.SetMethod("POST")
+	writer := multipart.NewWriter(req.BodyWriter())
+	req.Header.SetContentType(writer.FormDataContentType())
+	assert.Nil(t, writer.WriteField("name", "foobar"))
+	if field != "" {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", `form-data; name="`+field+`"; filename="upload.bin"`)
+		header.Set("Content-Type", contentType)
+		part, err := writer.CreatePart(header)
+		assert.Nil(t, err)
+		_, err = part.Write([]byte("content"))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, writer.Close())
+	return req
+}
+
+func TestUploadFilterExecutor_FilterLargeFile(t *testing.T) {
+	req := new(fasthttp.Request)
+	req.Header.SetMethod("POST")
+	writer := multipart.NewWriter(req.BodyWriter())
+	req.Header.SetContentType(writer.FormDataContentType())
+	part, err := writer.CreateFormFile("document", "large.bin")
+	assert.Nil(t, err)
+	_, err = part.Write(bytes.Repeat([]byte("x"), 32<<20))
+	assert.Nil(t, err)
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="avatar"; filename="avatar.png"`)
+	header.Set("Content-Type", "image/png")
+	_, err = writer.CreatePart(header)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Close())
+
+	// 文件内容不会被缓存，跳过大文件后仍能匹配后面的部分
+	png, err := (&UploadFilterParams{Field: "avatar", ContentType: "image/png"}).To()
+	assert.Nil(t, err)
+	assert.True(t, png.Filter(req))
+	pdf, err := (&UploadFilterParams{Field: "document", ContentType: "application/pdf"}).To()
+	assert.Nil(t, err)
+	assert.False(t, pdf.Filter(req))
+}
+
+func TestUploadFilterExecutor_Filter(t *testing.T) {
+	var nilExecutor *UploadFilterExecutor
+	assert.True(t, nilExecutor.Filter(new(fasthttp.Request)))
+
+	anyFile, err := (&UploadFilterParams{}).To()
+	assert.Nil(t, err)
+	avatar, err := (&UploadFilterParams{Field: "avatar"}).To()
+	assert.Nil(t, err)
+	image, err := (&UploadFilterParams{ContentType: "image/*"}).To()
+	assert.Nil(t, err)
+	png, err := (&UploadFilterParams{Field: "avatar", ContentType: "image/png"}).To()
+	assert.Nil(t, err)
+
+	cases := []struct {
+		name                        string
+		req                         *fasthttp.Request
+		anyFile, avatar, image, png bool
+	}{
+		{"no file", newUploadRequest(t, "", ""), false, false, false, false},
+		{"png avatar", newUploadRequest(t, "avatar", "image/png"), true, true, true, true},
+		{"jpeg avatar", newUploadRequest(t, "avatar", "image/jpeg"), true, true, true, false},
+		{"pdf document", newUploadRequest(t, "document", "application/pdf"), true, false, false, false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.anyFile, anyFile.Filter(c.req), c.name)
+		assert.Equal(t, c.avatar, avatar.Filter(c.req), c.name)
+		assert.Equal(t, c.image, image.Filter(c.req), c.name)
+		assert.Equal(t, c.png, png.Filter(c.req), c.name)
+	}
+
+	// 非multipart请求不会解析body
+	req := new(fasthttp.Request)
+	req.Header.SetContentType("application/json")
+	req.SetBodyString(`{"file": "content"}`)
+	assert.False(t, anyFile.Filter(req))
+
+	// 缺少boundary或者报文格式有误时不通过
+	req = new(fasthttp.Request)
+	req.Header.SetContentType("multipart/form-data")
+	req.SetBodyString("--foo\r\n")
+	assert.False(t, anyFile.Filter(req))
+	req.Header.SetContentType("multipart/form-data; boundary=foo")
+	assert.False(t, anyFile.Filter(req))
+
+	_, err = (&UploadFilterParams{ContentType: "image"}).To()
+	assert.EqualError(t, err, "bad upload content type: image")
+}
@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/valyala/fasthttp"
+)
+
+// 内置的User-Agent客户端类别
+const (
+	UserAgentClientBot     = "bot"
+	UserAgentClientMobile  = "mobile"
+	UserAgentClientDesktop = "desktop"
+	UserAgentClientUnknown = "unknown"
+)
+
+var builtinUserAgentPatterns = []*userAgentPattern{
+	{client: UserAgentClientBot, regex: regexp.MustCompile(`(?i)bot\b|crawl|spider|slurp|headless|curl/|wget/|python-requests|okhttp|go-http-client`)},
+	{client: UserAgentClientMobile, regex: regexp.MustCompile(`(?i)mobile|iphone|ipod|ipad|android|blackberry|windows phone|opera mini`)},
+}
+
+type (
+	// UserAgentFilterParams User-Agent筛选参数值对象，将User-Agent归类后判断是否属于Clients中的类别。
+	// 按顺序依次尝试Patterns中自定义的类别以及内置的bot、mobile类别，都不匹配时为desktop，缺少User-Agent时为unknown
+	UserAgentFilterParams struct {
+		Clients  []string            `json:"clients"`
+		Patterns []*UserAgentPattern `json:"patterns,omitempty"`
+	}
+
+	// UserAgentPattern 自定义的User-Agent客户端类别
+	UserAgentPattern struct {
+		Client string `json:"client"`
+		Regex  string `json:"regex"`
+	}
+
+	// UserAgentFilterExecutor User-Agent筛选执行器
+	UserAgentFilterExecutor struct {
+		clients  map[string]struct{}
+		patterns []*userAgentPattern
+	}
+
+	userAgentPattern struct {
+		client string
+		regex  *regexp.Regexp
+	}
+)
+
+// To 转换成UserAgentFilterExecutor，未设置时返回nil，即总是通过
+func (uap *UserAgentFilterParams) To() (*UserAgentFilterExecutor, error) {
+	if uap == nil {
+		return nil, nil
+	}
+	if len(uap.Clients) == 0 {
+		return nil, errors.New("missing clients in user agent filter")
+	}
+
+	known := map[string]bool{
+		UserAgentClientBot:     true,
+		UserAgentClientMobile:  true,
+		UserAgentClientDesktop: true,
+		UserAgentClientUnknown: true,
+	}
+	exec := &UserAgentFilterExecutor{clients: make(map[string]struct{}, len(uap.Clients))}
+	for _, pattern := range uap.Patterns {
+		if pattern == nil || pattern.Client == "" {
+			return nil, errors.New("missing client in user agent pattern")
+		}
+		regex, err := regexp.Compile(pattern.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("bad user agent pattern of client %s: %w", pattern.Client, err)
+		}
+		exec.patterns = append(exec.patterns, &userAgentPattern{client: pattern.Client, regex: regex})
+		known[pattern.Client] = true
+	}
+	exec.patterns = append(exec.patterns, builtinUserAgentPatterns...)
+
+	for _, client := range uap.Clients {
+		if !known[client] {
+			return nil, fmt.Errorf("unknown user agent client %s", client)
+		}
+		exec.clients[client] = struct{}{}
+	}
+	return exec, nil
+}
+
+// Classify 返回User-Agent所属的客户端类别
+func (uafe *UserAgentFilterExecutor) Classify(ua []byte) string {
+	if len(ua) == 0 {
+		return UserAgentClientUnknown
+	}
+	for _, pattern := range uafe.patterns {
+		if pattern.regex.Match(ua) {
+			return pattern.client
+		}
+	}
+	return UserAgentClientDesktop
+}
+
+// Filter 根据User-Agent所属的客户端类别筛选
+func (uafe *UserAgentFilterExecutor) Filter(header *fasthttp.RequestHeader) bool {
+	if uafe == nil {
+		return true
+	}
+	_, ok := uafe.clients[uafe.Classify(header.UserAgent())]
+	return ok
+}
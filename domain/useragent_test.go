@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	chromeDesktopUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	safariMacUA     = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15"
+	iPhoneUA        = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1"
+	androidUA       = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36"
+	wechatUA        = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 MicroMessenger/8.0.43"
+	googlebotUA     = "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+	bingbotUA       = "Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)"
+	curlUA          = "curl/8.4.0"
+)
+
+func newUserAgentHeader(ua string) *fasthttp.RequestHeader {
+	header := new(fasthttp.RequestHeader)
+	if ua != "" {
+		header.SetUserAgent(ua)
+	}
+	return header
+}
+
+func TestUserAgentFilter_Classify(t *testing.T) {
+	uafe, err := (&UserAgentFilterParams{
+		Clients:  []string{UserAgentClientMobile},
+		Patterns: []*UserAgentPattern{{Client: "wechat", Regex: "MicroMessenger"}},
+	}).To()
+	assert.NoError(t, err)
+
+	cases := map[string]string{
+		chromeDesktopUA: UserAgentClientDesktop,
+		safariMacUA:     UserAgentClientDesktop,
+		iPhoneUA:        UserAgentClientMobile,
+		androidUA:       UserAgentClientMobile,
+		wechatUA:        "wechat", // 自定义类别优先
+		googlebotUA:     UserAgentClientBot,
+		bingbotUA:       UserAgentClientBot,
+		curlUA:          UserAgentClientBot,
+		"":              UserAgentClientUnknown,
+	}
+	for ua, client := range cases {
+		assert.Equal(t, client, uafe.Classify([]byte(ua)), ua)
+	}
+}
+
+func TestUserAgentFilter_Filter(t *testing.T) {
+	var uap *UserAgentFilterParams
+	uafe, err := uap.To()
+	assert.NoError(t, err)
+	assert.True(t, uafe.Filter(newUserAgentHeader(googlebotUA)))
+
+	uafe, err = (&UserAgentFilterParams{Clients: []string{UserAgentClientMobile, UserAgentClientUnknown}}).To()
+	assert.NoError(t, err)
+	assert.True(t, uafe.Filter(newUserAgentHeader(iPhoneUA)))
+	assert.True(t, uafe.Filter(newUserAgentHeader(wechatUA)))
+	assert.True(t, uafe.Filter(newUserAgentHeader("")))
+	assert.False(t, uafe.Filter(newUserAgentHeader(chromeDesktopUA)))
+	assert.False(t, uafe.Filter(newUserAgentHeader(googlebotUA)))
+
+	_, err = (&UserAgentFilterParams{}).To()
+	assert.Error(t, err)
+	_, err = (&UserAgentFilterParams{Clients: []string{"tv"}}).To()
+	assert.Error(t, err)
+	_, err = (&UserAgentFilterParams{Clients: []string{"tv"}, Patterns: []*UserAgentPattern{{Client: "tv", Regex: "SmartTV("}}}).To()
+	assert.Error(t, err)
+	_, err = (&UserAgentFilterParams{Clients: []string{"tv"}, Patterns: []*UserAgentPattern{{Regex: "SmartTV"}}}).To()
+	assert.Error(t, err)
+}
+
+func TestFilterExecutor_DiagnoseUserAgent(t *testing.T) {
+	reg := &Regulation{
+		Filter:   &Filter{UserAgent: &UserAgentFilterParams{Clients: []string{UserAgentClientBot}}},
+		Template: &Template{Body: "robots"},
+	}
+	assert.NoError(t, reg.Validate())
+	exec, err := reg.To()
+	assert.NoError(t, err)
+
+	req := new(fasthttp.Request)
+	req.Header.SetUserAgent(chromeDesktopUA)
+	assert.False(t, exec.Filter.Filter(req))
+	assert.Equal(t, "user_agent", exec.Filter.Diagnose(req))
+
+	req.Header.SetUserAgent(googlebotUA)
+	assert.True(t, exec.Filter.Filter(req))
+	assert.Equal(t, "", exec.Filter.Diagnose(req))
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"errors"
+	"html/template"
+	"strconv"
+	"strings"
+)
+
+type (
+	// VariableReader 按点分路径读取规则变量，如: "user.address.city"，数组元素使用下标访问，如: "users.0.name"
+	VariableReader map[string]interface{}
+)
+
+// Lookup 读取路径对应的变量值，路径不存在时返回def中的第一个值，未提供默认值时返回错误
+func (vr VariableReader) Lookup(path string, def ...interface{}) (interface{}, error) {
+	var current interface{} = map[string]interface{}(vr)
+	for _, key := range strings.Split(path, ".") {
+		var exists bool
+		switch node := current.(type) {
+		case map[string]interface{}:
+			current, exists = node[key]
+
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if exists = err == nil && index >= 0 && index < len(node); exists {
+				current = node[index]
+			}
+		}
+
+		if !exists {
+			if len(def) > 0 {
+				return def[0], nil
+			}
+			return nil, errors.New("variable " + path + " was not defined")
+		}
+	}
+	return current, nil
+}
+
+// templateFuncs 返回绑定了规则变量的模板函数，优先于全局模板函数
+func (vr VariableReader) templateFuncs() template.FuncMap {
+	return template.FuncMap{"ctx": vr.Lookup}
+}
+
+func ctxWithoutRule(path string, def ...interface{}) (interface{}, error) {
+	return VariableReader(nil).Lookup(path, def...)
+}
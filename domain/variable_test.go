@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestVariableReader_Lookup(t *testing.T) {
+	var variable map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"user": {"name": "deepmock", "tags": ["a", "b"], "address": {"city": "shanghai"}}}`), &variable))
+	vr := VariableReader(variable)
+
+	v, err := vr.Lookup("user.address.city")
+	assert.NoError(t, err)
+	assert.Equal(t, "shanghai", v)
+
+	v, err = vr.Lookup("user.tags.1")
+	assert.NoError(t, err)
+	assert.Equal(t, "b", v)
+
+	v, err = vr.Lookup("user.address.zipcode", "200000")
+	assert.NoError(t, err)
+	assert.Equal(t, "200000", v)
+
+	v, err = vr.Lookup("user.tags.5", "none")
+	assert.NoError(t, err)
+	assert.Equal(t, "none", v)
+
+	_, err = vr.Lookup("user.name.first")
+	assert.Error(t, err)
+
+	_, err = VariableReader(nil).Lookup("user")
+	assert.Error(t, err)
+}
+
+func TestCtxFunc(t *testing.T) {
+	rule := &Rule{
+		Path:     "/api/v1/user",
+		Method:   "GET",
+		Variable: map[string]interface{}{"user": map[string]interface{}{"address": map[string]interface{}{"city": "shanghai"}}},
+		Regulations: []*Regulation{
+			{
+				IsDefault: true,
+				Template:  &Template{IsTemplate: true, Body: `{{ctx "user.address.city"}}-{{ctx "user.address.zipcode" "200000"}}`},
+			},
+		},
+	}
+	executor, err := rule.To()
+	assert.NoError(t, err)
+
+	ctx := new(fasthttp.RequestCtx)
+	assert.NoError(t, executor.Regulations[0].Render(ctx, executor.Variable, nil))
+	assert.Equal(t, "shanghai-200000", string(ctx.Response.Body()))
+
+	// 未绑定规则变量时仅能返回默认值
+	te, err := (&Template{IsTemplate: true, Body: `{{ctx "user.name" "anonymous"}}`}).To()
+	assert.NoError(t, err)
+	ctx = new(fasthttp.RequestCtx)
+	assert.NoError(t, te.Render(ctx, nil, nil))
+	assert.Equal(t, "anonymous", string(ctx.Response.Body()))
+}
@@ -0,0 +1,49 @@
+package domain
+
+import "sync/atomic"
+
+type weightTestMode struct {
+	enabled bool
+	prefer  map[string]struct{}
+}
+
+// weightMode 权重随机值的测试模式，值为*weightTestMode
+var weightMode atomic.Value
+
+// SetWeightTestMode 设置权重随机值的测试模式：开启后Dice不再随机，优先返回prefer中权重大于0的值，
+// 都不存在时返回权重最大的值(权重相同时取字典序最小的值)，便于集成测试断言固定的响应
+func SetWeightTestMode(enabled bool, prefer ...string) {
+	mode := &weightTestMode{enabled: enabled, prefer: make(map[string]struct{}, len(prefer))}
+	for _, v := range prefer {
+		mode.prefer[v] = struct{}{}
+	}
+	weightMode.Store(mode)
+}
+
+func loadWeightTestMode() *weightTestMode {
+	mode, _ := weightMode.Load().(*weightTestMode)
+	return mode
+}
+
+// pick 测试模式下确定地选出一个值
+func (mode *weightTestMode) pick(wd *WeightDice) string {
+	var (
+		picked    string
+		maxWeight uint
+		preferred bool
+	)
+	for k, v := range wd.factor {
+		if v == 0 {
+			continue
+		}
+		_, isPrefer := mode.prefer[k]
+		switch {
+		case isPrefer && !preferred:
+		case isPrefer == preferred && (v > maxWeight || (v == maxWeight && k < picked)):
+		default:
+			continue
+		}
+		picked, maxWeight, preferred = k, v, isPrefer
+	}
+	return picked
+}
@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetWeightTestMode(t *testing.T) {
+	defer SetWeightTestMode(false)
+
+	wp := NewWeightPicker(map[string]WeightFactor{
+		"status": {"ok": 90, "fail": 10},
+		"tie":    {"b": 5, "a": 5},
+		"zero":   {"x": 0, "y": 1},
+	})
+
+	SetWeightTestMode(true)
+	for i := 0; i < 50; i++ {
+		assert.Equal(t, map[string]string{"status": "ok", "tie": "a", "zero": "y"}, wp.DiceAll())
+	}
+
+	SetWeightTestMode(true, "fail", "x")
+	for i := 0; i < 50; i++ {
+		// 权重为0的x不会被选中
+		assert.Equal(t, map[string]string{"status": "fail", "tie": "a", "zero": "y"}, wp.DiceAll())
+	}
+
+	SetWeightTestMode(false)
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		seen[wp.DiceAll()["tie"]] = true
+	}
+	assert.True(t, seen["a"] && seen["b"], "dice should be random when test mode is off")
+	assert.Equal(t, "", WeightFactor{}.To().Dice())
+}
@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+type xmlNode struct {
+	name     string
+	fields   map[string]interface{}
+	text     strings.Builder
+	children bool
+}
+
+// decodeXML 将XML报文解析为通用的map，根元素名作为唯一的key：
+// 只有文本的元素解析为字符串，属性以@为前缀，同名的子元素合并为数组，同时存在子元素或属性时文本保存在#text中；
+// 元素名、属性名均不含命名空间前缀
+func decodeXML(body []byte) (map[string]interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+
+	var (
+		stack []*xmlNode
+		root  map[string]interface{}
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name.Local, fields: make(map[string]interface{})}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+					continue
+				}
+				node.fields["@"+attr.Name.Local] = attr.Value
+			}
+			if len(stack) > 0 {
+				stack[len(stack)-1].children = true
+			}
+			stack = append(stack, node)
+
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, errors.New("unexpected end element " + t.Name.Local)
+			}
+			node := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			value := node.value()
+			if len(stack) == 0 {
+				if root != nil {
+					return nil, errors.New("xml document has more than one root element")
+				}
+				root = map[string]interface{}{node.name: value}
+				continue
+			}
+			addXMLField(stack[len(stack)-1].fields, node.name, value)
+		}
+	}
+	if root == nil {
+		return nil, errors.New("xml document has no root element")
+	}
+	return root, nil
+}
+
+func (node *xmlNode) value() interface{} {
+	text := strings.TrimSpace(node.text.String())
+	if len(node.fields) == 0 && !node.children {
+		return text
+	}
+	if text != "" {
+		node.fields["#text"] = text
+	}
+	return node.fields
+}
+
+func addXMLField(fields map[string]interface{}, name string, value interface{}) {
+	current, exists := fields[name]
+	if !exists {
+		fields[name] = value
+		return
+	}
+	if list, ok := current.([]interface{}); ok {
+		fields[name] = append(list, value)
+		return
+	}
+	fields[name] = []interface{}{current, value}
+}
+
+// isXMLContentType 是否为application/xml、text/xml或者+xml结尾的Content-Type
+func isXMLContentType(ct []byte) bool {
+	if i := bytes.IndexByte(ct, ';'); i >= 0 {
+		ct = ct[:i]
+	}
+	ct = bytes.ToLower(bytes.TrimSpace(ct))
+	return bytes.Equal(ct, []byte("application/xml")) || bytes.Equal(ct, []byte("text/xml")) || bytes.HasSuffix(ct, []byte("+xml"))
+}
+
+// extractXMLBody 解析XML请求报文，非XML请求或者解析失败时返回nil
+func extractXMLBody(req *fasthttp.Request) map[string]interface{} {
+	if !isXMLContentType(req.Header.ContentType()) || len(req.Body()) == 0 {
+		return nil
+	}
+	doc, err := decodeXML(req.Body())
+	if err != nil {
+		return nil
+	}
+	return doc
+}
+
+// looksLikeXML body去除前导空白后是否以<开头
+func looksLikeXML(body []byte) bool {
+	trimmed := bytes.TrimSpace(body)
+	return len(trimmed) > 0 && trimmed[0] == '<'
+}
+
+// xmlPath 按照与ctx一致的路径格式读取.Xml中的字段，如{{xmlPath .Xml "order.items.item.0.id"}}
+func xmlPath(doc map[string]interface{}, path string, def ...interface{}) (interface{}, error) {
+	return VariableReader(doc).Lookup(path, def...)
+}
@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestDecodeXML(t *testing.T) {
+	doc, err := decodeXML([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<ns:order xmlns:ns="http://example.com/order" id="42">
+	<customer>deepmock</customer>
+	<items>
+		<item sku="a1">apple</item>
+		<item sku="b2">banana</item>
+	</items>
+	<note/>
+</ns:order>`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"order": map[string]interface{}{
+			"@id":      "42",
+			"customer": "deepmock",
+			"items": map[string]interface{}{
+				"item": []interface{}{
+					map[string]interface{}{"@sku": "a1", "#text": "apple"},
+					map[string]interface{}{"@sku": "b2", "#text": "banana"},
+				},
+			},
+			"note": "",
+		},
+	}, doc)
+
+	v, err := xmlPath(doc, "order.items.item.1.@sku")
+	assert.NoError(t, err)
+	assert.Equal(t, "b2", v)
+	v, err = xmlPath(doc, "order.missing", "none")
+	assert.NoError(t, err)
+	assert.Equal(t, "none", v)
+
+	_, err = decodeXML([]byte(`<a></a><b></b>`))
+	assert.EqualError(t, err, "xml document has more than one root element")
+	_, err = decodeXML([]byte(`just text`))
+	assert.EqualError(t, err, "xml document has no root element")
+	_, err = decodeXML([]byte(`<a><b></a>`))
+	assert.Error(t, err)
+}
+
+func TestExtractXMLBody(t *testing.T) {
+	req := new(fasthttp.Request)
+	req.SetBodyString(`<user><name>deepmock</name></user>`)
+	for _, ct := range []string{"application/xml", "text/xml; charset=utf-8", "application/soap+xml"} {
+		req.Header.SetContentType(ct)
+		assert.Equal(t, map[string]interface{}{"user": map[string]interface{}{"name": "deepmock"}}, extractXMLBody(req), ct)
+	}
+
+	req.Header.SetContentType("application/json")
+	assert.Nil(t, extractXMLBody(req))
+	req.Header.SetContentType("application/xml")
+	req.SetBodyString(`<user>`)
+	assert.Nil(t, extractXMLBody(req))
+}
+
+func TestBodyFilterExecutor_HasKeysXML(t *testing.T) {
+	bfe, err := BodyFilterParams{"mode": FilterModeHasKeys, "keys": "order.@id,order.items.item"}.To()
+	assert.NoError(t, err)
+	assert.True(t, bfe.Filter([]byte(`<order id="1"><items><item>a</item></items></order>`)))
+	assert.False(t, bfe.Filter([]byte(`<order><items><item>a</item></items></order>`)))
+	assert.False(t, bfe.Filter([]byte(`<order id="1">`)))
+	assert.True(t, bfe.Filter([]byte(`{"order": {"@id": 1, "items": {"item": "a"}}}`)))
+}
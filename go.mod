@@ -9,8 +9,10 @@ require (
 	github.com/hashicorp/golang-lru v0.5.4
 	github.com/jacexh/multiconfig v0.1.0
 	github.com/jacexh/requests v0.1.4
-	github.com/json-iterator/go v1.1.7
+	github.com/json-iterator/go v1.1.10
+	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
+	github.com/prometheus/client_golang v1.7.1
 	github.com/spaolacci/murmur3 v1.1.0
 	github.com/stretchr/testify v1.4.0
 	github.com/valyala/fasthttp v1.4.0
@@ -20,4 +22,5 @@ require (
 	go.uber.org/zap v1.10.0
 	google.golang.org/appengine v1.6.2 // indirect
 	gopkg.in/DATA-DOG/go-sqlmock.v1 v1.3.0 // indirect
+	gopkg.in/yaml.v2 v2.2.5
 )
@@ -0,0 +1,188 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/wosai/deepmock/domain"
+)
+
+type (
+	// FileRuleRepository RuleRepository的本地文件存储实现，每次变更后将全部规则写入JSON文件
+	FileRuleRepository struct {
+		path  string
+		rules map[string]*domain.Rule
+		mu    sync.RWMutex
+	}
+)
+
+// NewFileRuleRepository 工厂函数，载入文件中已保存的规则，文件不存在时从空的规则集合开始
+func NewFileRuleRepository(path string) (*FileRuleRepository, error) {
+	fr := &FileRuleRepository{path: path, rules: make(map[string]*domain.Rule)}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return fr, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []*domain.Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		fr.rules[rule.ID] = rule
+	}
+	return fr, nil
+}
+
+// CreateRule 插入新纪录
+func (fr *FileRuleRepository) CreateRule(_ context.Context, rule *domain.Rule) error {
+	return fr.mutate(func(rules map[string]*domain.Rule) error {
+		if _, exists := rules[rule.ID]; exists {
+			return errors.New("duplicate rule id: " + rule.ID)
+		}
+		rules[rule.ID] = rule
+		return nil
+	})
+}
+
+// UpdateRule 更新记录，与MySQL实现一致，要求已保存的版本号为rule.Version-1
+func (fr *FileRuleRepository) UpdateRule(_ context.Context, rule *domain.Rule) error {
+	return fr.mutate(func(rules map[string]*domain.Rule) error {
+		current, exists := rules[rule.ID]
+		if !exists {
+			return errors.New("cannot find rule by id: " + rule.ID)
+		}
+		if current.Version != rule.Version-1 { // 版本号已经被其他更新修改
+			return domain.ErrVersionConflict
+		}
+		rules[rule.ID] = rule
+		return nil
+	})
+}
+
+// GetRuleByID 获取记录，返回的是副本，修改后需要通过UpdateRule保存
+func (fr *FileRuleRepository) GetRuleByID(_ context.Context, rid string) (*domain.Rule, error) {
+	fr.mu.RLock()
+	defer fr.mu.RUnlock()
+
+	rule, exists := fr.rules[rid]
+	if !exists {
+		return nil, errors.New("cannot find rule by id: " + rid)
+	}
+	return cloneRule(rule)
+}
+
+// DeleteRule 删除记录
+func (fr *FileRuleRepository) DeleteRule(_ context.Context, rid string) error {
+	return fr.mutate(func(rules map[string]*domain.Rule) error {
+		delete(rules, rid)
+		return nil
+	})
+}
+
+// Export 导出记录，按ID排序
+func (fr *FileRuleRepository) Export(_ context.Context) ([]*domain.Rule, error) {
+	fr.mu.RLock()
+	defer fr.mu.RUnlock()
+
+	rules := make([]*domain.Rule, 0, len(fr.rules))
+	for _, id := range sortedRuleIDs(fr.rules) {
+		rule, err := cloneRule(fr.rules[id])
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Import 导入记录，覆盖ID相同的已有记录
+func (fr *FileRuleRepository) Import(_ context.Context, rules ...*domain.Rule) error {
+	return fr.mutate(func(current map[string]*domain.Rule) error {
+		for _, rule := range rules {
+			current[rule.ID] = rule
+		}
+		return nil
+	})
+}
+
+// mutate 在副本上执行变更，写入文件成功后才替换内存中的规则，写入失败时保持原状
+func (fr *FileRuleRepository) mutate(change func(map[string]*domain.Rule) error) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	rules := make(map[string]*domain.Rule, len(fr.rules))
+	for id, rule := range fr.rules {
+		rules[id] = rule
+	}
+	if err := change(rules); err != nil {
+		return err
+	}
+	if err := fr.save(rules); err != nil {
+		return err
+	}
+	fr.rules = rules
+	return nil
+}
+
+// save 先写入临时文件再重命名，避免写入中断导致文件损坏
+func (fr *FileRuleRepository) save(rules map[string]*domain.Rule) error {
+	list := make([]*domain.Rule, 0, len(rules))
+	for _, id := range sortedRuleIDs(rules) {
+		list = append(list, rules[id])
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(fr.path), ".rules-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	// 重命名前先落盘，避免宕机后规则文件指向尚未写入磁盘的内容
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fr.path)
+}
+
+func sortedRuleIDs(rules map[string]*domain.Rule) []string {
+	ids := make([]string, 0, len(rules))
+	for id := range rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// cloneRule 通过JSON序列化复制规则，避免调用方修改存储库中的数据
+func cloneRule(rule *domain.Rule) (*domain.Rule, error) {
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return nil, err
+	}
+	clone := new(domain.Rule)
+	if err := json.Unmarshal(data, clone); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
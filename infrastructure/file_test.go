@@ -0,0 +1,83 @@
+package infrastructure
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wosai/deepmock/domain"
+)
+
+func newFileTestRule(path, body string) *domain.Rule {
+	rule := &domain.Rule{
+		Path:        path,
+		Method:      "GET",
+		Variable:    map[string]interface{}{"name": "deepmock"},
+		Regulations: []*domain.Regulation{{IsDefault: true, Template: &domain.Template{IsTemplate: true, Body: body}}},
+	}
+	_, _ = rule.SupplyID()
+	return rule
+}
+
+func TestFileRuleRepository(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deepmock-rules")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rules.json")
+
+	fr, err := NewFileRuleRepository(path)
+	assert.NoError(t, err)
+	rules, err := fr.Export(context.TODO())
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+
+	user, order, stale := newFileTestRule("/api/user", `{{ctx "name"}}`), newFileTestRule("/api/order", "order"), newFileTestRule("/api/stale", "stale")
+	assert.NoError(t, fr.CreateRule(context.TODO(), user))
+	assert.NoError(t, fr.CreateRule(context.TODO(), order))
+	assert.NoError(t, fr.CreateRule(context.TODO(), stale))
+	assert.Error(t, fr.CreateRule(context.TODO(), user))
+
+	updated, err := fr.GetRuleByID(context.TODO(), order.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, updated.Patch(&domain.Rule{Regulations: []*domain.Regulation{{IsDefault: true, Template: &domain.Template{Body: "order v2"}}}}))
+	assert.NoError(t, fr.UpdateRule(context.TODO(), updated))
+	assert.Equal(t, domain.ErrVersionConflict, fr.UpdateRule(context.TODO(), updated)) // 基于旧版本的更新
+	assert.NoError(t, fr.DeleteRule(context.TODO(), stale.ID))
+
+	// 写入过程中的临时文件不会残留
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	// 模拟重启：从文件重建存储库，规则可以重新生成执行器
+	fr, err = NewFileRuleRepository(path)
+	assert.NoError(t, err)
+	rules, err = fr.Export(context.TODO())
+	assert.NoError(t, err)
+	assert.Len(t, rules, 2)
+
+	er := NewExecutorRepository(10)
+	executors := make([]*domain.Executor, len(rules))
+	for index, rule := range rules {
+		executors[index], err = rule.To()
+		assert.NoError(t, err)
+	}
+	er.ImportAll(context.TODO(), executors...)
+	exe, found := er.FindExecutor(context.TODO(), []byte("/api/order"), []byte("GET"), nil)
+	assert.True(t, found)
+	assert.Equal(t, 1, exe.Version)
+	_, found = er.FindExecutor(context.TODO(), []byte("/api/stale"), []byte("GET"), nil)
+	assert.False(t, found)
+
+	restored, err := fr.GetRuleByID(context.TODO(), user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, user.Variable, restored.Variable)
+	assert.Equal(t, `{{ctx "name"}}`, restored.Regulations[0].Template.Body)
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("bad"), 0644))
+	_, err = NewFileRuleRepository(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,51 @@
+package infrastructure
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type (
+	// GlobalIDFile 使用本地文件保存已分配的gid上限
+	GlobalIDFile struct {
+		path string
+	}
+)
+
+// NewGlobalIDFile 工厂函数
+func NewGlobalIDFile(path string) *GlobalIDFile {
+	return &GlobalIDFile{path: path}
+}
+
+// Load 读取保存的上限，文件不存在时返回0
+func (gf *GlobalIDFile) Load() (uint64, error) {
+	data, err := ioutil.ReadFile(gf.path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// Save 保存新的上限，先写入临时文件再重命名，避免写入中断导致文件损坏
+func (gf *GlobalIDFile) Save(limit uint64) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(gf.path), ".gid-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(strconv.FormatUint(limit, 10)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), gf.path)
+}
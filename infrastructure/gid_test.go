@@ -0,0 +1,30 @@
+package infrastructure
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlobalIDFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deepmock-gid")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	gf := NewGlobalIDFile(filepath.Join(dir, "gid"))
+	start, err := gf.Load()
+	assert.NoError(t, err)
+	assert.Zero(t, start)
+
+	assert.NoError(t, gf.Save(2000))
+	start, err = gf.Load()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2000, start)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "gid"), []byte("bad"), 0644))
+	_, err = gf.Load()
+	assert.Error(t, err)
+}
@@ -3,7 +3,9 @@ package infrastructure
 import (
 	"bytes"
 	"context"
+	"sort"
 	"sync"
+	"time"
 
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/wosai/deepmock/domain"
@@ -15,9 +17,16 @@ type (
 	// ExecutorRepository ExecutorRepository的内存存储库实现
 	ExecutorRepository struct {
 		executors map[string]*domain.Executor
+		methods   map[string]*methodIndex // 按请求方法建立的索引，executors变更后重建
 		cache     *lru.ARCCache
 		mu        sync.RWMutex
 	}
+
+	// methodIndex 同一请求方法下执行器的索引，literals为path不含正则元字符的执行器，同一path下可能有多个host不同的执行器
+	methodIndex struct {
+		literals  map[string][]*domain.Executor
+		executors []*domain.Executor
+	}
 )
 
 var (
@@ -33,17 +42,19 @@ func NewExecutorRepository(size int) *ExecutorRepository {
 
 	return &ExecutorRepository{
 		executors: map[string]*domain.Executor{},
+		methods:   map[string]*methodIndex{},
 		cache:     cache,
 	}
 }
 
-func (er *ExecutorRepository) cacheID(path, method []byte) string {
-	return string(bytes.Join([][]byte{path, method}, delimiter))
+func (er *ExecutorRepository) cacheID(path, method, host []byte) string {
+	return string(bytes.Join([][]byte{path, method, host}, delimiter))
 }
 
 // FindExecutor 查询执行器
-func (er *ExecutorRepository) FindExecutor(_ context.Context, path, method []byte) (*domain.Executor, bool) {
-	cid := er.cacheID(path, method)
+func (er *ExecutorRepository) FindExecutor(_ context.Context, path, method, host []byte) (*domain.Executor, bool) {
+	now := domain.Now()
+	cid := er.cacheID(path, method, host)
 	val, cached := er.cache.Get(cid)
 	// 如果存在缓存，需要再次从executors确认是否还在
 	if cached {
@@ -51,24 +62,117 @@ func (er *ExecutorRepository) FindExecutor(_ context.Context, path, method []byt
 		exe, exists := er.executors[val.(string)]
 		er.mu.RUnlock()
 
-		if exists {
+		if exists && !exe.Disabled && !exe.Expired(now) {
 			return exe, true
 		}
-		er.cache.Remove(cid) // 已经失效
-		return nil, false
+		er.cache.Remove(cid) // 已经失效、被停用或者过期，其他规则仍可能匹配，继续从索引中查找
 	}
 
-	// 不存在时，需要用正则匹配规则
+	// 不存在时，需要从索引中匹配规则
 	er.mu.RLock()
-	for eid, executor := range er.executors {
-		if executor.Match(path, method) {
-			er.mu.RUnlock()
-			er.cache.Add(cid, eid)
+	executor, exists := er.lookup(path, method, host, now)
+	er.mu.RUnlock()
+	if exists {
+		er.cache.Add(cid, executor.ID)
+	}
+	return executor, exists
+}
+
+// lookup 先按请求方法过滤，再精确匹配纯文本path，最后才按优先级逐个做正则匹配，跳过host不匹配以及已过期的执行器，调用方需持有读锁；
+// 精确匹配的执行器只会输给排序更靠前的执行器
+func (er *ExecutorRepository) lookup(path, method, host []byte, now time.Time) (*domain.Executor, bool) {
+	index, exists := er.methods[string(method)]
+	if !exists {
+		return nil, false
+	}
+	var literal *domain.Executor
+	for _, candidate := range index.literals[string(path)] {
+		if candidate.MatchHost(host) && !candidate.Expired(now) {
+			literal = candidate
+			break
+		}
+	}
+	for _, executor := range index.executors {
+		if literal != nil && !outranks(executor, literal) {
+			break
+		}
+		if executor.Path.Match(path) && executor.MatchHost(host) && !executor.Expired(now) {
 			return executor, true
 		}
 	}
-	er.mu.RUnlock()
-	return nil, false
+	return literal, literal != nil
+}
+
+// outranks 判断执行器a是否排在b之前：优先级高的在前，优先级相同时声明了host的在前
+func outranks(a, b *domain.Executor) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return a.Host != nil && b.Host == nil
+}
+
+// reindex 根据executors重建索引，调用方需持有写锁
+func (er *ExecutorRepository) reindex() {
+	methods := make(map[string]*methodIndex)
+	for _, executor := range er.executors {
+		if executor.Path == nil || executor.Disabled {
+			continue
+		}
+		index, exists := methods[string(executor.Method)]
+		if !exists {
+			index = &methodIndex{literals: make(map[string][]*domain.Executor)}
+			methods[string(executor.Method)] = index
+		}
+		// 正则匹配不要求完整匹配，纯文本path同样可能匹配更长的请求路径，因此也需要参与正则匹配
+		index.executors = append(index.executors, executor)
+	}
+	for _, index := range methods {
+		// 按outranks排序，相同时按ID排序，保证匹配结果与插入顺序无关
+		sort.Slice(index.executors, func(i, j int) bool {
+			a, b := index.executors[i], index.executors[j]
+			if outranks(a, b) || outranks(b, a) {
+				return outranks(a, b)
+			}
+			return a.ID < b.ID
+		})
+		for _, executor := range index.executors {
+			if literal, complete := executor.Path.LiteralPrefix(); complete {
+				index.literals[literal] = append(index.literals[literal], executor)
+			}
+		}
+	}
+	er.methods = methods
+}
+
+// ListExecutors 列出所有执行器
+func (er *ExecutorRepository) ListExecutors(_ context.Context) []*domain.Executor {
+	er.mu.RLock()
+	defer er.mu.RUnlock()
+
+	executors := make([]*domain.Executor, 0, len(er.executors))
+	for _, executor := range er.executors {
+		executors = append(executors, executor)
+	}
+	return executors
+}
+
+// RemoveExpired 在写锁内移除所有已过期的执行器，返回被移除的执行器ID
+func (er *ExecutorRepository) RemoveExpired(_ context.Context, now time.Time) []string {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+
+	var expired []string
+	for id, executor := range er.executors {
+		if executor.Expired(now) {
+			expired = append(expired, id)
+			delete(er.executors, id)
+		}
+	}
+	if len(expired) > 0 {
+		sort.Strings(expired)
+		er.reindex()
+	}
+	return expired
 }
 
 // Purge 清空存储库
@@ -79,6 +183,7 @@ func (er *ExecutorRepository) Purge(_ context.Context) {
 	for k := range er.executors {
 		delete(er.executors, k)
 	}
+	er.reindex()
 	er.cache.Purge()
 }
 
@@ -87,6 +192,7 @@ func (er *ExecutorRepository) ImportAll(_ context.Context, executors ...*domain.
 	er.mu.Lock()
 	defer er.mu.Unlock()
 
+	changed := false
 	toDelete := make(map[string]struct{}, len(er.executors))
 	for k := range er.executors {
 		toDelete[k] = struct{}{}
@@ -95,10 +201,11 @@ func (er *ExecutorRepository) ImportAll(_ context.Context, executors ...*domain.
 	for _, executor := range executors {
 		current, exists := er.executors[executor.ID]
 		delete(toDelete, executor.ID)
-		if exists && current.Version == executor.Version { // 记录未变更
+		if exists && current.Version == executor.Version && current.PartialsRevision == executor.PartialsRevision { // 记录未变更
 			continue
 		}
 		er.executors[executor.ID] = executor // 记录不存在或者版本不同了，都变更
+		changed = true
 	}
 
 	// toDelete中如果还存在数据，即表示需要删除
@@ -107,5 +214,11 @@ func (er *ExecutorRepository) ImportAll(_ context.Context, executors ...*domain.
 			misc.Logger.Info("deleted expired rules", zap.String("rule_id", k))
 			delete(er.executors, k)
 		}
+		changed = true
+	}
+	er.reindex()
+	if changed {
+		// 新增或更新的规则可能比缓存中的规则优先级更高
+		er.cache.Purge()
 	}
 }
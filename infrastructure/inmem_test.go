@@ -0,0 +1,314 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wosai/deepmock/domain"
+)
+
+func newTestExecutor(t *testing.T, path string, version int) *domain.Executor {
+	rule := &domain.Rule{
+		Path:        path,
+		Method:      "GET",
+		Version:     version,
+		Regulations: []*domain.Regulation{{IsDefault: true, Template: &domain.Template{Body: path}}},
+	}
+	executor, err := rule.To()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return executor
+}
+
+func TestExecutorRepository_FindExecutor(t *testing.T) {
+	er := NewExecutorRepository(10)
+	er.ImportAll(context.TODO(),
+		newTestExecutor(t, "/api/v1/user", 1),
+		newTestExecutor(t, `^/api/v1/order/\d+$`, 1),
+	)
+
+	exe, found := er.FindExecutor(context.TODO(), []byte("/api/v1/user"), []byte("GET"), nil)
+	assert.True(t, found)
+	assert.Equal(t, "/api/v1/user", exe.Path.String())
+
+	// 纯文本path与正则一样不要求完整匹配
+	exe, found = er.FindExecutor(context.TODO(), []byte("/api/v1/user/profile"), []byte("GET"), nil)
+	assert.True(t, found)
+	assert.Equal(t, "/api/v1/user", exe.Path.String())
+
+	exe, found = er.FindExecutor(context.TODO(), []byte("/api/v1/order/42"), []byte("GET"), nil)
+	assert.True(t, found)
+	assert.Equal(t, `^/api/v1/order/\d+$`, exe.Path.String())
+
+	_, found = er.FindExecutor(context.TODO(), []byte("/api/v1/order/abc"), []byte("GET"), nil)
+	assert.False(t, found)
+	_, found = er.FindExecutor(context.TODO(), []byte("/api/v1/user"), []byte("POST"), nil)
+	assert.False(t, found)
+
+	er.ImportAll(context.TODO(), newTestExecutor(t, `^/api/v1/order/\d+$`, 1))
+	_, found = er.FindExecutor(context.TODO(), []byte("/api/v1/user/profile"), []byte("GET"), nil)
+	assert.False(t, found)
+}
+
+func TestExecutorRepository_FindDisabledExecutor(t *testing.T) {
+	er := NewExecutorRepository(10)
+	er.ImportAll(context.TODO(), newTestExecutor(t, "/api/v1/user", 1), newTestExecutor(t, "/api/v1", 1))
+	exe, found := er.FindExecutor(context.TODO(), []byte("/api/v1/user"), []byte("GET"), nil)
+	assert.True(t, found)
+	assert.Equal(t, "/api/v1/user", exe.Path.String())
+
+	// 停用后不再匹配，缓存失效后由其他规则匹配
+	disabled := newTestExecutor(t, "/api/v1/user", 2)
+	disabled.Disabled = true
+	er.ImportAll(context.TODO(), disabled, newTestExecutor(t, "/api/v1", 1))
+	exe, found = er.FindExecutor(context.TODO(), []byte("/api/v1/user"), []byte("GET"), nil)
+	assert.True(t, found)
+	assert.Equal(t, "/api/v1", exe.Path.String())
+	assert.Len(t, er.ListExecutors(context.TODO()), 2)
+}
+
+func TestExecutorRepository_RemoveExpired(t *testing.T) {
+	er := NewExecutorRepository(10)
+	now := time.Now()
+	expiring := newTestExecutor(t, "/api/v1/session", 1)
+	expiring.ExpireAt = now.Add(time.Minute)
+	er.ImportAll(context.TODO(), expiring, newTestExecutor(t, "/api/v1/user", 1))
+
+	_, found := er.FindExecutor(context.TODO(), []byte("/api/v1/session"), []byte("GET"), nil)
+	assert.True(t, found)
+	assert.Empty(t, er.RemoveExpired(context.TODO(), now))
+
+	expiring.ExpireAt = now.Add(-time.Second) // 已经过期，即使被缓存也不再匹配
+	_, found = er.FindExecutor(context.TODO(), []byte("/api/v1/session"), []byte("GET"), nil)
+	assert.False(t, found)
+	assert.Equal(t, []string{expiring.ID}, er.RemoveExpired(context.TODO(), now))
+	assert.Len(t, er.ListExecutors(context.TODO()), 1)
+}
+
+func TestExecutorRepository_FindByPriority(t *testing.T) {
+	newExecutor := func(id, path string, priority int) *domain.Executor {
+		exe := newTestExecutor(t, path, 1)
+		exe.ID = id
+		exe.Priority = priority
+		return exe
+	}
+	er := NewExecutorRepository(10)
+	er.ImportAll(context.TODO(),
+		newExecutor("a", "/api/v1/.*", 0),
+		newExecutor("b", `^/api/v1/order/\d+$`, 10),
+		newExecutor("c", "/api/v1/user", 0),
+		newExecutor("d", "/api/v1/user", 0),
+	)
+
+	// 优先级高的规则胜出，与导入顺序无关
+	exe, found := er.FindExecutor(context.TODO(), []byte("/api/v1/order/42"), []byte("GET"), nil)
+	assert.True(t, found)
+	assert.Equal(t, "b", exe.ID)
+	exe, _ = er.FindExecutor(context.TODO(), []byte("/api/v1/order"), []byte("GET"), nil)
+	assert.Equal(t, "a", exe.ID)
+
+	// 优先级相同时精确匹配的纯文本path胜出，多条纯文本path相同时按ID排序
+	exe, _ = er.FindExecutor(context.TODO(), []byte("/api/v1/user"), []byte("GET"), nil)
+	assert.Equal(t, "c", exe.ID)
+
+	// 提高通配规则的优先级后，缓存失效，通配规则胜出
+	bumped := newExecutor("a", "/api/v1/.*", 20)
+	bumped.Version = 2
+	er.ImportAll(context.TODO(), bumped, newExecutor("b", `^/api/v1/order/\d+$`, 10), newExecutor("c", "/api/v1/user", 0), newExecutor("d", "/api/v1/user", 0))
+	for _, path := range []string{"/api/v1/order/42", "/api/v1/user"} {
+		exe, _ = er.FindExecutor(context.TODO(), []byte(path), []byte("GET"), nil)
+		assert.Equal(t, "a", exe.ID, path)
+	}
+}
+
+func TestExecutorRepository_FindByHost(t *testing.T) {
+	newExecutor := func(path, host string) *domain.Executor {
+		rule := &domain.Rule{
+			Path:        path,
+			Method:      "GET",
+			Host:        host,
+			Regulations: []*domain.Regulation{{IsDefault: true, Template: &domain.Template{Body: host}}},
+		}
+		exe, err := rule.To()
+		assert.NoError(t, err)
+		return exe
+	}
+	order, pay, any := newExecutor("/api/v1/status", "order.example.com"), newExecutor("/api/v1/status", "pay.example.com"), newExecutor("/api/v1/status", "")
+	regex := newExecutor(`^/api/v1/items/\d+$`, "order.example.com")
+	er := NewExecutorRepository(10)
+	er.ImportAll(context.TODO(), order, pay, any, regex)
+
+	// path相同时按host区分，未声明host的规则兜底
+	for host, expected := range map[string]*domain.Executor{
+		"order.example.com":      order,
+		"pay.example.com:8080":   pay,
+		"unknown.example.com":    any,
+		"PAY.EXAMPLE.COM":        pay,
+		"order.example.com:8443": order,
+	} {
+		exe, found := er.FindExecutor(context.TODO(), []byte("/api/v1/status"), []byte("GET"), []byte(host))
+		assert.True(t, found, host)
+		assert.Equal(t, expected.ID, exe.ID, host)
+	}
+
+	_, found := er.FindExecutor(context.TODO(), []byte("/api/v1/items/42"), []byte("GET"), []byte("pay.example.com"))
+	assert.False(t, found)
+	exe, found := er.FindExecutor(context.TODO(), []byte("/api/v1/items/42"), []byte("GET"), []byte("order.example.com"))
+	assert.True(t, found)
+	assert.Equal(t, regex.ID, exe.ID)
+}
+
+// TestExecutorRepository_Concurrent 需要配合 go test -race 运行
+func TestExecutorRepository_Concurrent(t *testing.T) {
+	er := NewExecutorRepository(10)
+	var wg sync.WaitGroup
+
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				executors := make([]*domain.Executor, 0, 8)
+				for j := 0; j < 8; j++ {
+					executors = append(executors, newTestExecutor(t, fmt.Sprintf("/api/v1/rule/%d", j), i))
+				}
+				er.ImportAll(context.TODO(), executors...)
+				if i%50 == 0 {
+					er.Purge(context.TODO())
+				}
+			}
+		}(w)
+	}
+
+	for r := 0; r < 8; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				path := []byte(fmt.Sprintf("/api/v1/rule/%d", (r+i)%8))
+				if exe, found := er.FindExecutor(context.TODO(), path, []byte("GET"), nil); found {
+					assert.True(t, exe.Match(path, []byte("GET")))
+				}
+				_ = er.ListExecutors(context.TODO())
+			}
+		}(r)
+	}
+	wg.Wait()
+}
+
+func newBenchmarkRepository(b *testing.B) *ExecutorRepository {
+	er := NewExecutorRepository(10)
+	executors := make([]*domain.Executor, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		path := fmt.Sprintf("/api/v1/resource%d/items", i)
+		if i%10 == 0 { // 十分之一的规则使用正则表达式
+			path = fmt.Sprintf(`^/api/v1/regexp%d/\d+$`, i)
+		}
+		rule := &domain.Rule{
+			Path:        path,
+			Method:      "GET",
+			Regulations: []*domain.Regulation{{IsDefault: true, Template: &domain.Template{Body: path}}},
+		}
+		executor, err := rule.To()
+		if err != nil {
+			b.Fatal(err)
+		}
+		executors = append(executors, executor)
+	}
+	er.ImportAll(context.TODO(), executors...)
+	return er
+}
+
+// scan 原先的查找方式：遍历所有执行器逐个做正则匹配
+func (er *ExecutorRepository) scan(path, method []byte) (*domain.Executor, bool) {
+	for _, executor := range er.executors {
+		if executor.Match(path, method) {
+			return executor, true
+		}
+	}
+	return nil, false
+}
+
+func BenchmarkExecutorRepository_Lookup(b *testing.B) {
+	er := newBenchmarkRepository(b)
+	paths := [][]byte{[]byte("/api/v1/resource999/items"), []byte("/api/v1/resource501/items"), []byte("/api/v1/regexp990/42")}
+	method := []byte("GET")
+	now := time.Now()
+
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, found := er.scan(paths[i%len(paths)], method); !found {
+				b.Fatal("not found")
+			}
+		}
+	})
+	b.Run("indexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, found := er.lookup(paths[i%len(paths)], method, nil, now); !found {
+				b.Fatal("not found")
+			}
+		}
+	})
+}
+
+// TestExecutorRepository_ConcurrentWeightUpdate 需要配合 go test -race 运行
+func TestExecutorRepository_ConcurrentWeightUpdate(t *testing.T) {
+	newWeightExecutor := func(version int, weight domain.WeightFactor) *domain.Executor {
+		rule := &domain.Rule{
+			Path:        "/weight",
+			Method:      "GET",
+			Version:     version,
+			Weight:      map[string]domain.WeightFactor{"x": weight, "y": weight},
+			Regulations: []*domain.Regulation{{IsDefault: true, Template: &domain.Template{Body: "ok"}}},
+		}
+		executor, err := rule.To()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return executor
+	}
+	er := NewExecutorRepository(10)
+	er.ImportAll(context.TODO(), newWeightExecutor(1, domain.WeightFactor{"a": 1}))
+
+	// 更新规则时整体替换执行器，正在渲染的请求看到的权重来自同一个版本
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for version := 2; ; version++ {
+			select {
+			case <-stop:
+				return
+			default:
+				weight := domain.WeightFactor{"a": 1}
+				if version%2 == 0 {
+					weight = domain.WeightFactor{"b": 1}
+				}
+				er.ImportAll(context.TODO(), newWeightExecutor(version, weight))
+			}
+		}
+	}()
+
+	var readers sync.WaitGroup
+	for r := 0; r < 8; r++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for i := 0; i < 500; i++ {
+				exe, found := er.FindExecutor(context.TODO(), []byte("/weight"), []byte("GET"), nil)
+				assert.True(t, found)
+				rolled := exe.Weight.DiceAll()
+				assert.Equal(t, rolled["x"], rolled["y"])
+			}
+		}()
+	}
+	readers.Wait()
+	close(stop)
+	wg.Wait()
+}
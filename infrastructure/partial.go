@@ -0,0 +1,106 @@
+package infrastructure
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/wosai/deepmock/domain"
+	"github.com/wosai/deepmock/misc"
+	"go.uber.org/zap"
+)
+
+type (
+	// PartialLoader 从目录中载入模板片段，文件名（去掉扩展名）即片段名称
+	PartialLoader struct {
+		dir      string
+		modified time.Time
+		names    map[string]struct{} // 上次载入的文件名，用于发现新增、删除与重命名
+	}
+)
+
+// NewPartialLoader 工厂函数
+func NewPartialLoader(dir string) *PartialLoader {
+	return &PartialLoader{dir: dir}
+}
+
+// Load 载入目录下所有的模板片段
+func (pl *PartialLoader) Load() error {
+	files, err := ioutil.ReadDir(pl.dir)
+	if err != nil {
+		return err
+	}
+
+	texts := make(map[string]string)
+	names := make(map[string]struct{})
+	var modified time.Time
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		names[file.Name()] = struct{}{}
+		data, err := ioutil.ReadFile(filepath.Join(pl.dir, file.Name()))
+		if err != nil {
+			return err
+		}
+		texts[strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))] = string(data)
+		if file.ModTime().After(modified) {
+			modified = file.ModTime()
+		}
+	}
+
+	if err := domain.SetTemplatePartials(texts); err != nil {
+		return err
+	}
+	pl.modified = modified
+	pl.names = names
+	misc.Logger.Info("loaded template partials", zap.String("dir", pl.dir), zap.Int("count", len(texts)))
+	return nil
+}
+
+// changed 判断目录下的模板片段是否有增删改，重命名不会改变修改时间，因此需要比较文件名
+func (pl *PartialLoader) changed() (bool, error) {
+	files, err := ioutil.ReadDir(pl.dir)
+	if err != nil {
+		return false, err
+	}
+
+	var count int
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		count++
+		if _, exists := pl.names[file.Name()]; !exists || file.ModTime().After(pl.modified) {
+			return true, nil
+		}
+	}
+	return count != len(pl.names), nil
+}
+
+// Reload 模板片段有变更时重新载入
+func (pl *PartialLoader) Reload() error {
+	changed, err := pl.changed()
+	if err != nil || !changed {
+		return err
+	}
+	return pl.Load()
+}
+
+// Watch 按周期检查并重新载入模板片段，直到stop被关闭
+func (pl *PartialLoader) Watch(period time.Duration, stop <-chan struct{}) {
+	t := time.NewTicker(period)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			if err := pl.Reload(); err != nil && !os.IsNotExist(err) {
+				misc.Logger.Error("failed to reload template partials", zap.String("dir", pl.dir), zap.Error(err))
+			}
+		}
+	}
+}
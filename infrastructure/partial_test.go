@@ -0,0 +1,122 @@
+package infrastructure
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"github.com/wosai/deepmock/domain"
+)
+
+func renderPartialRule(t *testing.T) (*domain.Executor, string) {
+	rule := &domain.Rule{
+		Path:     "/api/v1/partial",
+		Method:   "GET",
+		Variable: map[string]interface{}{"version": "1.0"},
+		Regulations: []*domain.Regulation{
+			{
+				IsDefault: true,
+				Template:  &domain.Template{IsTemplate: true, Body: `{"code": 200, "meta": {{template "meta" .}}}`},
+			},
+		},
+	}
+	executor, err := rule.To()
+	assert.NoError(t, err)
+
+	ctx := new(fasthttp.RequestCtx)
+	assert.NoError(t, executor.Regulations[0].Render(ctx, executor.Variable, nil))
+	return executor, string(ctx.Response.Body())
+}
+
+func TestPartialLoader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deepmock-partials")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	meta := filepath.Join(dir, "meta.tmpl")
+	assert.NoError(t, ioutil.WriteFile(meta, []byte(`{"version": "{{.Variable.version}}"}`), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".swp"), []byte(`{{`), 0644))
+
+	loader := NewPartialLoader(dir)
+	assert.NoError(t, loader.Load())
+	before, body := renderPartialRule(t)
+	assert.Equal(t, `{"code": 200, "meta": {"version": "1.0"}}`, body)
+
+	// 未变更时不会重新载入
+	changed, err := loader.changed()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	assert.NoError(t, ioutil.WriteFile(meta, []byte(`{"version": "{{.Variable.version}}", "mocked": true}`), 0644))
+	future := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(meta, future, future))
+	assert.NoError(t, loader.Reload())
+	after, body := renderPartialRule(t)
+	assert.Equal(t, `{"code": 200, "meta": {"version": "1.0", "mocked": true}}`, body)
+	assert.NotEqual(t, before.PartialsRevision, after.PartialsRevision)
+
+	// 存在无法解析的模板片段时保留原有片段
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "broken.tmpl"), []byte(`{{ if }}`), 0644))
+	assert.Error(t, loader.Reload())
+	_, body = renderPartialRule(t)
+	assert.Equal(t, `{"code": 200, "meta": {"version": "1.0", "mocked": true}}`, body)
+}
+
+func TestPartialLoader_Rename(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deepmock-partials")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.tmpl"), []byte(`{"version": "{{.Variable.version}}"}`), 0644))
+	loader := NewPartialLoader(dir)
+	assert.NoError(t, loader.Load())
+
+	// 重命名不改变修改时间与文件数量，也需要重新载入
+	assert.NoError(t, os.Rename(filepath.Join(dir, "a.tmpl"), filepath.Join(dir, "meta.tmpl")))
+	changed, err := loader.changed()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.NoError(t, loader.Reload())
+	_, body := renderPartialRule(t)
+	assert.Equal(t, `{"code": 200, "meta": {"version": "1.0"}}`, body)
+
+	changed, err = loader.changed()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestPartialLoader_WatchStop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deepmock-partials")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	loader := NewPartialLoader(dir)
+	assert.NoError(t, loader.Load())
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		loader.Watch(time.Millisecond, stop)
+		close(done)
+	}()
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watch did not stop")
+	}
+}
+
+func TestExecutorRepository_ImportAllWithPartialsRevision(t *testing.T) {
+	er := NewExecutorRepository(10)
+	exec := &domain.Executor{ID: "1", Version: 1, PartialsRevision: 1}
+	er.ImportAll(context.TODO(), exec)
+
+	rebuilt := &domain.Executor{ID: "1", Version: 1, PartialsRevision: 2}
+	er.ImportAll(context.TODO(), rebuilt)
+	assert.Equal(t, rebuilt, er.executors["1"])
+}
@@ -26,11 +26,19 @@ type (
 
 func convertRuleEntity(rule *domain.Rule) (*types.RuleDO, error) {
 	do := &types.RuleDO{
-		ID:       rule.ID,
-		Path:     rule.Path,
-		Method:   rule.Method,
-		Version:  rule.Version,
-		Disabled: false,
+		ID:             rule.ID,
+		Path:           rule.Path,
+		PathType:       rule.PathType,
+		Host:           rule.Host,
+		HostType:       rule.HostType,
+		Method:         rule.Method,
+		Version:        rule.Version,
+		Disabled:       !rule.IsEnabled(),
+		Debug:          rule.Debug,
+		RateLimit:      rule.RateLimit,
+		MaxConcurrency: rule.MaxConcurrency,
+		TTLSeconds:     rule.TTLSeconds,
+		Priority:       rule.Priority,
 	}
 	var err error
 	if rule.Variable != nil {
@@ -48,16 +56,64 @@ func convertRuleEntity(rule *domain.Rule) (*types.RuleDO, error) {
 			return nil, err
 		}
 	}
+	if rule.CORS != nil {
+		if do.CORS, err = json.Marshal(rule.CORS); err != nil {
+			return nil, err
+		}
+	}
+	if rule.SlowStart != nil {
+		if do.SlowStart, err = json.Marshal(rule.SlowStart); err != nil {
+			return nil, err
+		}
+	}
+	if rule.OverflowResponse != nil {
+		if do.OverflowResponse, err = json.Marshal(rule.OverflowResponse); err != nil {
+			return nil, err
+		}
+	}
+	if rule.Duplicate != nil {
+		if do.Duplicate, err = json.Marshal(rule.Duplicate); err != nil {
+			return nil, err
+		}
+	}
+	if rule.ResponseSchema != nil {
+		if do.ResponseSchema, err = json.Marshal(rule.ResponseSchema); err != nil {
+			return nil, err
+		}
+	}
+	if rule.StatusDelay != nil {
+		if do.StatusDelay, err = json.Marshal(rule.StatusDelay); err != nil {
+			return nil, err
+		}
+	}
+	if rule.Maintenance != nil {
+		if do.Maintenance, err = json.Marshal(rule.Maintenance); err != nil {
+			return nil, err
+		}
+	}
 	return do, nil
 }
 
 // todo: 现在通过在entity上加tag实现转换，domain层不应该感知infra的数据结构，不合理，之后要优化
 func convertRuleDO(rule *types.RuleDO) (*domain.Rule, error) {
 	entity := &domain.Rule{
-		ID:      rule.ID,
-		Path:    rule.Path,
-		Method:  rule.Method,
-		Version: rule.Version,
+		ID:             rule.ID,
+		Path:           rule.Path,
+		PathType:       rule.PathType,
+		Host:           rule.Host,
+		HostType:       rule.HostType,
+		Method:         rule.Method,
+		Version:        rule.Version,
+		Debug:          rule.Debug,
+		RateLimit:      rule.RateLimit,
+		MaxConcurrency: rule.MaxConcurrency,
+		TTLSeconds:     rule.TTLSeconds,
+		Priority:       rule.Priority,
+		CreatedAt:      rule.CTime,
+	}
+	if rule.Disabled {
+		enabled := false
+		entity.Enabled = &enabled
 	}
 	if rule.Weight != nil {
 		if err := json.Unmarshal(rule.Weight, &entity.Weight); err != nil {
@@ -71,6 +127,48 @@ func convertRuleDO(rule *types.RuleDO) (*domain.Rule, error) {
 		}
 	}
 
+	if rule.CORS != nil {
+		if err := json.Unmarshal(rule.CORS, &entity.CORS); err != nil {
+			return nil, err
+		}
+	}
+
+	if rule.SlowStart != nil {
+		if err := json.Unmarshal(rule.SlowStart, &entity.SlowStart); err != nil {
+			return nil, err
+		}
+	}
+
+	if rule.OverflowResponse != nil {
+		if err := json.Unmarshal(rule.OverflowResponse, &entity.OverflowResponse); err != nil {
+			return nil, err
+		}
+	}
+
+	if rule.Duplicate != nil {
+		if err := json.Unmarshal(rule.Duplicate, &entity.Duplicate); err != nil {
+			return nil, err
+		}
+	}
+
+	if rule.ResponseSchema != nil {
+		if err := json.Unmarshal(rule.ResponseSchema, &entity.ResponseSchema); err != nil {
+			return nil, err
+		}
+	}
+
+	if rule.StatusDelay != nil {
+		if err := json.Unmarshal(rule.StatusDelay, &entity.StatusDelay); err != nil {
+			return nil, err
+		}
+	}
+
+	if rule.Maintenance != nil {
+		if err := json.Unmarshal(rule.Maintenance, &entity.Maintenance); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := json.Unmarshal(rule.Responses, &entity.Regulations); err != nil {
 		return nil, err
 	}
@@ -117,17 +215,38 @@ func (r *RuleRepository) UpdateRule(ctx context.Context, rule *domain.Rule) erro
 			"version": do.Version - 1,
 		},
 		map[string]interface{}{
-			"variable":  do.Variable,
-			"weight":    do.Weight,
-			"responses": do.Responses,
-			"version":   do.Version,
+			"variable":          do.Variable,
+			"weight":            do.Weight,
+			"responses":         do.Responses,
+			"version":           do.Version,
+			"disabled":          do.Disabled,
+			"debug":             do.Debug,
+			"rate_limit":        do.RateLimit,
+			"cors":              do.CORS,
+			"max_concurrency":   do.MaxConcurrency,
+			"slow_start":        do.SlowStart,
+			"overflow_response": do.OverflowResponse,
+			"duplicate":         do.Duplicate,
+			"response_schema":   do.ResponseSchema,
+			"ttl_seconds":       do.TTLSeconds,
+			"status_delay":      do.StatusDelay,
+			"priority":          do.Priority,
+			"maintenance":       do.Maintenance,
+			"path_type":         do.PathType,
 		},
 	)
 	if err != nil {
 		return err
 	}
-	_, err = r.db.ExecContext(ctx, cond, values...)
-	return err
+	result, err := r.db.ExecContext(ctx, cond, values...)
+	if err != nil {
+		return err
+	}
+	// 版本号已经被其他更新修改
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return domain.ErrVersionConflict
+	}
+	return nil
 }
 
 // GetRuleByID 获取记录
@@ -135,9 +254,8 @@ func (r *RuleRepository) GetRuleByID(ctx context.Context, rid string) (*domain.R
 	query, values, _ := builder.BuildSelect(
 		r.table,
 		map[string]interface{}{
-			"id":       rid,
-			"_limit":   []uint{1},
-			"disabled": 0,
+			"id":     rid,
+			"_limit": []uint{1},
 		},
 		[]string{"*"},
 	)
@@ -161,7 +279,7 @@ func (r *RuleRepository) GetRuleByID(ctx context.Context, rid string) (*domain.R
 
 // DeleteRule 删除记录
 func (r *RuleRepository) DeleteRule(ctx context.Context, rid string) error {
-	cond, values, err := builder.BuildDelete(r.table, map[string]interface{}{"id": rid, "disabled": 0})
+	cond, values, err := builder.BuildDelete(r.table, map[string]interface{}{"id": rid})
 	if err != nil {
 		return err
 	}
@@ -173,7 +291,7 @@ func (r *RuleRepository) DeleteRule(ctx context.Context, rid string) error {
 func (r *RuleRepository) Export(ctx context.Context) ([]*domain.Rule, error) {
 	query, values, _ := builder.BuildSelect(
 		r.table,
-		map[string]interface{}{"disabled": 0},
+		nil,
 		[]string{"*"},
 	)
 	rows, err := r.db.QueryContext(ctx, query, values...)
@@ -0,0 +1,8 @@
+package misc
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	// MetricsRegistry DeepMock全局的prometheus指标注册中心
+	MetricsRegistry = prometheus.NewRegistry()
+)
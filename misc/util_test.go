@@ -3,8 +3,24 @@ package misc
 import (
 	"fmt"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestGenID(t *testing.T) {
 	fmt.Println(GenID([]byte("/rpc/token"), []byte("POST")))
 }
+
+func TestStringValues_JSON(t *testing.T) {
+	var header map[string]StringValues
+	err := json.Unmarshal([]byte(`{"Content-Type":"application/json","Link":["</a>; rel=\"next\"","</b>; rel=\"last\""]}`), &header)
+	assert.NoError(t, err)
+	assert.Equal(t, StringValues{"application/json"}, header["Content-Type"])
+	assert.Equal(t, StringValues{`</a>; rel="next"`, `</b>; rel="last"`}, header["Link"])
+
+	data, err := json.Marshal(header)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"Content-Type":"application/json","Link":["</a>; rel=\"next\"","</b>; rel=\"last\""]}`, string(data))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"Content-Type":1}`), &header))
+}
@@ -26,6 +26,9 @@ const (
 )
 
 var (
+	// Version deepmock的版本号，启动时由main写入构建时注入的git revision
+	Version = "unknown"
+
 	defaultHashPoll *hashPool
 	salt            = []byte(`6ee30676-6c88-4d3a-86b1-bb61e82da1c9`)
 )
@@ -46,13 +49,17 @@ func (fp *hashPool) put(h hash.Hash) {
 	fp.pool.Put(h)
 }
 
-// GenID 基于murmur3的哈希函数
-func GenID(path, method []byte) string {
+// GenID 基于murmur3的哈希函数，extras为参与区分规则的其他字段(如host)，为空时与只使用path、method生成的ID相同
+func GenID(path, method []byte, extras ...[]byte) string {
 	h := defaultHashPoll.get()
 	defer defaultHashPoll.put(h)
 
 	h.Write(bytes.ToUpper(method))
 	h.Write(path)
+	for _, extra := range extras {
+		h.Write([]byte{0})
+		h.Write(extra)
+	}
 	h.Write(salt)
 	return hex.EncodeToString(h.Sum(nil))
 }
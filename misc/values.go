@@ -0,0 +1,43 @@
+package misc
+
+import "errors"
+
+type (
+	// StringValues 字符串切片，JSON格式兼容单个字符串与字符串数组两种写法
+	StringValues []string
+)
+
+// UnmarshalJSON json.Unmarshaler的实现
+func (sv *StringValues) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '[' {
+		var values []string
+		if err := json.Unmarshal(data, &values); err != nil {
+			return err
+		}
+		*sv = values
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return errors.New("value must be a string or an array of strings")
+	}
+	*sv = StringValues{value}
+	return nil
+}
+
+// MarshalJSON json.Marshaler的实现，只有一个值时输出为字符串，以保持与旧格式兼容
+func (sv StringValues) MarshalJSON() ([]byte, error) {
+	if len(sv) == 1 {
+		return json.Marshal(sv[0])
+	}
+	return json.Marshal([]string(sv))
+}
+
+// MarshalYAML yaml.Marshaler的实现，与MarshalJSON一致，只有一个值时输出为字符串
+func (sv StringValues) MarshalYAML() (interface{}, error) {
+	if len(sv) == 1 {
+		return sv[0], nil
+	}
+	return []string(sv), nil
+}
@@ -1,9 +1,12 @@
 package option
 
+import "time"
+
 type (
 	Option struct {
 		Server ServerOption
 		DB     DatabaseOption
+		Mock   MockOption
 	}
 
 	DatabaseOption struct {
@@ -16,8 +19,38 @@ type (
 	}
 
 	ServerOption struct {
-		Port     string `default:":16600"`
-		KeyFile  string `yaml:"key_file,omitempty" json:"key_file,omitempty"`
-		CertFile string `yaml:"cert_file,omitempty" json:"cert_file,omitempty"`
+		Port               string `default:":16600"`
+		KeyFile            string `yaml:"key_file,omitempty" json:"key_file,omitempty"`
+		CertFile           string `yaml:"cert_file,omitempty" json:"cert_file,omitempty"`
+		MaxRequestBodySize int    `default:"4194304" yaml:"max_request_body_size" json:"max_request_body_size"` // 请求body的大小上限，单位为字节
+		MaxDrainBodySize   int    `yaml:"max_drain_body_size,omitempty" json:"max_drain_body_size,omitempty"`   // 大于MaxRequestBodySize时，不超过该值的超限body读取完整后再返回413，0表示不读取直接返回413
+	}
+
+	MockOption struct {
+		RequestLogSize     int           `default:"100" yaml:"request_log_size" json:"request_log_size"`                // 保留最近多少条请求记录，0表示不记录
+		PartialsDir        string        `yaml:"partials_dir,omitempty" json:"partials_dir,omitempty"`                  // 模板片段所在目录
+		PartialsReload     time.Duration `yaml:"partials_reload,omitempty" json:"partials_reload,omitempty"`            // 模板片段的重新载入周期，0表示不重新载入
+		MaxConcurrency     uint          `yaml:"max_concurrency,omitempty" json:"max_concurrency,omitempty"`            // 全局同时处理的mock请求数上限，0表示不限制
+		Upstream           string        `yaml:"upstream,omitempty" json:"upstream,omitempty"`                          // 未匹配任何规则时转发请求的上游服务地址，为空表示不转发
+		Record             bool          `yaml:"record,omitempty" json:"record,omitempty"`                              // 是否开启录制模式，将上游的响应保存为规则，需要同时设置Upstream
+		GIDFile            string        `yaml:"gid_file,omitempty" json:"gid_file,omitempty"`                          // 保存gid模板函数已分配上限的文件，为空表示不持久化
+		RuleFile           string        `yaml:"rule_file,omitempty" json:"rule_file,omitempty"`                        // 保存规则的本地JSON文件，设置后不再连接MySQL，为空表示使用MySQL
+		RuleConflict       string        `yaml:"rule_conflict,omitempty" json:"rule_conflict,omitempty"`                // 创建规则时与已有规则重叠的处理方式：warn记录日志，reject拒绝创建，为空表示不检测
+		TemplateFuncs      []string      `yaml:"template_funcs,omitempty" json:"template_funcs,omitempty"`              // 允许规则模板使用的模板函数，为空表示不限制
+		RuleSweepInterval  time.Duration `yaml:"rule_sweep_interval,omitempty" json:"rule_sweep_interval,omitempty"`    // 清理过期规则的周期，0表示不主动清理，过期规则仍然不会被匹配
+		Compress           string        `yaml:"compress,omitempty" json:"compress,omitempty"`                          // 未设置compress的响应使用的压缩方式：gzip、deflate或auto，为空表示不压缩
+		CompressMinSize    int           `yaml:"compress_min_size,omitempty" json:"compress_min_size,omitempty"`        // 小于该字节数的响应body不压缩，0表示不限制
+		MaxDecodedBodySize int           `default:"16777216" yaml:"max_decoded_body_size" json:"max_decoded_body_size"` // gzip、deflate编码的请求body解压后的大小上限，单位为字节
+		SniffBinaryBody    bool          `yaml:"sniff_binary_body,omitempty" json:"sniff_binary_body,omitempty"`        // 未配置Content-Type时是否根据base64编码的body推断
+		BodyFileDir        string        `yaml:"body_file_dir,omitempty" json:"body_file_dir,omitempty"`                // body_file所在的目录，body_file只能是其中的相对路径，为空表示不允许使用body_file
+		TimeRandSeed       int64         `yaml:"time_rand_seed,omitempty" json:"time_rand_seed,omitempty"`              // randTime模板函数的随机数种子，固定后生成的时间可以复现，0表示使用随机种子
+		BoolRandSeed       int64         `yaml:"bool_rand_seed,omitempty" json:"bool_rand_seed,omitempty"`              // randBool模板函数的随机数种子，固定后生成的结果可以复现，0表示使用随机种子
 	}
 )
+
+const (
+	// RuleConflictWarn 创建重叠的规则时记录警告日志
+	RuleConflictWarn = "warn"
+	// RuleConflictReject 拒绝创建与已有规则重叠的规则
+	RuleConflictReject = "reject"
+)
@@ -4,20 +4,27 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	jsoniter "github.com/json-iterator/go"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
 	"github.com/wosai/deepmock/application"
+	"github.com/wosai/deepmock/domain"
 	"github.com/wosai/deepmock/misc"
 	"github.com/wosai/deepmock/types"
 	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
 )
 
 var (
 	slash          = []byte(`/`)
 	apiGetRulePath = []byte(`/api/v1/rule`)
 	json           = jsoniter.ConfigCompatibleWithStandardLibrary
+	metricsHandler = fasthttpadaptor.NewFastHTTPHandler(promhttp.HandlerFor(misc.MetricsRegistry, promhttp.HandlerOpts{}))
 )
 
 func parsePathVar(path, uri []byte) string {
@@ -36,7 +43,7 @@ func parsePathVar(path, uri []byte) string {
 func HandleMockedAPI(ctx *fasthttp.RequestCtx, _ func(error)) {
 	err := application.MockApplication.MockAPI(ctx)
 	if err != nil {
-		renderFailedAPIResponse(&ctx.Response, err)
+		renderFailedAPIResponse(ctx, err)
 		return
 	}
 }
@@ -50,27 +57,27 @@ func HandleCreateRule(ctx *fasthttp.RequestCtx, _ func(error)) {
 
 	rid, err := application.MockApplication.CreateRule(context.TODO(), rule)
 	if err != nil {
-		renderFailedAPIResponse(&ctx.Response, err)
+		renderFailedAPIResponse(ctx, err)
 		return
 	}
 	rule, err = application.MockApplication.GetRule(context.TODO(), rid)
 	if err != nil {
-		renderFailedAPIResponse(&ctx.Response, err)
+		renderFailedAPIResponse(ctx, err)
 		return
 	}
-	renderSuccessfulResponse(&ctx.Response, rule)
+	renderSuccessfulResponse(ctx, rule)
 }
 
 // HandleGetRule 根据rule id获取规则
 func HandleGetRule(ctx *fasthttp.RequestCtx, _ func(error)) {
-	ruleID := parsePathVar(apiGetRulePath, ctx.RequestURI())
+	ruleID := parsePathVar(apiGetRulePath, ctx.Path())
 
 	rule, err := application.MockApplication.GetRule(context.TODO(), ruleID)
 	if err != nil {
-		renderFailedAPIResponse(&ctx.Response, err)
+		renderFailedAPIResponse(ctx, err)
 		return
 	}
-	renderSuccessfulResponse(&ctx.Response, rule)
+	renderSuccessfulResponse(ctx, rule)
 }
 
 // HandleDeleteRule 根据rule id删除规则
@@ -82,10 +89,10 @@ func HandleDeleteRule(ctx *fasthttp.RequestCtx, _ func(error)) {
 
 	err := application.MockApplication.DeleteRule(context.TODO(), res.ID)
 	if err != nil {
-		renderFailedAPIResponse(&ctx.Response, err)
+		renderFailedAPIResponse(ctx, err)
 		return
 	}
-	renderSuccessfulResponse(&ctx.Response, nil)
+	renderSuccessfulResponse(ctx, nil)
 }
 
 // HandlePutRule 根据rule id更新目前规则，如果规则不存在，不会新建
@@ -97,15 +104,15 @@ func HandlePutRule(ctx *fasthttp.RequestCtx, _ func(error)) {
 
 	err := application.MockApplication.PutRule(context.TODO(), res)
 	if err != nil {
-		renderFailedAPIResponse(&ctx.Response, err)
+		renderFailedAPIResponse(ctx, err)
 		return
 	}
 	rule, err := application.MockApplication.GetRule(context.TODO(), res.ID)
 	if err != nil {
-		renderFailedAPIResponse(&ctx.Response, err)
+		renderFailedAPIResponse(ctx, err)
 		return
 	}
-	renderSuccessfulResponse(&ctx.Response, rule)
+	renderSuccessfulResponse(ctx, rule)
 }
 
 // HandlePatchRule 根据rule id更新目前规则，与put的区别在于：put需要传入完整的rule对象，而patch只需要传入更新部分即可
@@ -117,45 +124,235 @@ func HandlePatchRule(ctx *fasthttp.RequestCtx, _ func(error)) {
 
 	err := application.MockApplication.PatchRule(context.TODO(), res)
 	if err != nil {
-		renderFailedAPIResponse(&ctx.Response, err)
+		renderFailedAPIResponse(ctx, err)
 		return
 	}
 	rule, err := application.MockApplication.GetRule(context.TODO(), res.ID)
 	if err != nil {
-		renderFailedAPIResponse(&ctx.Response, err)
+		renderFailedAPIResponse(ctx, err)
 		return
 	}
-	renderSuccessfulResponse(&ctx.Response, rule)
+	renderSuccessfulResponse(ctx, rule)
 }
 
-// HandleExportRules 导出当前所有规则
+// HandleExportRules 导出当前所有规则，format=yaml时直接输出YAML格式的规则列表，可以原样导入
 func HandleExportRules(ctx *fasthttp.RequestCtx, _ func(error)) {
 	rules, err := application.MockApplication.Export(context.TODO())
 	if err != nil {
-		renderFailedAPIResponse(&ctx.Response, err)
+		renderFailedAPIResponse(ctx, err)
 		return
 	}
-	renderSuccessfulResponse(&ctx.Response, rules)
+	if isYAMLFormat(ctx) {
+		data, err := yaml.Marshal(rules)
+		if err != nil {
+			renderFailedAPIResponse(ctx, err)
+			return
+		}
+		ctx.Response.Header.SetContentType("application/x-yaml")
+		ctx.Response.SetBody(data)
+		return
+	}
+	renderSuccessfulResponse(ctx, rules)
+}
+
+// HandleListRuleIDs 列出所有规则的id、path与method
+func HandleListRuleIDs(ctx *fasthttp.RequestCtx, _ func(error)) {
+	summaries, err := application.MockApplication.ListRuleSummaries(context.TODO())
+	if err != nil {
+		renderFailedAPIResponse(ctx, err)
+		return
+	}
+	renderSuccessfulResponse(ctx, summaries)
 }
 
-// HandleImportRules 导入规则，将会清空目前所有规则
+// HandleListRules 分页查询规则，支持offset、limit分页以及method、path_contains筛选
+func HandleListRules(ctx *fasthttp.RequestCtx, _ func(error)) {
+	args := ctx.QueryArgs()
+	query := &types.RuleQueryDTO{
+		Method:       string(args.Peek("method")),
+		PathContains: string(args.Peek("path_contains")),
+	}
+	var err error
+	if query.Offset, err = parseIntArg(args, "offset"); err != nil {
+		renderFailedAPIResponse(ctx, err)
+		return
+	}
+	if query.Limit, err = parseIntArg(args, "limit"); err != nil {
+		renderFailedAPIResponse(ctx, err)
+		return
+	}
+
+	list, err := application.MockApplication.ListRules(context.TODO(), query)
+	if err != nil {
+		renderFailedAPIResponse(ctx, err)
+		return
+	}
+	renderSuccessfulResponse(ctx, list)
+}
+
+// parseIntArg 解析整数查询参数，参数不存在时返回0
+func parseIntArg(args *fasthttp.Args, key string) (int, error) {
+	value := args.Peek(key)
+	if len(value) == 0 {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(string(value))
+	if err != nil {
+		return 0, fmt.Errorf("bad %s: %s", key, value)
+	}
+	return n, nil
+}
+
+// HandleImportRules 导入规则，将会清空目前所有规则；validate_only=true时仅返回校验报告，不导入
 func HandleImportRules(ctx *fasthttp.RequestCtx, _ func(error)) {
 	var rules []*types.RuleDTO
-	if err := bindBody(ctx, &rules); err != nil {
+	bind := bindBody
+	if isYAMLFormat(ctx) {
+		bind = bindYAMLBody
+	}
+	if err := bind(ctx, &rules); err != nil {
+		return
+	}
+
+	if ctx.QueryArgs().GetBool("validate_only") {
+		renderSuccessfulResponse(ctx, application.MockApplication.ValidateImport(context.TODO(), rules...))
 		return
 	}
 
 	err := application.MockApplication.Import(context.TODO(), rules...)
 	if err != nil {
-		renderFailedAPIResponse(&ctx.Response, err)
+		renderFailedAPIResponse(ctx, err)
+		return
+	}
+	renderSuccessfulResponse(ctx, nil)
+}
+
+// HandleGetRequestCount 获取启动以来收到的mock请求总数，包括未匹配任何规则的请求
+func HandleGetRequestCount(ctx *fasthttp.RequestCtx, _ func(error)) {
+	renderSuccessfulResponse(ctx, application.MockApplication.RequestCount(context.TODO()))
+}
+
+// HandleGetRequestLog 获取最近收到的mock请求记录，用于排查筛选规则未命中的问题
+func HandleGetRequestLog(ctx *fasthttp.RequestCtx, _ func(error)) {
+	renderSuccessfulResponse(ctx, application.MockApplication.RequestLog(context.TODO()))
+}
+
+// HandleGetHits 获取所有规则及其报文规则的命中次数
+func HandleGetHits(ctx *fasthttp.RequestCtx, _ func(error)) {
+	renderSuccessfulResponse(ctx, application.MockApplication.Hits(context.TODO()))
+}
+
+// HandleResetSequence 根据rule id重置规则中顺序响应的进度
+func HandleResetSequence(ctx *fasthttp.RequestCtx, _ func(error)) {
+	res := new(types.RuleDTO)
+	if err := bindBody(ctx, res); err != nil {
+		return
+	}
+
+	if err := application.MockApplication.ResetSequence(context.TODO(), res.ID); err != nil {
+		renderFailedAPIResponse(ctx, err)
+		return
+	}
+	renderSuccessfulResponse(ctx, nil)
+}
+
+// HandleTraceRule 使用模拟的请求评估规则的筛选条件，返回每个条件是否通过以及最终命中的报文规则
+func HandleTraceRule(ctx *fasthttp.RequestCtx, _ func(error)) {
+	req := new(types.TraceRuleDTO)
+	if err := bindBody(ctx, req); err != nil {
+		return
+	}
+
+	trace, err := application.MockApplication.TraceRule(context.TODO(), req)
+	if err != nil {
+		renderFailedAPIResponse(ctx, err)
+		return
+	}
+	renderSuccessfulResponse(ctx, trace)
+}
+
+// HandleRenderTemplate 使用模拟的请求上下文试渲染响应模板
+func HandleRenderTemplate(ctx *fasthttp.RequestCtx, _ func(error)) {
+	req := new(types.RenderTemplateDTO)
+	if err := bindBody(ctx, req); err != nil {
+		return
+	}
+
+	rendered, err := application.MockApplication.RenderTemplate(context.TODO(), req)
+	if err != nil {
+		renderFailedAPIResponse(ctx, err)
+		return
+	}
+	renderSuccessfulResponse(ctx, rendered)
+}
+
+// HandleCompileTemplate 使用当前注册的模板函数编译测试模板
+func HandleCompileTemplate(ctx *fasthttp.RequestCtx, _ func(error)) {
+	req := new(types.CompileTemplateDTO)
+	if err := bindBody(ctx, req); err != nil {
+		return
+	}
+
+	compiled, err := application.MockApplication.CompileTemplate(context.TODO(), req)
+	if err != nil {
+		renderFailedAPIResponse(ctx, err)
 		return
 	}
-	renderSuccessfulResponse(&ctx.Response, nil)
+	renderSuccessfulResponse(ctx, compiled)
+}
+
+// HandleListTemplateFuncs 列出已注册的模板函数，便于确认当前部署支持哪些模板函数
+func HandleListTemplateFuncs(ctx *fasthttp.RequestCtx, _ func(error)) {
+	renderSuccessfulResponse(ctx, application.MockApplication.ListTemplateFuncs(context.TODO()))
+}
+
+// HandleInspectTemplateFuncs 检查模板中引用的函数是否都已注册
+func HandleInspectTemplateFuncs(ctx *fasthttp.RequestCtx, _ func(error)) {
+	req := new(types.InspectTemplateFuncsDTO)
+	if err := bindBody(ctx, req); err != nil {
+		return
+	}
+
+	report, err := application.MockApplication.InspectTemplateFuncs(context.TODO(), req)
+	if err != nil {
+		renderFailedAPIResponse(ctx, err)
+		return
+	}
+	renderSuccessfulResponse(ctx, report)
+}
+
+// HandleGetRecord 查看是否处于录制模式
+func HandleGetRecord(ctx *fasthttp.RequestCtx, _ func(error)) {
+	renderSuccessfulResponse(ctx, &types.RecordDTO{Enabled: application.MockApplication.Recording(context.TODO())})
+}
+
+// HandleSetRecord 开启或关闭录制模式
+func HandleSetRecord(ctx *fasthttp.RequestCtx, _ func(error)) {
+	record := new(types.RecordDTO)
+	if err := bindBody(ctx, record); err != nil {
+		return
+	}
+
+	if err := application.MockApplication.SetRecording(context.TODO(), record.Enabled); err != nil {
+		renderFailedAPIResponse(ctx, err)
+		return
+	}
+	renderSuccessfulResponse(ctx, record)
+}
+
+// HandleMetrics 以prometheus文本格式输出监控指标
+func HandleMetrics(ctx *fasthttp.RequestCtx, _ func(error)) {
+	metricsHandler(ctx)
+}
+
+// HandleHealth 供容器编排使用的存活检查接口，返回运行时长、已载入的规则数以及版本号
+func HandleHealth(ctx *fasthttp.RequestCtx, _ func(error)) {
+	renderSuccessfulResponse(ctx, application.MockApplication.Health(context.TODO()))
 }
 
 // HandleAPIVersion 健康检查用途
 func HandleAPIVersion(ctx *fasthttp.RequestCtx, _ func(error)) {
-	renderSuccessfulResponse(&ctx.Response, "1.0")
+	renderSuccessfulResponse(ctx, "1.0")
 }
 
 func bindBody(ctx *fasthttp.RequestCtx, v interface{}) error {
@@ -167,26 +364,84 @@ func bindBody(ctx *fasthttp.RequestCtx, v interface{}) error {
 		res := new(types.CommonResponseDTO)
 		res.Code = fasthttp.StatusBadRequest
 		res.ErrorMessage = err.Error()
-		data, _ := json.Marshal(res)
-		ctx.SetBody(data)
+		ctx.SetBody(marshalResponse(ctx, res))
 		return err
 	}
 	return nil
 }
 
-func renderSuccessfulResponse(resp *fasthttp.Response, v interface{}) {
+// isYAMLFormat 请求是否使用YAML格式：format=yaml或者Content-Type为YAML
+func isYAMLFormat(ctx *fasthttp.RequestCtx) bool {
+	if format := string(ctx.QueryArgs().Peek("format")); format != "" {
+		return format == "yaml"
+	}
+	contentType := ctx.Request.Header.ContentType()
+	return bytes.Contains(contentType, []byte("yaml"))
+}
+
+// bindYAMLBody 解析YAML格式的请求body，先转换成JSON再按照JSON的方式绑定，保证字段与校验规则一致
+func bindYAMLBody(ctx *fasthttp.RequestCtx, v interface{}) error {
+	var doc interface{}
+	err := yaml.Unmarshal(ctx.Request.Body(), &doc)
+	if err == nil {
+		var data []byte
+		if data, err = json.Marshal(normalizeYAML(doc)); err == nil {
+			ctx.Request.SetBody(data)
+			return bindBody(ctx, v)
+		}
+	}
+
+	misc.Logger.Error("failed to parse yaml request body", zap.ByteString("path", ctx.Request.URI().Path()), zap.ByteString("method", ctx.Request.Header.Method()), zap.Error(err))
+	ctx.Response.Header.SetContentType("application/json")
+	ctx.Response.SetStatusCode(fasthttp.StatusOK)
+	res := &types.CommonResponseDTO{Code: fasthttp.StatusBadRequest, ErrorMessage: err.Error()}
+	ctx.SetBody(marshalResponse(ctx, res))
+	return err
+}
+
+// normalizeYAML 将YAML解析出的map[interface{}]interface{}递归转换为map[string]interface{}
+func normalizeYAML(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[fmt.Sprint(k)] = normalizeYAML(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range val {
+			val[i] = normalizeYAML(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// marshalResponse 序列化管理接口的响应，请求带有pretty=1时输出缩进格式的JSON
+func marshalResponse(ctx *fasthttp.RequestCtx, res *types.CommonResponseDTO) []byte {
+	if ctx.QueryArgs().GetBool("pretty") {
+		data, _ := json.MarshalIndent(res, "", "    ")
+		return data
+	}
+	data, _ := json.Marshal(res)
+	return data
+}
+
+func renderSuccessfulResponse(ctx *fasthttp.RequestCtx, v interface{}) {
 	res := &types.CommonResponseDTO{
 		Code: http.StatusOK,
 		Data: v,
 	}
-	data, _ := json.Marshal(res)
-	resp.Header.SetContentType("application/json")
-	resp.SetBody(data)
+	ctx.Response.Header.SetContentType("application/json")
+	ctx.Response.SetBody(marshalResponse(ctx, res))
 }
 
-func renderFailedAPIResponse(resp *fasthttp.Response, err error) {
+func renderFailedAPIResponse(ctx *fasthttp.RequestCtx, err error) {
 	res := &types.CommonResponseDTO{Code: http.StatusBadRequest, ErrorMessage: err.Error()}
-	data, _ := json.Marshal(res)
-	resp.Header.SetContentType("application/json")
-	resp.SetBody(data)
+	if errors.Is(err, domain.ErrVersionConflict) || errors.Is(err, domain.ErrRuleConflict) {
+		res.Code = http.StatusConflict
+	}
+	ctx.Response.Header.SetContentType("application/json")
+	ctx.Response.SetBody(marshalResponse(ctx, res))
 }
@@ -1,14 +1,2160 @@
 package api
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io/ioutil"
+	"mime/multipart"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"github.com/wosai/deepmock/application"
+	"github.com/wosai/deepmock/domain"
+	"github.com/wosai/deepmock/infrastructure"
+	"github.com/wosai/deepmock/misc"
+	"github.com/wosai/deepmock/option"
+	"github.com/wosai/deepmock/types"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
+type (
+	memRuleRepository struct {
+		rules map[string]*domain.Rule
+		mu    sync.RWMutex
+	}
+
+	idleJob struct{}
+)
+
+func (m *memRuleRepository) CreateRule(_ context.Context, rule *domain.Rule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.rules[rule.ID]; exists {
+		return errors.New("duplicate rule id: " + rule.ID)
+	}
+	m.rules[rule.ID] = rule
+	return nil
+}
+
+func (m *memRuleRepository) UpdateRule(_ context.Context, rule *domain.Rule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rules[rule.ID] = rule
+	return nil
+}
+
+func (m *memRuleRepository) GetRuleByID(_ context.Context, rid string) (*domain.Rule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rule, exists := m.rules[rid]
+	if !exists {
+		return nil, errors.New("cannot find rule by id: " + rid)
+	}
+	return rule, nil
+}
+
+func (m *memRuleRepository) DeleteRule(_ context.Context, rid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.rules, rid)
+	return nil
+}
+
+func (m *memRuleRepository) Export(_ context.Context) ([]*domain.Rule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rules := make([]*domain.Rule, 0, len(m.rules))
+	for _, rule := range m.rules {
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (m *memRuleRepository) Import(_ context.Context, rules ...*domain.Rule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, rule := range rules {
+		m.rules[rule.ID] = rule
+	}
+	return nil
+}
+
+func (idleJob) Period() time.Duration                            { return time.Hour }
+func (idleJob) Do() error                                        { return nil }
+func (idleJob) WithRuleRepository(domain.RuleRepository)         {}
+func (idleJob) WithExecutorRepository(domain.ExecutorRepository) {}
+
+// setupMockApplication 使用内存存储库初始化MockApplication，并同步执行器
+func setupMockApplication(t *testing.T, opt option.MockOption, rules ...*types.RuleDTO) (*memRuleRepository, *infrastructure.ExecutorRepository) {
+	rr := &memRuleRepository{rules: make(map[string]*domain.Rule)}
+	er := infrastructure.NewExecutorRepository(100)
+	if application.MockApplication != nil {
+		application.MockApplication.Close() // 停止上一个测试的后台任务
+	}
+	application.BuildMockApplication(rr, er, idleJob{}, opt)
+
+	for _, rule := range rules {
+		_, err := application.MockApplication.CreateRule(context.TODO(), rule)
+		assert.NoError(t, err)
+	}
+	syncExecutors(t, rr, er)
+	return rr, er
+}
+
+func syncExecutors(t *testing.T, rr *memRuleRepository, er *infrastructure.ExecutorRepository) {
+	rules, _ := rr.Export(context.TODO())
+	executors := make([]*domain.Executor, len(rules))
+	for index, rule := range rules {
+		executor, err := rule.To()
+		assert.NoError(t, err)
+		executors[index] = executor
+	}
+	er.ImportAll(context.TODO(), executors...)
+}
+
+func newRequestCtx(method, uri string, body []byte) *fasthttp.RequestCtx {
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetMethod(method)
+	ctx.Request.SetRequestURI(uri)
+	ctx.Request.SetBody(body)
+	return ctx
+}
+
 func TestParsePathVar(t *testing.T) {
 	path := []byte("/api/v1/rule")
 	uri := []byte("/api/v1/rule/123")
 
 	assert.Equal(t, parsePathVar(path, uri), "123")
 }
+
+func TestHandleGetRequestLog(t *testing.T) {
+	setupMockApplication(t, option.MockOption{RequestLogSize: 2}, &types.RuleDTO{
+		Path:   "/whoami",
+		Method: "post",
+		Regulations: []*types.RegulationDTO{
+			{
+				IsDefault: true,
+				Template:  &types.TemplateDTO{Body: `{"im": "deepmock"}`},
+			},
+		},
+	})
+
+	for _, path := range []string{"/not_found", "/whoami?name=foobar", "/whoami"} {
+		ctx := newRequestCtx("POST", path, []byte(`{"hello":"deepmock"}`))
+		ctx.Request.Header.Set("X-Trace-Id", "123")
+		HandleMockedAPI(ctx, nil)
+	}
+
+	ctx := newRequestCtx("GET", "/api/v1/requests", nil)
+	HandleGetRequestLog(ctx, nil)
+
+	res := new(struct {
+		Code int                    `json:"code"`
+		Data []*types.RequestLogDTO `json:"data"`
+	})
+	assert.NoError(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.Equal(t, 200, res.Code)
+	assert.Len(t, res.Data, 2) // 最早的请求已被覆盖
+
+	assert.Equal(t, "/whoami", res.Data[0].Path)
+	assert.Equal(t, "name=foobar", res.Data[0].Query)
+	assert.Equal(t, "POST", res.Data[0].Method)
+	assert.Equal(t, `{"hello":"deepmock"}`, res.Data[0].Body)
+	assert.Equal(t, "123", res.Data[0].Header["X-Trace-Id"])
+	assert.NotEmpty(t, res.Data[0].RuleID)
+	assert.Equal(t, 0, res.Data[0].Regulation)
+	assert.Equal(t, "/whoami", res.Data[1].Path)
+}
+
+func TestHandleGetRequestCount(t *testing.T) {
+	setupMockApplication(t, option.MockOption{RequestLogSize: 3}, &types.RuleDTO{
+		Path:        "/count",
+		Method:      "get",
+		Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "counted"}}},
+	})
+
+	count := func() *types.RequestCountDTO {
+		ctx := newRequestCtx("GET", "/api/v1/requests/count", nil)
+		HandleGetRequestCount(ctx, nil)
+		res := new(struct {
+			Code int                    `json:"code"`
+			Data *types.RequestCountDTO `json:"data"`
+		})
+		assert.NoError(t, json.Unmarshal(ctx.Response.Body(), res))
+		assert.Equal(t, 200, res.Code)
+		return res.Data
+	}
+	assert.Equal(t, &types.RequestCountDTO{}, count())
+
+	for _, path := range []string{"/count", "/not_found", "/count", "/count?a=1", "/missing"} {
+		HandleMockedAPI(newRequestCtx("GET", path, nil), nil)
+	}
+	// 计数不受请求记录条数的限制
+	assert.Equal(t, &types.RequestCountDTO{Total: 5, Unmatched: 2}, count())
+
+	// 解码失败的请求在匹配规则之前就被拒绝，只计入总数
+	ctx := newRequestCtx("POST", "/count", []byte("not gzip"))
+	ctx.Request.Header.Set(fasthttp.HeaderContentEncoding, "gzip")
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode())
+	assert.Equal(t, &types.RequestCountDTO{Total: 6, Unmatched: 2}, count())
+
+	ctx = newRequestCtx("GET", "/api/v1/requests", nil)
+	HandleGetRequestLog(ctx, nil)
+	res := new(struct {
+		Data []*types.RequestLogDTO `json:"data"`
+	})
+	assert.NoError(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.Len(t, res.Data, 3)
+	assert.Equal(t, []string{"/count", "/missing", "/count"}, []string{res.Data[0].Path, res.Data[1].Path, res.Data[2].Path})
+	assert.Empty(t, res.Data[2].RuleID)
+}
+
+func TestHandleGetRequestLog_Unmatched(t *testing.T) {
+	setupMockApplication(t, option.MockOption{RequestLogSize: 10})
+
+	HandleMockedAPI(newRequestCtx("GET", "/not_found", nil), nil)
+	ctx := newRequestCtx("GET", "/api/v1/requests", nil)
+	HandleGetRequestLog(ctx, nil)
+
+	res := new(struct {
+		Data []*types.RequestLogDTO `json:"data"`
+	})
+	assert.NoError(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.Len(t, res.Data, 1)
+	assert.Empty(t, res.Data[0].RuleID)
+	assert.Equal(t, -1, res.Data[0].Regulation)
+}
+
+// scrapeMetric 从prometheus文本格式的输出中读取指定指标的值，不存在时返回0
+func scrapeMetric(t *testing.T, series string) float64 {
+	ctx := newRequestCtx("GET", "/api/metrics", nil)
+	HandleMetrics(ctx, nil)
+
+	scanner := bufio.NewScanner(bytes.NewReader(ctx.Response.Body()))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, series+" ") {
+			v, err := strconv.ParseFloat(strings.TrimPrefix(line, series+" "), 64)
+			assert.NoError(t, err)
+			return v
+		}
+	}
+	return 0
+}
+
+func TestHandleMetrics(t *testing.T) {
+	setupMockApplication(t, option.MockOption{}, &types.RuleDTO{
+		Path:   "/metrics/whoami",
+		Method: "get",
+		Regulations: []*types.RegulationDTO{
+			{
+				IsDefault: true,
+				Template:  &types.TemplateDTO{Body: `{"im": "deepmock"}`},
+			},
+		},
+	})
+	rid, _ := (&domain.Rule{Path: "/metrics/whoami", Method: "GET"}).SupplyID()
+	requests := `deepmock_mocked_requests_total{rule_id="` + rid + `",status_code="200"}`
+	matched := `deepmock_rule_match_total{result="matched"}`
+	unmatched := `deepmock_rule_match_total{result="unmatched"}`
+
+	before := scrapeMetric(t, requests)
+	beforeMatched := scrapeMetric(t, matched)
+	beforeUnmatched := scrapeMetric(t, unmatched)
+
+	HandleMockedAPI(newRequestCtx("GET", "/metrics/whoami", nil), nil)
+	HandleMockedAPI(newRequestCtx("GET", "/metrics/whoami", nil), nil)
+	HandleMockedAPI(newRequestCtx("GET", "/metrics/nobody", nil), nil)
+
+	assert.Equal(t, before+2, scrapeMetric(t, requests))
+	assert.Equal(t, beforeMatched+2, scrapeMetric(t, matched))
+	assert.Equal(t, beforeUnmatched+1, scrapeMetric(t, unmatched))
+	assert.Equal(t, float64(2), scrapeMetric(t, `deepmock_render_duration_seconds_count{rule_id="`+rid+`"}`))
+}
+
+// observeLogger 将全局Logger替换为可观察的Logger，返回恢复函数
+func observeLogger() (*observer.ObservedLogs, func()) {
+	core, logs := observer.New(zap.InfoLevel)
+	origin := misc.Logger
+	misc.Logger = zap.New(core)
+	return logs, func() { misc.Logger = origin }
+}
+
+func debugRuleDTO(path string, debug bool) *types.RuleDTO {
+	return &types.RuleDTO{
+		Path:   path,
+		Method: "get",
+		Debug:  debug,
+		Regulations: []*types.RegulationDTO{
+			{
+				Filter:   &types.FilterDTO{Header: map[string]string{"mode": "exact", "X-Env": "prod"}},
+				Template: &types.TemplateDTO{Body: "prod"},
+			},
+			{
+				Filter:   &types.FilterDTO{Query: map[string]string{"mode": "exact", "version": "2"}},
+				Template: &types.TemplateDTO{Body: "v2"},
+			},
+			{
+				IsDefault: true,
+				Template:  &types.TemplateDTO{Body: "default"},
+			},
+		},
+	}
+}
+
+func TestHandleMockedAPI_Debug(t *testing.T) {
+	setupMockApplication(t, option.MockOption{}, debugRuleDTO("/debug/on", true), debugRuleDTO("/debug/off", false))
+	logs, restore := observeLogger()
+	defer restore()
+
+	ctx := newRequestCtx("GET", "/debug/on?version=2", nil)
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, "v2", string(ctx.Response.Body()))
+
+	entries := logs.FilterMessage("debug regulation matching").All()
+	assert.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.EqualValues(t, 1, fields["regulation"])
+	assert.Equal(t, false, fields["is_default"])
+	assert.Equal(t, map[int]string{0: "header"}, fields["skipped"])
+	assert.NotEmpty(t, fields["rule_id"])
+
+	HandleMockedAPI(newRequestCtx("GET", "/debug/on", nil), nil)
+	entries = logs.FilterMessage("debug regulation matching").All()
+	assert.Len(t, entries, 2)
+	fields = entries[1].ContextMap()
+	assert.EqualValues(t, 2, fields["regulation"])
+	assert.Equal(t, true, fields["is_default"])
+	assert.Equal(t, map[int]string{0: "header", 1: "query"}, fields["skipped"])
+
+	HandleMockedAPI(newRequestCtx("GET", "/debug/off?version=2", nil), nil)
+	assert.Len(t, logs.FilterMessage("debug regulation matching").All(), 2)
+}
+
+func TestHandleGetHits(t *testing.T) {
+	setupMockApplication(t, option.MockOption{}, &types.RuleDTO{
+		Path:   "/hits",
+		Method: "get",
+		Regulations: []*types.RegulationDTO{
+			{
+				Filter:   &types.FilterDTO{Query: map[string]string{"mode": "exact", "version": "2"}},
+				Template: &types.TemplateDTO{Body: "v2"},
+			},
+			{
+				IsDefault: true,
+				Template:  &types.TemplateDTO{Body: "default"},
+			},
+		},
+	}, &types.RuleDTO{
+		Path:   "/hits/idle",
+		Method: "get",
+		Regulations: []*types.RegulationDTO{
+			{IsDefault: true, Template: &types.TemplateDTO{Body: "idle"}},
+		},
+	})
+
+	for i := 0; i < 5; i++ {
+		HandleMockedAPI(newRequestCtx("GET", "/hits", nil), nil)
+	}
+	for i := 0; i < 3; i++ {
+		HandleMockedAPI(newRequestCtx("GET", "/hits?version=2", nil), nil)
+	}
+
+	ctx := newRequestCtx("GET", "/api/v1/hits", nil)
+	HandleGetHits(ctx, nil)
+	res := new(struct {
+		Data []*types.RuleHitsDTO `json:"data"`
+	})
+	assert.NoError(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.Len(t, res.Data, 2)
+
+	hits := make(map[string]*types.RuleHitsDTO)
+	for _, h := range res.Data {
+		hits[h.Path] = h
+	}
+	assert.EqualValues(t, 8, hits["/hits"].Total)
+	assert.Equal(t, "GET", hits["/hits"].Method)
+	assert.NotNil(t, hits["/hits"].LastHit)
+	assert.Len(t, hits["/hits"].Regulations, 2)
+	assert.EqualValues(t, 3, hits["/hits"].Regulations[0].Total)
+	assert.EqualValues(t, 5, hits["/hits"].Regulations[1].Total)
+
+	assert.EqualValues(t, 0, hits["/hits/idle"].Total)
+	assert.Nil(t, hits["/hits/idle"].LastHit)
+}
+
+// TestHandleMockedAPI_ConcurrentCreate 并发创建、更新、删除规则的同时处理mock请求，需要配合 go test -race 运行
+func TestHandleMockedAPI_ConcurrentCreate(t *testing.T) {
+	rr, er := setupMockApplication(t, option.MockOption{}, &types.RuleDTO{
+		Path:        "/concurrent/0",
+		Method:      "get",
+		Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "0"}}},
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		rids := make([]string, 51)
+		for i := 1; i <= 50; i++ {
+			rid, err := application.MockApplication.CreateRule(context.TODO(), &types.RuleDTO{
+				Path:        "/concurrent/" + strconv.Itoa(i) + "/detail",
+				Method:      "get",
+				Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: strconv.Itoa(i)}}},
+			})
+			assert.NoError(t, err)
+			rids[i] = rid
+			if i > 1 {
+				assert.NoError(t, application.MockApplication.PatchRule(context.TODO(), &types.RuleDTO{
+					ID:          rids[i-1],
+					Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "patched"}}},
+				}))
+			}
+			if i%10 == 0 {
+				assert.NoError(t, application.MockApplication.DeleteRule(context.TODO(), rids[i-5]))
+			}
+			syncExecutors(t, rr, er)
+		}
+	}()
+
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				ctx := newRequestCtx("GET", "/concurrent/0", nil)
+				HandleMockedAPI(ctx, nil)
+				assert.Equal(t, "0", string(ctx.Response.Body()))
+
+				HandleMockedAPI(newRequestCtx("GET", "/concurrent/"+strconv.Itoa(i%50+1)+"/detail", nil), nil)
+				HandleGetHits(newRequestCtx("GET", "/api/v1/hits", nil), nil)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	ctx := newRequestCtx("GET", "/concurrent/50/detail", nil)
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, "50", string(ctx.Response.Body()))
+	ctx = newRequestCtx("GET", "/concurrent/49/detail", nil)
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, "patched", string(ctx.Response.Body()))
+	assert.Error(t, application.MockApplication.MockAPI(newRequestCtx("GET", "/concurrent/45/detail", nil)))
+}
+
+func TestHandleResetSequence(t *testing.T) {
+	setupMockApplication(t, option.MockOption{}, &types.RuleDTO{
+		Path:   "/sequence",
+		Method: "get",
+		Regulations: []*types.RegulationDTO{
+			{
+				IsDefault: true,
+				Sequence: &types.SequenceDTO{Responses: []*types.TemplateDTO{
+					{StatusCode: fasthttp.StatusAccepted, Body: "processing"},
+					{Body: "done"},
+				}},
+			},
+		},
+	})
+
+	call := func() (int, string) {
+		ctx := newRequestCtx("GET", "/sequence", nil)
+		HandleMockedAPI(ctx, nil)
+		return ctx.Response.StatusCode(), string(ctx.Response.Body())
+	}
+	for _, expected := range []string{"processing", "done", "done"} {
+		_, body := call()
+		assert.Equal(t, expected, body)
+	}
+
+	rule, err := application.MockApplication.Export(context.TODO())
+	assert.NoError(t, err)
+	assert.Len(t, rule[0].Regulations[0].Sequence.Responses, 2)
+
+	ctx := newRequestCtx("POST", "/api/v1/sequence/reset", []byte(`{"id": "`+rule[0].ID+`"}`))
+	HandleResetSequence(ctx, nil)
+	res := new(types.CommonResponseDTO)
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.Equal(t, fasthttp.StatusOK, res.Code)
+
+	status, body := call()
+	assert.Equal(t, fasthttp.StatusAccepted, status)
+	assert.Equal(t, "processing", body)
+
+	ctx = newRequestCtx("POST", "/api/v1/sequence/reset", []byte(`{"id": "not-exists"}`))
+	HandleResetSequence(ctx, nil)
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.Equal(t, fasthttp.StatusBadRequest, res.Code)
+}
+
+func TestHandleTraceRule(t *testing.T) {
+	setupMockApplication(t, option.MockOption{}, &types.RuleDTO{
+		Path:   "/trace",
+		Method: "post",
+		Regulations: []*types.RegulationDTO{
+			{
+				Filter: &types.FilterDTO{
+					Header: map[string]string{"mode": "exact", "X-Env": "test"},
+					Query:  map[string]string{"mode": "keyword", "name": "ali"},
+					Body:   map[string]string{"mode": "keyword", "keyword": "vip"},
+				},
+				Template: &types.TemplateDTO{Body: "vip"},
+			},
+			{IsDefault: true, Template: &types.TemplateDTO{Body: "default"}},
+		},
+	})
+	rule, err := application.MockApplication.Export(context.TODO())
+	assert.NoError(t, err)
+
+	trace := func(body string) (int, *types.RuleTraceDTO) {
+		ctx := newRequestCtx("POST", "/api/v1/rule/trace", []byte(body))
+		HandleTraceRule(ctx, nil)
+		res := &struct {
+			Code int                 `json:"code"`
+			Data *types.RuleTraceDTO `json:"data"`
+		}{}
+		assert.NoError(t, json.Unmarshal(ctx.Response.Body(), res))
+		return res.Code, res.Data
+	}
+
+	// 请求头通过，query与body未通过，命中默认的报文规则
+	code, data := trace(`{"id": "` + rule[0].ID + `", "path": "/trace", "query": "name=bob", "header": {"X-Env": "test"}, "body": "normal"}`)
+	assert.Equal(t, fasthttp.StatusOK, code)
+	assert.Equal(t, rule[0].ID, data.RuleID)
+	assert.Equal(t, 1, data.Regulation)
+	assert.True(t, data.IsDefault)
+	assert.Len(t, data.Regulations, 2)
+	assert.False(t, data.Regulations[0].Passed)
+	assert.Equal(t, []*types.FilterTraceDTO{
+		{Filter: "header", Key: "X-Env", Passed: true},
+		{Filter: "query", Key: "name", Passed: false},
+		{Filter: "body", Passed: false},
+	}, data.Regulations[0].Filters)
+	assert.True(t, data.Regulations[1].Passed)
+	assert.Empty(t, data.Regulations[1].Filters)
+
+	// 全部通过时命中第一个报文规则
+	code, data = trace(`{"id": "` + rule[0].ID + `", "query": "name=alice", "header": {"X-Env": "test"}, "body": "vip user"}`)
+	assert.Equal(t, fasthttp.StatusOK, code)
+	assert.Equal(t, 0, data.Regulation)
+	assert.False(t, data.IsDefault)
+	assert.True(t, data.Regulations[0].Passed)
+	for _, filter := range data.Regulations[0].Filters {
+		assert.True(t, filter.Passed, filter.Filter)
+	}
+
+	// 模拟请求不计入命中次数
+	hits := application.MockApplication.Hits(context.TODO())
+	assert.Equal(t, uint64(0), hits[0].Total)
+
+	code, _ = trace(`{"id": "not-exists"}`)
+	assert.Equal(t, fasthttp.StatusBadRequest, code)
+}
+
+func TestHandleTraceRule_DefaultPath(t *testing.T) {
+	setupMockApplication(t, option.MockOption{}, &types.RuleDTO{
+		Path:   "/trace/order/detail",
+		Method: "get",
+		Regulations: []*types.RegulationDTO{
+			{Filter: &types.FilterDTO{PathSegments: &types.PathSegmentsFilterDTO{Exact: 3}}, Template: &types.TemplateDTO{Body: "detail"}},
+			{IsDefault: true, Template: &types.TemplateDTO{Body: "default"}},
+		},
+	})
+	rule, err := application.MockApplication.Export(context.TODO())
+	assert.NoError(t, err)
+
+	trace := func(body string) *types.RuleTraceDTO {
+		ctx := newRequestCtx("POST", "/api/v1/rule/trace", []byte(body))
+		HandleTraceRule(ctx, nil)
+		res := &struct {
+			Data *types.RuleTraceDTO `json:"data"`
+		}{}
+		assert.NoError(t, json.Unmarshal(ctx.Response.Body(), res))
+		return res.Data
+	}
+
+	// 未提供path时按规则的path评估
+	data := trace(`{"id": "` + rule[0].ID + `"}`)
+	assert.Equal(t, 0, data.Regulation)
+	assert.Equal(t, []*types.FilterTraceDTO{{Filter: "path_segments", Passed: true}}, data.Regulations[0].Filters)
+
+	data = trace(`{"id": "` + rule[0].ID + `", "path": "/trace"}`)
+	assert.Equal(t, 1, data.Regulation)
+	assert.Equal(t, []*types.FilterTraceDTO{{Filter: "path_segments", Passed: false}}, data.Regulations[0].Filters)
+}
+
+func TestHandleMockedAPI_Retry(t *testing.T) {
+	setupMockApplication(t, option.MockOption{}, &types.RuleDTO{
+		Path:   "/retry",
+		Method: "post",
+		Regulations: []*types.RegulationDTO{
+			{
+				IsDefault: true,
+				Template:  &types.TemplateDTO{Body: "created"},
+				Retry: &types.RetryDTO{Responses: []*types.TemplateDTO{
+					{Body: "unavailable"},
+					{StatusCode: fasthttp.StatusGatewayTimeout, Body: "timeout"},
+				}},
+			},
+		},
+	})
+
+	cases := []struct {
+		retry  string
+		status int
+		body   string
+	}{
+		{"", fasthttp.StatusServiceUnavailable, "unavailable"},
+		{"1", fasthttp.StatusGatewayTimeout, "timeout"},
+		{"2", fasthttp.StatusOK, "created"},
+		{"0", fasthttp.StatusServiceUnavailable, "unavailable"},
+	}
+	for _, c := range cases {
+		ctx := newRequestCtx("POST", "/retry", nil)
+		if c.retry != "" {
+			ctx.Request.Header.Set("X-Retry", c.retry)
+		}
+		HandleMockedAPI(ctx, nil)
+		assert.Equal(t, c.status, ctx.Response.StatusCode(), c.retry)
+		assert.Equal(t, c.body, string(ctx.Response.Body()), c.retry)
+	}
+
+	rule, err := application.MockApplication.Export(context.TODO())
+	assert.NoError(t, err)
+	assert.Len(t, rule[0].Regulations[0].Retry.Responses, 2)
+	assert.Zero(t, rule[0].Regulations[0].Retry.Responses[0].StatusCode)
+}
+
+func TestHandleMockedAPI_Created(t *testing.T) {
+	setupMockApplication(t, option.MockOption{}, &types.RuleDTO{
+		Path:   "/users",
+		Method: "post",
+		Regulations: []*types.RegulationDTO{
+			{IsDefault: true, Created: &types.CreatedDTO{Location: "/users/{id}", IDField: "user_id"}},
+		},
+	})
+
+	ctx := newRequestCtx("POST", "/users", []byte(`{"name": "deepmock"}`))
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, fasthttp.StatusCreated, ctx.Response.StatusCode())
+	resource := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), &resource))
+	assert.Equal(t, "deepmock", resource["name"])
+	assert.NotEmpty(t, resource["user_id"])
+	assert.Equal(t, "/users/"+resource["user_id"].(string), string(ctx.Response.Header.Peek("Location")))
+
+	rule, err := application.MockApplication.Export(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, &types.CreatedDTO{Location: "/users/{id}", IDField: "user_id"}, rule[0].Regulations[0].Created)
+}
+
+func TestHandleMockedAPI_ResponseSchema(t *testing.T) {
+	schema := map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"id"},
+		"properties": map[string]interface{}{
+			"id": map[string]interface{}{"type": "integer"},
+		},
+	}
+	regulations := []*types.RegulationDTO{
+		{IsDefault: true, Template: &types.TemplateDTO{IsTemplate: true, Body: `{"id": "{{.Query.id}}"}`}},
+	}
+	setupMockApplication(t, option.MockOption{},
+		&types.RuleDTO{Path: "/debug/orders", Method: "get", Debug: true, ResponseSchema: schema, Regulations: regulations},
+		&types.RuleDTO{Path: "/release/orders", Method: "get", ResponseSchema: schema, Regulations: regulations},
+	)
+
+	ctx := newRequestCtx("GET", "/debug/orders?id=1", nil)
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode())
+	assert.Equal(t, "response violates schema: $.id: expected integer but got string", string(ctx.Response.Body()))
+
+	// 非调试模式下不校验
+	ctx = newRequestCtx("GET", "/release/orders?id=1", nil)
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+	assert.Equal(t, `{"id": "1"}`, string(ctx.Response.Body()))
+
+	rule, err := application.MockApplication.Export(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, "object", rule[0].ResponseSchema["type"])
+}
+
+func TestHandleCreateRule_BadResponseSchema(t *testing.T) {
+	setupMockApplication(t, option.MockOption{})
+
+	body := []byte(`{"path": "/schemas", "method": "GET", "response_schema": {"type": "decimal"}, "responses": [{"is_default": true, "response": {"body": "{}"}}]}`)
+	ctx := newRequestCtx("POST", "/api/v1/rule", body)
+	HandleCreateRule(ctx, nil)
+	res := new(types.CommonResponseDTO)
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.Equal(t, fasthttp.StatusBadRequest, res.Code)
+	assert.Contains(t, res.ErrorMessage, "bad response schema: bad schema at $: unsupported type decimal")
+}
+
+func TestHandleCreateRule_BadPath(t *testing.T) {
+	setupMockApplication(t, option.MockOption{})
+
+	body := []byte(`{"path": "/users/[0-9+", "method": "GET", "responses": [{"is_default": true, "response": {"body": "ok"}}]}`)
+	ctx := newRequestCtx("POST", "/api/v1/rule", body)
+	HandleCreateRule(ctx, nil)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+	res := new(types.CommonResponseDTO)
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.Equal(t, fasthttp.StatusBadRequest, res.Code)
+	assert.Contains(t, res.ErrorMessage, "invalid path regexp /users/[0-9+")
+	assert.Contains(t, res.ErrorMessage, "missing closing ]")
+}
+
+func TestHandleCreateRule_TemplateFuncs(t *testing.T) {
+	setupMockApplication(t, option.MockOption{TemplateFuncs: []string{"uuid"}})
+	defer domain.SetAllowedTemplateFuncs()
+
+	create := func(path, body string) *types.CommonResponseDTO {
+		rule := []byte(`{"path": "` + path + `", "method": "GET", "responses": [{"is_default": true, "response": {"is_template": true, "body": "` + body + `"}}]}`)
+		ctx := newRequestCtx("POST", "/api/v1/rule", rule)
+		HandleCreateRule(ctx, nil)
+		res := new(types.CommonResponseDTO)
+		assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+		return res
+	}
+
+	res := create("/funcs/denied", `{{timestamp \"ms\"}}`)
+	assert.Equal(t, fasthttp.StatusBadRequest, res.Code)
+	assert.Contains(t, res.ErrorMessage, "template func timestamp is not allowed")
+
+	res = create("/funcs/allowed", `{{uuid}} {{len .Query}}`)
+	assert.Equal(t, fasthttp.StatusOK, res.Code)
+}
+
+func TestHandleMockedAPI_BodyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deepmock-body")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(dir+"/items.json", []byte(`{"items": [1, 2, 3]}`), 0644))
+
+	setupMockApplication(t, option.MockOption{BodyFileDir: dir}, &types.RuleDTO{
+		Path:        "/body-file",
+		Method:      "get",
+		Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{BodyFile: "items.json"}}},
+	})
+	ctx := newRequestCtx("GET", "/body-file", nil)
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, `{"items": [1, 2, 3]}`, string(ctx.Response.Body()))
+
+	create := func(bodyFile string) *types.CommonResponseDTO {
+		body := []byte(`{"path": "/body-file/bad", "method": "GET", "responses": [{"is_default": true, "response": {"body_file": "` + bodyFile + `"}}]}`)
+		ctx := newRequestCtx("POST", "/api/v1/rule", body)
+		HandleCreateRule(ctx, nil)
+		res := new(types.CommonResponseDTO)
+		assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+		return res
+	}
+	res := create("items.json.missing")
+	assert.Equal(t, fasthttp.StatusBadRequest, res.Code)
+	assert.Contains(t, res.ErrorMessage, "failed to read body file")
+
+	// 不能读取body_file_dir之外的文件
+	for _, bodyFile := range []string{"/etc/passwd", "../../../../etc/passwd"} {
+		res = create(bodyFile)
+		assert.Equal(t, fasthttp.StatusBadRequest, res.Code)
+		assert.NotContains(t, res.ErrorMessage, "failed to read body file")
+	}
+}
+
+func TestHandleCreateRule_Conflict(t *testing.T) {
+	existing := &types.RuleDTO{
+		Path:        "/conflict/\\d+",
+		Method:      "get",
+		Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "any"}}},
+	}
+	create := func(path, method string) *types.CommonResponseDTO {
+		body := []byte(`{"path": "` + path + `", "method": "` + method + `", "responses": [{"is_default": true, "response": {"body": "one"}}]}`)
+		ctx := newRequestCtx("POST", "/api/v1/rule", body)
+		HandleCreateRule(ctx, nil)
+		res := new(types.CommonResponseDTO)
+		assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+		return res
+	}
+
+	setupMockApplication(t, option.MockOption{RuleConflict: option.RuleConflictReject}, existing)
+	rules, err := application.MockApplication.Export(context.TODO())
+	assert.NoError(t, err)
+
+	res := create("/conflict/1", "GET")
+	assert.Equal(t, fasthttp.StatusConflict, res.Code)
+	assert.Contains(t, res.ErrorMessage, rules[0].ID)
+
+	res = create("/conflict/1", "POST")
+	assert.Equal(t, fasthttp.StatusOK, res.Code)
+
+	setupMockApplication(t, option.MockOption{RuleConflict: option.RuleConflictWarn}, existing)
+	res = create("/conflict/1", "GET")
+	assert.Equal(t, fasthttp.StatusOK, res.Code)
+
+	setupMockApplication(t, option.MockOption{}, existing)
+	res = create("/conflict/1", "GET")
+	assert.Equal(t, fasthttp.StatusOK, res.Code)
+}
+
+func TestRenderResponse_Pretty(t *testing.T) {
+	setupMockApplication(t, option.MockOption{}, &types.RuleDTO{
+		Path:        "/pretty",
+		Method:      "get",
+		Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "ok"}}},
+	})
+
+	ctx := newRequestCtx("GET", "/api/v1/rules", nil)
+	HandleExportRules(ctx, nil)
+	assert.NotContains(t, string(ctx.Response.Body()), "\n")
+	compact := new(types.CommonResponseDTO)
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), compact))
+
+	ctx = newRequestCtx("GET", "/api/v1/rules?pretty=1", nil)
+	HandleExportRules(ctx, nil)
+	assert.True(t, strings.HasPrefix(string(ctx.Response.Body()), "{\n    \"code\": 200,\n"))
+	pretty := new(types.CommonResponseDTO)
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), pretty))
+	assert.Equal(t, compact, pretty)
+
+	rules := compact.Data.([]interface{})
+	id := rules[0].(map[string]interface{})["id"].(string)
+	ctx = newRequestCtx("GET", "/api/v1/rule/"+id+"?pretty=1", nil)
+	HandleGetRule(ctx, nil)
+	assert.Contains(t, string(ctx.Response.Body()), "\n    \"code\": 200,")
+
+	ctx = newRequestCtx("GET", "/api/v1/rule/not-exists?pretty=1", nil)
+	HandleGetRule(ctx, nil)
+	assert.Contains(t, string(ctx.Response.Body()), "\n    \"code\": 400,")
+}
+
+func TestHandleListRules(t *testing.T) {
+	newRule := func(path, method string) *types.RuleDTO {
+		return &types.RuleDTO{
+			Path:        path,
+			Method:      method,
+			Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "ok"}}},
+		}
+	}
+	setupMockApplication(t, option.MockOption{},
+		newRule("/users", "get"),
+		newRule("/users", "post"),
+		newRule("/users/\\d+", "get"),
+		newRule("/orders", "get"),
+		newRule("/orders/\\d+", "delete"),
+	)
+
+	list := func(query string) (*types.RuleListDTO, *types.CommonResponseDTO) {
+		ctx := newRequestCtx("GET", "/api/v1/rules/list"+query, nil)
+		HandleListRules(ctx, nil)
+		ret := new(types.RuleListDTO)
+		res := &types.CommonResponseDTO{Data: ret}
+		assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+		return ret, res
+	}
+	paths := func(ret *types.RuleListDTO) []string {
+		var p []string
+		for _, rule := range ret.Rules {
+			assert.NotEmpty(t, rule.ID)
+			p = append(p, rule.Method+" "+rule.Path)
+		}
+		return p
+	}
+
+	ret, _ := list("")
+	assert.Equal(t, 5, ret.Total)
+	assert.Equal(t, []string{"GET /orders", "DELETE /orders/\\d+", "GET /users", "POST /users", "GET /users/\\d+"}, paths(ret))
+
+	ret, _ = list("?offset=1&limit=2")
+	assert.Equal(t, 5, ret.Total)
+	assert.Equal(t, []string{"DELETE /orders/\\d+", "GET /users"}, paths(ret))
+
+	ret, _ = list("?offset=4&limit=2")
+	assert.Equal(t, []string{"GET /users/\\d+"}, paths(ret))
+
+	ret, _ = list("?offset=5")
+	assert.Equal(t, 5, ret.Total)
+	assert.Empty(t, ret.Rules)
+
+	ret, _ = list("?path_contains=users&method=get")
+	assert.Equal(t, 2, ret.Total)
+	assert.Equal(t, []string{"GET /users", "GET /users/\\d+"}, paths(ret))
+
+	ret, _ = list("?path_contains=orders&limit=1")
+	assert.Equal(t, 2, ret.Total)
+	assert.Equal(t, []string{"GET /orders"}, paths(ret))
+
+	_, res := list("?offset=-1")
+	assert.Equal(t, fasthttp.StatusBadRequest, res.Code)
+	_, res = list("?limit=ten")
+	assert.Equal(t, fasthttp.StatusBadRequest, res.Code)
+}
+
+func TestHandleImportRules_ValidateOnly(t *testing.T) {
+	rr, _ := setupMockApplication(t, option.MockOption{})
+
+	body := []byte(`[
+		{"path": "/import/ok", "method": "get", "responses": [{"is_default": true, "response": {"body": "ok"}}]},
+		{"path": "/import/no-default", "method": "get", "responses": [{"filter": {"query": {"mode": "exact", "a": "1"}}, "response": {"body": "a"}}]},
+		{"path": "/import/bad-template", "method": "post", "responses": [{"is_default": true, "response": {"is_template": true, "body": "{{notExists}}"}}]},
+		{"path": "/import/ok", "method": "GET", "responses": [{"is_default": true, "response": {"body": "again"}}]}
+	]`)
+	ctx := newRequestCtx("POST", "/api/v1/rules?validate_only=true", body)
+	HandleImportRules(ctx, nil)
+
+	res := new(struct {
+		Code int                    `json:"code"`
+		Data *types.ImportReportDTO `json:"data"`
+	})
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.Equal(t, fasthttp.StatusOK, res.Code)
+	assert.False(t, res.Data.Valid)
+	assert.Len(t, res.Data.Rules, 4)
+
+	assert.True(t, res.Data.Rules[0].Valid)
+	assert.Equal(t, "/import/ok", res.Data.Rules[0].Path)
+	assert.Equal(t, "GET", res.Data.Rules[0].Method)
+	assert.NotEmpty(t, res.Data.Rules[0].ID)
+	assert.Empty(t, res.Data.Rules[0].Error)
+
+	assert.False(t, res.Data.Rules[1].Valid)
+	assert.Equal(t, "no default regulation or provided more than one", res.Data.Rules[1].Error)
+	assert.False(t, res.Data.Rules[2].Valid)
+	assert.Contains(t, res.Data.Rules[2].Error, `function "notExists" not defined`)
+	assert.False(t, res.Data.Rules[3].Valid)
+	assert.Equal(t, "duplicate rule with index 0", res.Data.Rules[3].Error)
+
+	// 仅校验时不会导入任何规则
+	assert.Empty(t, rr.rules)
+
+	ctx = newRequestCtx("POST", "/api/v1/rules?validate_only=true", []byte(`[{"path": "/import/ok", "method": "get", "responses": [{"is_default": true, "response": {"body": "ok"}}]}]`))
+	HandleImportRules(ctx, nil)
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.True(t, res.Data.Valid)
+	assert.Empty(t, rr.rules)
+}
+
+func TestHandlePutRule_VersionConflict(t *testing.T) {
+	setupMockApplication(t, option.MockOption{})
+	rid, err := application.MockApplication.CreateRule(context.TODO(), &types.RuleDTO{
+		Path:        "/version",
+		Method:      "get",
+		Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "v0"}}},
+	})
+	assert.NoError(t, err)
+
+	getVersion := func() int {
+		ctx := newRequestCtx("GET", "/api/v1/rule/"+rid, nil)
+		HandleGetRule(ctx, nil)
+		res := new(struct {
+			Data *types.RuleDTO `json:"data"`
+		})
+		assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+		assert.NotNil(t, res.Data.Version)
+		return *res.Data.Version
+	}
+	update := func(handler func(*fasthttp.RequestCtx, func(error)), version string, body string) *types.CommonResponseDTO {
+		if version != "" {
+			version = `"version": ` + version + `, `
+		}
+		payload := `{"id": "` + rid + `", ` + version + `"path": "/version", "method": "get", "responses": [{"is_default": true, "response": {"body": "` + body + `"}}]}`
+		ctx := newRequestCtx("PUT", "/api/v1/rule", []byte(payload))
+		handler(ctx, nil)
+		res := new(types.CommonResponseDTO)
+		assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+		return res
+	}
+
+	// 新建的规则版本号为0，同样可以作为乐观锁：两个客户端都基于版本0更新时，后到的被拒绝
+	assert.Equal(t, 0, getVersion())
+	assert.Equal(t, fasthttp.StatusOK, update(HandlePutRule, "0", "v1").Code)
+	res := update(HandlePutRule, "0", "race")
+	assert.Equal(t, fasthttp.StatusConflict, res.Code)
+	assert.Equal(t, "rule version conflict: expected version 0 but current version is 1", res.ErrorMessage)
+	assert.Equal(t, 1, getVersion())
+
+	assert.Equal(t, fasthttp.StatusOK, update(HandlePatchRule, "1", "v2").Code)
+	assert.Equal(t, 2, getVersion())
+
+	// 基于旧版本的更新被拒绝
+	for _, handler := range []func(*fasthttp.RequestCtx, func(error)){HandlePutRule, HandlePatchRule} {
+		res := update(handler, "1", "stale")
+		assert.Equal(t, fasthttp.StatusConflict, res.Code)
+		assert.Equal(t, "rule version conflict: expected version 1 but current version is 2", res.ErrorMessage)
+	}
+	assert.Equal(t, 2, getVersion())
+
+	// 不提供版本号时不校验
+	assert.Equal(t, fasthttp.StatusOK, update(HandlePutRule, "", "v3").Code)
+	assert.Equal(t, 3, getVersion())
+
+	rule, err := application.MockApplication.GetRule(context.TODO(), rid)
+	assert.NoError(t, err)
+	assert.Equal(t, "v3", rule.Regulations[0].Template.Body)
+}
+
+func TestHandlePatchRule_Enabled(t *testing.T) {
+	rr, er := setupMockApplication(t, option.MockOption{}, &types.RuleDTO{
+		Path:        "/toggle",
+		Method:      "get",
+		Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "on"}}},
+	})
+	rules, err := application.MockApplication.Export(context.TODO())
+	assert.NoError(t, err)
+	rid := rules[0].ID
+	assert.Nil(t, rules[0].Enabled)
+
+	toggle := func(enabled bool) {
+		ctx := newRequestCtx("PATCH", "/api/v1/rule", []byte(`{"id": "`+rid+`", "enabled": `+strconv.FormatBool(enabled)+`}`))
+		HandlePatchRule(ctx, nil)
+		res := new(types.CommonResponseDTO)
+		assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+		assert.Equal(t, fasthttp.StatusOK, res.Code)
+		syncExecutors(t, rr, er)
+	}
+	mock := func() string {
+		ctx := newRequestCtx("GET", "/toggle", nil)
+		HandleMockedAPI(ctx, nil)
+		return string(ctx.Response.Body())
+	}
+	assert.Equal(t, "on", mock())
+
+	toggle(false)
+	assert.NotEqual(t, "on", mock())
+	rules, err = application.MockApplication.Export(context.TODO())
+	assert.NoError(t, err)
+	if assert.NotNil(t, rules[0].Enabled) {
+		assert.False(t, *rules[0].Enabled)
+	}
+	assert.Equal(t, "on", rules[0].Regulations[0].Template.Body) // 定义仍然保留
+
+	toggle(true)
+	assert.Equal(t, "on", mock())
+}
+
+func TestHandleMockedAPI_TTL(t *testing.T) {
+	var offset int64
+	domain.SetClock(func() time.Time { return time.Now().Add(time.Duration(atomic.LoadInt64(&offset))) })
+	defer domain.SetClock(nil)
+
+	rr, _ := setupMockApplication(t, option.MockOption{}, &types.RuleDTO{
+		Path:        "/ephemeral",
+		Method:      "get",
+		TTLSeconds:  1,
+		Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "alive"}}},
+	})
+
+	ctx := newRequestCtx("GET", "/ephemeral", nil)
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, "alive", string(ctx.Response.Body()))
+	rules, err := application.MockApplication.Export(context.TODO())
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, rules[0].TTLSeconds)
+	rid := rules[0].ID
+
+	atomic.StoreInt64(&offset, int64(2*time.Second))
+	ctx = newRequestCtx("GET", "/ephemeral", nil)
+	HandleMockedAPI(ctx, nil)
+	assert.NotEqual(t, "alive", string(ctx.Response.Body()))
+	res := new(types.CommonResponseDTO)
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.Equal(t, application.ErrRuleNotFound.Error(), res.ErrorMessage)
+
+	// 清理之前，查询接口同样视为不存在
+	assert.Len(t, rr.rules, 1)
+	_, err = application.MockApplication.GetRule(context.TODO(), rid)
+	assert.Equal(t, application.ErrRuleNotFound, err)
+	rules, err = application.MockApplication.Export(context.TODO())
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+	summaries, err := application.MockApplication.ListRuleSummaries(context.TODO())
+	assert.NoError(t, err)
+	assert.Empty(t, summaries)
+	list, err := application.MockApplication.ListRules(context.TODO(), &types.RuleQueryDTO{})
+	assert.NoError(t, err)
+	assert.Empty(t, list.Rules)
+
+	// 清理后规则被删除
+	assert.Equal(t, []string{rid}, application.MockApplication.SweepExpiredRules(context.TODO(), domain.Now()))
+	assert.Empty(t, rr.rules)
+}
+
+func TestMockApplication_CloseStopsSweeper(t *testing.T) {
+	var offset int64
+	domain.SetClock(func() time.Time { return time.Now().Add(time.Duration(atomic.LoadInt64(&offset))) })
+	defer domain.SetClock(nil)
+
+	rr, _ := setupMockApplication(t, option.MockOption{RuleSweepInterval: 5 * time.Millisecond}, &types.RuleDTO{
+		Path:        "/ephemeral",
+		Method:      "get",
+		TTLSeconds:  1,
+		Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "alive"}}},
+	})
+	application.MockApplication.Close()
+	application.MockApplication.Close() // 可以重复调用
+
+	// 停止后不再清理过期规则
+	atomic.StoreInt64(&offset, int64(2*time.Second))
+	time.Sleep(50 * time.Millisecond)
+	rr.mu.RLock()
+	defer rr.mu.RUnlock()
+	assert.Len(t, rr.rules, 1)
+}
+
+func TestHandleMockedAPI_StatusDelay(t *testing.T) {
+	setupMockApplication(t, option.MockOption{}, &types.RuleDTO{
+		Path:        "/flaky",
+		Method:      "get",
+		StatusDelay: &types.StatusDelayDTO{DelayMS: 200, StatusCodes: []int{500}},
+		Regulations: []*types.RegulationDTO{
+			{IsDefault: true, Template: &types.TemplateDTO{StatusTemplate: "{{.Query.status}}", Body: "done"}},
+		},
+	})
+
+	mock := func(status string) time.Duration {
+		ctx := newRequestCtx("GET", "/flaky?status="+status, nil)
+		start := time.Now()
+		HandleMockedAPI(ctx, nil)
+		elapsed := time.Since(start)
+		assert.Equal(t, status, strconv.Itoa(ctx.Response.StatusCode()))
+		return elapsed
+	}
+	assert.True(t, mock("500") >= 200*time.Millisecond)
+	assert.True(t, mock("200") < 200*time.Millisecond)
+
+	rules, err := application.MockApplication.Export(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, &types.StatusDelayDTO{DelayMS: 200, StatusCodes: []int{500}}, rules[0].StatusDelay)
+}
+
+func TestHandleMockedAPI_DefaultCompress(t *testing.T) {
+	body := `{"items": ["deepmock", "deepmock", "deepmock", "deepmock"]}`
+	setupMockApplication(t, option.MockOption{Compress: domain.CompressAuto, CompressMinSize: 32},
+		&types.RuleDTO{Path: "/large", Method: "get", Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: body}}}},
+		&types.RuleDTO{Path: "/tiny", Method: "get", Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: `{}`}}}},
+	)
+
+	ctx := newRequestCtx("GET", "/large", nil)
+	ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, "gzip, deflate")
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, domain.CompressGzip, string(ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding)))
+	reader, err := gzip.NewReader(bytes.NewReader(ctx.Response.Body()))
+	assert.NoError(t, err)
+	decompressed, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, body, string(decompressed))
+	// 序列化后的Content-Length为压缩后的长度
+	sent := new(fasthttp.Response)
+	assert.NoError(t, sent.Read(bufio.NewReader(strings.NewReader(ctx.Response.String()))))
+	assert.Equal(t, len(ctx.Response.Body()), sent.Header.ContentLength())
+
+	// 客户端不支持压缩或者body过小时不压缩
+	ctx = newRequestCtx("GET", "/large", nil)
+	HandleMockedAPI(ctx, nil)
+	assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding))
+	assert.Equal(t, body, string(ctx.Response.Body()))
+
+	ctx = newRequestCtx("GET", "/tiny", nil)
+	ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, "gzip")
+	HandleMockedAPI(ctx, nil)
+	assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding))
+	assert.Equal(t, `{}`, string(ctx.Response.Body()))
+}
+
+func TestHandleMockedAPI_CompressWithoutAcceptEncoding(t *testing.T) {
+	body := `{"items": ["deepmock", "deepmock", "deepmock", "deepmock"]}`
+	setupMockApplication(t, option.MockOption{Compress: domain.CompressGzip},
+		&types.RuleDTO{Path: "/global", Method: "get", Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: body}}}},
+		&types.RuleDTO{Path: "/deflate", Method: "get", Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: body, Compress: domain.CompressDeflate}}}},
+	)
+
+	// 客户端未声明支持配置的压缩算法时返回原始报文
+	for _, path := range []string{"/global", "/deflate"} {
+		ctx := newRequestCtx("GET", path, nil)
+		HandleMockedAPI(ctx, nil)
+		assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding), path)
+		assert.Equal(t, body, string(ctx.Response.Body()), path)
+		assert.Equal(t, fasthttp.HeaderAcceptEncoding, string(ctx.Response.Header.Peek(fasthttp.HeaderVary)), path)
+	}
+
+	ctx := newRequestCtx("GET", "/deflate", nil)
+	ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, "gzip")
+	HandleMockedAPI(ctx, nil)
+	assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding))
+
+	ctx = newRequestCtx("GET", "/global", nil)
+	ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, "gzip")
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, domain.CompressGzip, string(ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding)))
+	decompressed, err := ctx.Response.BodyGunzip()
+	assert.NoError(t, err)
+	assert.Equal(t, body, string(decompressed))
+}
+
+func TestHandleMockedAPI_Duplicate(t *testing.T) {
+	setupMockApplication(t, option.MockOption{}, &types.RuleDTO{
+		Path:   "/duplicate/order",
+		Method: "post",
+		Duplicate: &types.DuplicateDTO{
+			WindowSeconds: 60,
+			Response:      &types.TemplateDTO{IsTemplate: true, Body: `{"code": "DUPLICATE", "order": "{{.Json.order}}"}`},
+		},
+		Regulations: []*types.RegulationDTO{
+			{IsDefault: true, Template: &types.TemplateDTO{IsTemplate: true, StatusCode: fasthttp.StatusCreated, Body: `{"order": "{{.Json.order}}"}`}},
+		},
+	})
+
+	submit := func(body string) *fasthttp.RequestCtx {
+		ctx := newRequestCtx("POST", "/duplicate/order", []byte(body))
+		ctx.Request.Header.SetContentType("application/json")
+		HandleMockedAPI(ctx, nil)
+		return ctx
+	}
+
+	ctx := submit(`{"order": "A1"}`)
+	assert.Equal(t, fasthttp.StatusCreated, ctx.Response.StatusCode())
+	assert.Equal(t, `{"order": "A1"}`, string(ctx.Response.Body()))
+
+	ctx = submit(`{"order": "A1"}`)
+	assert.Equal(t, fasthttp.StatusConflict, ctx.Response.StatusCode())
+	assert.Equal(t, `{"code": "DUPLICATE", "order": "A1"}`, string(ctx.Response.Body()))
+
+	ctx = submit(`{"order": "A2"}`)
+	assert.Equal(t, fasthttp.StatusCreated, ctx.Response.StatusCode())
+
+	// 不带body的请求不视为重复提交
+	for i := 0; i < 2; i++ {
+		ctx = submit("")
+		assert.Equal(t, fasthttp.StatusCreated, ctx.Response.StatusCode())
+	}
+
+	rule, err := application.MockApplication.Export(context.TODO())
+	assert.NoError(t, err)
+	assert.EqualValues(t, 60, rule[0].Duplicate.WindowSeconds)
+	assert.Zero(t, rule[0].Duplicate.Response.StatusCode)
+}
+
+func TestHandleMockedAPI_RateLimit(t *testing.T) {
+	setupMockApplication(t, option.MockOption{}, &types.RuleDTO{
+		Path:      "/rate_limit",
+		Method:    "get",
+		RateLimit: 3,
+		Regulations: []*types.RegulationDTO{
+			{IsDefault: true, Template: &types.TemplateDTO{Body: "ok"}},
+		},
+	})
+
+	var passed, limited int
+	for i := 0; i < 10; i++ {
+		ctx := newRequestCtx("GET", "/rate_limit", nil)
+		HandleMockedAPI(ctx, nil)
+		switch ctx.Response.StatusCode() {
+		case fasthttp.StatusOK:
+			passed++
+			assert.Equal(t, "ok", string(ctx.Response.Body()))
+		case fasthttp.StatusTooManyRequests:
+			limited++
+			assert.Equal(t, "1", string(ctx.Response.Header.Peek("Retry-After")))
+		}
+	}
+	assert.Equal(t, 3, passed)
+	assert.Equal(t, 7, limited)
+}
+
+func TestHandleMockedAPI_CORS(t *testing.T) {
+	setupMockApplication(t, option.MockOption{}, &types.RuleDTO{
+		Path:   "/cors",
+		Method: "post",
+		CORS: &types.CORSDTO{
+			AllowOrigins: []string{"http://example.com"},
+			AllowHeaders: []string{"Content-Type", "X-Token"},
+			MaxAge:       600,
+		},
+		Regulations: []*types.RegulationDTO{
+			{IsDefault: true, Template: &types.TemplateDTO{Body: "ok"}},
+		},
+	})
+
+	ctx := newRequestCtx("OPTIONS", "/cors", nil)
+	ctx.Request.Header.Set(fasthttp.HeaderOrigin, "http://example.com")
+	ctx.Request.Header.Set(fasthttp.HeaderAccessControlRequestMethod, "POST")
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, fasthttp.StatusNoContent, ctx.Response.StatusCode())
+	assert.Equal(t, "http://example.com", string(ctx.Response.Header.Peek(fasthttp.HeaderAccessControlAllowOrigin)))
+	assert.Equal(t, "POST", string(ctx.Response.Header.Peek(fasthttp.HeaderAccessControlAllowMethods)))
+	assert.Equal(t, "Content-Type, X-Token", string(ctx.Response.Header.Peek(fasthttp.HeaderAccessControlAllowHeaders)))
+	assert.Equal(t, "600", string(ctx.Response.Header.Peek(fasthttp.HeaderAccessControlMaxAge)))
+	assert.Empty(t, ctx.Response.Body())
+
+	ctx = newRequestCtx("POST", "/cors", nil)
+	ctx.Request.Header.Set(fasthttp.HeaderOrigin, "http://example.com")
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+	assert.Equal(t, "ok", string(ctx.Response.Body()))
+	assert.Equal(t, "http://example.com", string(ctx.Response.Header.Peek(fasthttp.HeaderAccessControlAllowOrigin)))
+
+	ctx = newRequestCtx("POST", "/cors", nil)
+	ctx.Request.Header.Set(fasthttp.HeaderOrigin, "http://evil.com")
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, "ok", string(ctx.Response.Body()))
+	assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderAccessControlAllowOrigin))
+}
+
+func TestHandleRenderTemplate(t *testing.T) {
+	setupMockApplication(t, option.MockOption{})
+
+	body := []byte(`{
+		"response": {"is_template": true, "status_code": 201, "header": {"X-Uid": "1"}, "body": "{{.Query.name}}-{{.Json.age}}-{{.Variable.tag}}"},
+		"variable": {"tag": "mock"},
+		"query": {"name": "deepmock"},
+		"json": {"age": 3}
+	}`)
+	ctx := newRequestCtx("POST", "/api/v1/template/render", body)
+	HandleRenderTemplate(ctx, nil)
+
+	res := new(struct {
+		Code int                        `json:"code"`
+		Data *types.RenderedTemplateDTO `json:"data"`
+	})
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.Equal(t, fasthttp.StatusOK, res.Code)
+	assert.Equal(t, fasthttp.StatusCreated, res.Data.StatusCode)
+	assert.Equal(t, misc.StringValues{"1"}, res.Data.Header["X-Uid"])
+	assert.Equal(t, "deepmock-3-mock", res.Data.Body)
+}
+
+func TestHandleRenderTemplate_UndefinedFunction(t *testing.T) {
+	setupMockApplication(t, option.MockOption{})
+
+	body := []byte(`{"response": {"is_template": true, "body": "{{notExists .Query.name}}"}}`)
+	ctx := newRequestCtx("POST", "/api/v1/template/render", body)
+	HandleRenderTemplate(ctx, nil)
+
+	res := new(types.CommonResponseDTO)
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.Equal(t, fasthttp.StatusBadRequest, res.Code)
+	assert.Contains(t, res.ErrorMessage, `function "notExists" not defined`)
+}
+
+// registerBlockingFunc 注册一个阻塞的模板函数，用于模拟长时间占用连接的请求
+func registerBlockingFunc(t *testing.T, name string) (entered chan struct{}, release chan struct{}) {
+	entered, release = make(chan struct{}), make(chan struct{})
+	assert.NoError(t, domain.RegisterTemplateFunc(name, func() string {
+		entered <- struct{}{}
+		<-release
+		return "done"
+	}))
+	return entered, release
+}
+
+func TestHandleCompileTemplate(t *testing.T) {
+	setupMockApplication(t, option.MockOption{})
+	assert.NoError(t, domain.RegisterTemplateFunc("compileTestGreet", func(name string) (string, error) {
+		if name == "" {
+			return "", errors.New("empty name")
+		}
+		return "hello " + name, nil
+	}))
+
+	compile := func(body string) *fasthttp.RequestCtx {
+		ctx := newRequestCtx("POST", "/api/v1/template/compile", []byte(body))
+		HandleCompileTemplate(ctx, nil)
+		return ctx
+	}
+
+	ctx := compile(`{"template": "{{compileTestGreet .Query.name}}, {{ctx \"tag\"}}", "query": {"name": "deepmock"}, "variable": {"tag": "mock"}}`)
+	res := new(struct {
+		Code int                        `json:"code"`
+		Data *types.CompiledTemplateDTO `json:"data"`
+	})
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.Equal(t, fasthttp.StatusOK, res.Code)
+	assert.Equal(t, "hello deepmock, mock", res.Data.Output)
+
+	failed := new(types.CommonResponseDTO)
+	ctx = compile(`{"template": "{{notExists .Query.name}}"}`)
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), failed))
+	assert.Equal(t, fasthttp.StatusBadRequest, failed.Code)
+	assert.Contains(t, failed.ErrorMessage, "failed to parse template")
+	assert.Contains(t, failed.ErrorMessage, `function "notExists" not defined`)
+
+	ctx = compile(`{"template": "{{compileTestGreet \"\"}}"}`)
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), failed))
+	assert.Equal(t, fasthttp.StatusBadRequest, failed.Code)
+	assert.Contains(t, failed.ErrorMessage, "failed to execute template")
+	assert.Contains(t, failed.ErrorMessage, "empty name")
+
+	ctx = compile(`{}`)
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), failed))
+	assert.Equal(t, "missing template", failed.ErrorMessage)
+}
+
+func TestHandleInspectTemplateFuncs(t *testing.T) {
+	setupMockApplication(t, option.MockOption{})
+
+	inspect := func(body string) *fasthttp.RequestCtx {
+		ctx := newRequestCtx("POST", "/api/v1/template/funcs", []byte(body))
+		HandleInspectTemplateFuncs(ctx, nil)
+		return ctx
+	}
+	res := new(struct {
+		Code int                           `json:"code"`
+		Data *types.TemplateFuncsReportDTO `json:"data"`
+	})
+
+	ctx := inspect(`{"template": "{{uuid}} {{if eq .Query.a \"1\"}}{{date \"2006\"}}{{end}}"}`)
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.Equal(t, fasthttp.StatusOK, res.Code)
+	assert.True(t, res.Data.Valid)
+	assert.Equal(t, []string{"date", "eq", "uuid"}, res.Data.Functions)
+	assert.Empty(t, res.Data.Unregistered)
+
+	ctx = inspect(`{"template": "{{uuid}} {{timestmap}} {{range .Json.items}}{{upperCase .}}{{end}}"}`)
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.False(t, res.Data.Valid)
+	assert.Equal(t, []string{"timestmap", "upperCase", "uuid"}, res.Data.Functions)
+	assert.Equal(t, []string{"timestmap", "upperCase"}, res.Data.Unregistered)
+
+	failed := new(types.CommonResponseDTO)
+	ctx = inspect(`{"template": "{{uuid"}`)
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), failed))
+	assert.Equal(t, fasthttp.StatusBadRequest, failed.Code)
+	assert.Contains(t, failed.ErrorMessage, "failed to parse template")
+}
+
+func TestHandleMockedAPI_RuleConcurrency(t *testing.T) {
+	entered, release := registerBlockingFunc(t, "blockRuleConcurrency")
+	setupMockApplication(t, option.MockOption{}, &types.RuleDTO{
+		Path:           "/concurrency",
+		Method:         "get",
+		MaxConcurrency: 2,
+		Regulations: []*types.RegulationDTO{
+			{IsDefault: true, Template: &types.TemplateDTO{IsTemplate: true, Body: "{{blockRuleConcurrency}}"}},
+		},
+	})
+
+	holding := make([]*fasthttp.RequestCtx, 2)
+	done := make(chan struct{})
+	for i := range holding {
+		holding[i] = newRequestCtx("GET", "/concurrency", nil)
+		go func(ctx *fasthttp.RequestCtx) {
+			HandleMockedAPI(ctx, nil)
+			done <- struct{}{}
+		}(holding[i])
+		<-entered
+	}
+
+	for i := 0; i < 3; i++ {
+		ctx := newRequestCtx("GET", "/concurrency", nil)
+		HandleMockedAPI(ctx, nil)
+		assert.Equal(t, fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode())
+	}
+
+	close(release)
+	for range holding {
+		<-done
+	}
+	for _, ctx := range holding {
+		assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+		assert.Equal(t, "done", string(ctx.Response.Body()))
+	}
+
+	// 占用的名额归还后可以继续处理
+	ctx := newRequestCtx("GET", "/concurrency", nil)
+	go func() { <-entered }()
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+}
+
+func TestHandleMockedAPI_OverflowResponse(t *testing.T) {
+	entered, release := registerBlockingFunc(t, "blockOverflowResponse")
+	setupMockApplication(t, option.MockOption{}, &types.RuleDTO{
+		Path:           "/overflow",
+		Method:         "get",
+		Variable:       map[string]interface{}{"retry": 3},
+		MaxConcurrency: 1,
+		OverflowResponse: &types.TemplateDTO{
+			IsTemplate: true,
+			StatusCode: fasthttp.StatusTooManyRequests,
+			Header:     map[string]misc.StringValues{"Retry-After": {"{{.Variable.retry}}"}, "Content-Type": {"application/json"}},
+			Body:       `{"code": "SYSTEM_BUSY", "retry_after": {{.Variable.retry}}}`,
+		},
+		Regulations: []*types.RegulationDTO{
+			{IsDefault: true, Template: &types.TemplateDTO{IsTemplate: true, Body: "{{blockOverflowResponse}}"}},
+		},
+	}, &types.RuleDTO{
+		Path:             "/busy",
+		Method:           "get",
+		MaxConcurrency:   1,
+		OverflowResponse: &types.TemplateDTO{Body: "busy"},
+		Regulations: []*types.RegulationDTO{
+			{IsDefault: true, Template: &types.TemplateDTO{IsTemplate: true, Body: "{{blockOverflowResponse}}"}},
+		},
+	})
+
+	for _, path := range []string{"/overflow", "/busy"} {
+		holding := newRequestCtx("GET", path, nil)
+		done := make(chan struct{})
+		go func() {
+			HandleMockedAPI(holding, nil)
+			close(done)
+		}()
+		<-entered
+
+		ctx := newRequestCtx("GET", path, nil)
+		HandleMockedAPI(ctx, nil)
+		if path == "/overflow" {
+			assert.Equal(t, fasthttp.StatusTooManyRequests, ctx.Response.StatusCode())
+			assert.Equal(t, "3", string(ctx.Response.Header.Peek("Retry-After")))
+			assert.Equal(t, "application/json", string(ctx.Response.Header.ContentType()))
+			assert.Equal(t, `{"code": "SYSTEM_BUSY", "retry_after": 3}`, string(ctx.Response.Body()))
+		} else {
+			// 未指定状态码时仍返回503
+			assert.Equal(t, fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode())
+			assert.Equal(t, "busy", string(ctx.Response.Body()))
+		}
+
+		release <- struct{}{}
+		<-done
+		assert.Equal(t, fasthttp.StatusOK, holding.Response.StatusCode())
+		assert.Equal(t, "done", string(holding.Response.Body()))
+	}
+}
+
+func TestHandleCreateRule_OverflowWithoutConcurrency(t *testing.T) {
+	setupMockApplication(t, option.MockOption{})
+
+	_, err := application.MockApplication.CreateRule(context.TODO(), &types.RuleDTO{
+		Path:             "/overflow/unlimited",
+		Method:           "get",
+		OverflowResponse: &types.TemplateDTO{Body: "busy"},
+		Regulations:      []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "ok"}}},
+	})
+	assert.EqualError(t, err, "overflow response requires max concurrency")
+}
+
+func TestHandleMockedAPI_ServerConcurrency(t *testing.T) {
+	entered, release := registerBlockingFunc(t, "blockServerConcurrency")
+	setupMockApplication(t, option.MockOption{MaxConcurrency: 1},
+		&types.RuleDTO{
+			Path:   "/concurrency/slow",
+			Method: "get",
+			Regulations: []*types.RegulationDTO{
+				{IsDefault: true, Template: &types.TemplateDTO{IsTemplate: true, Body: "{{blockServerConcurrency}}"}},
+			},
+		},
+		&types.RuleDTO{
+			Path:   "/concurrency/fast",
+			Method: "get",
+			Regulations: []*types.RegulationDTO{
+				{IsDefault: true, Template: &types.TemplateDTO{Body: "ok"}},
+			},
+		},
+	)
+
+	slow := newRequestCtx("GET", "/concurrency/slow", nil)
+	done := make(chan struct{})
+	go func() {
+		HandleMockedAPI(slow, nil)
+		close(done)
+	}()
+	<-entered
+
+	ctx := newRequestCtx("GET", "/concurrency/fast", nil)
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode())
+
+	close(release)
+	<-done
+	assert.Equal(t, fasthttp.StatusOK, slow.Response.StatusCode())
+
+	ctx = newRequestCtx("GET", "/concurrency/fast", nil)
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+	assert.Equal(t, "ok", string(ctx.Response.Body()))
+}
+
+func TestHandleMockedAPI_SlowStart(t *testing.T) {
+	setupMockApplication(t, option.MockOption{}, &types.RuleDTO{
+		Path:      "/slow_start",
+		Method:    "get",
+		SlowStart: &types.SlowStartDTO{DelayMS: 60, Requests: 3},
+		Regulations: []*types.RegulationDTO{
+			{IsDefault: true, Template: &types.TemplateDTO{Body: "ok"}},
+		},
+	})
+
+	elapsed := make([]time.Duration, 4)
+	for i := range elapsed {
+		ctx := newRequestCtx("GET", "/slow_start", nil)
+		start := time.Now()
+		HandleMockedAPI(ctx, nil)
+		elapsed[i] = time.Since(start)
+		assert.Equal(t, "ok", string(ctx.Response.Body()))
+	}
+	assert.True(t, elapsed[0] >= 60*time.Millisecond, elapsed[0])
+	assert.True(t, elapsed[0] > elapsed[2], elapsed)
+	assert.True(t, elapsed[3] < 20*time.Millisecond, elapsed[3])
+}
+
+func TestHandleCreateRule_BadTemplate(t *testing.T) {
+	rr, _ := setupMockApplication(t, option.MockOption{})
+
+	body := []byte(`{
+		"path": "/bad_template",
+		"method": "get",
+		"responses": [
+			{"is_default": true, "response": {"body": "ok"}},
+			{"filter": {"query": {"mode": "exact", "v": "1"}}, "response": {"is_template": true, "body": "{{.Query.v"}}
+		]
+	}`)
+	ctx := newRequestCtx("POST", "/api/v1/rule", body)
+	HandleCreateRule(ctx, nil)
+
+	res := new(types.CommonResponseDTO)
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.Equal(t, fasthttp.StatusBadRequest, res.Code)
+	assert.Contains(t, res.ErrorMessage, "bad response at index 1")
+	assert.Contains(t, res.ErrorMessage, "unclosed action")
+	assert.Empty(t, rr.rules)
+}
+
+func TestHandleMockedAPI_Upstream(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	upstream := &fasthttp.Server{Handler: func(ctx *fasthttp.RequestCtx) {
+		ctx.Response.Header.Set("X-Upstream", "real")
+		ctx.SetStatusCode(fasthttp.StatusAccepted)
+		ctx.SetBodyString(string(ctx.Method()) + " " + string(ctx.RequestURI()) + " " + string(ctx.PostBody()))
+	}}
+	go upstream.Serve(ln)
+	defer ln.Close()
+
+	setupMockApplication(t, option.MockOption{Upstream: "http://" + ln.Addr().String() + "/"}, &types.RuleDTO{
+		Path:   "/mocked",
+		Method: "get",
+		Regulations: []*types.RegulationDTO{
+			{IsDefault: true, Template: &types.TemplateDTO{Body: "mocked"}},
+		},
+	})
+
+	ctx := newRequestCtx("GET", "/mocked", nil)
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+	assert.Equal(t, "mocked", string(ctx.Response.Body()))
+	assert.Empty(t, ctx.Response.Header.Peek("X-Upstream"))
+
+	ctx = newRequestCtx("POST", "/unmatched?id=1", []byte("payload"))
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, fasthttp.StatusAccepted, ctx.Response.StatusCode())
+	assert.Equal(t, "POST /unmatched?id=1 payload", string(ctx.Response.Body()))
+	assert.Equal(t, "real", string(ctx.Response.Header.Peek("X-Upstream")))
+
+	// 上游不可用时返回502
+	dead, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	assert.NoError(t, dead.Close())
+	setupMockApplication(t, option.MockOption{Upstream: "http://" + dead.Addr().String()})
+	ctx = newRequestCtx("GET", "/unmatched", nil)
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, fasthttp.StatusBadGateway, ctx.Response.StatusCode())
+}
+
+func TestHandleMockedAPI_Record(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	var forwarded int32
+	upstream := &fasthttp.Server{Handler: func(ctx *fasthttp.RequestCtx) {
+		atomic.AddInt32(&forwarded, 1)
+		ctx.Response.Header.Set("X-Upstream", "real")
+		ctx.SetStatusCode(fasthttp.StatusCreated)
+		if string(ctx.Path()) == "/binary" {
+			ctx.SetContentType("application/octet-stream")
+			ctx.SetBody([]byte{0xff, 0xfe, 0x00, 0x01})
+			return
+		}
+		ctx.SetContentType("application/json")
+		ctx.SetBodyString(`{"path": "` + string(ctx.Path()) + `"}`)
+	}}
+	go upstream.Serve(ln)
+	defer ln.Close()
+
+	rr, er := setupMockApplication(t, option.MockOption{Upstream: "http://" + ln.Addr().String(), Record: true})
+
+	exchange := func(path string) *fasthttp.RequestCtx {
+		ctx := newRequestCtx("GET", path, nil)
+		HandleMockedAPI(ctx, nil)
+		return ctx
+	}
+	recorded := []*fasthttp.RequestCtx{exchange("/users/1"), exchange("/binary")}
+	assert.EqualValues(t, 2, atomic.LoadInt32(&forwarded))
+	assert.Len(t, rr.rules, 2)
+
+	// 同步执行器后由录制的规则响应，不再转发
+	syncExecutors(t, rr, er)
+	for index, path := range []string{"/users/1", "/binary"} {
+		replayed := exchange(path)
+		assert.Equal(t, recorded[index].Response.StatusCode(), replayed.Response.StatusCode())
+		assert.Equal(t, recorded[index].Response.Body(), replayed.Response.Body())
+		assert.Equal(t, recorded[index].Response.Header.ContentType(), replayed.Response.Header.ContentType())
+		assert.Equal(t, "real", string(replayed.Response.Header.Peek("X-Upstream")))
+	}
+	assert.EqualValues(t, 2, atomic.LoadInt32(&forwarded))
+	// 录制的规则精确匹配路径，其他路径仍然转发并录制
+	exchange("/users/10")
+	assert.EqualValues(t, 3, atomic.LoadInt32(&forwarded))
+	assert.Len(t, rr.rules, 3)
+
+	// 关闭录制后仅转发
+	ctx := newRequestCtx("PUT", "/api/v1/record", []byte(`{"enabled": false}`))
+	HandleSetRecord(ctx, nil)
+	ctx = newRequestCtx("GET", "/api/v1/record", nil)
+	HandleGetRecord(ctx, nil)
+	assert.JSONEq(t, `{"code": 200, "data": {"enabled": false}}`, string(ctx.Response.Body()))
+
+	exchange("/orders/1")
+	assert.Len(t, rr.rules, 3)
+}
+
+func TestHandleSetRecord_NoUpstream(t *testing.T) {
+	setupMockApplication(t, option.MockOption{})
+
+	ctx := newRequestCtx("PUT", "/api/v1/record", []byte(`{"enabled": true}`))
+	HandleSetRecord(ctx, nil)
+	res := new(types.CommonResponseDTO)
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.Equal(t, fasthttp.StatusBadRequest, res.Code)
+}
+
+func TestHandleExportRules_YAML(t *testing.T) {
+	setupMockApplication(t, option.MockOption{},
+		&types.RuleDTO{
+			Path:           "/yaml/a",
+			Method:         "post",
+			Variable:       types.VariableDTO{"name": "deepmock", "nested": map[string]interface{}{"n": 1}},
+			Weight:         types.WeightDTO{"status": {"ok": 9, "fail": 1}},
+			ResponseSchema: map[string]interface{}{"type": "object", "properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}}},
+			Regulations: []*types.RegulationDTO{
+				{
+					Filter:   &types.FilterDTO{Query: map[string]string{"mode": "exact", "a": "1"}},
+					Template: &types.TemplateDTO{IsTemplate: true, Header: map[string]misc.StringValues{"Content-Type": {"application/json"}, "X-Multi": {"a", "b"}}, Body: "{\n  \"name\": \"{{.Variable.name}}\"\n}"},
+				},
+				{IsDefault: true, Template: &types.TemplateDTO{StatusCode: 404, Body: "not found"}},
+			},
+		},
+		&types.RuleDTO{Path: "/yaml/b", Method: "get", Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "b"}}}},
+	)
+	expected, err := application.MockApplication.Export(context.TODO())
+	assert.NoError(t, err)
+
+	ctx := newRequestCtx("GET", "/api/v1/rules?format=yaml", nil)
+	HandleExportRules(ctx, nil)
+	assert.Equal(t, "application/x-yaml", string(ctx.Response.Header.ContentType()))
+	exported := append([]byte(nil), ctx.Response.Body()...)
+	assert.Contains(t, string(exported), "path: /yaml/a")
+	assert.Contains(t, string(exported), "is_default: true")
+
+	// 导入YAML后再导出，规则与原来一致
+	setupMockApplication(t, option.MockOption{})
+	ctx = newRequestCtx("POST", "/api/v1/rules?format=yaml", exported)
+	HandleImportRules(ctx, nil)
+	assert.Equal(t, `{"code":200}`, string(ctx.Response.Body()))
+
+	actual, err := application.MockApplication.Export(context.TODO())
+	assert.NoError(t, err)
+	for _, rules := range [][]*types.RuleDTO{expected, actual} {
+		sort.Slice(rules, func(i, j int) bool { return rules[i].Path < rules[j].Path })
+	}
+	want, _ := json.Marshal(expected)
+	got, _ := json.Marshal(actual)
+	assert.JSONEq(t, string(want), string(got))
+
+	// 通过Content-Type识别YAML
+	ctx = newRequestCtx("POST", "/api/v1/rules?validate_only=true", exported)
+	ctx.Request.Header.SetContentType("application/x-yaml")
+	HandleImportRules(ctx, nil)
+	assert.Contains(t, string(ctx.Response.Body()), `"valid":true`)
+
+	ctx = newRequestCtx("POST", "/api/v1/rules?format=yaml", []byte("- path: [unclosed"))
+	HandleImportRules(ctx, nil)
+	assert.Contains(t, string(ctx.Response.Body()), `"code":400`)
+}
+
+func TestHandleMockedAPI_CompressedRequestBody(t *testing.T) {
+	setupMockApplication(t, option.MockOption{MaxDecodedBodySize: 1024}, &types.RuleDTO{
+		Path:   "/gzip/users",
+		Method: "post",
+		Regulations: []*types.RegulationDTO{
+			{
+				Filter:   &types.FilterDTO{Body: map[string]string{"mode": "keyword", "keyword": "deepmock"}},
+				Template: &types.TemplateDTO{IsTemplate: true, Body: `{"hello": "{{.Json.user.name}}"}`},
+			},
+			{IsDefault: true, Template: &types.TemplateDTO{StatusCode: 404, Body: "not matched"}},
+		},
+	})
+
+	ctx := newRequestCtx("POST", "/gzip/users", fasthttp.AppendGzipBytes(nil, []byte(`{"user": {"name": "deepmock"}}`)))
+	ctx.Request.Header.SetContentType("application/json")
+	ctx.Request.Header.Set(fasthttp.HeaderContentEncoding, "gzip")
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+	assert.Equal(t, `{"hello": "deepmock"}`, string(ctx.Response.Body()))
+
+	ctx = newRequestCtx("POST", "/gzip/users", []byte(`not gzip`))
+	ctx.Request.Header.Set(fasthttp.HeaderContentEncoding, "gzip")
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode())
+
+	ctx = newRequestCtx("POST", "/gzip/users", fasthttp.AppendGzipBytes(nil, bytes.Repeat([]byte("a"), 2048)))
+	ctx.Request.Header.Set(fasthttp.HeaderContentEncoding, "gzip")
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, fasthttp.StatusRequestEntityTooLarge, ctx.Response.StatusCode())
+}
+
+func TestHandleMockedAPI_Priority(t *testing.T) {
+	setupMockApplication(t, option.MockOption{},
+		&types.RuleDTO{Path: "/priority/.*", Method: "get", Priority: 10, Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "catch-all"}}}},
+		&types.RuleDTO{Path: "/priority/vip/.*", Method: "get", Priority: 20, Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "vip"}}}},
+		&types.RuleDTO{Path: "/priority/users", Method: "get", Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "users"}}}},
+	)
+
+	for path, expected := range map[string]string{
+		"/priority/vip/42": "vip",
+		"/priority/other":  "catch-all",
+		"/priority/users":  "catch-all", // 精确匹配的规则优先级更低
+	} {
+		ctx := newRequestCtx("GET", path, nil)
+		HandleMockedAPI(ctx, nil)
+		assert.Equal(t, expected, string(ctx.Response.Body()), path)
+	}
+}
+
+func TestHandleMockedAPI_RuleVersionHeader(t *testing.T) {
+	rr, er := setupMockApplication(t, option.MockOption{})
+	rid, err := application.MockApplication.CreateRule(context.TODO(), &types.RuleDTO{
+		Path:        "/rule/version",
+		Method:      "get",
+		Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "v0"}}},
+	})
+	assert.NoError(t, err)
+	syncExecutors(t, rr, er)
+
+	mockVersion := func() string {
+		ctx := newRequestCtx("GET", "/rule/version", nil)
+		HandleMockedAPI(ctx, nil)
+		return string(ctx.Response.Header.Peek(application.HeaderRuleVersion))
+	}
+	assert.Equal(t, "0", mockVersion())
+
+	for i, handler := range []func(*fasthttp.RequestCtx, func(error)){HandlePutRule, HandlePatchRule} {
+		ctx := newRequestCtx("PUT", "/api/v1/rule", []byte(`{"id": "`+rid+`", "path": "/rule/version", "method": "get", "responses": [{"is_default": true, "response": {"body": "updated"}}]}`))
+		handler(ctx, nil)
+		assert.Contains(t, string(ctx.Response.Body()), `"code":200`)
+		syncExecutors(t, rr, er)
+		assert.Equal(t, strconv.Itoa(i+1), mockVersion())
+	}
+
+	// 未匹配任何规则时没有该响应头
+	ctx := newRequestCtx("GET", "/not/exists", nil)
+	HandleMockedAPI(ctx, nil)
+	assert.Empty(t, ctx.Response.Header.Peek(application.HeaderRuleVersion))
+}
+
+func TestHandleMockedAPI_MultipartForm(t *testing.T) {
+	setupMockApplication(t, option.MockOption{}, &types.RuleDTO{
+		Path:   "/multipart/profile",
+		Method: "post",
+		Regulations: []*types.RegulationDTO{
+			{
+				Filter:   &types.FilterDTO{Form: map[string]string{"mode": "keyword", "nickname": "deep"}},
+				Template: &types.TemplateDTO{IsTemplate: true, Body: `{"nickname": "{{.Form.nickname}}"}`},
+			},
+			{IsDefault: true, Template: &types.TemplateDTO{StatusCode: 404, Body: "not matched"}},
+		},
+	})
+
+	post := func(nickname string) *fasthttp.RequestCtx {
+		ctx := newRequestCtx("POST", "/multipart/profile", nil)
+		writer := multipart.NewWriter(ctx.Request.BodyWriter())
+		ctx.Request.Header.SetContentType(writer.FormDataContentType())
+		assert.Nil(t, writer.WriteField("nickname", nickname))
+		file, err := writer.CreateFormFile("avatar", "avatar.png")
+		assert.Nil(t, err)
+		_, _ = file.Write([]byte("binary"))
+		assert.Nil(t, writer.Close())
+		HandleMockedAPI(ctx, nil)
+		return ctx
+	}
+
+	ctx := post("deepmock")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+	assert.Equal(t, `{"nickname": "deepmock"}`, string(ctx.Response.Body()))
+
+	ctx = post("other")
+	assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode())
+}
+
+func TestHandleListRuleIDs(t *testing.T) {
+	newRule := func(path, method string) *types.RuleDTO {
+		return &types.RuleDTO{
+			Path:        path,
+			Method:      method,
+			Variable:    types.VariableDTO{"large": strings.Repeat("a", 1024)},
+			Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "ok"}}},
+		}
+	}
+	rr, _ := setupMockApplication(t, option.MockOption{},
+		newRule("/users", "post"),
+		newRule("/orders", "get"),
+		newRule("/users", "get"),
+	)
+
+	ctx := newRequestCtx("GET", "/api/v1/rules/ids", nil)
+	HandleListRuleIDs(ctx, nil)
+	var summaries []*types.RuleSummaryDTO
+	res := &types.CommonResponseDTO{Data: &summaries}
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.Equal(t, fasthttp.StatusOK, res.Code)
+	assert.NotContains(t, string(ctx.Response.Body()), "large")
+
+	assert.Len(t, summaries, 3)
+	expected := [][2]string{{"/orders", "GET"}, {"/users", "GET"}, {"/users", "POST"}}
+	for i, summary := range summaries {
+		assert.Equal(t, expected[i][0], summary.Path)
+		assert.Equal(t, expected[i][1], summary.Method)
+		assert.Contains(t, rr.rules, summary.ID)
+	}
+
+	setupMockApplication(t, option.MockOption{})
+	ctx = newRequestCtx("GET", "/api/v1/rules/ids", nil)
+	HandleListRuleIDs(ctx, nil)
+	assert.Equal(t, `{"code":200,"data":[]}`, string(ctx.Response.Body()))
+}
+
+func TestHandleMockedAPI_XMLBody(t *testing.T) {
+	setupMockApplication(t, option.MockOption{}, &types.RuleDTO{
+		Path:   "/xml/orders",
+		Method: "post",
+		Regulations: []*types.RegulationDTO{
+			{
+				Filter: &types.FilterDTO{Body: map[string]string{"mode": "has_keys", "keys": "order.@id"}},
+				Template: &types.TemplateDTO{
+					IsTemplate: true,
+					Header:     map[string]misc.StringValues{"Content-Type": {"application/xml"}},
+					Body:       `<result><id>{{index .Xml.order "@id"}}</id><customer>{{.Xml.order.customer}}</customer><second>{{xmlPath .Xml "order.items.item.1"}}</second></result>`,
+				},
+			},
+			{IsDefault: true, Template: &types.TemplateDTO{StatusCode: 400, Body: "missing order id"}},
+		},
+	})
+
+	ctx := newRequestCtx("POST", "/xml/orders", []byte(`<order id="42"><customer>deepmock</customer><items><item>a</item><item>b</item></items></order>`))
+	ctx.Request.Header.SetContentType("application/xml")
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+	assert.Equal(t, `<result><id>42</id><customer>deepmock</customer><second>b</second></result>`, string(ctx.Response.Body()))
+
+	ctx = newRequestCtx("POST", "/xml/orders", []byte(`<order><customer>deepmock</customer></order>`))
+	ctx.Request.Header.SetContentType("application/xml")
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode())
+}
+
+func TestHandleMockedAPI_Maintenance(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	var offset int64
+	domain.SetClock(func() time.Time { return now.Add(time.Duration(atomic.LoadInt64(&offset))) })
+	defer domain.SetClock(nil)
+
+	setupMockApplication(t, option.MockOption{},
+		&types.RuleDTO{
+			Path:        "/maintenance/inside",
+			Method:      "get",
+			Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "normal"}}},
+			Maintenance: &types.MaintenanceDTO{Windows: []*types.MaintenanceWindowDTO{
+				{Start: now.Add(-time.Hour).Format(time.RFC3339), End: now.Add(90 * time.Second).Format(time.RFC3339)},
+			}},
+		},
+		&types.RuleDTO{
+			Path:        "/maintenance/custom",
+			Method:      "get",
+			Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "normal"}}},
+			Maintenance: &types.MaintenanceDTO{
+				Windows:  []*types.MaintenanceWindowDTO{{Start: now.Add(-time.Hour).Format(time.RFC3339), End: now.Add(time.Hour).Format(time.RFC3339)}},
+				Response: &types.TemplateDTO{Body: `{"message": "under maintenance"}`},
+			},
+		},
+		&types.RuleDTO{
+			Path:        "/maintenance/outside",
+			Method:      "get",
+			Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "normal"}}},
+			Maintenance: &types.MaintenanceDTO{Windows: []*types.MaintenanceWindowDTO{
+				{Start: now.Add(time.Hour).Format(time.RFC3339), End: now.Add(2 * time.Hour).Format(time.RFC3339)},
+			}},
+		},
+	)
+
+	ctx := newRequestCtx("GET", "/maintenance/inside", nil)
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, 503, ctx.Response.StatusCode())
+	assert.Equal(t, "90", string(ctx.Response.Header.Peek("Retry-After")))
+
+	ctx = newRequestCtx("GET", "/maintenance/custom", nil)
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, 503, ctx.Response.StatusCode())
+	assert.Equal(t, `{"message": "under maintenance"}`, string(ctx.Response.Body()))
+	assert.NotEmpty(t, ctx.Response.Header.Peek("Retry-After"))
+
+	ctx = newRequestCtx("GET", "/maintenance/outside", nil)
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, 200, ctx.Response.StatusCode())
+	assert.Equal(t, "normal", string(ctx.Response.Body()))
+	assert.Empty(t, ctx.Response.Header.Peek("Retry-After"))
+
+	// 维护窗口结束后恢复正常响应，另一个窗口开始后进入维护
+	atomic.StoreInt64(&offset, int64(90*time.Second))
+	ctx = newRequestCtx("GET", "/maintenance/inside", nil)
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, 200, ctx.Response.StatusCode())
+	assert.Equal(t, "normal", string(ctx.Response.Body()))
+
+	atomic.StoreInt64(&offset, int64(time.Hour))
+	ctx = newRequestCtx("GET", "/maintenance/outside", nil)
+	HandleMockedAPI(ctx, nil)
+	assert.Equal(t, 503, ctx.Response.StatusCode())
+	assert.Equal(t, "3600", string(ctx.Response.Header.Peek("Retry-After")))
+}
+
+func TestHandleListTemplateFuncs(t *testing.T) {
+	setupMockApplication(t, option.MockOption{})
+
+	ctx := newRequestCtx("GET", "/api/v1/template/funcs", nil)
+	HandleListTemplateFuncs(ctx, nil)
+	res := new(struct {
+		Code int      `json:"code"`
+		Data []string `json:"data"`
+	})
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.Equal(t, fasthttp.StatusOK, res.Code)
+	assert.Contains(t, res.Data, "uuid")
+	assert.Contains(t, res.Data, "xmlPath")
+	assert.True(t, sort.StringsAreSorted(res.Data))
+}
+
+func TestHandleMockedAPI_Host(t *testing.T) {
+	setupMockApplication(t, option.MockOption{},
+		&types.RuleDTO{
+			Path:        "/virtual/host",
+			Method:      "get",
+			Host:        "order.example.com",
+			Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "order"}}},
+		},
+		&types.RuleDTO{
+			Path:        "/virtual/host",
+			Method:      "get",
+			Host:        `^pay\.`,
+			HostType:    "regex",
+			Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "pay"}}},
+		},
+	)
+
+	mock := func(host string) *fasthttp.RequestCtx {
+		ctx := newRequestCtx("GET", "/virtual/host", nil)
+		ctx.Request.Header.SetHost(host)
+		HandleMockedAPI(ctx, nil)
+		return ctx
+	}
+	assert.Equal(t, "order", string(mock("order.example.com").Response.Body()))
+	assert.Equal(t, "pay", string(mock("pay.example.com:8080").Response.Body()))
+	assert.Contains(t, string(mock("user.example.com").Response.Body()), application.ErrRuleNotFound.Error())
+
+	// 大小写不同、显式声明exact的host与已有规则是同一个规则
+	body := []byte(`{"path": "/virtual/host", "method": "get", "host": "Order.Example.com", "host_type": "exact", "responses": [{"is_default": true, "response": {"body": "dup"}}]}`)
+	ctx := newRequestCtx("POST", "/api/v1/rule", body)
+	HandleCreateRule(ctx, nil)
+	res := new(types.CommonResponseDTO)
+	assert.Nil(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.NotEqual(t, fasthttp.StatusOK, res.Code)
+	assert.Contains(t, res.ErrorMessage, "duplicate rule id")
+	assert.Equal(t, "order", string(mock("order.example.com").Response.Body()))
+}
+
+func TestHandleHealth(t *testing.T) {
+	setupMockApplication(t, option.MockOption{},
+		&types.RuleDTO{Path: "/health/a", Method: "get", Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "a"}}}},
+		&types.RuleDTO{Path: "/health/b", Method: "get", Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{Body: "b"}}}},
+	)
+
+	ctx := newRequestCtx("GET", "/api/health", nil)
+	HandleHealth(ctx, nil)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+
+	res := new(struct {
+		Code int                    `json:"code"`
+		Data map[string]interface{} `json:"data"`
+	})
+	assert.NoError(t, json.Unmarshal(ctx.Response.Body(), res))
+	assert.Equal(t, 200, res.Code)
+	assert.Len(t, res.Data, 4)
+	assert.Equal(t, "ok", res.Data["status"])
+	assert.Equal(t, float64(2), res.Data["rules"])
+	assert.IsType(t, float64(0), res.Data["uptime_seconds"])
+	assert.Equal(t, misc.Version, res.Data["version"])
+}
+
+func TestHandleMockedAPI_TimeRandSeed(t *testing.T) {
+	rule := &types.RuleDTO{
+		Path:        "/rand/time",
+		Method:      "get",
+		Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{IsTemplate: true, Body: `{{randTime "2020-06-01T12:00:00Z" "1h"}}`}}},
+	}
+	sequence := func() []string {
+		setupMockApplication(t, option.MockOption{TimeRandSeed: 7}, rule)
+		ret := make([]string, 5)
+		for i := range ret {
+			ctx := newRequestCtx("GET", "/rand/time", nil)
+			HandleMockedAPI(ctx, nil)
+			ret[i] = string(ctx.Response.Body())
+		}
+		return ret
+	}
+	// 相同的种子生成相同的时间序列
+	assert.Equal(t, sequence(), sequence())
+}
+
+func TestHandleMockedAPI_BoolRandSeed(t *testing.T) {
+	rule := &types.RuleDTO{
+		Path:        "/rand/bool",
+		Method:      "get",
+		Regulations: []*types.RegulationDTO{{IsDefault: true, Template: &types.TemplateDTO{IsTemplate: true, Body: `{{randBool 0.5}}`}}},
+	}
+	sequence := func() []string {
+		setupMockApplication(t, option.MockOption{BoolRandSeed: 7}, rule)
+		ret := make([]string, 20)
+		for i := range ret {
+			ctx := newRequestCtx("GET", "/rand/bool", nil)
+			HandleMockedAPI(ctx, nil)
+			ret[i] = string(ctx.Response.Body())
+		}
+		return ret
+	}
+	// 相同的种子生成相同的结果序列
+	first := sequence()
+	assert.Equal(t, first, sequence())
+	assert.Contains(t, first, "true")
+	assert.Contains(t, first, "false")
+}
@@ -9,6 +9,7 @@ import (
 func BuildRouter() *lu.Lu {
 	app := lu.New()
 
+	app.Post("/api/v1/rule/trace", api.HandleTraceRule) // 需要在/api/v1/rule之前注册
 	app.Get("/api/v1/rule", api.HandleGetRule)
 	app.Post("/api/v1/rule", api.HandleCreateRule)
 	app.Put("/api/v1/rule", api.HandlePutRule)
@@ -16,10 +17,27 @@ func BuildRouter() *lu.Lu {
 	app.Delete("/api/v1/rule", api.HandleDeleteRule)
 
 	app.Get("/api/version", api.HandleAPIVersion)
+	app.Get("/api/health", api.HandleHealth)
+	app.Get("/api/metrics", api.HandleMetrics)
 
+	app.Get("/api/v1/rules/list", api.HandleListRules) // 需要在/api/v1/rules之前注册，否则会被导出接口匹配
+	app.Get("/api/v1/rules/ids", api.HandleListRuleIDs)
 	app.Get("/api/v1/rules", api.HandleExportRules)
 	app.Post("/api/v1/rules", api.HandleImportRules)
 
+	app.Get("/api/v1/requests/count", api.HandleGetRequestCount) // 需要在/api/v1/requests之前注册
+	app.Get("/api/v1/requests", api.HandleGetRequestLog)
+	app.Get("/api/v1/hits", api.HandleGetHits)
+	app.Post("/api/v1/sequence/reset", api.HandleResetSequence)
+
+	app.Post("/api/v1/template/render", api.HandleRenderTemplate)
+	app.Post("/api/v1/template/compile", api.HandleCompileTemplate)
+	app.Get("/api/v1/template/funcs", api.HandleListTemplateFuncs)
+	app.Post("/api/v1/template/funcs", api.HandleInspectTemplateFuncs)
+
+	app.Get("/api/v1/record", api.HandleGetRecord)
+	app.Put("/api/v1/record", api.HandleSetRecord)
+
 	app.Use("/", api.HandleMockedAPI)
 	return app
 }
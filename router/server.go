@@ -0,0 +1,47 @@
+package router
+
+import (
+	"github.com/valyala/fasthttp"
+	"github.com/wosai/deepmock/option"
+)
+
+// BuildServer http server的工厂函数，fasthttp会在读取完整的请求body后才调用handler，
+// 即使响应中没有使用body，客户端上传较大的报文时也不会因为连接提前关闭而失败；
+// 但超过MaxRequestBodySize的body会在读取前直接返回413，客户端仍在上传时可能写入失败，
+// 设置MaxDrainBodySize后，不超过该值的超限body同样读取完整后才返回413
+func BuildServer(opt option.ServerOption, handler fasthttp.RequestHandler) *fasthttp.Server {
+	maxBodySize := opt.MaxRequestBodySize
+	if opt.MaxRequestBodySize > 0 && opt.MaxDrainBodySize > opt.MaxRequestBodySize {
+		maxBodySize = opt.MaxDrainBodySize
+		handler = rejectDrainedBody(opt.MaxRequestBodySize, handler)
+	}
+	return &fasthttp.Server{
+		Name:               "DeepMock Service",
+		Handler:            handler,
+		Concurrency:        1024 * 1024,
+		MaxRequestBodySize: maxBodySize,
+		ErrorHandler:       handleServerError,
+	}
+}
+
+// rejectDrainedBody 已经读取完整但超过上限的body返回413，与handleServerError的响应一致
+func rejectDrainedBody(limit int, handler fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		if len(ctx.Request.Body()) > limit {
+			ctx.Error("Request body exceeds the size limit", fasthttp.StatusRequestEntityTooLarge)
+			return
+		}
+		handler(ctx)
+	}
+}
+
+// handleServerError 读取或解析请求失败时的响应，body超过上限时返回413而不是默认的400
+func handleServerError(ctx *fasthttp.RequestCtx, err error) {
+	if _, ok := err.(*fasthttp.ErrSmallBuffer); ok {
+		ctx.Error("Too big request header", fasthttp.StatusRequestHeaderFieldsTooLarge)
+	} else if err == fasthttp.ErrBodyTooLarge {
+		ctx.Error("Request body exceeds the size limit", fasthttp.StatusRequestEntityTooLarge)
+	} else {
+		ctx.Error("Error when parsing request", fasthttp.StatusBadRequest)
+	}
+}
@@ -0,0 +1,94 @@
+package router
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+	"github.com/wosai/deepmock/option"
+)
+
+func TestBuildServer_LargeBody(t *testing.T) {
+	var received int
+	server := BuildServer(option.ServerOption{MaxRequestBodySize: 16 << 20}, func(ctx *fasthttp.RequestCtx) {
+		received = len(ctx.Request.Body())
+		ctx.SetBodyString("ok") // 响应中不使用body
+	})
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+	go server.Serve(ln)
+	client := &fasthttp.Client{Dial: func(string) (net.Conn, error) { return ln.Dial() }}
+
+	upload := func(size int) *fasthttp.Response {
+		req := fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(req)
+		req.SetRequestURI("http://deepmock/upload")
+		req.Header.SetMethod("POST")
+		req.SetBody(bytes.Repeat([]byte("x"), size))
+		resp := new(fasthttp.Response)
+		assert.NoError(t, client.Do(req, resp), strconv.Itoa(size))
+		return resp
+	}
+
+	resp := upload(12 << 20)
+	assert.Equal(t, fasthttp.StatusOK, resp.StatusCode())
+	assert.Equal(t, "ok", string(resp.Body()))
+	assert.Equal(t, 12<<20, received)
+
+	// 连接复用时后续请求不受影响
+	resp = upload(1024)
+	assert.Equal(t, fasthttp.StatusOK, resp.StatusCode())
+	assert.Equal(t, 1024, received)
+
+}
+
+func TestBuildServer_DrainOversizedBody(t *testing.T) {
+	var called int
+	server := BuildServer(option.ServerOption{MaxRequestBodySize: 1 << 20, MaxDrainBodySize: 4 << 20}, func(ctx *fasthttp.RequestCtx) {
+		called++
+		ctx.SetBodyString("ok")
+	})
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+	go server.Serve(ln)
+	client := &fasthttp.Client{Dial: func(string) (net.Conn, error) { return ln.Dial() }}
+
+	upload := func(size int) *fasthttp.Response {
+		req := fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(req)
+		req.SetRequestURI("http://deepmock/upload")
+		req.Header.SetMethod("POST")
+		req.SetBody(bytes.Repeat([]byte("x"), size))
+		resp := new(fasthttp.Response)
+		assert.NoError(t, client.Do(req, resp), strconv.Itoa(size))
+		return resp
+	}
+
+	// 超过上限的body被完整读取后才返回413，客户端的上传不会失败
+	resp := upload(2 << 20)
+	assert.Equal(t, fasthttp.StatusRequestEntityTooLarge, resp.StatusCode())
+	assert.Equal(t, 0, called)
+	resp = upload(4 << 20)
+	assert.Equal(t, fasthttp.StatusRequestEntityTooLarge, resp.StatusCode())
+
+	// 不超过上限的请求正常处理
+	resp = upload(1 << 20)
+	assert.Equal(t, fasthttp.StatusOK, resp.StatusCode())
+	assert.Equal(t, "ok", string(resp.Body()))
+	assert.Equal(t, 1, called)
+
+	// 超过MaxDrainBodySize时根据Content-Length直接拒绝，不再读取body
+	conn, err := ln.Dial()
+	assert.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("POST /upload HTTP/1.1\r\nHost: deepmock\r\nContent-Length: " + strconv.Itoa(5<<20) + "\r\n\r\n"))
+	assert.NoError(t, err)
+	resp = new(fasthttp.Response)
+	assert.NoError(t, resp.Read(bufio.NewReader(conn)))
+	assert.Equal(t, fasthttp.StatusRequestEntityTooLarge, resp.StatusCode())
+}
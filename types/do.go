@@ -5,15 +5,30 @@ import "time"
 type (
 	// RuleDO Rule在mysql存储结构
 	RuleDO struct {
-		ID        string    `ddb:"id"`
-		Path      string    `ddb:"path"`
-		Method    string    `ddb:"method"`
-		Variable  []byte    `ddb:"variable"`
-		Weight    []byte    `ddb:"weight"`
-		Responses []byte    `ddb:"responses"`
-		Version   int       `ddb:"version"`
-		CTime     time.Time `ddb:"ctime"`
-		MTime     time.Time `ddb:"mtime"`
-		Disabled  bool      `ddb:"disabled"`
+		ID               string    `ddb:"id"`
+		Path             string    `ddb:"path"`
+		PathType         string    `ddb:"path_type"`
+		Host             string    `ddb:"host"`
+		HostType         string    `ddb:"host_type"`
+		Method           string    `ddb:"method"`
+		Variable         []byte    `ddb:"variable"`
+		Weight           []byte    `ddb:"weight"`
+		Responses        []byte    `ddb:"responses"`
+		Version          int       `ddb:"version"`
+		CTime            time.Time `ddb:"ctime"`
+		MTime            time.Time `ddb:"mtime"`
+		Disabled         bool      `ddb:"disabled"`
+		Debug            bool      `ddb:"debug"`
+		RateLimit        uint      `ddb:"rate_limit"`
+		CORS             []byte    `ddb:"cors"`
+		MaxConcurrency   uint      `ddb:"max_concurrency"`
+		SlowStart        []byte    `ddb:"slow_start"`
+		OverflowResponse []byte    `ddb:"overflow_response"`
+		Duplicate        []byte    `ddb:"duplicate"`
+		ResponseSchema   []byte    `ddb:"response_schema"`
+		TTLSeconds       uint      `ddb:"ttl_seconds"`
+		StatusDelay      []byte    `ddb:"status_delay"`
+		Priority         int       `ddb:"priority"`
+		Maintenance      []byte    `ddb:"maintenance"`
 	}
 )
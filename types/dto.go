@@ -1,5 +1,11 @@
 package types
 
+import (
+	"time"
+
+	"github.com/wosai/deepmock/misc"
+)
+
 type (
 	// CommonResponseDTO 通用的返回报文结构体
 	CommonResponseDTO struct {
@@ -10,12 +16,72 @@ type (
 
 	// RuleDTO Rule的HTTP报文结构
 	RuleDTO struct {
-		ID          string           `json:"id,omitempty"`
-		Path        string           `json:"path,omitempty"`
-		Method      string           `json:"method,omitempty"`
-		Variable    VariableDTO      `json:"variable,omitempty"`
-		Weight      WeightDTO        `json:"weight,omitempty"`
-		Regulations []*RegulationDTO `json:"responses,omitempty"`
+		ID               string           `json:"id,omitempty" yaml:"id,omitempty"`
+		Path             string           `json:"path,omitempty" yaml:"path,omitempty"`
+		PathType         string           `json:"path_type,omitempty" yaml:"path_type,omitempty"` // path的语法，regex(默认)或glob
+		Host             string           `json:"host,omitempty" yaml:"host,omitempty"`           // 匹配请求的Host头，为空时匹配任意Host
+		HostType         string           `json:"host_type,omitempty" yaml:"host_type,omitempty"` // host的语法，exact(默认)或regex
+		Method           string           `json:"method,omitempty" yaml:"method,omitempty"`
+		Version          *int             `json:"version,omitempty" yaml:"version,omitempty"` // 更新规则时提供则作为乐观锁(包括0)，与当前版本不一致时拒绝更新
+		Variable         VariableDTO      `json:"variable,omitempty" yaml:"variable,omitempty"`
+		Weight           WeightDTO        `json:"weight,omitempty" yaml:"weight,omitempty"`
+		Regulations      []*RegulationDTO `json:"responses,omitempty" yaml:"responses,omitempty"`
+		Enabled          *bool            `json:"enabled,omitempty" yaml:"enabled,omitempty"` // 未设置时视为启用
+		Debug            bool             `json:"debug,omitempty" yaml:"debug,omitempty"`
+		RateLimit        uint             `json:"rate_limit,omitempty" yaml:"rate_limit,omitempty"`
+		CORS             *CORSDTO         `json:"cors,omitempty" yaml:"cors,omitempty"`
+		MaxConcurrency   uint             `json:"max_concurrency,omitempty" yaml:"max_concurrency,omitempty"`
+		SlowStart        *SlowStartDTO    `json:"slow_start,omitempty" yaml:"slow_start,omitempty"`
+		OverflowResponse *TemplateDTO     `json:"overflow_response,omitempty" yaml:"overflow_response,omitempty"`
+		Duplicate        *DuplicateDTO    `json:"duplicate,omitempty" yaml:"duplicate,omitempty"`
+		// ResponseSchema 响应报文的JSON Schema，调试模式下渲染结果不符合时返回500
+		ResponseSchema map[string]interface{} `json:"response_schema,omitempty" yaml:"response_schema,omitempty"`
+		// TTLSeconds 规则自创建起的存活秒数，过期后自动删除，0表示永不过期
+		TTLSeconds  uint            `json:"ttl_seconds,omitempty" yaml:"ttl_seconds,omitempty"`
+		StatusDelay *StatusDelayDTO `json:"status_delay,omitempty" yaml:"status_delay,omitempty"`
+		// Priority 规则的优先级，多条规则的path匹配同一个请求时优先级高的规则响应
+		Priority    int             `json:"priority,omitempty" yaml:"priority,omitempty"`
+		Maintenance *MaintenanceDTO `json:"maintenance,omitempty" yaml:"maintenance,omitempty"`
+	}
+
+	// MaintenanceDTO 计划维护配置的HTTP报文结构
+	MaintenanceDTO struct {
+		Windows  []*MaintenanceWindowDTO `json:"windows" yaml:"windows"`
+		Response *TemplateDTO            `json:"response,omitempty" yaml:"response,omitempty"`
+	}
+
+	// MaintenanceWindowDTO 维护窗口的HTTP报文结构，start与end同为RFC3339时间或同为HH:MM
+	MaintenanceWindowDTO struct {
+		Start    string `json:"start" yaml:"start"`
+		End      string `json:"end" yaml:"end"`
+		Weekdays []int  `json:"weekdays,omitempty" yaml:"weekdays,omitempty"`
+	}
+
+	// DuplicateDTO 重复提交检测配置的HTTP报文结构
+	DuplicateDTO struct {
+		WindowSeconds uint         `json:"window_seconds" yaml:"window_seconds"`
+		Response      *TemplateDTO `json:"response" yaml:"response"`
+	}
+
+	// CORSDTO 跨域配置的HTTP报文结构
+	CORSDTO struct {
+		AllowOrigins []string `json:"allow_origins,omitempty" yaml:"allow_origins,omitempty"`
+		AllowMethods []string `json:"allow_methods,omitempty" yaml:"allow_methods,omitempty"`
+		AllowHeaders []string `json:"allow_headers,omitempty" yaml:"allow_headers,omitempty"`
+		MaxAge       int      `json:"max_age,omitempty" yaml:"max_age,omitempty"`
+	}
+
+	// StatusDelayDTO 按响应状态码延迟配置的HTTP报文结构
+	StatusDelayDTO struct {
+		DelayMS     uint  `json:"delay_ms" yaml:"delay_ms"`
+		StatusCodes []int `json:"status_codes" yaml:"status_codes"`
+	}
+
+	// SlowStartDTO 慢启动配置的HTTP报文结构
+	SlowStartDTO struct {
+		DelayMS  uint `json:"delay_ms" yaml:"delay_ms"`
+		Requests uint `json:"requests,omitempty" yaml:"requests,omitempty"`
+		Seconds  uint `json:"seconds,omitempty" yaml:"seconds,omitempty"`
 	}
 
 	// VariableDTO 变量的HTTP报文结构
@@ -26,24 +92,280 @@ type (
 
 	// RegulationDTO 响应报文规则的结构
 	RegulationDTO struct {
-		IsDefault bool         `json:"is_default,omitempty"`
-		Filter    *FilterDTO   `json:"filter,omitempty"`
-		Template  *TemplateDTO `json:"response,omitempty"`
+		IsDefault      bool         `json:"is_default,omitempty" yaml:"is_default,omitempty"`
+		Filter         *FilterDTO   `json:"filter,omitempty" yaml:"filter,omitempty"`
+		Template       *TemplateDTO `json:"response,omitempty" yaml:"response,omitempty"`
+		ReflectHeaders []string     `json:"reflect_headers,omitempty" yaml:"reflect_headers,omitempty"`
+		Sequence       *SequenceDTO `json:"sequence,omitempty" yaml:"sequence,omitempty"`
+		Retry          *RetryDTO    `json:"retry,omitempty" yaml:"retry,omitempty"`
+		Created        *CreatedDTO  `json:"created,omitempty" yaml:"created,omitempty"`
+	}
+
+	// CreatedDTO 模拟创建资源的HTTP报文结构
+	CreatedDTO struct {
+		Location string `json:"location" yaml:"location"`
+		IDField  string `json:"id_field,omitempty" yaml:"id_field,omitempty"`
+		IDType   string `json:"id_type,omitempty" yaml:"id_type,omitempty"`
+	}
+
+	// RetryDTO 按重试次数返回响应的HTTP报文结构
+	RetryDTO struct {
+		Header    string         `json:"header,omitempty" yaml:"header,omitempty"`
+		Responses []*TemplateDTO `json:"responses" yaml:"responses"`
+	}
+
+	// SequenceDTO 顺序响应的HTTP报文结构
+	SequenceDTO struct {
+		Responses []*TemplateDTO `json:"responses" yaml:"responses"`
+		Loop      bool           `json:"loop,omitempty" yaml:"loop,omitempty"`
 	}
 
 	// FilterDTO 筛选器的HTTP报文结构
 	FilterDTO struct {
-		Header map[string]string `json:"header,omitempty"`
-		Query  map[string]string `json:"query,omitempty"`
-		Body   map[string]string `json:"body,omitempty"`
+		Header        map[string]string       `json:"header,omitempty" yaml:"header,omitempty"`
+		Cookie        map[string]string       `json:"cookie,omitempty" yaml:"cookie,omitempty"`
+		Query         map[string]string       `json:"query,omitempty" yaml:"query,omitempty"`
+		RawQuery      map[string]string       `json:"raw_query,omitempty" yaml:"raw_query,omitempty"`
+		Body          map[string]string       `json:"body,omitempty" yaml:"body,omitempty"`
+		Expression    string                  `json:"expression,omitempty" yaml:"expression,omitempty"`
+		ContentLength *ContentLengthFilterDTO `json:"content_length,omitempty" yaml:"content_length,omitempty"`
+		PathSegments  *PathSegmentsFilterDTO  `json:"path_segments,omitempty" yaml:"path_segments,omitempty"`
+		UserAgent     *UserAgentFilterDTO     `json:"user_agent,omitempty" yaml:"user_agent,omitempty"`
+		Sample        *SampleFilterDTO        `json:"sample,omitempty" yaml:"sample,omitempty"`
+		Upload        *UploadFilterDTO        `json:"upload,omitempty" yaml:"upload,omitempty"`
+		Form          map[string]string       `json:"form,omitempty" yaml:"form,omitempty"`
+	}
+
+	// UploadFilterDTO 文件上传筛选器的HTTP报文结构
+	UploadFilterDTO struct {
+		Field       string `json:"field,omitempty" yaml:"field,omitempty"`
+		ContentType string `json:"content_type,omitempty" yaml:"content_type,omitempty"`
+	}
+
+	// SampleFilterDTO 抽样筛选器的HTTP报文结构
+	SampleFilterDTO struct {
+		Source  string  `json:"source" yaml:"source"`
+		Key     string  `json:"key" yaml:"key"`
+		Percent float64 `json:"percent" yaml:"percent"`
+		Salt    string  `json:"salt,omitempty" yaml:"salt,omitempty"`
+	}
+
+	// UserAgentFilterDTO User-Agent筛选器的HTTP报文结构
+	UserAgentFilterDTO struct {
+		Clients  []string               `json:"clients" yaml:"clients"`
+		Patterns []*UserAgentPatternDTO `json:"patterns,omitempty" yaml:"patterns,omitempty"`
+	}
+
+	// UserAgentPatternDTO 自定义User-Agent客户端类别的HTTP报文结构
+	UserAgentPatternDTO struct {
+		Client string `json:"client" yaml:"client"`
+		Regex  string `json:"regex" yaml:"regex"`
+	}
+
+	// PathSegmentsFilterDTO 请求路径段数筛选器的HTTP报文结构
+	PathSegmentsFilterDTO struct {
+		Exact int `json:"exact,omitempty" yaml:"exact,omitempty"`
+		Min   int `json:"min,omitempty" yaml:"min,omitempty"`
+		Max   int `json:"max,omitempty" yaml:"max,omitempty"`
+	}
+
+	// ContentLengthFilterDTO Content-Length筛选器的HTTP报文结构
+	ContentLengthFilterDTO struct {
+		Min          int  `json:"min,omitempty" yaml:"min,omitempty"`
+		Max          int  `json:"max,omitempty" yaml:"max,omitempty"`
+		AllowUnknown bool `json:"allow_unknown,omitempty" yaml:"allow_unknown,omitempty"`
 	}
 
 	// TemplateDTO 模板的HTTP报文结构
 	TemplateDTO struct {
-		IsTemplate    bool              `json:"is_template,omitempty"`
-		Header        map[string]string `json:"header,omitempty"`
-		StatusCode    int               `json:"status_code,omitempty"`
-		Body          string            `json:"body,omitempty"`
-		B64EncodeBody string            `json:"base64encoded_body,omitempty"`
+		IsTemplate     bool                         `json:"is_template,omitempty" yaml:"is_template,omitempty"`
+		Header         map[string]misc.StringValues `json:"header,omitempty" yaml:"header,omitempty"`
+		StatusCode     int                          `json:"status_code,omitempty" yaml:"status_code,omitempty"`
+		StatusTemplate string                       `json:"status_template,omitempty" yaml:"status_template,omitempty"`
+		Body           string                       `json:"body,omitempty" yaml:"body,omitempty"`
+		B64EncodeBody  string                       `json:"base64encoded_body,omitempty" yaml:"base64encoded_body,omitempty"`
+		BodyFile       string                       `json:"body_file,omitempty" yaml:"body_file,omitempty"`
+		Compress       string                       `json:"compress,omitempty" yaml:"compress,omitempty"`
+		Multipart      []*PartDTO                   `json:"multipart,omitempty" yaml:"multipart,omitempty"`
+		MultipartType  string                       `json:"multipart_type,omitempty" yaml:"multipart_type,omitempty"`
+		Fragments      []string                     `json:"fragments,omitempty" yaml:"fragments,omitempty"`           // 依次拼接的模板片段名称，与body互斥
+		Locales        map[string]string            `json:"locales,omitempty" yaml:"locales,omitempty"`               // 按Accept-Language协商的本地化body
+		DefaultLocale  string                       `json:"default_locale,omitempty" yaml:"default_locale,omitempty"` // 都不匹配时使用的语言标签
+	}
+
+	// PartDTO multipart响应报文中一个部分的HTTP报文结构
+	PartDTO struct {
+		Name     string            `json:"name,omitempty" yaml:"name,omitempty"`
+		FileName string            `json:"filename,omitempty" yaml:"filename,omitempty"`
+		Header   map[string]string `json:"header,omitempty" yaml:"header,omitempty"`
+		Body     string            `json:"body,omitempty" yaml:"body,omitempty"`
+	}
+
+	// RenderTemplateDTO 试渲染模板的请求报文结构，除模板外的字段组成模拟的渲染上下文
+	RenderTemplateDTO struct {
+		Template   *TemplateDTO           `json:"response"`
+		Variable   VariableDTO            `json:"variable,omitempty"`
+		Weight     map[string]string      `json:"weight,omitempty"`
+		Header     map[string]string      `json:"header,omitempty"`
+		Query      map[string]string      `json:"query,omitempty"`
+		Form       map[string]string      `json:"form,omitempty"`
+		Json       map[string]interface{} `json:"json,omitempty"`
+		Cookie     map[string]string      `json:"cookie,omitempty"`
+		RemoteAddr string                 `json:"remote_addr,omitempty"`
+	}
+
+	// RenderedTemplateDTO 试渲染模板的结果
+	RenderedTemplateDTO struct {
+		StatusCode    int                          `json:"status_code"`
+		Header        map[string]misc.StringValues `json:"header,omitempty"`
+		Body          string                       `json:"body,omitempty"`
+		B64EncodeBody string                       `json:"base64encoded_body,omitempty"`
+	}
+
+	// CompileTemplateDTO 编译测试模板的请求报文结构，除模板外的字段组成模拟的渲染上下文
+	CompileTemplateDTO struct {
+		Template   string                 `json:"template"`
+		Variable   VariableDTO            `json:"variable,omitempty"`
+		Weight     map[string]string      `json:"weight,omitempty"`
+		Header     map[string]string      `json:"header,omitempty"`
+		Query      map[string]string      `json:"query,omitempty"`
+		Form       map[string]string      `json:"form,omitempty"`
+		Json       map[string]interface{} `json:"json,omitempty"`
+		Cookie     map[string]string      `json:"cookie,omitempty"`
+		RemoteAddr string                 `json:"remote_addr,omitempty"`
+	}
+
+	// CompiledTemplateDTO 编译测试模板的结果
+	CompiledTemplateDTO struct {
+		Output string `json:"output"`
+	}
+
+	// InspectTemplateFuncsDTO 检查模板函数引用的请求报文结构
+	InspectTemplateFuncsDTO struct {
+		Template string `json:"template"`
+	}
+
+	// TemplateFuncsReportDTO 模板函数引用的检查结果，Valid表示不存在未注册的函数
+	TemplateFuncsReportDTO struct {
+		Valid        bool     `json:"valid"`
+		Functions    []string `json:"functions"`
+		Unregistered []string `json:"unregistered"`
+	}
+
+	// TraceRuleDTO 模拟请求评估规则筛选条件的请求报文结构，除ID外的字段组成模拟的请求，Method、Path为空时使用规则的method与path
+	TraceRuleDTO struct {
+		ID     string            `json:"id"`
+		Method string            `json:"method,omitempty"`
+		Path   string            `json:"path,omitempty"`
+		Query  string            `json:"query,omitempty"`
+		Header map[string]string `json:"header,omitempty"`
+		Body   string            `json:"body,omitempty"`
+	}
+
+	// RuleTraceDTO 规则筛选条件的评估结果，Regulation为命中的报文规则下标，没有命中任何报文规则时为-1
+	RuleTraceDTO struct {
+		RuleID      string                `json:"rule_id"`
+		Regulation  int                   `json:"regulation"`
+		IsDefault   bool                  `json:"is_default"`
+		Regulations []*RegulationTraceDTO `json:"regulations"`
+	}
+
+	// RegulationTraceDTO 单个报文规则的筛选条件评估结果，Passed表示所有筛选条件都通过
+	RegulationTraceDTO struct {
+		Index     int               `json:"index"`
+		IsDefault bool              `json:"is_default"`
+		Passed    bool              `json:"passed"`
+		Filters   []*FilterTraceDTO `json:"filters"`
+	}
+
+	// FilterTraceDTO 单个筛选条件的评估结果，Key为请求头、cookie、query或表单筛选中的参数名
+	FilterTraceDTO struct {
+		Filter string `json:"filter"`
+		Key    string `json:"key,omitempty"`
+		Passed bool   `json:"passed"`
+	}
+
+	// RuleQueryDTO 分页查询规则的条件，Limit为0时返回offset之后的所有规则
+	RuleQueryDTO struct {
+		Offset       int
+		Limit        int
+		Method       string
+		PathContains string
+	}
+
+	// RuleListDTO 分页查询规则的结果，Total为符合条件的规则总数
+	RuleListDTO struct {
+		Total int        `json:"total"`
+		Rules []*RuleDTO `json:"rules"`
+	}
+
+	// RuleSummaryDTO 规则的概要信息，用于列出所有规则而不返回完整的规则定义
+	RuleSummaryDTO struct {
+		ID     string `json:"id"`
+		Path   string `json:"path"`
+		Method string `json:"method"`
+	}
+
+	// ImportReportDTO 仅校验导入规则时返回的校验报告
+	ImportReportDTO struct {
+		Valid bool                 `json:"valid"`
+		Rules []*RuleValidationDTO `json:"rules"`
+	}
+
+	// RuleValidationDTO 单条规则的校验结果，Index为规则在导入报文中的位置
+	RuleValidationDTO struct {
+		Index  int    `json:"index"`
+		ID     string `json:"id,omitempty"`
+		Path   string `json:"path"`
+		Method string `json:"method"`
+		Valid  bool   `json:"valid"`
+		Error  string `json:"error,omitempty"`
+	}
+
+	// RecordDTO 录制模式开关的HTTP报文结构
+	RecordDTO struct {
+		Enabled bool `json:"enabled"`
+	}
+
+	// RuleHitsDTO 规则命中次数统计的HTTP报文结构
+	RuleHitsDTO struct {
+		RuleID      string     `json:"rule_id"`
+		Path        string     `json:"path"`
+		Method      string     `json:"method"`
+		Total       uint64     `json:"total"`
+		LastHit     *time.Time `json:"last_hit,omitempty"`
+		Regulations []*HitsDTO `json:"regulations"`
+	}
+
+	// HitsDTO 报文规则命中次数统计的HTTP报文结构
+	HitsDTO struct {
+		Total   uint64     `json:"total"`
+		LastHit *time.Time `json:"last_hit,omitempty"`
+	}
+
+	// HealthDTO 存活检查的HTTP报文结构
+	HealthDTO struct {
+		Status        string `json:"status"`
+		UptimeSeconds int64  `json:"uptime_seconds"`
+		Rules         int    `json:"rules"` // 已载入的规则数
+		Version       string `json:"version"`
+	}
+
+	// RequestCountDTO mock请求计数的HTTP报文结构
+	RequestCountDTO struct {
+		Total     uint64 `json:"total"`
+		Unmatched uint64 `json:"unmatched"` // 未匹配到任何规则的请求数，不含解码失败等在匹配前被拒绝的请求
+	}
+
+	// RequestLogDTO mock请求记录的HTTP报文结构
+	RequestLogDTO struct {
+		Time       time.Time         `json:"time"`
+		Method     string            `json:"method"`
+		Path       string            `json:"path"`
+		Query      string            `json:"query,omitempty"`
+		Header     map[string]string `json:"header,omitempty"`
+		Body       string            `json:"body,omitempty"`
+		RuleID     string            `json:"rule_id,omitempty"`
+		Regulation int               `json:"regulation"` // 命中的response regulation下标，未命中时为-1
 	}
 )